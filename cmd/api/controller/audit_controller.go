@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"SangXanh/cmd/api/middleware"
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/permission"
+	"SangXanh/pkg/service"
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do/v2"
+)
+
+type auditController struct {
+	auditService   service.AuditService
+	authMiddleware echo.MiddlewareFunc
+}
+
+func NewAuditController(di do.Injector, auth echo.MiddlewareFunc) (api.Controller, error) {
+	return &auditController{
+		auditService:   do.MustInvoke[service.AuditService](di),
+		authMiddleware: auth,
+	}, nil
+}
+
+func (c *auditController) Register(g *echo.Group) {
+	g = g.Group("/audit")
+	g.GET("", c.List, c.authMiddleware, middleware.Require(permission.Audit, permission.Read))
+}
+
+func (c *auditController) List(e echo.Context) error {
+	return api.Execute[dto.AuditFilter](e, func(ctx context.Context, req dto.AuditFilter) (api.Response, error) {
+		return c.auditService.ListAudits(ctx, req)
+	})
+}
@@ -1,7 +1,9 @@
 package controller
 
 import (
+	"SangXanh/cmd/api/middleware"
 	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/dto"
 	"SangXanh/pkg/service"
 	"context"
 	"github.com/labstack/echo/v4"
@@ -25,14 +27,23 @@ func (c *authController) Register(g *echo.Group) {
 	g.POST("/login", c.Login)
 	g.POST("/refresh", c.Refresh)
 	g.GET("/current-user", c.CurrentUser, c.auth)
+	g.GET("/sessions", c.Sessions, c.auth)
+	g.POST("/logout", c.Logout, c.auth)
+	g.POST("/logout-all", c.LogoutAll, c.auth) // every device of the user
 }
 
 func (c *authController) Login(e echo.Context) error {
-	return api.Execute(e, c.authService.Login)
+	return api.Execute(e, func(ctx context.Context, req dto.LoginRequest) (api.Response, error) {
+		req.Client = middleware.SessionClient(e)
+		return c.authService.Login(ctx, req)
+	})
 }
 
 func (c *authController) Refresh(e echo.Context) error {
-	return api.Execute(e, c.authService.Refresh)
+	return api.Execute(e, func(ctx context.Context, req dto.RefreshTokenRequest) (api.Response, error) {
+		req.Client = middleware.SessionClient(e)
+		return c.authService.Refresh(ctx, req)
+	})
 }
 
 func (c *authController) CurrentUser(e echo.Context) error {
@@ -40,3 +51,21 @@ func (c *authController) CurrentUser(e echo.Context) error {
 		return c.authService.GetCurrentUser(ctx)
 	})
 }
+
+func (c *authController) Sessions(e echo.Context) error {
+	return api.Execute(e, func(ctx context.Context, _ struct{}) (api.Response, error) {
+		return c.authService.ListSessions(ctx)
+	})
+}
+
+func (c *authController) Logout(e echo.Context) error {
+	return api.Execute(e, func(ctx context.Context, _ struct{}) (api.Response, error) {
+		return c.authService.Logout(ctx)
+	})
+}
+
+func (c *authController) LogoutAll(e echo.Context) error {
+	return api.Execute(e, func(ctx context.Context, _ struct{}) (api.Response, error) {
+		return c.authService.LogoutAll(ctx)
+	})
+}
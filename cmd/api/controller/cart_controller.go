@@ -1,8 +1,9 @@
 package controller
 
 import (
+	"SangXanh/cmd/api/middleware"
 	"SangXanh/pkg/common/api"
-	"SangXanh/pkg/dto"
+	"SangXanh/pkg/permission"
 	"SangXanh/pkg/service"
 	"context"
 	"github.com/labstack/echo/v4"
@@ -23,31 +24,25 @@ func NewCartController(di do.Injector, auth echo.MiddlewareFunc) (api.Controller
 
 func (c *cartController) Register(g *echo.Group) {
 	g = g.Group("/cart")
-	g.GET("", c.List, c.authMiddleware)             // List all carts for the current user
-	g.POST("/create", c.Create, c.authMiddleware)   // Create a new cart
-	g.PUT("/update", c.Update, c.authMiddleware)    // Update cart quantity
-	g.DELETE("/delete", c.Delete, c.authMiddleware) // Delete a cart
+	g.GET("", c.List, c.authMiddleware, middleware.Require(permission.Carts, permission.Read))                  // List all carts for the current user
+	g.POST("/create", c.Create, c.authMiddleware, middleware.Require(permission.Carts, permission.Create))      // Create a new cart
+	g.PUT("/update", c.Update, c.authMiddleware, middleware.Require(permission.Carts, permission.Update))       // Update cart quantity
+	g.DELETE("/delete", c.Delete, c.authMiddleware, middleware.Require(permission.Carts, permission.Delete))    // Delete a cart
+	g.POST("/checkout", c.Checkout, c.authMiddleware, middleware.Require(permission.Orders, permission.Create)) // Turn the cart into an order
 }
 
 func (c *cartController) List(e echo.Context) error {
-	userID := e.Get("user_id").(string) // Get user ID from the access token
 	return api.Execute(e, func(ctx context.Context, _ struct{}) (api.Response, error) {
-		return c.cartService.GetCartsByUserID(ctx, userID)
+		return c.cartService.ListCarts(ctx)
 	})
 }
 
 func (c *cartController) Create(e echo.Context) error {
-	userID := e.Get("user_id").(string) // Get user ID from the access token
-	return api.Execute(e, func(ctx context.Context, req dto.CartCreateRequest) (api.Response, error) {
-		return c.cartService.CreateCart(ctx, req, userID)
-	})
+	return api.Execute(e, c.cartService.CreateCart)
 }
 
 func (c *cartController) Update(e echo.Context) error {
-	userID := e.Get("user_id").(string)
-	return api.Execute(e, func(ctx context.Context, req dto.CartUpdate) (api.Response, error) {
-		return c.cartService.UpdateCart(ctx, req, userID)
-	})
+	return api.Execute(e, c.cartService.UpdateCart)
 }
 
 func (c *cartController) Delete(e echo.Context) error {
@@ -56,3 +51,7 @@ func (c *cartController) Delete(e echo.Context) error {
 		return c.cartService.DeleteCart(ctx, id)
 	})
 }
+
+func (c *cartController) Checkout(e echo.Context) error {
+	return api.Execute(e, c.cartService.Checkout)
+}
@@ -4,6 +4,7 @@ import (
 	"SangXanh/cmd/api/middleware"
 	"SangXanh/pkg/common/api"
 	"SangXanh/pkg/dto"
+	"SangXanh/pkg/permission"
 	"SangXanh/pkg/service"
 	"context"
 	"github.com/labstack/echo/v4"
@@ -25,10 +26,13 @@ func NewCategoryController(di do.Injector, middleware echo.MiddlewareFunc) (api.
 func (c *categoryController) Register(g *echo.Group) {
 	g = g.Group("/category")
 	g.GET("", c.List)
+	g.GET("/slug/:slug", c.GetBySlug)
 	g.GET("/:id", c.GetById)
-	g.POST("/create", c.Create, c.middleware, middleware.RequireRoles("admin"))
-	g.PUT("/update", c.Update, c.middleware, middleware.RequireRoles("admin"))
-	g.DELETE("/delete", c.Delete, c.middleware, middleware.RequireRoles("admin"))
+	g.POST("/create", c.Create, c.middleware, middleware.Require(permission.Categories, permission.Create))
+	g.PUT("/update", c.Update, c.middleware, middleware.Require(permission.Categories, permission.Update))
+	g.PUT("/move", c.Move, c.middleware, middleware.Require(permission.Categories, permission.Update))
+	g.PUT("/reorder", c.Reorder, c.middleware, middleware.Require(permission.Categories, permission.Update))
+	g.DELETE("/delete", c.Delete, c.middleware, middleware.Require(permission.Categories, permission.Delete))
 }
 
 func (c *categoryController) List(e echo.Context) error {
@@ -44,6 +48,13 @@ func (c *categoryController) GetById(e echo.Context) error {
 	})
 }
 
+func (c *categoryController) GetBySlug(e echo.Context) error {
+	slug := e.Param("slug")
+	return api.Execute(e, func(ctx context.Context, _ struct{}) (api.Response, error) {
+		return c.categoryService.GetCategoryBySlug(ctx, slug)
+	})
+}
+
 func (c *categoryController) Create(e echo.Context) error {
 	return api.Execute(e, c.categoryService.CreateCategory)
 }
@@ -52,9 +63,14 @@ func (c *categoryController) Update(e echo.Context) error {
 	return api.Execute(e, c.categoryService.UpdateCategory)
 }
 
+func (c *categoryController) Move(e echo.Context) error {
+	return api.Execute(e, c.categoryService.MoveCategory)
+}
+
+func (c *categoryController) Reorder(e echo.Context) error {
+	return api.Execute(e, c.categoryService.ReorderCategories)
+}
+
 func (c *categoryController) Delete(e echo.Context) error {
-	id := e.QueryParam("categoryId") // Get "id" parameter from URL
-	return api.Execute(e, func(ctx context.Context, _ struct{}) (api.Response, error) {
-		return c.categoryService.DeleteCategory(ctx, id)
-	})
+	return api.Execute(e, c.categoryService.DeleteCategory)
 }
@@ -1,7 +1,9 @@
 package controller
 
 import (
+	"SangXanh/cmd/api/middleware"
 	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/permission"
 	"SangXanh/pkg/service"
 	"context"
 	"mime/multipart"
@@ -12,18 +14,20 @@ import (
 )
 
 type imageController struct {
-	imageSvc service.ImageService
+	imageSvc       service.ImageService
+	authMiddleware echo.MiddlewareFunc
 }
 
 func NewImageController(di do.Injector, auth echo.MiddlewareFunc) (api.Controller, error) {
 	return &imageController{
-		imageSvc: do.MustInvoke[service.ImageService](di),
+		imageSvc:       do.MustInvoke[service.ImageService](di),
+		authMiddleware: auth,
 	}, nil
 }
 
 func (c *imageController) Register(g *echo.Group) {
 	g = g.Group("/image")
-	g.POST("/upload", c.Upload) // POST /image/upload
+	g.POST("/upload", c.Upload, c.authMiddleware, middleware.Require(permission.Images, permission.Create)) // POST /image/upload
 }
 
 func (c *imageController) Upload(e echo.Context) error {
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"SangXanh/cmd/api/middleware"
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/permission"
+	"SangXanh/pkg/service"
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do/v2"
+)
+
+type inventoryController struct {
+	inventoryService service.InventoryService
+	authMiddleware   echo.MiddlewareFunc
+}
+
+func NewInventoryController(di do.Injector, auth echo.MiddlewareFunc) (api.Controller, error) {
+	return &inventoryController{
+		inventoryService: do.MustInvoke[service.InventoryService](di),
+		authMiddleware:   auth,
+	}, nil
+}
+
+func (c *inventoryController) Register(g *echo.Group) {
+	g = g.Group("/inventory")
+	g.GET("/low-stock", c.LowStock, c.authMiddleware, middleware.Require(permission.Inventory, permission.Read))
+	g.POST("/adjust", c.Adjust, c.authMiddleware, middleware.Require(permission.Inventory, permission.Update))
+}
+
+func (c *inventoryController) LowStock(e echo.Context) error {
+	return api.Execute[dto.LowStockFilter](e, func(ctx context.Context, req dto.LowStockFilter) (api.Response, error) {
+		return c.inventoryService.ListLowStock(ctx, req)
+	})
+}
+
+func (c *inventoryController) Adjust(e echo.Context) error {
+	return api.Execute(e, c.inventoryService.Adjust)
+}
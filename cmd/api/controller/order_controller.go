@@ -1,9 +1,10 @@
 package controller
 
 import (
+	"SangXanh/cmd/api/middleware"
 	"SangXanh/pkg/common/api"
 	"SangXanh/pkg/dto"
-	"SangXanh/pkg/enum"
+	"SangXanh/pkg/permission"
 	"SangXanh/pkg/service"
 	"context"
 	"github.com/labstack/echo/v4"
@@ -24,12 +25,13 @@ func NewOrderController(di do.Injector, auth echo.MiddlewareFunc) (api.Controlle
 
 func (c *orderController) Register(g *echo.Group) {
 	g = g.Group("/order")
-	g.GET("", c.List, c.authMiddleware)
-	g.GET("/:id", c.GetById)
-	g.POST("/create", c.Create, c.authMiddleware)
-	g.PUT("/update", c.Update, c.authMiddleware)
-	g.DELETE("/delete", c.Delete, c.authMiddleware)
-	g.PUT("/update-status", c.UpdateStatus, c.authMiddleware)
+	g.GET("", c.List, c.authMiddleware, middleware.Require(permission.Orders, permission.Read))
+	g.GET("/:id", c.GetById, c.authMiddleware, middleware.Require(permission.Orders, permission.Read))
+	g.GET("/:id/history", c.History, c.authMiddleware, middleware.Require(permission.Orders, permission.Read))
+	g.POST("/create", c.Create, c.authMiddleware, middleware.Require(permission.Orders, permission.Create))
+	g.PUT("/update", c.Update, c.authMiddleware, middleware.Require(permission.Orders, permission.Update))
+	g.DELETE("/delete", c.Delete, c.authMiddleware, middleware.Require(permission.Orders, permission.Delete))
+	g.PUT("/update-status", c.UpdateStatus, c.authMiddleware, middleware.Require(permission.Orders, permission.Manage))
 }
 
 func (c *orderController) List(e echo.Context) error {
@@ -45,6 +47,13 @@ func (c *orderController) GetById(e echo.Context) error {
 	})
 }
 
+func (c *orderController) History(e echo.Context) error {
+	id := e.Param("id")
+	return api.Execute(e, func(ctx context.Context, _ struct{}) (api.Response, error) {
+		return c.orderService.GetOrderHistory(ctx, id)
+	})
+}
+
 func (c *orderController) Create(e echo.Context) error {
 	return api.Execute(e, c.orderService.CreateOrder)
 }
@@ -61,12 +70,5 @@ func (c *orderController) Delete(e echo.Context) error {
 }
 
 func (c *orderController) UpdateStatus(e echo.Context) error {
-	type Req struct {
-		OrderId string           `json:"order_id" validate:"required"`
-		Status  enum.OrderStatus `json:"status" validate:"required"`
-	}
-
-	return api.Execute[Req](e, func(ctx context.Context, req Req) (api.Response, error) {
-		return c.orderService.UpdateOrderStatus(ctx, req.OrderId, req.Status)
-	})
+	return api.Execute(e, c.orderService.UpdateOrderStatus)
 }
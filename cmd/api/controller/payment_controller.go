@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"SangXanh/cmd/api/middleware"
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/enum"
+	"SangXanh/pkg/permission"
+	"SangXanh/pkg/service"
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do/v2"
+	"io"
+)
+
+type paymentController struct {
+	paymentService service.PaymentService
+	authMiddleware echo.MiddlewareFunc
+}
+
+func NewPaymentController(di do.Injector, auth echo.MiddlewareFunc) (api.Controller, error) {
+	return &paymentController{
+		paymentService: do.MustInvoke[service.PaymentService](di),
+		authMiddleware: auth,
+	}, nil
+}
+
+func (c *paymentController) Register(g *echo.Group) {
+	g = g.Group("/payment")
+	g.POST("/create", c.Create, c.authMiddleware, middleware.Require(permission.Payments, permission.Create))
+	g.GET("/order/:orderId", c.ListByOrder, c.authMiddleware, middleware.Require(permission.Payments, permission.Read))
+	g.PUT("/confirm", c.Confirm, c.authMiddleware, middleware.Require(permission.Payments, permission.Manage))
+	// called by the gateways themselves, authenticated by the body signature
+	g.POST("/webhook/:method", c.Webhook)
+}
+
+func (c *paymentController) Create(e echo.Context) error {
+	return api.Execute(e, c.paymentService.CreatePayment)
+}
+
+func (c *paymentController) ListByOrder(e echo.Context) error {
+	orderId := e.Param("orderId")
+	return api.Execute(e, func(ctx context.Context, _ struct{}) (api.Response, error) {
+		return c.paymentService.GetOrderPayments(ctx, orderId)
+	})
+}
+
+func (c *paymentController) Confirm(e echo.Context) error {
+	return api.Execute(e, c.paymentService.ConfirmPayment)
+}
+
+// Webhook needs the raw body to check the signature, so it does not go through api.Execute.
+func (c *paymentController) Webhook(e echo.Context) error {
+	method := enum.PaymentMethod(e.Param("method"))
+	body, err := io.ReadAll(e.Request().Body)
+	if err != nil {
+		return api.Serve(e, nil, errors.BadRequest("failed to read webhook body"))
+	}
+	resp, err := c.paymentService.HandleWebhook(e.Request().Context(), method, e.Request().Header, body)
+	return api.Serve(e, resp, err)
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"SangXanh/cmd/api/middleware"
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/permission"
+	"SangXanh/pkg/service"
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do/v2"
+)
+
+type postController struct {
+	postService    service.PostService
+	authMiddleware echo.MiddlewareFunc
+}
+
+func NewPostController(di do.Injector, auth echo.MiddlewareFunc) (api.Controller, error) {
+	return &postController{
+		postService:    do.MustInvoke[service.PostService](di),
+		authMiddleware: auth,
+	}, nil
+}
+
+func (c *postController) Register(g *echo.Group) {
+	g = g.Group("/post")
+	g.GET("", c.List)
+	g.GET("/slug/:slug", c.GetBySlug)
+	g.GET("/manage", c.Manage, c.authMiddleware, middleware.Require(permission.Posts, permission.Read))
+	g.GET("/:id", c.GetById, c.authMiddleware, middleware.Require(permission.Posts, permission.Read))
+	g.POST("/create", c.Create, c.authMiddleware, middleware.Require(permission.Posts, permission.Create))
+	g.PUT("/update", c.Update, c.authMiddleware, middleware.Require(permission.Posts, permission.Update))
+	g.PUT("/assign", c.Assign, c.authMiddleware, middleware.Require(permission.Posts, permission.Manage))
+	g.PUT("/publish", c.Publish, c.authMiddleware, middleware.Require(permission.Posts, permission.Manage))
+	g.DELETE("/delete", c.Delete, c.authMiddleware, middleware.Require(permission.Posts, permission.Delete))
+}
+
+func (c *postController) List(e echo.Context) error {
+	return api.Execute[dto.PostFilter](e, func(ctx context.Context, req dto.PostFilter) (api.Response, error) {
+		return c.postService.ListPublishedPosts(ctx, req)
+	})
+}
+
+func (c *postController) Manage(e echo.Context) error {
+	return api.Execute[dto.PostFilter](e, func(ctx context.Context, req dto.PostFilter) (api.Response, error) {
+		return c.postService.ListPosts(ctx, req)
+	})
+}
+
+func (c *postController) GetBySlug(e echo.Context) error {
+	slug := e.Param("slug")
+	return api.Execute(e, func(ctx context.Context, _ struct{}) (api.Response, error) {
+		return c.postService.GetPostBySlug(ctx, slug)
+	})
+}
+
+func (c *postController) GetById(e echo.Context) error {
+	id := e.Param("id")
+	return api.Execute(e, func(ctx context.Context, _ struct{}) (api.Response, error) {
+		return c.postService.GetPostById(ctx, id)
+	})
+}
+
+func (c *postController) Create(e echo.Context) error {
+	return api.Execute(e, c.postService.CreatePost)
+}
+
+func (c *postController) Update(e echo.Context) error {
+	return api.Execute(e, c.postService.UpdatePost)
+}
+
+func (c *postController) Assign(e echo.Context) error {
+	return api.Execute(e, c.postService.AssignPost)
+}
+
+func (c *postController) Publish(e echo.Context) error {
+	return api.Execute(e, c.postService.PublishPost)
+}
+
+func (c *postController) Delete(e echo.Context) error {
+	id := e.QueryParam("postId")
+	return api.Execute(e, func(ctx context.Context, _ struct{}) (api.Response, error) {
+		return c.postService.DeletePost(ctx, id)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"SangXanh/cmd/api/middleware"
 	"SangXanh/pkg/common/api"
 	"SangXanh/pkg/dto"
+	"SangXanh/pkg/permission"
 	"SangXanh/pkg/service"
 	"context"
 	"github.com/labstack/echo/v4"
@@ -25,9 +26,11 @@ func NewProductController(di do.Injector, auth echo.MiddlewareFunc) (api.Control
 func (c *productController) Register(g *echo.Group) {
 	g = g.Group("/product")
 	g.GET("", c.List)
-	g.POST("/create", c.Create, c.authMiddleware, middleware.RequireRoles("admin"))
-	g.PUT("/update", c.Update, c.authMiddleware, middleware.RequireRoles("admin"))
-	g.DELETE("/delete", c.Delete, c.authMiddleware, middleware.RequireRoles("admin"))
+	g.GET("/search", c.Search)
+	g.GET("/slug/:slug", c.GetBySlug)
+	g.POST("/create", c.Create, c.authMiddleware, middleware.Require(permission.Products, permission.Create))
+	g.PUT("/update", c.Update, c.authMiddleware, middleware.Require(permission.Products, permission.Update))
+	g.DELETE("/delete", c.Delete, c.authMiddleware, middleware.Require(permission.Products, permission.Delete))
 	g.GET("/:id", c.GetById)
 }
 
@@ -40,6 +43,17 @@ func (c *productController) List(e echo.Context) error {
 	})
 }
 
+func (c *productController) Search(e echo.Context) error {
+	return api.Execute(e, c.productService.SearchProducts)
+}
+
+func (c *productController) GetBySlug(e echo.Context) error {
+	slug := e.Param("slug")
+	return api.Execute(e, func(ctx context.Context, _ struct{}) (api.Response, error) {
+		return c.productService.GetProductBySlug(ctx, slug)
+	})
+}
+
 func (c *productController) Create(e echo.Context) error {
 	return api.Execute(e, c.productService.CreateProduct)
 }
@@ -1,7 +1,9 @@
 package controller
 
 import (
+	"SangXanh/cmd/api/middleware"
 	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/permission"
 	"SangXanh/pkg/service"
 	"context"
 	"github.com/labstack/echo/v4"
@@ -23,11 +25,11 @@ func NewProductOptionController(di do.Injector, auth echo.MiddlewareFunc) (api.C
 func (c *productOptionController) Register(g *echo.Group) {
 	g = g.Group("/product-option")
 	g.GET("", c.List)
-	g.POST("/create", c.Create)
-	g.POST("/create-bulk", c.CreateBulk)
-	g.PUT("/update", c.Update)
-	g.PUT("/update-bulk", c.UpdateBulk)
-	g.DELETE("/delete", c.Delete)
+	g.POST("/create", c.Create, c.auth, middleware.Require(permission.ProductOptions, permission.Create))
+	g.POST("/create-bulk", c.CreateBulk, c.auth, middleware.Require(permission.ProductOptions, permission.Create))
+	g.PUT("/update", c.Update, c.auth, middleware.Require(permission.ProductOptions, permission.Update))
+	g.PUT("/update-bulk", c.UpdateBulk, c.auth, middleware.Require(permission.ProductOptions, permission.Update))
+	g.DELETE("/delete", c.Delete, c.auth, middleware.Require(permission.ProductOptions, permission.Delete))
 }
 
 func (c *productOptionController) List(e echo.Context) error {
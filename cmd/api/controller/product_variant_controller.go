@@ -1,7 +1,9 @@
 package controller
 
 import (
+	"SangXanh/cmd/api/middleware"
 	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/permission"
 	"SangXanh/pkg/service"
 	"context"
 	"github.com/labstack/echo/v4"
@@ -23,11 +25,11 @@ func NewProductVariantController(di do.Injector, auth echo.MiddlewareFunc) (api.
 func (c *productVariantController) Register(g *echo.Group) {
 	g = g.Group("/product-variant")
 	g.GET("", c.List)
-	g.POST("/create", c.Create)
-	g.PUT("/update", c.Update)
-	g.DELETE("/delete", c.Delete)
-	g.POST("/create-bulk", c.CreateBulk)
-	g.PUT("/update-bulk", c.UpdateBulk)
+	g.POST("/create", c.Create, c.authMiddleware, middleware.Require(permission.ProductVariants, permission.Create))
+	g.PUT("/update", c.Update, c.authMiddleware, middleware.Require(permission.ProductVariants, permission.Update))
+	g.DELETE("/delete", c.Delete, c.authMiddleware, middleware.Require(permission.ProductVariants, permission.Delete))
+	g.POST("/create-bulk", c.CreateBulk, c.authMiddleware, middleware.Require(permission.ProductVariants, permission.Create))
+	g.PUT("/update-bulk", c.UpdateBulk, c.authMiddleware, middleware.Require(permission.ProductVariants, permission.Update))
 }
 
 func (c *productVariantController) List(e echo.Context) error {
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"SangXanh/cmd/api/middleware"
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/permission"
+	"SangXanh/pkg/service"
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do/v2"
+)
+
+type promotionController struct {
+	promotionService service.PromotionService
+	authMiddleware   echo.MiddlewareFunc
+}
+
+func NewPromotionController(di do.Injector, auth echo.MiddlewareFunc) (api.Controller, error) {
+	return &promotionController{
+		promotionService: do.MustInvoke[service.PromotionService](di),
+		authMiddleware:   auth,
+	}, nil
+}
+
+func (c *promotionController) Register(g *echo.Group) {
+	g = g.Group("/promotion")
+	g.GET("", c.List, c.authMiddleware, middleware.Require(permission.Promotions, permission.Read))
+	g.GET("/:id", c.GetById, c.authMiddleware, middleware.Require(permission.Promotions, permission.Read))
+	g.POST("/create", c.Create, c.authMiddleware, middleware.Require(permission.Promotions, permission.Create))
+	g.PUT("/update", c.Update, c.authMiddleware, middleware.Require(permission.Promotions, permission.Update))
+	g.DELETE("/delete", c.Delete, c.authMiddleware, middleware.Require(permission.Promotions, permission.Delete))
+	// checking a code is part of placing an order
+	g.POST("/validate", c.Validate, c.authMiddleware, middleware.Require(permission.Orders, permission.Create))
+}
+
+func (c *promotionController) List(e echo.Context) error {
+	return api.Execute[dto.PromotionFilter](e, func(ctx context.Context, req dto.PromotionFilter) (api.Response, error) {
+		return c.promotionService.ListPromotions(ctx, req)
+	})
+}
+
+func (c *promotionController) GetById(e echo.Context) error {
+	id := e.Param("id")
+	return api.Execute(e, func(ctx context.Context, _ struct{}) (api.Response, error) {
+		return c.promotionService.GetPromotionById(ctx, id)
+	})
+}
+
+func (c *promotionController) Create(e echo.Context) error {
+	return api.Execute(e, c.promotionService.CreatePromotion)
+}
+
+func (c *promotionController) Update(e echo.Context) error {
+	return api.Execute(e, c.promotionService.UpdatePromotion)
+}
+
+func (c *promotionController) Delete(e echo.Context) error {
+	id := e.QueryParam("promotionId")
+	return api.Execute(e, func(ctx context.Context, _ struct{}) (api.Response, error) {
+		return c.promotionService.DeletePromotion(ctx, id)
+	})
+}
+
+func (c *promotionController) Validate(e echo.Context) error {
+	return api.Execute(e, c.promotionService.PreviewPromotion)
+}
@@ -19,6 +19,12 @@ func RegisterAPI(di do.Injector, e *echo.Group, auth echo.MiddlewareFunc) error
 		NewAuthController,
 		NewCartController,
 		NewOrderController,
+		NewInventoryController,
+		NewPromotionController,
+		NewPaymentController,
+		NewAuditController,
+		NewPostController,
+		NewWsController,
 	}
 
 	for _, c := range controllers {
@@ -1,8 +1,10 @@
 package controller
 
 import (
+	"SangXanh/cmd/api/middleware"
 	"SangXanh/pkg/common/api"
 	"SangXanh/pkg/dto"
+	"SangXanh/pkg/permission"
 	"SangXanh/pkg/service"
 	"context"
 
@@ -33,15 +35,15 @@ func NewUserController(di do.Injector, auth echo.MiddlewareFunc) (api.Controller
 func (c *userController) Register(g *echo.Group) {
 	g = g.Group("/user")
 
-	g.GET("", c.List)
+	g.GET("", c.List, c.authMiddleware, middleware.Require(permission.Users, permission.Read))
 	g.POST("/register", c.Create)
-	g.PUT("/update", c.Update, c.authMiddleware)
-	g.PUT("/address", c.Address, c.authMiddleware)
+	g.PUT("/update", c.Update, c.authMiddleware, middleware.Require(permission.Users, permission.Update))
+	g.PUT("/address", c.Address, c.authMiddleware, middleware.Require(permission.Users, permission.Update))
 	g.PUT("/change-password", c.ChangePassword, c.authMiddleware)
 	g.PUT("/send-magic-link", c.SendMagicLink)
 	g.PUT("/forgot-password", c.ForgotPassword, c.authMiddleware)
 
-	g.GET("/:id", c.GetById) // ← NEW
+	g.GET("/:id", c.GetById, c.authMiddleware, middleware.Require(permission.Users, permission.Read))
 }
 
 func (c *userController) GetById(e echo.Context) error {
@@ -61,7 +63,10 @@ func (c *userController) List(e echo.Context) error {
 
 // POST /user/register
 func (c *userController) Create(e echo.Context) error {
-	return api.Execute(e, c.userService.Register)
+	return api.Execute(e, func(ctx context.Context, req dto.UserRegisterRequest) (api.Response, error) {
+		req.Client = middleware.SessionClient(e)
+		return c.userService.Register(ctx, req)
+	})
 }
 
 // PUT /user/update
@@ -79,7 +84,10 @@ func (c *userController) ChangePassword(e echo.Context) error {
 }
 
 func (c *userController) SendMagicLink(e echo.Context) error {
-	return api.Execute(e, c.userService.SendMagicLink)
+	return api.Execute(e, func(ctx context.Context, req dto.ResetPasswordRequest) (api.Response, error) {
+		req.Client = middleware.SessionClient(e)
+		return c.userService.SendMagicLink(ctx, req)
+	})
 }
 
 func (c *userController) ForgotPassword(e echo.Context) error {
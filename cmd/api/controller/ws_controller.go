@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"SangXanh/pkg/auth"
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/log"
+	"SangXanh/pkg/ws"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do/v2"
+	"net/http"
+	"strconv"
+)
+
+type wsController struct {
+	hub            *ws.Hub
+	authMiddleware echo.MiddlewareFunc
+}
+
+func NewWsController(di do.Injector, auth echo.MiddlewareFunc) (api.Controller, error) {
+	return &wsController{
+		hub:            do.MustInvoke[*ws.Hub](di),
+		authMiddleware: auth,
+	}, nil
+}
+
+func (c *wsController) Register(g *echo.Group) {
+	g.GET("/ws", c.Connect, c.authMiddleware)
+}
+
+// Connect upgrades to a WebSocket. A reconnecting client passes the seq of the last
+// event it received as ?since= to get what it missed in the meantime.
+func (c *wsController) Connect(e echo.Context) error {
+	caller, ok := auth.FromContext(e.Request().Context())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User ID not found in context")
+	}
+
+	var since uint64
+	if raw := e.QueryParam("since"); raw != "" {
+		v, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "since must be a sequence number")
+		}
+		since = v
+	}
+	if err := c.hub.Serve(e.Response(), e.Request(), ws.RoomsFor(caller.Id, caller.Role, caller.SessionId), since); err != nil {
+		// the upgrader has answered the request already
+		log.Errorf("websocket upgrade failed: %v", err)
+	}
+	return nil
+}
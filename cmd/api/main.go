@@ -6,7 +6,11 @@ import (
 	"SangXanh/pkg/config"
 	"SangXanh/pkg/connection"
 	"SangXanh/pkg/log"
+	"SangXanh/pkg/ratelimit"
+	"SangXanh/pkg/repository"
 	"SangXanh/pkg/service"
+	"SangXanh/pkg/util"
+	"SangXanh/pkg/ws"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/samber/do/v2"
@@ -16,18 +20,38 @@ func main() {
 	di := do.New()
 	config.Inject(di)
 	connection.Inject(di)
+	repository.Inject(di)
+	ratelimit.Inject(di)
 	service.Inject(di)
+	ws.Inject(di)
 
 	serverConf := do.MustInvoke[config.Server](di)
 
+	ipExtractor, err := middleware1.IPExtractor(serverConf.TrustedProxies)
+	if err != nil {
+		panic(err)
+	}
+
 	e := echo.New()
+	e.IPExtractor = ipExtractor
 
 	e.Use(middleware.CORS())
 	e.Use(middleware.Recover())
 	e.Use(log.Middleware())
 
 	jwtConf := do.MustInvoke[config.JWTKey](di)
-	authMiddleware := middleware1.AuthenticationMiddleware(jwtConf.Key)
+	verifier, err := util.NewJWTVerifier(util.JWTOptions{
+		Secret:      jwtConf.Key,
+		JWKS:        jwtConf.JWKS,
+		JWKSRefresh: jwtConf.JWKSRefresh,
+		Issuer:      jwtConf.Issuer,
+		Audience:    jwtConf.Audience,
+		ClockSkew:   jwtConf.ClockSkew,
+	})
+	if err != nil {
+		panic(err)
+	}
+	authMiddleware := middleware1.AuthenticationMiddleware(verifier, do.MustInvoke[service.SessionService](di))
 
 	api := e.Group("/api")
 	if err := controller.RegisterAPI(di, api, authMiddleware); err != nil {
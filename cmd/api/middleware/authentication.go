@@ -1,60 +1,84 @@
 package middleware
 
 import (
+	"SangXanh/pkg/auth"
 	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/log"
+	"SangXanh/pkg/service"
 	"SangXanh/pkg/util"
-	"context"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"net/http"
 	"strings"
 )
 
-func AuthenticationMiddleware(jwtKey string) echo.MiddlewareFunc {
+// AuthenticationMiddleware lets through requests with a bearer token verifier
+// accepts whose session was not logged out, and puts the caller the token was
+// issued to in the request context.
+func AuthenticationMiddleware(verifier *util.JWTVerifier, sessions service.SessionService) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			authHeader := c.Request().Header.Get("Authorization")
-			if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+			tokenString, ok := bearerToken(c)
+			if !ok {
 				return echo.ErrUnauthorized
 			}
 
-			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-			token, err := util.VerifyJWT(tokenString, jwtKey)
+			token, err := verifier.Verify(c.Request().Context(), tokenString)
 			if err != nil {
-				return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+				log.Infow("token refused", "error", err)
+				return echo.NewHTTPError(http.StatusUnauthorized, util.JWTReason(err).Error())
 			}
 
-			// Extract claims
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
-				ctx := c.Request().Context()
-				ctx = context.WithValue(ctx, "token", tokenString)
-				ctx = context.WithValue(ctx, "user_id", claims["sub"])
-				ctx = context.WithValue(ctx, "user_role", claims["user_role"])
-
-				// Replace request context
-				c.SetRequest(c.Request().WithContext(ctx))
+			claims, ok := token.Claims.(jwt.MapClaims)
+			if !ok {
+				return echo.ErrUnauthorized
+			}
+			principal := auth.FromClaims(claims, tokenString)
+			if principal.Id == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "token has no subject")
+			}
+			if err := sessions.Verify(c.Request().Context(), principal, SessionClient(c)); err != nil {
+				return api.Serve(c, nil, err)
 			}
+			c.SetRequest(c.Request().WithContext(auth.WithPrincipal(c.Request().Context(), principal)))
 
 			return next(c)
 		}
 	}
 }
 
-func GetCurrentUser(c echo.Context) (api.Response, error) {
-	ctx := c.Request().Context()
+// bearerToken reads the token from the Authorization header. Browsers cannot set
+// headers on a WebSocket handshake, so there it may come as ?access_token= instead.
+func bearerToken(c echo.Context) (string, bool) {
+	authHeader := c.Request().Header.Get("Authorization")
+	if strings.HasPrefix(authHeader, "Bearer ") {
+		return strings.TrimPrefix(authHeader, "Bearer "), true
+	}
+	if c.IsWebSocket() {
+		if token := c.QueryParam("access_token"); token != "" {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// SessionClient describes the device a request comes from.
+func SessionClient(c echo.Context) dto.SessionClient {
+	return dto.SessionClient{UserAgent: c.Request().UserAgent(), Ip: c.RealIP()}
+}
 
-	userID, ok := ctx.Value("user_id").(string)
-	if !ok || userID == "" {
+func GetCurrentUser(c echo.Context) (api.Response, error) {
+	caller, ok := auth.FromContext(c.Request().Context())
+	if !ok {
 		return nil, echo.NewHTTPError(http.StatusUnauthorized, "User ID not found in context")
 	}
-
-	userRole, ok := ctx.Value("user_role").(string)
-	if !ok || userRole == "" {
+	if caller.Role == "" {
 		return nil, echo.NewHTTPError(http.StatusUnauthorized, "User role not found in context")
 	}
 
 	return api.Success(util.CustomClaims{
-		UserID:   userID,
-		UserRole: userRole,
+		UserID:   caller.Id,
+		UserRole: caller.Role,
 	}), nil
 }
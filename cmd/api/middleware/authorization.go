@@ -1,28 +1,21 @@
 package middleware
 
 import (
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/permission"
 	"github.com/labstack/echo/v4"
-	"net/http"
 )
 
-// RequireRoles ensures the user has one of the allowed roles.
-// Assumes the AuthenticationMiddleware has already stored "user_role" in the context.
-func RequireRoles(allowedRoles ...string) echo.MiddlewareFunc {
+// Require lets a request through when the caller's role has the permission for
+// action on resource in some scope; whether a record is the caller's own is checked
+// by the services. Assumes the AuthenticationMiddleware has already run.
+func Require(resource permission.Resource, action permission.Action) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			ctx := c.Request().Context()
-			role, ok := ctx.Value("user_role").(string)
-			if !ok || role == "" {
-				return echo.NewHTTPError(http.StatusForbidden, "Missing user role")
+			if _, err := permission.Check(c.Request().Context(), resource, action); err != nil {
+				return api.Serve(c, nil, err)
 			}
-
-			for _, allowed := range allowedRoles {
-				if role == allowed {
-					return next(c)
-				}
-			}
-
-			return echo.NewHTTPError(http.StatusForbidden, "Access denied: insufficient role")
+			return next(c)
 		}
 	}
 }
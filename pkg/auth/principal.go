@@ -0,0 +1,73 @@
+// Package auth carries who is calling through a request context. The authentication
+// middleware puts the Principal in; services take it out with FromContext or
+// Authenticated instead of reading loose context values.
+package auth
+
+import (
+	"SangXanh/pkg/common/errors"
+	"context"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Principal is the signed-in caller a request is made for.
+type Principal struct {
+	Id    string
+	Role  string
+	Token string
+	// SessionId is the login session the token was issued for.
+	SessionId string
+	// Scopes are the OAuth scopes the token was granted, if any.
+	Scopes []string
+	// IssuedAt is when the token was issued, zero when it does not say.
+	IssuedAt time.Time
+}
+
+// FromClaims reads the caller from the claims of an access token. Scopes come as the
+// space separated OAuth "scope" claim.
+func FromClaims(claims map[string]interface{}, token string) Principal {
+	str := func(name string) string {
+		v, _ := claims[name].(string)
+		return v
+	}
+	p := Principal{
+		Id:        str("sub"),
+		Role:      str("user_role"),
+		Token:     token,
+		SessionId: str("session_id"),
+		Scopes:    strings.Fields(str("scope")),
+	}
+	if iat, ok := claims["iat"].(float64); ok {
+		p.IssuedAt = time.Unix(int64(iat), 0)
+	}
+	return p
+}
+
+// HasScope reports whether the token was granted scope.
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx that carries p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the caller of ctx; ok is false when nobody is signed in.
+func FromContext(ctx context.Context) (p Principal, ok bool) {
+	p, ok = ctx.Value(principalKey{}).(Principal)
+	return p, ok && p.Id != ""
+}
+
+// Authenticated is FromContext for the work that cannot be done anonymously: it
+// fails with 401 Unauthorized when nobody is signed in.
+func Authenticated(ctx context.Context) (Principal, error) {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return Principal{}, errors.Unauthorized("authentication required")
+	}
+	return p, nil
+}
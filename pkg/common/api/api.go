@@ -4,6 +4,7 @@ import (
 	"SangXanh/pkg/common/errors"
 	"SangXanh/pkg/common/query"
 	"context"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"net/http"
@@ -16,7 +17,9 @@ type Response interface {
 
 type responseMeta struct {
 	*query.Pagination
+	Facets  any    `json:"facets,omitempty"`
 	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
 	Debug   any    `json:"debug,omitempty"`
 	Error   string `json:"error,omitempty"`
 }
@@ -45,6 +48,28 @@ func SuccessPagination(data any, p *query.Pagination) Response {
 	}
 }
 
+// SuccessFacets is SuccessPagination with the facet counts of a filtered list.
+func SuccessFacets(data any, p *query.Pagination, facets any) Response {
+	return response{
+		Meta: responseMeta{
+			Pagination: p,
+			Facets:     facets,
+			Message:    "success",
+		},
+		Data: data,
+	}
+}
+
+type redirect struct {
+	location string
+}
+
+// Redirect answers with a 301 to location, which may be relative to the request URL:
+// a lookup by a slug that moved on redirects to just the new slug.
+func Redirect(location string) Response {
+	return redirect{location: location}
+}
+
 type API[Req any] func(e echo.Context, req Req) (Response, error)
 
 func Execute[Req any](c echo.Context, f func(e context.Context, req Req) (Response, error)) error {
@@ -59,25 +84,41 @@ func Execute[Req any](c echo.Context, f func(e context.Context, req Req) (Respon
 	return Serve(c, resp, err)
 }
 
+// Serve writes resp, or maps err to a status: typed errors of pkg/common/errors
+// and *echo.HTTPError keep their status, anything else is a 500.
 func Serve(c echo.Context, resp Response, err error) error {
 	if err != nil {
-		var badRequest *errors.BadRequestError
-		ok := errors.As(err, &badRequest)
-		if ok {
-			return c.JSON(http.StatusBadRequest, response{
-				Meta: responseMeta{
-					Message: "",
-					Debug:   badRequest.Debug,
-					Error:   badRequest.Wrapper.Error(),
-				},
-			})
-		}
-		return c.JSON(http.StatusInternalServerError, response{
-			Meta: responseMeta{
-				Message: "",
-				Error:   err.Error(),
-			},
-		})
+		status, meta := errorMeta(err)
+		return c.JSON(status, response{Meta: meta})
+	}
+	if r, ok := resp.(redirect); ok {
+		return c.Redirect(http.StatusMovedPermanently, r.location)
 	}
 	return c.JSON(http.StatusOK, resp)
 }
+
+func errorMeta(err error) (int, responseMeta) {
+	var typed errors.HTTPError
+	if errors.As(err, &typed) {
+		return typed.StatusCode(), responseMeta{
+			Code:  typed.ErrorCode(),
+			Debug: typed.DebugInfo(),
+			Error: typed.Error(),
+		}
+	}
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		msg := fmt.Sprint(httpErr.Message)
+		if httpErr.Internal != nil {
+			msg = fmt.Sprintf("%s: %v", msg, httpErr.Internal)
+		}
+		return httpErr.Code, responseMeta{
+			Code:  errors.CodeForStatus(httpErr.Code),
+			Error: msg,
+		}
+	}
+	return http.StatusInternalServerError, responseMeta{
+		Code:  errors.CodeInternal,
+		Error: err.Error(),
+	}
+}
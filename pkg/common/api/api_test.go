@@ -1,9 +1,13 @@
 package api
 
 import (
+	"SangXanh/pkg/common/errors"
 	"SangXanh/pkg/common/query"
 	"encoding/json"
 	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -18,3 +22,52 @@ func TestSuccess(t *testing.T) {
 	v, _ := json.MarshalIndent(s, "", "  ")
 	fmt.Println(string(v))
 }
+
+func TestServeErrorStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"bad request", errors.BadRequest("invalid"), http.StatusBadRequest, errors.CodeBadRequest},
+		{"not found", errors.NotFound("order not found"), http.StatusNotFound, errors.CodeNotFound},
+		{"unauthorized", errors.Unauthorized("no token"), http.StatusUnauthorized, errors.CodeUnauthorized},
+		{"forbidden", errors.Forbidden("not yours"), http.StatusForbidden, errors.CodeForbidden},
+		{"conflict with code", errors.Conflict("bad transition").WithCode("invalid_status_transition"), http.StatusConflict, "invalid_status_transition"},
+		{"unprocessable", errors.Unprocessable("not enough stock"), http.StatusUnprocessableEntity, errors.CodeUnprocessable},
+		{"wrapped", fmt.Errorf("outer: %w", errors.NotFound("inner")), http.StatusNotFound, errors.CodeNotFound},
+		{"echo", echo.NewHTTPError(http.StatusTooManyRequests, "slow down"), http.StatusTooManyRequests, "too_many_requests"},
+		{"plain", fmt.Errorf("boom"), http.StatusInternalServerError, errors.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+			if err := Serve(c, nil, tt.err); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			var body response
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Meta.Code != tt.code {
+				t.Errorf("code = %q, want %q", body.Meta.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestServeRedirect(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/api/product/slug/cay-cu", nil), rec)
+	if err := Serve(c, Redirect("cay-moi"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "cay-moi" {
+		t.Errorf("got %d to %q, want 301 to cay-moi", rec.Code, rec.Header().Get("Location"))
+	}
+}
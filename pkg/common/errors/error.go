@@ -3,16 +3,57 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Machine readable codes returned next to the message; services may narrow them
+// down with WithCode, e.g. "order_not_found".
+const (
+	CodeBadRequest      = "bad_request"
+	CodeUnauthorized    = "unauthorized"
+	CodeForbidden       = "forbidden"
+	CodeNotFound        = "not_found"
+	CodeConflict        = "conflict"
+	CodeUnprocessable   = "unprocessable"
+	CodeTooManyRequests = "too_many_requests"
+	CodeInternal        = "internal_error"
 )
 
 type BadRequestError struct {
 	Wrapper
 }
 
+type UnauthorizedError struct {
+	Wrapper
+}
+
+type ForbiddenError struct {
+	Wrapper
+}
+
+type NotFoundError struct {
+	Wrapper
+}
+
+type ConflictError struct {
+	Wrapper
+}
+
+type UnprocessableError struct {
+	Wrapper
+}
+
+type TooManyRequestsError struct {
+	Wrapper
+}
+
 type Wrapper struct {
-	Base  error
-	Msg   string
-	Debug any
+	Base   error
+	Msg    string
+	Debug  any
+	Code   string
+	Status int
 }
 
 func (e *Wrapper) Error() string {
@@ -25,19 +66,178 @@ func (e *Wrapper) Error() string {
 	return ""
 }
 
+func (e *Wrapper) Unwrap() error {
+	return e.Base
+}
+
+// StatusCode is the HTTP status the error is answered with.
+func (e *Wrapper) StatusCode() int {
+	if e.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return e.Status
+}
+
+func (e *Wrapper) ErrorCode() string {
+	if e.Code == "" {
+		return CodeForStatus(e.StatusCode())
+	}
+	return e.Code
+}
+
+func (e *Wrapper) DebugInfo() any {
+	return e.Debug
+}
+
 func (e *Wrapper) WithDebug(debug any) *Wrapper {
 	e.Debug = debug
 	return e
 }
 
+func (e *Wrapper) WithCode(code string) *Wrapper {
+	e.Code = code
+	return e
+}
+
+// The kinds repeat WithDebug and WithCode so that they keep their own type, which
+// errors.As is matched against.
+
+func (e *BadRequestError) WithDebug(debug any) *BadRequestError {
+	e.Debug = debug
+	return e
+}
+
+func (e *BadRequestError) WithCode(code string) *BadRequestError {
+	e.Code = code
+	return e
+}
+
+func (e *UnauthorizedError) WithDebug(debug any) *UnauthorizedError {
+	e.Debug = debug
+	return e
+}
+
+func (e *UnauthorizedError) WithCode(code string) *UnauthorizedError {
+	e.Code = code
+	return e
+}
+
+func (e *ForbiddenError) WithDebug(debug any) *ForbiddenError {
+	e.Debug = debug
+	return e
+}
+
+func (e *ForbiddenError) WithCode(code string) *ForbiddenError {
+	e.Code = code
+	return e
+}
+
+func (e *NotFoundError) WithDebug(debug any) *NotFoundError {
+	e.Debug = debug
+	return e
+}
+
+func (e *NotFoundError) WithCode(code string) *NotFoundError {
+	e.Code = code
+	return e
+}
+
+func (e *ConflictError) WithDebug(debug any) *ConflictError {
+	e.Debug = debug
+	return e
+}
+
+func (e *ConflictError) WithCode(code string) *ConflictError {
+	e.Code = code
+	return e
+}
+
+func (e *UnprocessableError) WithDebug(debug any) *UnprocessableError {
+	e.Debug = debug
+	return e
+}
+
+func (e *UnprocessableError) WithCode(code string) *UnprocessableError {
+	e.Code = code
+	return e
+}
+
+func (e *TooManyRequestsError) WithDebug(debug any) *TooManyRequestsError {
+	e.Debug = debug
+	return e
+}
+
+func (e *TooManyRequestsError) WithCode(code string) *TooManyRequestsError {
+	e.Code = code
+	return e
+}
+
+// HTTPError is implemented by every error kind of this package.
+type HTTPError interface {
+	error
+	StatusCode() int
+	ErrorCode() string
+	DebugInfo() any
+}
+
+func wrapper(status int, code, template string, args []any) Wrapper {
+	return Wrapper{Msg: fmt.Sprintf(template, args...), Code: code, Status: status}
+}
+
 func New(template string, args ...any) error {
 	return fmt.Errorf(template, args...)
 }
 
 func BadRequest(template string, args ...any) *BadRequestError {
-	return &BadRequestError{
-		Wrapper{Msg: fmt.Sprintf(template, args...)},
+	return &BadRequestError{wrapper(http.StatusBadRequest, CodeBadRequest, template, args)}
+}
+
+func Unauthorized(template string, args ...any) *UnauthorizedError {
+	return &UnauthorizedError{wrapper(http.StatusUnauthorized, CodeUnauthorized, template, args)}
+}
+
+func Forbidden(template string, args ...any) *ForbiddenError {
+	return &ForbiddenError{wrapper(http.StatusForbidden, CodeForbidden, template, args)}
+}
+
+func NotFound(template string, args ...any) *NotFoundError {
+	return &NotFoundError{wrapper(http.StatusNotFound, CodeNotFound, template, args)}
+}
+
+func Conflict(template string, args ...any) *ConflictError {
+	return &ConflictError{wrapper(http.StatusConflict, CodeConflict, template, args)}
+}
+
+func Unprocessable(template string, args ...any) *UnprocessableError {
+	return &UnprocessableError{wrapper(http.StatusUnprocessableEntity, CodeUnprocessable, template, args)}
+}
+
+func TooManyRequests(template string, args ...any) *TooManyRequestsError {
+	return &TooManyRequestsError{wrapper(http.StatusTooManyRequests, CodeTooManyRequests, template, args)}
+}
+
+// CodeForStatus gives the code used for errors that only carry an HTTP status,
+// such as *echo.HTTPError.
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case http.StatusInternalServerError:
+		return CodeInternal
 	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
 }
 
 func Is(err error, target error) bool {
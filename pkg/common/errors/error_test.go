@@ -1,11 +1,56 @@
 package errors
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"net/http"
 	"testing"
 )
 
 func TestBadRequest(t *testing.T) {
 	e := BadRequest("error %v", 1).WithDebug(map[string]any{"key": "value"})
 	assert.Equal(t, "error 1", e.Error())
+	assert.Equal(t, http.StatusBadRequest, e.StatusCode())
+}
+
+func TestKinds(t *testing.T) {
+	for _, tc := range []struct {
+		err    HTTPError
+		status int
+		code   string
+	}{
+		{Unauthorized("x"), http.StatusUnauthorized, CodeUnauthorized},
+		{Forbidden("x"), http.StatusForbidden, CodeForbidden},
+		{NotFound("x"), http.StatusNotFound, CodeNotFound},
+		{Conflict("x"), http.StatusConflict, CodeConflict},
+		{Unprocessable("x"), http.StatusUnprocessableEntity, CodeUnprocessable},
+		{TooManyRequests("x"), http.StatusTooManyRequests, CodeTooManyRequests},
+		{NotFound("x").WithCode("order_not_found"), http.StatusNotFound, "order_not_found"},
+	} {
+		assert.Equal(t, tc.status, tc.err.StatusCode())
+		assert.Equal(t, tc.code, tc.err.ErrorCode())
+	}
+}
+
+func TestAsHTTPError(t *testing.T) {
+	err := fmt.Errorf("checkout: %w", NotFound("cart not found"))
+
+	var typed HTTPError
+	assert.True(t, As(err, &typed))
+	assert.Equal(t, http.StatusNotFound, typed.StatusCode())
+
+	var notFound *NotFoundError
+	assert.True(t, As(err, &notFound))
+	assert.False(t, As(fmt.Errorf("plain"), &typed))
+
+	// a code or debug info does not lose the kind
+	err = fmt.Errorf("checkout: %w", Conflict("cart changed").WithCode("cart_changed").WithDebug("v2"))
+	var conflict *ConflictError
+	assert.True(t, As(err, &conflict))
+	assert.Equal(t, "cart_changed", conflict.ErrorCode())
+}
+
+func TestCodeForStatus(t *testing.T) {
+	assert.Equal(t, CodeInternal, CodeForStatus(http.StatusInternalServerError))
+	assert.Equal(t, "too_many_requests", CodeForStatus(http.StatusTooManyRequests))
 }
@@ -0,0 +1,38 @@
+package query
+
+import (
+	"SangXanh/pkg/common/errors"
+	"encoding/base64"
+	"encoding/json"
+)
+
+// Cursor is the position a keyset page is read from. Clients only see it encoded and
+// hand it back as is.
+type Cursor struct {
+	// Order is the ordering the cursor was taken under; it is only valid for the same one.
+	Order string `json:"o"`
+	// Values holds the sort columns of the boundary row, nil for a NULL.
+	Values []*string `json:"v"`
+	// Before reads the page that ends right before the row instead of the one after it.
+	Before bool `json:"b,omitempty"`
+}
+
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor reads the cursor parameter; ok is false when the request has none.
+func (p *Pagination) DecodeCursor() (c Cursor, ok bool, err error) {
+	if p.Cursor == "" {
+		return c, false, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+	if err == nil {
+		err = json.Unmarshal(raw, &c)
+	}
+	if err != nil || len(c.Values) == 0 {
+		return c, false, errors.BadRequest("invalid cursor")
+	}
+	return c, true, nil
+}
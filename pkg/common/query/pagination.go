@@ -17,11 +17,22 @@ type Pagination struct {
 	Total     int64  `json:"total"`
 	TotalPage int64  `json:"total_page"`
 	Sort      string `json:"sort" query:"sort"`
+	// Cursor switches a list to keyset paging: the page starts right after (or, for a
+	// previous cursor, right before) the row the cursor was taken from.
+	Cursor     string `json:"-" query:"cursor"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
+// Correct fills in a missing page or limit and caps the limit; a small limit the
+// client asked for is kept.
 func (p *Pagination) Correct() {
-	p.Page = max(p.Page, DefaultPage)
-	p.Limit = max(p.Limit, DefaultLimit)
+	if p.Page < 1 {
+		p.Page = DefaultPage
+	}
+	if p.Limit < 1 {
+		p.Limit = DefaultLimit
+	}
 	p.Limit = min(p.Limit, MaxLimit)
 }
 
@@ -40,6 +51,28 @@ func (p *Pagination) Range() (from, to int) {
 	return
 }
 
+// SortField is one entry of the sort parameter.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// SortFields parses the sort parameter, e.g. "-price,name", keeping the order the
+// fields were given in; a leading '-' sorts descending, '+' or nothing ascending.
+func (p *Pagination) SortFields() []SortField {
+	var fields []SortField
+	for _, part := range strings.Split(p.Sort, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		part = strings.TrimLeft(part, "+-")
+		if part == "" {
+			continue
+		}
+		fields = append(fields, SortField{Field: part, Desc: desc})
+	}
+	return fields
+}
+
 func (p *Pagination) GetSort() bson.M {
 	if len(p.Sort) == 0 {
 		return nil
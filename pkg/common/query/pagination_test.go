@@ -16,6 +16,9 @@ func TestPagination_Init(t *testing.T) {
 	p.Limit = MaxLimit + 1
 	p.Correct()
 	assert.Equal(t, MaxLimit, p.Limit)
+	p.Limit = 5
+	p.Correct()
+	assert.Equal(t, int64(5), p.Limit)
 }
 
 func TestPagination_SetTotal(t *testing.T) {
@@ -40,3 +43,13 @@ func TestPagination_GetSort(t *testing.T) {
 	assert.Equal(t, -1, sort["name"])
 	assert.Equal(t, 1, sort["age"])
 }
+
+func TestPagination_SortFields(t *testing.T) {
+	p := Pagination{Sort: "-price, name,+created_at,-,"}
+	assert.Equal(t, []SortField{
+		{Field: "price", Desc: true},
+		{Field: "name"},
+		{Field: "created_at"},
+	}, p.SortFields())
+	assert.Empty(t, (&Pagination{}).SortFields())
+}
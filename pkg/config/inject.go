@@ -7,4 +7,6 @@ func Inject(di do.Injector) {
 	do.Provide(di, Parse[Server])
 	do.Provide(di, Parse[JWTKey])
 	do.Provide(di, Parse[Cloudinary])
+	do.Provide(di, Parse[Payment])
+	do.Provide(di, Parse[RateLimit])
 }
@@ -1,5 +1,16 @@
 package config
 
+import "time"
+
+// JWTKey holds how access tokens are verified: HS256 tokens against the shared Key,
+// RS256 and ES256 tokens against the keys published at JWKS, a URL or a file path.
+// At least one of the two has to be set.
 type JWTKey struct {
-	Key string `envconfig:"JWT_KEY" required:"true"`
+	Key         string        `envconfig:"JWT_KEY"`
+	JWKS        string        `envconfig:"JWT_JWKS"`
+	JWKSRefresh time.Duration `envconfig:"JWT_JWKS_REFRESH" default:"1h"`
+	// Issuer and Audience are checked when set.
+	Issuer    string        `envconfig:"JWT_ISSUER"`
+	Audience  string        `envconfig:"JWT_AUDIENCE"`
+	ClockSkew time.Duration `envconfig:"JWT_CLOCK_SKEW" default:"30s"`
 }
@@ -0,0 +1,11 @@
+package config
+
+// Payment holds the settings of the payment gateways. Webhooks are rejected while
+// WebhookSecret is empty.
+type Payment struct {
+	WebhookSecret   string `envconfig:"PAYMENT_WEBHOOK_SECRET"`
+	BankName        string `envconfig:"PAYMENT_BANK_NAME"`
+	BankAccount     string `envconfig:"PAYMENT_BANK_ACCOUNT"`
+	BankAccountName string `envconfig:"PAYMENT_BANK_ACCOUNT_NAME"`
+	ReferencePrefix string `envconfig:"PAYMENT_REFERENCE_PREFIX" default:"SX"`
+}
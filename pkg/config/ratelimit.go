@@ -0,0 +1,7 @@
+package config
+
+// RateLimit picks where the rate limiter keeps its counters: "memory" for a single
+// instance, "supabase" to share them between instances.
+type RateLimit struct {
+	Store string `envconfig:"RATE_LIMIT_STORE" default:"memory"`
+}
@@ -5,6 +5,9 @@ import "fmt"
 type Server struct {
 	Host string `envconfig:"SERVER_HOST" default:"localhost"`
 	Port int    `envconfig:"SERVER_PORT" default:"8080"`
+	// TrustedProxies are the CIDR ranges of the proxies whose X-Forwarded-For is
+	// believed; without any the address of the connection is the client's.
+	TrustedProxies []string `envconfig:"SERVER_TRUSTED_PROXIES"`
 }
 
 func (s *Server) Address() string {
@@ -187,6 +187,7 @@ type PublicProductOptionsSelect struct {
   Name       sql.NullString  `json:"name"`
   ProductId  sql.NullString  `json:"product_id"`
   Quantity   sql.NullInt32   `json:"quantity"`
+  Stock      sql.NullInt32   `json:"stock"`
   UpdatedAt  sql.NullString  `json:"updated_at"`
 }
 
@@ -201,6 +202,7 @@ type PublicProductOptionsInsert struct {
   Name       sql.NullString  `json:"name"`
   ProductId  sql.NullString  `json:"product_id"`
   Quantity   sql.NullInt32   `json:"quantity"`
+  Stock      sql.NullInt32   `json:"stock"`
   UpdatedAt  sql.NullString  `json:"updated_at"`
 }
 
@@ -215,79 +217,104 @@ type PublicProductOptionsUpdate struct {
   Name       sql.NullString  `json:"name"`
   ProductId  sql.NullString  `json:"product_id"`
   Quantity   sql.NullInt32   `json:"quantity"`
+  Stock      sql.NullInt32   `json:"stock"`
   UpdatedAt  sql.NullString  `json:"updated_at"`
 }
 
 type PublicOrdersSelect struct {
-  Address   sql.NullString `json:"address"`
-  CreatedAt sql.NullString `json:"created_at"`
-  DeletedAt sql.NullString `json:"deleted_at"`
-  Id        string         `json:"id"`
-  Metadata  []interface{}  `json:"metadata"`
-  Status    sql.NullString `json:"status"`
-  UpdatedAt sql.NullString `json:"updated_at"`
-  UserId    sql.NullString `json:"user_id"`
+  Address           sql.NullString  `json:"address"`
+  CreatedAt         sql.NullString  `json:"created_at"`
+  DeletedAt         sql.NullString  `json:"deleted_at"`
+  DiscountTotal     sql.NullFloat64 `json:"discount_total"`
+  GrandTotal        sql.NullFloat64 `json:"grand_total"`
+  Id                string          `json:"id"`
+  Metadata          []interface{}   `json:"metadata"`
+  PromotionCode     sql.NullString  `json:"promotion_code"`
+  PromotionDiscount sql.NullFloat64 `json:"promotion_discount"`
+  Status            sql.NullString  `json:"status"`
+  Subtotal          sql.NullFloat64 `json:"subtotal"`
+  UpdatedAt         sql.NullString  `json:"updated_at"`
+  UserId            sql.NullString  `json:"user_id"`
 }
 
 type PublicOrdersInsert struct {
-  Address   sql.NullString `json:"address"`
-  CreatedAt sql.NullString `json:"created_at"`
-  DeletedAt sql.NullString `json:"deleted_at"`
-  Id        sql.NullString `json:"id"`
-  Metadata  []interface{}  `json:"metadata"`
-  Status    sql.NullString `json:"status"`
-  UpdatedAt sql.NullString `json:"updated_at"`
-  UserId    sql.NullString `json:"user_id"`
+  Address           sql.NullString  `json:"address"`
+  CreatedAt         sql.NullString  `json:"created_at"`
+  DeletedAt         sql.NullString  `json:"deleted_at"`
+  DiscountTotal     sql.NullFloat64 `json:"discount_total"`
+  GrandTotal        sql.NullFloat64 `json:"grand_total"`
+  Id                sql.NullString  `json:"id"`
+  Metadata          []interface{}   `json:"metadata"`
+  PromotionCode     sql.NullString  `json:"promotion_code"`
+  PromotionDiscount sql.NullFloat64 `json:"promotion_discount"`
+  Status            sql.NullString  `json:"status"`
+  Subtotal          sql.NullFloat64 `json:"subtotal"`
+  UpdatedAt         sql.NullString  `json:"updated_at"`
+  UserId            sql.NullString  `json:"user_id"`
 }
 
 type PublicOrdersUpdate struct {
-  Address   sql.NullString `json:"address"`
-  CreatedAt sql.NullString `json:"created_at"`
-  DeletedAt sql.NullString `json:"deleted_at"`
-  Id        sql.NullString `json:"id"`
-  Metadata  []interface{}  `json:"metadata"`
-  Status    sql.NullString `json:"status"`
-  UpdatedAt sql.NullString `json:"updated_at"`
-  UserId    sql.NullString `json:"user_id"`
+  Address           sql.NullString  `json:"address"`
+  CreatedAt         sql.NullString  `json:"created_at"`
+  DeletedAt         sql.NullString  `json:"deleted_at"`
+  DiscountTotal     sql.NullFloat64 `json:"discount_total"`
+  GrandTotal        sql.NullFloat64 `json:"grand_total"`
+  Id                sql.NullString  `json:"id"`
+  Metadata          []interface{}   `json:"metadata"`
+  PromotionCode     sql.NullString  `json:"promotion_code"`
+  PromotionDiscount sql.NullFloat64 `json:"promotion_discount"`
+  Status            sql.NullString  `json:"status"`
+  Subtotal          sql.NullFloat64 `json:"subtotal"`
+  UpdatedAt         sql.NullString  `json:"updated_at"`
+  UserId            sql.NullString  `json:"user_id"`
 }
 
 type PublicOrderDetailsSelect struct {
-  CreatedAt     sql.NullString `json:"created_at"`
-  DeletedAt     sql.NullString `json:"deleted_at"`
-  Discount      sql.NullInt32  `json:"discount"`
-  DiscountType  sql.NullString `json:"discount_type"`
-  Id            string         `json:"id"`
-  Metadata      []interface{}  `json:"metadata"`
-  OrderId       sql.NullString `json:"order_id"`
-  ProductDetail []interface{}  `json:"product_detail"`
-  Quantity      sql.NullInt32  `json:"quantity"`
-  UpdatedAt     sql.NullString `json:"updated_at"`
+  CreatedAt      sql.NullString  `json:"created_at"`
+  DeletedAt      sql.NullString  `json:"deleted_at"`
+  Discount       sql.NullInt32   `json:"discount"`
+  DiscountAmount sql.NullFloat64 `json:"discount_amount"`
+  DiscountType   sql.NullString  `json:"discount_type"`
+  Id             string          `json:"id"`
+  LineTotal      sql.NullFloat64 `json:"line_total"`
+  Metadata       []interface{}   `json:"metadata"`
+  OrderId        sql.NullString  `json:"order_id"`
+  ProductDetail  []interface{}   `json:"product_detail"`
+  Quantity       sql.NullInt32   `json:"quantity"`
+  UnitPrice      sql.NullFloat64 `json:"unit_price"`
+  UpdatedAt      sql.NullString  `json:"updated_at"`
 }
 
 type PublicOrderDetailsInsert struct {
-  CreatedAt     sql.NullString `json:"created_at"`
-  DeletedAt     sql.NullString `json:"deleted_at"`
-  Discount      sql.NullInt32  `json:"discount"`
-  DiscountType  sql.NullString `json:"discount_type"`
-  Id            sql.NullString `json:"id"`
-  Metadata      []interface{}  `json:"metadata"`
-  OrderId       sql.NullString `json:"order_id"`
-  ProductDetail []interface{}  `json:"product_detail"`
-  Quantity      sql.NullInt32  `json:"quantity"`
-  UpdatedAt     sql.NullString `json:"updated_at"`
+  CreatedAt      sql.NullString  `json:"created_at"`
+  DeletedAt      sql.NullString  `json:"deleted_at"`
+  Discount       sql.NullInt32   `json:"discount"`
+  DiscountAmount sql.NullFloat64 `json:"discount_amount"`
+  DiscountType   sql.NullString  `json:"discount_type"`
+  Id             sql.NullString  `json:"id"`
+  LineTotal      sql.NullFloat64 `json:"line_total"`
+  Metadata       []interface{}   `json:"metadata"`
+  OrderId        sql.NullString  `json:"order_id"`
+  ProductDetail  []interface{}   `json:"product_detail"`
+  Quantity       sql.NullInt32   `json:"quantity"`
+  UnitPrice      sql.NullFloat64 `json:"unit_price"`
+  UpdatedAt      sql.NullString  `json:"updated_at"`
 }
 
 type PublicOrderDetailsUpdate struct {
-  CreatedAt     sql.NullString `json:"created_at"`
-  DeletedAt     sql.NullString `json:"deleted_at"`
-  Discount      sql.NullInt32  `json:"discount"`
-  DiscountType  sql.NullString `json:"discount_type"`
-  Id            sql.NullString `json:"id"`
-  Metadata      []interface{}  `json:"metadata"`
-  OrderId       sql.NullString `json:"order_id"`
-  ProductDetail []interface{}  `json:"product_detail"`
-  Quantity      sql.NullInt32  `json:"quantity"`
-  UpdatedAt     sql.NullString `json:"updated_at"`
+  CreatedAt      sql.NullString  `json:"created_at"`
+  DeletedAt      sql.NullString  `json:"deleted_at"`
+  Discount       sql.NullInt32   `json:"discount"`
+  DiscountAmount sql.NullFloat64 `json:"discount_amount"`
+  DiscountType   sql.NullString  `json:"discount_type"`
+  Id             sql.NullString  `json:"id"`
+  LineTotal      sql.NullFloat64 `json:"line_total"`
+  Metadata       []interface{}   `json:"metadata"`
+  OrderId        sql.NullString  `json:"order_id"`
+  ProductDetail  []interface{}   `json:"product_detail"`
+  Quantity       sql.NullInt32   `json:"quantity"`
+  UnitPrice      sql.NullFloat64 `json:"unit_price"`
+  UpdatedAt      sql.NullString  `json:"updated_at"`
 }
 
 type PublicCartsSelect struct {
@@ -324,45 +351,54 @@ type PublicCartsUpdate struct {
 }
 
 type PublicPostsSelect struct {
-  Assignee  sql.NullString `json:"assignee"`
-  Content   sql.NullString `json:"content"`
-  CreatedAt sql.NullString `json:"created_at"`
-  CreatedBy sql.NullString `json:"created_by"`
-  DeletedAt sql.NullString `json:"deleted_at"`
-  Id        string         `json:"id"`
-  Metadata  []interface{}  `json:"metadata"`
-  Status    bool           `json:"status"`
-  Thumbnail sql.NullString `json:"thumbnail"`
-  Type      sql.NullString `json:"type"`
-  UpdatedAt sql.NullString `json:"updated_at"`
+  Assignee    sql.NullString `json:"assignee"`
+  Content     sql.NullString `json:"content"`
+  CreatedAt   sql.NullString `json:"created_at"`
+  CreatedBy   sql.NullString `json:"created_by"`
+  DeletedAt   sql.NullString `json:"deleted_at"`
+  Id          string         `json:"id"`
+  Metadata    []interface{}  `json:"metadata"`
+  PublishedAt sql.NullString `json:"published_at"`
+  Slug        sql.NullString `json:"slug"`
+  Status      bool           `json:"status"`
+  Thumbnail   sql.NullString `json:"thumbnail"`
+  Title       sql.NullString `json:"title"`
+  Type        sql.NullString `json:"type"`
+  UpdatedAt   sql.NullString `json:"updated_at"`
 }
 
 type PublicPostsInsert struct {
-  Assignee  sql.NullString `json:"assignee"`
-  Content   sql.NullString `json:"content"`
-  CreatedAt sql.NullString `json:"created_at"`
-  CreatedBy sql.NullString `json:"created_by"`
-  DeletedAt sql.NullString `json:"deleted_at"`
-  Id        sql.NullString `json:"id"`
-  Metadata  []interface{}  `json:"metadata"`
-  Status    sql.NullBool   `json:"status"`
-  Thumbnail sql.NullString `json:"thumbnail"`
-  Type      sql.NullString `json:"type"`
-  UpdatedAt sql.NullString `json:"updated_at"`
+  Assignee    sql.NullString `json:"assignee"`
+  Content     sql.NullString `json:"content"`
+  CreatedAt   sql.NullString `json:"created_at"`
+  CreatedBy   sql.NullString `json:"created_by"`
+  DeletedAt   sql.NullString `json:"deleted_at"`
+  Id          sql.NullString `json:"id"`
+  Metadata    []interface{}  `json:"metadata"`
+  PublishedAt sql.NullString `json:"published_at"`
+  Slug        sql.NullString `json:"slug"`
+  Status      sql.NullBool   `json:"status"`
+  Thumbnail   sql.NullString `json:"thumbnail"`
+  Title       sql.NullString `json:"title"`
+  Type        sql.NullString `json:"type"`
+  UpdatedAt   sql.NullString `json:"updated_at"`
 }
 
 type PublicPostsUpdate struct {
-  Assignee  sql.NullString `json:"assignee"`
-  Content   sql.NullString `json:"content"`
-  CreatedAt sql.NullString `json:"created_at"`
-  CreatedBy sql.NullString `json:"created_by"`
-  DeletedAt sql.NullString `json:"deleted_at"`
-  Id        sql.NullString `json:"id"`
-  Metadata  []interface{}  `json:"metadata"`
-  Status    sql.NullBool   `json:"status"`
-  Thumbnail sql.NullString `json:"thumbnail"`
-  Type      sql.NullString `json:"type"`
-  UpdatedAt sql.NullString `json:"updated_at"`
+  Assignee    sql.NullString `json:"assignee"`
+  Content     sql.NullString `json:"content"`
+  CreatedAt   sql.NullString `json:"created_at"`
+  CreatedBy   sql.NullString `json:"created_by"`
+  DeletedAt   sql.NullString `json:"deleted_at"`
+  Id          sql.NullString `json:"id"`
+  Metadata    []interface{}  `json:"metadata"`
+  PublishedAt sql.NullString `json:"published_at"`
+  Slug        sql.NullString `json:"slug"`
+  Status      sql.NullBool   `json:"status"`
+  Thumbnail   sql.NullString `json:"thumbnail"`
+  Title       sql.NullString `json:"title"`
+  Type        sql.NullString `json:"type"`
+  UpdatedAt   sql.NullString `json:"updated_at"`
 }
 
 type PublicAuditTrailsSelect struct {
@@ -400,3 +436,204 @@ type PublicAuditTrailsUpdate struct {
   Metadata     []interface{}  `json:"metadata"`
   UpdatedAt    sql.NullString `json:"updated_at"`
 }
+
+type PublicOrderStatusHistorySelect struct {
+  CreatedAt  sql.NullString `json:"created_at"`
+  CreatedBy  sql.NullString `json:"created_by"`
+  FromStatus sql.NullString `json:"from_status"`
+  Id         string         `json:"id"`
+  Note       sql.NullString `json:"note"`
+  OrderId    string         `json:"order_id"`
+  ToStatus   string         `json:"to_status"`
+}
+
+type PublicOrderStatusHistoryInsert struct {
+  CreatedAt  sql.NullString `json:"created_at"`
+  CreatedBy  sql.NullString `json:"created_by"`
+  FromStatus sql.NullString `json:"from_status"`
+  Id         sql.NullString `json:"id"`
+  Note       sql.NullString `json:"note"`
+  OrderId    string         `json:"order_id"`
+  ToStatus   string         `json:"to_status"`
+}
+
+type PublicOrderStatusHistoryUpdate struct {
+  CreatedAt  sql.NullString `json:"created_at"`
+  CreatedBy  sql.NullString `json:"created_by"`
+  FromStatus sql.NullString `json:"from_status"`
+  Id         sql.NullString `json:"id"`
+  Note       sql.NullString `json:"note"`
+  OrderId    sql.NullString `json:"order_id"`
+  ToStatus   sql.NullString `json:"to_status"`
+}
+
+type PublicPromotionsSelect struct {
+  CategoryIds       []interface{}  `json:"category_ids"`
+  Code              string         `json:"code"`
+  CreatedAt         string         `json:"created_at"`
+  CreatedBy         sql.NullString `json:"created_by"`
+  DeletedAt         sql.NullString `json:"deleted_at"`
+  Description       sql.NullString `json:"description"`
+  DiscountType      string         `json:"discount_type"`
+  DiscountValue     float64        `json:"discount_value"`
+  EndsAt            sql.NullString `json:"ends_at"`
+  Id                string         `json:"id"`
+  MaxDiscount       float64        `json:"max_discount"`
+  MinOrderValue     float64        `json:"min_order_value"`
+  ProductIds        []interface{}  `json:"product_ids"`
+  StartsAt          sql.NullString `json:"starts_at"`
+  Status            bool           `json:"status"`
+  UpdatedAt         sql.NullString `json:"updated_at"`
+  UsageLimit        int32          `json:"usage_limit"`
+  UsageLimitPerUser int32          `json:"usage_limit_per_user"`
+  UsedCount         int32          `json:"used_count"`
+}
+
+type PublicPromotionsInsert struct {
+  CategoryIds       []interface{}   `json:"category_ids"`
+  Code              string          `json:"code"`
+  CreatedAt         sql.NullString  `json:"created_at"`
+  CreatedBy         sql.NullString  `json:"created_by"`
+  DeletedAt         sql.NullString  `json:"deleted_at"`
+  Description       sql.NullString  `json:"description"`
+  DiscountType      string          `json:"discount_type"`
+  DiscountValue     float64         `json:"discount_value"`
+  EndsAt            sql.NullString  `json:"ends_at"`
+  Id                sql.NullString  `json:"id"`
+  MaxDiscount       sql.NullFloat64 `json:"max_discount"`
+  MinOrderValue     sql.NullFloat64 `json:"min_order_value"`
+  ProductIds        []interface{}   `json:"product_ids"`
+  StartsAt          sql.NullString  `json:"starts_at"`
+  Status            sql.NullBool    `json:"status"`
+  UpdatedAt         sql.NullString  `json:"updated_at"`
+  UsageLimit        sql.NullInt32   `json:"usage_limit"`
+  UsageLimitPerUser sql.NullInt32   `json:"usage_limit_per_user"`
+  UsedCount         sql.NullInt32   `json:"used_count"`
+}
+
+type PublicPromotionsUpdate struct {
+  CategoryIds       []interface{}   `json:"category_ids"`
+  Code              sql.NullString  `json:"code"`
+  CreatedAt         sql.NullString  `json:"created_at"`
+  CreatedBy         sql.NullString  `json:"created_by"`
+  DeletedAt         sql.NullString  `json:"deleted_at"`
+  Description       sql.NullString  `json:"description"`
+  DiscountType      sql.NullString  `json:"discount_type"`
+  DiscountValue     sql.NullFloat64 `json:"discount_value"`
+  EndsAt            sql.NullString  `json:"ends_at"`
+  Id                sql.NullString  `json:"id"`
+  MaxDiscount       sql.NullFloat64 `json:"max_discount"`
+  MinOrderValue     sql.NullFloat64 `json:"min_order_value"`
+  ProductIds        []interface{}   `json:"product_ids"`
+  StartsAt          sql.NullString  `json:"starts_at"`
+  Status            sql.NullBool    `json:"status"`
+  UpdatedAt         sql.NullString  `json:"updated_at"`
+  UsageLimit        sql.NullInt32   `json:"usage_limit"`
+  UsageLimitPerUser sql.NullInt32   `json:"usage_limit_per_user"`
+  UsedCount         sql.NullInt32   `json:"used_count"`
+}
+
+type PublicPromotionUsagesSelect struct {
+  CreatedAt   string         `json:"created_at"`
+  DeletedAt   sql.NullString `json:"deleted_at"`
+  Discount    float64        `json:"discount"`
+  Id          string         `json:"id"`
+  OrderId     string         `json:"order_id"`
+  PromotionId string         `json:"promotion_id"`
+  UserId      string         `json:"user_id"`
+}
+
+type PublicPromotionUsagesInsert struct {
+  CreatedAt   sql.NullString  `json:"created_at"`
+  DeletedAt   sql.NullString  `json:"deleted_at"`
+  Discount    sql.NullFloat64 `json:"discount"`
+  Id          sql.NullString  `json:"id"`
+  OrderId     string          `json:"order_id"`
+  PromotionId string          `json:"promotion_id"`
+  UserId      string          `json:"user_id"`
+}
+
+type PublicPromotionUsagesUpdate struct {
+  CreatedAt   sql.NullString  `json:"created_at"`
+  DeletedAt   sql.NullString  `json:"deleted_at"`
+  Discount    sql.NullFloat64 `json:"discount"`
+  Id          sql.NullString  `json:"id"`
+  OrderId     sql.NullString  `json:"order_id"`
+  PromotionId sql.NullString  `json:"promotion_id"`
+  UserId      sql.NullString  `json:"user_id"`
+}
+
+type PublicPaymentsSelect struct {
+  Amount        float64        `json:"amount"`
+  CreatedAt     string         `json:"created_at"`
+  Id            string         `json:"id"`
+  Instructions  interface{}    `json:"instructions"`
+  Method        string         `json:"method"`
+  OrderId       string         `json:"order_id"`
+  PaidAt        sql.NullString `json:"paid_at"`
+  Reference     string         `json:"reference"`
+  Status        string         `json:"status"`
+  TransactionId sql.NullString `json:"transaction_id"`
+  UpdatedAt     sql.NullString `json:"updated_at"`
+  UserId        string         `json:"user_id"`
+}
+
+type PublicPaymentsInsert struct {
+  Amount        float64        `json:"amount"`
+  CreatedAt     sql.NullString `json:"created_at"`
+  Id            sql.NullString `json:"id"`
+  Instructions  interface{}    `json:"instructions"`
+  Method        string         `json:"method"`
+  OrderId       string         `json:"order_id"`
+  PaidAt        sql.NullString `json:"paid_at"`
+  Reference     string         `json:"reference"`
+  Status        sql.NullString `json:"status"`
+  TransactionId sql.NullString `json:"transaction_id"`
+  UpdatedAt     sql.NullString `json:"updated_at"`
+  UserId        string         `json:"user_id"`
+}
+
+type PublicPaymentsUpdate struct {
+  Amount        sql.NullFloat64 `json:"amount"`
+  CreatedAt     sql.NullString  `json:"created_at"`
+  Id            sql.NullString  `json:"id"`
+  Instructions  interface{}     `json:"instructions"`
+  Method        sql.NullString  `json:"method"`
+  OrderId       sql.NullString  `json:"order_id"`
+  PaidAt        sql.NullString  `json:"paid_at"`
+  Reference     sql.NullString  `json:"reference"`
+  Status        sql.NullString  `json:"status"`
+  TransactionId sql.NullString  `json:"transaction_id"`
+  UpdatedAt     sql.NullString  `json:"updated_at"`
+  UserId        sql.NullString  `json:"user_id"`
+}
+
+type PublicPaymentEventsSelect struct {
+  CreatedAt string      `json:"created_at"`
+  EventId   string      `json:"event_id"`
+  Id        string      `json:"id"`
+  Method    string      `json:"method"`
+  Payload   interface{} `json:"payload"`
+  PaymentId string      `json:"payment_id"`
+  Status    string      `json:"status"`
+}
+
+type PublicPaymentEventsInsert struct {
+  CreatedAt sql.NullString `json:"created_at"`
+  EventId   string         `json:"event_id"`
+  Id        sql.NullString `json:"id"`
+  Method    string         `json:"method"`
+  Payload   interface{}    `json:"payload"`
+  PaymentId string         `json:"payment_id"`
+  Status    string         `json:"status"`
+}
+
+type PublicPaymentEventsUpdate struct {
+  CreatedAt sql.NullString `json:"created_at"`
+  EventId   sql.NullString `json:"event_id"`
+  Id        sql.NullString `json:"id"`
+  Method    sql.NullString `json:"method"`
+  Payload   interface{}    `json:"payload"`
+  PaymentId sql.NullString `json:"payment_id"`
+  Status    sql.NullString `json:"status"`
+}
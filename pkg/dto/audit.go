@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"SangXanh/pkg/common/query"
+	"SangXanh/pkg/enum"
+	"time"
+)
+
+// AuditChange is one column that differs between the row before and after a change.
+type AuditChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type AuditTrail struct {
+	Id           string                   `json:"id"`
+	AuditId      string                   `json:"audit_id"`
+	AuditType    enum.AuditEntity         `json:"audit_type"`
+	AuditContent []AuditChange            `json:"audit_content"`
+	CreatedBy    string                   `json:"created_by"`
+	Metadata     []map[string]interface{} `json:"metadata"`
+	CreatedAt    time.Time                `json:"created_at"`
+}
+
+// AuditFilter narrows the audit log; From and To accept RFC 3339 timestamps or
+// plain dates, a plain To date includes the whole day.
+type AuditFilter struct {
+	query.Pagination
+	EntityType enum.AuditEntity `query:"entity_type"`
+	EntityId   string           `query:"entity_id"`
+	Actor      string           `query:"actor"`
+	From       string           `query:"from"`
+	To         string           `query:"to"`
+}
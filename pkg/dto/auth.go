@@ -4,10 +4,15 @@ type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 	Email    string `json:"email"`
+	// Device names the device in the session list, like "Lan's phone"; the user
+	// agent is shown when it is empty.
+	Device string        `json:"device"`
+	Client SessionClient `json:"-"`
 }
 
 type RefreshTokenRequest struct {
-	RefreshToken string `json:"refresh_token"`
+	RefreshToken string        `json:"refresh_token"`
+	Client       SessionClient `json:"-"`
 }
 
 type AuthResponse struct {
@@ -31,3 +31,14 @@ type CartFilter struct {
 	UserID string `json:"user_id"`
 	query.Pagination
 }
+
+// CartCheckout turns the current user's cart into an order. Either Address or
+// AddressIndex (position in the user's saved address list) must be given; when
+// CartIds is empty the whole cart is checked out.
+type CartCheckout struct {
+	CartIds       []string                 `json:"cart_ids"`
+	Address       string                   `json:"address"`
+	AddressIndex  *int                     `json:"address_index"`
+	PromotionCode string                   `json:"promotion_code"`
+	Metadata      []map[string]interface{} `json:"metadata"`
+}
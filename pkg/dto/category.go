@@ -9,34 +9,45 @@ import (
 type Category struct {
 	Id                string                   `json:"id"`
 	Name              string                   `json:"name"`
+	Slug              string                   `json:"slug"`
 	Metadata          []map[string]interface{} `json:"metadata"`
 	Status            bool                     `json:"status"`
 	Thumbnail         string                   `json:"thumbnail"`
 	Level             int                      `json:"level"`
 	ParentId          string                   `json:"parent_id"`
+	Position          int                      `json:"position"`
 	CreatedAt         time.Time                `json:"created_at"`
 	UpdatedAt         time.Time                `json:"updated_at"`
 	DeletedAt         time.Time                `json:"deleted_at"`
 	Description       string                   `json:"description"`
 	IsDisplayHomepage bool                     `json:"is_display_homepage"`
 	IsDisplayHeader   bool                     `json:"is_display_header"`
+	Seo
 }
 
+// CategoryCreate leaves Slug empty to derive it from the name.
 type CategoryCreate struct {
-	Name              string                   `json:"name"`
-	Thumbnail         string                   `json:"thumbnail"`
-	ParentId          string                   `json:"parent_id,omitempty"`
-	Status            bool                     `json:"status"`
-	Metadata          []map[string]interface{} `json:"metadata"`
-	Description       string                   `json:"description"`
-	Level             int                      `json:"level"`
-	IsDisplayHomepage bool                     `json:"is_display_homepage"`
-	IsDisplayHeader   bool                     `json:"is_display_header"`
+	Name        string                   `json:"name"`
+	Slug        string                   `json:"slug"`
+	Thumbnail   string                   `json:"thumbnail"`
+	ParentId    string                   `json:"parent_id,omitempty"`
+	Status      bool                     `json:"status"`
+	Metadata    []map[string]interface{} `json:"metadata"`
+	Description string                   `json:"description"`
+	// Level and Position are set by the service from where the category is placed.
+	Level             int  `json:"level"`
+	Position          int  `json:"position"`
+	IsDisplayHomepage bool `json:"is_display_homepage"`
+	IsDisplayHeader   bool `json:"is_display_header"`
+	Seo
 }
 
+// CategoryUpdate keeps the slug unless a new Slug is given or the name changes; the
+// old slug then redirects to the new one.
 type CategoryUpdate struct {
 	Id                string                   `json:"id"`
 	Name              string                   `json:"name"`
+	Slug              string                   `json:"slug"`
 	Thumbnail         string                   `json:"thumbnail"`
 	Status            bool                     `json:"status"`
 	Metadata          []map[string]interface{} `json:"metadata"`
@@ -44,15 +55,21 @@ type CategoryUpdate struct {
 	IsDisplayHomepage bool                     `json:"is_display_homepage"`
 	ParentId          string                   `json:"parent_id,omitempty"`
 	IsDisplayHeader   bool                     `json:"is_display_header"`
+	Seo
 }
 
 type CategoryResponse struct {
-	Id                string                   `json:"id"`
-	Name              string                   `json:"name"`
-	Thumbnail         string                   `json:"thumbnail"`
-	Level             int                      `json:"level"`
-	Description       string                   `json:"description"`
-	Categories        []Category               `json:"categories"`
+	Id          string     `json:"id"`
+	Name        string     `json:"name"`
+	Slug        string     `json:"slug"`
+	Thumbnail   string     `json:"thumbnail"`
+	Level       int        `json:"level"`
+	Description string     `json:"description"`
+	Categories  []Category `json:"categories"`
+	// Breadcrumb is the path from the root down to this category, itself included.
+	Breadcrumb []CategoryProduct `json:"breadcrumb,omitempty"`
+	// Seo has its empty fields filled from the name, description and thumbnail.
+	Seo               Seo                      `json:"seo"`
 	UpdatedAt         time.Time                `json:"updated_at"`
 	CreatedAt         time.Time                `json:"created_at"`
 	Status            enum.Status              `json:"status"`
@@ -64,11 +81,13 @@ type CategoryResponse struct {
 type CategoryListResponse struct {
 	Id                string                   `json:"id"`
 	Name              string                   `json:"name"`
+	Slug              string                   `json:"slug"`
 	Thumbnail         string                   `json:"thumbnail"`
 	Level             int                      `json:"level"`
 	Description       string                   `json:"description"`
 	Categories        []CategoryListResponse   `json:"categories"`
 	ParentId          string                   `json:"parent_id"`
+	Position          int                      `json:"position"`
 	Status            enum.Status              `json:"status"`
 	Metadata          []map[string]interface{} `json:"metadata"`
 	IsDisplayHomepage bool                     `json:"is_display_homepage"`
@@ -77,6 +96,43 @@ type CategoryListResponse struct {
 	UpdatedAt         time.Time                `json:"updated_at"`
 }
 
+// CategoryMove places a category under ParentId, or at the top level when it is
+// empty, at Position among its new siblings counted from 0; a position past the last
+// sibling puts it at the end. Levels follow from where the category ends up.
+type CategoryMove struct {
+	Id       string `json:"id" validate:"required"`
+	ParentId string `json:"parent_id"`
+	Position int    `json:"position" validate:"min=0"`
+}
+
+// CategoryReorder applies the moves of a drag-and-drop menu together. Moves are
+// applied by ascending position, so listing every child of a parent with its new
+// index gives exactly that order.
+type CategoryReorder struct {
+	Items []CategoryMove `json:"items" validate:"required,min=1,dive"`
+}
+
+// CategoryDelete deletes a category, by default only when it is empty. With DryRun
+// nothing is written and the result tells what the delete would have done.
+type CategoryDelete struct {
+	Id   string                  `query:"categoryId" validate:"required"`
+	Mode enum.CategoryDeleteMode `query:"mode"`
+	// TargetId receives the subcategories and products in reassign mode.
+	TargetId string `query:"target_id"`
+	DryRun   bool   `query:"dry_run"`
+}
+
+// CategoryDeleteResult counts what a delete touched, or would touch on a dry run.
+type CategoryDeleteResult struct {
+	Mode              enum.CategoryDeleteMode `json:"mode"`
+	DryRun            bool                    `json:"dry_run"`
+	DeletedCategories int                     `json:"deleted_categories"`
+	DeletedProducts   int                     `json:"deleted_products"`
+	MovedCategories   int                     `json:"moved_categories"`
+	MovedProducts     int                     `json:"moved_products"`
+	TargetId          string                  `json:"target_id,omitempty"`
+}
+
 type ListCategory struct {
 	query.Pagination
 	IsDisplayHomepage bool   `query:"is_display_homepage"`
@@ -92,6 +148,7 @@ func GetResponse(cate *Category) CategoryResponse {
 	cateResponse := CategoryResponse{
 		Id:                cate.Id,
 		Name:              cate.Name,
+		Slug:              cate.Slug,
 		Thumbnail:         cate.Thumbnail,
 		Level:             cate.Level,
 		Description:       cate.Description,
@@ -101,6 +158,7 @@ func GetResponse(cate *Category) CategoryResponse {
 		IsDisplayHomepage: cate.IsDisplayHomepage,
 		IsDisplayHeader:   cate.IsDisplayHeader,
 		Metadata:          cate.Metadata,
+		Seo:               cate.Seo.Or(cate.Name, cate.Description, cate.Thumbnail),
 	}
 	return cateResponse
 }
@@ -0,0 +1,25 @@
+package dto
+
+import "SangXanh/pkg/enum"
+
+// Payloads of the realtime events pushed over /api/ws.
+
+type OrderEvent struct {
+	OrderId    string           `json:"order_id"`
+	UserId     string           `json:"user_id"`
+	FromStatus enum.OrderStatus `json:"from_status,omitempty"`
+	Status     enum.OrderStatus `json:"status"`
+	GrandTotal float64          `json:"grand_total,omitempty"`
+}
+
+type StockEvent struct {
+	ProductOptionId string `json:"product_option_id"`
+	Stock           int    `json:"stock"`
+}
+
+type PostEvent struct {
+	Id    string        `json:"id"`
+	Title string        `json:"title"`
+	Slug  string        `json:"slug"`
+	Type  enum.PostType `json:"type"`
+}
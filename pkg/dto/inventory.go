@@ -0,0 +1,29 @@
+package dto
+
+import "SangXanh/pkg/common/query"
+
+type StockLine struct {
+	ProductOptionId string `json:"product_option_id"`
+	Quantity        int    `json:"quantity"`
+}
+
+// StockAdjust adds Delta units to the stock of an option, or takes them away when it
+// is negative, e.g. for a delivery or a stock count.
+type StockAdjust struct {
+	ProductOptionId string `json:"product_option_id" validate:"required"`
+	Delta           int    `json:"delta"`
+}
+
+type LowStockFilter struct {
+	query.Pagination
+	Threshold int `query:"threshold"`
+}
+
+type LowStockItem struct {
+	Id        string          `json:"id"`
+	Name      string          `json:"name"`
+	ProductId string          `json:"product_id"`
+	Price     float64         `json:"price"`
+	Stock     int             `json:"stock"`
+	Product   CategoryProduct `json:"products"`
+}
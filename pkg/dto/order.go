@@ -7,13 +7,18 @@ import (
 )
 
 type Order struct {
-	Id        string                   `json:"id"`
-	CreatedAt time.Time                `json:"created_at"`
-	UpdatedAt time.Time                `json:"updated_at"`
-	UserId    string                   `json:"user_id"`
-	Address   string                   `json:"address"`
-	Status    enum.OrderStatus         `json:"status"`
-	Metadata  []map[string]interface{} `json:"metadata"`
+	Id                string                   `json:"id"`
+	CreatedAt         time.Time                `json:"created_at"`
+	UpdatedAt         time.Time                `json:"updated_at"`
+	UserId            string                   `json:"user_id"`
+	Address           string                   `json:"address"`
+	Status            enum.OrderStatus         `json:"status"`
+	Subtotal          float64                  `json:"subtotal"`
+	DiscountTotal     float64                  `json:"discount_total"`
+	PromotionCode     string                   `json:"promotion_code"`
+	PromotionDiscount float64                  `json:"promotion_discount"`
+	GrandTotal        float64                  `json:"grand_total"`
+	Metadata          []map[string]interface{} `json:"metadata"`
 }
 
 type OrderDetail struct {
@@ -24,26 +29,30 @@ type OrderDetail struct {
 	OrderId         string                   `json:"order_id"`
 	ProductOptionId string                   `json:"product_option_id"`
 	Quantity        int                      `json:"quantity"`
+	UnitPrice       float64                  `json:"unit_price"`
 	Discount        float64                  `json:"discount"`
 	DiscountType    enum.DiscountType        `json:"discount_type"`
+	DiscountAmount  float64                  `json:"discount_amount"`
+	LineTotal       float64                  `json:"line_total"`
 	Metadata        []map[string]interface{} `json:"metadata"`
 }
 
+// OrderDetailBase is what a client may send for an order line; prices and
+// discounts are always resolved on the server.
 type OrderDetailBase struct {
 	Id              string                   `json:"id"`
 	ProductOptionId string                   `json:"product_option_id"`
 	Quantity        int                      `json:"quantity"`
-	Discount        float64                  `json:"discount"`
-	DiscountType    enum.DiscountType        `json:"discount_type"`
 	Metadata        []map[string]interface{} `json:"metadata"`
 }
 
 type OrderCreate struct {
-	UserId       string                   `json:"user_id"`
-	Address      string                   `json:"address"`
-	Status       enum.OrderStatus         `json:"status"`
-	Metadata     []map[string]interface{} `json:"metadata"`
-	OrderDetails []OrderDetailBase        `json:"order_details"`
+	UserId        string                   `json:"user_id"`
+	Address       string                   `json:"address"`
+	Status        enum.OrderStatus         `json:"status"`
+	PromotionCode string                   `json:"promotion_code"`
+	Metadata      []map[string]interface{} `json:"metadata"`
+	OrderDetails  []OrderDetailBase        `json:"order_details"`
 }
 type OrderUpdate struct {
 	Id           string                   `json:"id"`
@@ -63,3 +72,19 @@ type OrderListFilter struct {
 	UserId string           `query:"user_id"`
 	query.Pagination
 }
+
+type OrderStatusUpdate struct {
+	OrderId string           `json:"order_id" validate:"required"`
+	Status  enum.OrderStatus `json:"status" validate:"required"`
+	Note    string           `json:"note"`
+}
+
+type OrderStatusHistory struct {
+	Id         string           `json:"id"`
+	OrderId    string           `json:"order_id"`
+	FromStatus enum.OrderStatus `json:"from_status"`
+	ToStatus   enum.OrderStatus `json:"to_status"`
+	Note       string           `json:"note"`
+	CreatedBy  string           `json:"created_by"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"SangXanh/pkg/enum"
+	"time"
+)
+
+type Payment struct {
+	Id            string                 `json:"id"`
+	OrderId       string                 `json:"order_id"`
+	UserId        string                 `json:"user_id"`
+	Method        enum.PaymentMethod     `json:"method"`
+	Status        enum.PaymentStatus     `json:"status"`
+	Amount        float64                `json:"amount"`
+	Reference     string                 `json:"reference"`
+	TransactionId string                 `json:"transaction_id"`
+	Instructions  map[string]interface{} `json:"instructions"`
+	PaidAt        *time.Time             `json:"paid_at"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+}
+
+type PaymentCreate struct {
+	OrderId string             `json:"order_id" validate:"required"`
+	Method  enum.PaymentMethod `json:"method" validate:"required"`
+}
+
+type PaymentConfirm struct {
+	Id            string `json:"id" validate:"required"`
+	TransactionId string `json:"transaction_id"`
+}
+
+// PaymentEvent is a gateway callback after its signature has been checked.
+// EventId identifies the callback itself, so a redelivered callback can be ignored.
+type PaymentEvent struct {
+	EventId       string             `json:"id"`
+	Reference     string             `json:"reference"`
+	TransactionId string             `json:"transaction_id"`
+	Amount        float64            `json:"amount"`
+	Status        enum.PaymentStatus `json:"status"`
+}
@@ -0,0 +1,93 @@
+package dto
+
+import (
+	"SangXanh/pkg/common/query"
+	"SangXanh/pkg/enum"
+	"time"
+)
+
+type Post struct {
+	Id          string                   `json:"id"`
+	Title       string                   `json:"title"`
+	Slug        string                   `json:"slug"`
+	Content     string                   `json:"content"`
+	Thumbnail   string                   `json:"thumbnail"`
+	Type        enum.PostType            `json:"type"`
+	Status      bool                     `json:"status"`
+	Assignee    string                   `json:"assignee"`
+	CreatedBy   string                   `json:"created_by"`
+	Metadata    []map[string]interface{} `json:"metadata"`
+	PublishedAt *time.Time               `json:"published_at"`
+	CreatedAt   time.Time                `json:"created_at"`
+	UpdatedAt   time.Time                `json:"updated_at"`
+}
+
+type PostResponse struct {
+	Id          string                   `json:"id"`
+	Title       string                   `json:"title"`
+	Slug        string                   `json:"slug"`
+	Content     string                   `json:"content"`
+	Thumbnail   string                   `json:"thumbnail"`
+	Type        enum.PostType            `json:"type"`
+	Status      enum.PostStatus          `json:"status"`
+	Assignee    string                   `json:"assignee"`
+	CreatedBy   string                   `json:"created_by"`
+	Metadata    []map[string]interface{} `json:"metadata"`
+	PublishedAt *time.Time               `json:"published_at"`
+	CreatedAt   time.Time                `json:"created_at"`
+	UpdatedAt   time.Time                `json:"updated_at"`
+}
+
+func GetPostResponse(post *Post) PostResponse {
+	return PostResponse{
+		Id:          post.Id,
+		Title:       post.Title,
+		Slug:        post.Slug,
+		Content:     post.Content,
+		Thumbnail:   post.Thumbnail,
+		Type:        post.Type,
+		Status:      enum.ToPostStatus(post.Status),
+		Assignee:    post.Assignee,
+		CreatedBy:   post.CreatedBy,
+		Metadata:    post.Metadata,
+		PublishedAt: post.PublishedAt,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+	}
+}
+
+// PostCreate leaves Slug empty to derive it from the title.
+type PostCreate struct {
+	Title     string                   `json:"title" validate:"required"`
+	Slug      string                   `json:"slug"`
+	Content   string                   `json:"content"`
+	Thumbnail string                   `json:"thumbnail"`
+	Type      enum.PostType            `json:"type" validate:"required"`
+	Assignee  string                   `json:"assignee"`
+	Metadata  []map[string]interface{} `json:"metadata"`
+}
+
+type PostUpdate struct {
+	Id string `json:"id" validate:"required"`
+	PostCreate
+}
+
+type PostAssign struct {
+	Id       string `json:"id" validate:"required"`
+	Assignee string `json:"assignee" validate:"required"`
+}
+
+type PostPublish struct {
+	Id     string          `json:"id" validate:"required"`
+	Status enum.PostStatus `json:"status" validate:"required"`
+}
+
+// PostFilter lists posts. Status and Assignee are only honoured for staff; the
+// public listing always shows published posts.
+type PostFilter struct {
+	query.Pagination
+	Type     enum.PostType   `query:"type"`
+	Title    string          `query:"title"`
+	Status   enum.PostStatus `query:"status"`
+	Assignee string          `query:"assignee"`
+}
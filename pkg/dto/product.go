@@ -9,6 +9,7 @@ import (
 type Product struct {
 	Id           string              `json:"id"`
 	Name         string              `json:"name"`
+	Slug         string              `json:"slug"`
 	Price        float32             `json:"price"`
 	Content      string              `json:"content"`
 	ImageDetail  string              `json:"image_detail"`
@@ -19,13 +20,17 @@ type Product struct {
 	ProductCode  string              `json:"product_code"`
 	Description  string              `json:"description"`
 	Metadata     []map[string]string `json:"metadata"`
-	CreatedAt    time.Time           `json:"created_at"`
-	UpdatedAt    time.Time           `json:"updated_at"`
-	DeletedAt    time.Time           `json:"deleted_at"`
+	Seo
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	DeletedAt time.Time `json:"deleted_at"`
 }
 
+// ProductCreated leaves Slug empty to derive it from the name, and the Seo fields
+// empty to fall back to the name, description and thumbnail.
 type ProductCreated struct {
 	Name         string              `json:"name"`
+	Slug         string              `json:"slug"`
 	Price        float32             `json:"price"`
 	Content      string              `json:"content"`
 	ImageDetail  string              `json:"image_detail"`
@@ -36,11 +41,15 @@ type ProductCreated struct {
 	ProductCode  string              `json:"product_code"`
 	Description  string              `json:"description"`
 	Metadata     []map[string]string `json:"metadata"`
+	Seo
 }
 
+// ProductUpdated keeps the slug unless a new Slug is given or the name changes; the
+// old slug then redirects to the new one.
 type ProductUpdated struct {
 	Id           string              `json:"id"`
 	Name         string              `json:"name"`
+	Slug         string              `json:"slug"`
 	Price        float32             `json:"price"`
 	Content      string              `json:"content"`
 	ProductCode  string              `json:"product_code"`
@@ -51,6 +60,7 @@ type ProductUpdated struct {
 	Discount     float32             `json:"discount"`
 	DiscountType enum.DiscountType   `json:"discount_type"`
 	Metadata     []map[string]string `json:"metadata"`
+	Seo
 }
 
 type ProductResponse struct {
@@ -72,6 +82,7 @@ type ProductResponse struct {
 type ProductList struct {
 	Id           string            `json:"id"`
 	Name         string            `json:"name"`
+	Slug         string            `json:"slug"`
 	Price        float64           `json:"price"`
 	Content      string            `json:"content"`
 	Thumbnail    string            `json:"thumbnail"`
@@ -86,31 +97,90 @@ type ProductList struct {
 type CategoryProduct struct {
 	Id   string `json:"id"`
 	Name string `json:"name"`
+	Slug string `json:"slug,omitempty"`
 }
 
 type ProductFilter struct {
 	query.Pagination
-	Name        string  `query:"name"`
-	CategoryId  string  `query:"category_id"`
-	IsDiscount  bool    `query:"is_discount"`
-	GreaterThan float64 `query:"greater_than"`
-	SmallerThan float64 `query:"smaller_than"`
+	Name       string `query:"name"`
+	CategoryId string `query:"category_id"`
+	// IncludeDescendants widens CategoryId to the products of its subcategories.
+	IncludeDescendants bool    `query:"include_descendants"`
+	IsDiscount         bool    `query:"is_discount"`
+	GreaterThan        float64 `query:"greater_than"`
+	SmallerThan        float64 `query:"smaller_than"`
+	// CategoryIds selects the products of any of the categories, subcategories
+	// included; ids may be repeated or comma separated.
+	CategoryIds []string `query:"category_ids"`
+	// Variants selects by variant value as "name:value", e.g. "Kích thước:Lớn". Values
+	// of one variant are alternatives, every variant named must match.
+	Variants []string `query:"variant"`
+	// PriceRanges selects the products priced in any of the ranges, given as
+	// "min-max" with the max excluded and either end optional, e.g. "500000-".
+	PriceRanges []string `query:"price"`
+	InStock     bool     `query:"in_stock"`
+	// Facets asks for the facet counts in the response meta.
+	Facets bool `query:"facets"`
+}
+
+// PriceBuckets are the price ranges counted for the price facet, in VND.
+var PriceBuckets = []string{"0-100000", "100000-200000", "200000-500000", "500000-1000000", "1000000-"}
+
+// ProductFacets counts the products for each facet value. A facet is counted with
+// every other facet filter applied but not its own, so the counts show what picking
+// one more value of it would add.
+type ProductFacets struct {
+	Categories []FacetCount   `json:"categories"`
+	Variants   []VariantFacet `json:"variants"`
+	Prices     []FacetCount   `json:"prices"`
+	InStock    int            `json:"in_stock"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+type VariantFacet struct {
+	Name   string       `json:"name"`
+	Values []FacetCount `json:"values"`
+}
+
+// ProductSearch is a full-text search over the name, product code, description and
+// category name of the live products; matching ignores case and diacritics.
+type ProductSearch struct {
+	query.Pagination
+	Q string `query:"q" validate:"required"`
+}
+
+// ProductSearchHit is a product found by a search. Highlights maps the fields that
+// matched to their text with the matching words in <mark> tags.
+type ProductSearchHit struct {
+	ProductList
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
 }
 
 type ProductDetail struct {
-	Id              string                  `json:"id"`
-	Discount        float32                 `json:"discount"`
-	Name            string                  `json:"name"`
-	Price           float32                 `json:"price"`
-	Content         string                  `json:"content"`
-	ProductCode     string                  `json:"product_code"`
-	Description     string                  `json:"description"`
-	ImageDetail     string                  `json:"image_detail"`
-	Thumbnail       string                  `json:"thumbnail"`
-	DiscountType    enum.DiscountType       `json:"discount_type"`
-	MaxPrice        float32                 `json:"max_price"`
-	MinPrice        float32                 `json:"min_price"`
-	CategoryProduct CategoryProduct         `json:"categories"`
+	Id              string            `json:"id"`
+	Discount        float32           `json:"discount"`
+	Name            string            `json:"name"`
+	Slug            string            `json:"slug"`
+	Price           float32           `json:"price"`
+	Content         string            `json:"content"`
+	ProductCode     string            `json:"product_code"`
+	Description     string            `json:"description"`
+	ImageDetail     string            `json:"image_detail"`
+	Thumbnail       string            `json:"thumbnail"`
+	DiscountType    enum.DiscountType `json:"discount_type"`
+	MaxPrice        float32           `json:"max_price"`
+	MinPrice        float32           `json:"min_price"`
+	CategoryProduct CategoryProduct   `json:"categories"`
+	// Seo has its empty fields filled from the name, description and thumbnail.
+	Seo Seo `json:"seo"`
+	// Breadcrumb is the category path from the root down to the product's category.
+	Breadcrumb      []CategoryProduct       `json:"breadcrumb"`
 	ProductOptions  []ProductOptionResponse `json:"product_option_detail"`
 	ProductVariants []ProductVariant        `json:"product_variant_detail"`
 }
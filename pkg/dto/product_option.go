@@ -1,12 +1,16 @@
 package dto
 
-import "time"
+import (
+	"SangXanh/pkg/enum"
+	"time"
+)
 
 type ProductOption struct {
 	Id        string                `json:"id"`
 	Name      string                `json:"name"`
 	ProductId string                `json:"product_id"`
 	Price     float64               `json:"price"`
+	Stock     int                   `json:"stock"`
 	Metadata  []map[string]string   `json:"metadata"`
 	Detail    []ProductOptionDetail `json:"detail"`
 	CreatedAt time.Time             `json:"created_at"`
@@ -32,15 +36,19 @@ type ProductOptionCreate struct {
 	Name      string                `json:"name"`
 	ProductId string                `json:"product_id"`
 	Price     float64               `json:"price"`
+	Stock     int                   `json:"stock"`
 	Detail    []ProductOptionDetail `json:"detail"`
 	Metadata  []map[string]string   `json:"metadata"`
 }
 
+// ProductOptionUpdate edits an option. Stock is only read for the new options of a
+// bulk update; the stock of an existing option is changed with a StockAdjust.
 type ProductOptionUpdate struct {
 	Id        string                `json:"id"`
 	Name      string                `json:"name"`
 	ProductId string                `json:"product_id"`
 	Price     float64               `json:"price"`
+	Stock     int                   `json:"stock"`
 	Detail    []ProductOptionDetail `json:"detail"`
 	Metadata  []map[string]string   `json:"metadata"`
 }
@@ -50,6 +58,7 @@ type ProductOptionResponse struct {
 	Name      string                       `json:"name"`
 	ProductId string                       `json:"product_id"`
 	Price     float64                      `json:"price"`
+	Stock     int                          `json:"stock"`
 	Metadata  []map[string]string          `json:"metadata"`
 	Detail    []ProductOptionVariantDetail `json:"detail"`
 	CreatedAt time.Time                    `json:"created_at"`
@@ -61,3 +70,15 @@ type ProductOptionVariantDetail struct {
 	VariantName  string `json:"variant_name"`
 	VariantValue string `json:"variant_value"` // == Name from the request
 }
+
+// ProductOptionPrice is a live option of a live product together with the product
+// fields an order line is priced from.
+type ProductOptionPrice struct {
+	Id           string            `json:"id"`
+	Price        float64           `json:"price"`
+	ProductId    string            `json:"product_id"`
+	CategoryId   string            `json:"category_id"`
+	ProductPrice float64           `json:"product_price"`
+	Discount     float64           `json:"discount"`
+	DiscountType enum.DiscountType `json:"discount_type"`
+}
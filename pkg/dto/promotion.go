@@ -0,0 +1,86 @@
+package dto
+
+import (
+	"SangXanh/pkg/common/query"
+	"SangXanh/pkg/enum"
+	"time"
+)
+
+type Promotion struct {
+	Id                string            `json:"id"`
+	Code              string            `json:"code"`
+	Description       string            `json:"description"`
+	DiscountType      enum.DiscountType `json:"discount_type"`
+	DiscountValue     float64           `json:"discount_value"`
+	MaxDiscount       float64           `json:"max_discount"`
+	MinOrderValue     float64           `json:"min_order_value"`
+	StartsAt          *time.Time        `json:"starts_at"`
+	EndsAt            *time.Time        `json:"ends_at"`
+	UsageLimit        int               `json:"usage_limit"`
+	UsageLimitPerUser int               `json:"usage_limit_per_user"`
+	UsedCount         int               `json:"used_count"`
+	CategoryIds       []string          `json:"category_ids"`
+	ProductIds        []string          `json:"product_ids"`
+	Status            bool              `json:"status"`
+	CreatedBy         string            `json:"created_by"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+}
+
+// PromotionCreate describes a promotion code. Zero limits mean "no limit"; empty
+// CategoryIds and ProductIds make the code apply to the whole order.
+type PromotionCreate struct {
+	Code              string            `json:"code" validate:"required"`
+	Description       string            `json:"description"`
+	DiscountType      enum.DiscountType `json:"discount_type" validate:"required"`
+	DiscountValue     float64           `json:"discount_value"`
+	MaxDiscount       float64           `json:"max_discount"`
+	MinOrderValue     float64           `json:"min_order_value"`
+	StartsAt          *time.Time        `json:"starts_at"`
+	EndsAt            *time.Time        `json:"ends_at"`
+	UsageLimit        int               `json:"usage_limit"`
+	UsageLimitPerUser int               `json:"usage_limit_per_user"`
+	CategoryIds       []string          `json:"category_ids"`
+	ProductIds        []string          `json:"product_ids"`
+	Status            bool              `json:"status"`
+}
+
+type PromotionUpdate struct {
+	Id string `json:"id" validate:"required"`
+	PromotionCreate
+}
+
+type PromotionFilter struct {
+	query.Pagination
+	Code   string `query:"code"`
+	Active bool   `query:"active"`
+}
+
+type PromotionValidate struct {
+	Code         string            `json:"code" validate:"required"`
+	OrderDetails []OrderDetailBase `json:"order_details"`
+}
+
+// PromotionLine is the part of a priced order line a promotion needs to decide
+// whether, and by how much, the line is discounted.
+type PromotionLine struct {
+	ProductId  string  `json:"product_id"`
+	CategoryId string  `json:"category_id"`
+	LineTotal  float64 `json:"line_total"`
+}
+
+type AppliedPromotion struct {
+	PromotionId      string  `json:"promotion_id"`
+	Code             string  `json:"code"`
+	EligibleSubtotal float64 `json:"eligible_subtotal"`
+	Discount         float64 `json:"discount"`
+}
+
+// PromotionUsage records that an order redeemed a promotion.
+type PromotionUsage struct {
+	Id          string  `json:"id"`
+	PromotionId string  `json:"promotion_id"`
+	OrderId     string  `json:"order_id"`
+	UserId      string  `json:"user_id"`
+	Discount    float64 `json:"discount"`
+}
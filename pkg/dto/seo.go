@@ -0,0 +1,42 @@
+package dto
+
+import "strings"
+
+// Seo holds what a storefront page puts in its head for a product or category. It is
+// stored in the meta_title, meta_description and canonical_image columns.
+type Seo struct {
+	MetaTitle       string `json:"meta_title"`
+	MetaDescription string `json:"meta_description"`
+	CanonicalImage  string `json:"canonical_image"`
+}
+
+// seoDescriptionLength is about what search engines show of a description.
+const seoDescriptionLength = 160
+
+// Or fills the fields left empty from the name, description and thumbnail of what
+// the page is about.
+func (s Seo) Or(name, description, thumbnail string) Seo {
+	if s.MetaTitle == "" {
+		s.MetaTitle = name
+	}
+	if s.MetaDescription == "" {
+		s.MetaDescription = shorten(strings.Join(strings.Fields(description), " "), seoDescriptionLength)
+	}
+	if s.CanonicalImage == "" {
+		s.CanonicalImage = thumbnail
+	}
+	return s
+}
+
+// shorten cuts text to at most max runes, at a space when there is one.
+func shorten(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	cut := string(runes[:max-1])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
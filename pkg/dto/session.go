@@ -0,0 +1,33 @@
+package dto
+
+import "time"
+
+// Session is one signed-in device of a user. Its id is the session_id claim of the
+// access tokens issued for it.
+type Session struct {
+	Id         string     `json:"id"`
+	UserId     string     `json:"user_id"`
+	Device     string     `json:"device"`
+	UserAgent  string     `json:"user_agent"`
+	Ip         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// SessionClient is what a request tells about the device it comes from.
+type SessionClient struct {
+	Device    string
+	UserAgent string
+	Ip        string
+}
+
+type SessionResponse struct {
+	Session
+	// Current marks the session of the token the list was asked with.
+	Current bool `json:"current"`
+}
+
+type LogoutResponse struct {
+	Revoked int `json:"revoked"`
+}
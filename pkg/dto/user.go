@@ -52,6 +52,7 @@ type UserRegisterRequest struct {
 	BasicAddress string            `json:"basic_address"`
 	Metadata     map[string]string `json:"metadata"`
 	FullName     string            `json:"full_name"`
+	Client       SessionClient     `json:"-"`
 }
 
 type UserRegisterData struct {
@@ -88,7 +89,8 @@ type ListUser struct {
 }
 
 type ResetPasswordRequest struct {
-	Email string `json:"email"`
+	Email  string        `json:"email"`
+	Client SessionClient `json:"-"`
 }
 
 type ForgotPasswordRequest struct {
@@ -0,0 +1,18 @@
+package enum
+
+type AuditEntity string
+
+const (
+	AuditProduct  AuditEntity = "product"
+	AuditCategory AuditEntity = "category"
+	AuditOrder    AuditEntity = "order"
+	AuditUser     AuditEntity = "user"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
@@ -0,0 +1,23 @@
+package enum
+
+// CategoryDeleteMode says what happens to the subcategories and products of a
+// category being deleted.
+type CategoryDeleteMode string
+
+const (
+	// CategoryDeleteRestrict refuses to delete a category that still has
+	// subcategories or products.
+	CategoryDeleteRestrict CategoryDeleteMode = "restrict"
+	// CategoryDeleteCascade deletes the subcategories and products along with it.
+	CategoryDeleteCascade CategoryDeleteMode = "cascade"
+	// CategoryDeleteReassign moves the subcategories and products to another category.
+	CategoryDeleteReassign CategoryDeleteMode = "reassign"
+)
+
+func (m CategoryDeleteMode) IsValid() bool {
+	switch m {
+	case CategoryDeleteRestrict, CategoryDeleteCascade, CategoryDeleteReassign:
+		return true
+	}
+	return false
+}
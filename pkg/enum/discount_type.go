@@ -2,5 +2,23 @@ package enum
 
 type DiscountType string
 
-const Percent = "percent"
-const Number = "number"
+const (
+	Percent DiscountType = "percent"
+	Number  DiscountType = "number"
+)
+
+// Amount returns how much is taken off a single unit priced at price.
+// The result never exceeds the price itself; unknown types give no discount.
+func (t DiscountType) Amount(price, discount float64) float64 {
+	if price <= 0 || discount <= 0 {
+		return 0
+	}
+	var amount float64
+	switch t {
+	case Percent:
+		amount = price * min(discount, 100) / 100
+	case Number:
+		amount = discount
+	}
+	return min(amount, price)
+}
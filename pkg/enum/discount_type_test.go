@@ -0,0 +1,15 @@
+package enum
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDiscountType_Amount(t *testing.T) {
+	assert.Equal(t, 25.0, Percent.Amount(100, 25))
+	assert.Equal(t, 100.0, Percent.Amount(100, 150))
+	assert.Equal(t, 30.0, Number.Amount(100, 30))
+	assert.Equal(t, 100.0, Number.Amount(100, 300))
+	assert.Equal(t, 0.0, Number.Amount(100, -5))
+	assert.Equal(t, 0.0, DiscountType("").Amount(100, 10))
+}
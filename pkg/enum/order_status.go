@@ -2,6 +2,64 @@ package enum
 
 type OrderStatus string
 
-const Pending = "pending"
-const Complete = "complete"
-const Cancelled = "cancelled"
+const (
+	Pending         OrderStatus = "pending"
+	Paid            OrderStatus = "paid"
+	Confirmed       OrderStatus = "confirmed"
+	Packed          OrderStatus = "packed"
+	Shipping        OrderStatus = "shipping"
+	Delivered       OrderStatus = "delivered"
+	Complete        OrderStatus = "complete"
+	Cancelled       OrderStatus = "cancelled"
+	ReturnRequested OrderStatus = "return_requested"
+	Returned        OrderStatus = "returned"
+)
+
+// orderTransitions lists, for every status, the statuses an order may move to next.
+// Statuses without an entry are terminal.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	Pending:         {Paid, Confirmed, Cancelled},
+	Paid:            {Confirmed, Cancelled},
+	Confirmed:       {Packed, Cancelled},
+	Packed:          {Shipping, Cancelled},
+	Shipping:        {Delivered},
+	Delivered:       {Complete, ReturnRequested},
+	ReturnRequested: {Returned, Complete},
+}
+
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case Pending, Paid, Confirmed, Packed, Shipping, Delivered, Complete, Cancelled, ReturnRequested, Returned:
+		return true
+	}
+	return false
+}
+
+func (s OrderStatus) IsTerminal() bool {
+	return len(orderTransitions[s]) == 0
+}
+
+// IsStockReserved reports whether stock taken by an order in status s has not left
+// the warehouse yet, i.e. it must be handed back if the order goes away.
+func (s OrderStatus) IsStockReserved() bool {
+	switch s {
+	case Pending, Paid, Confirmed, Packed:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether an order in status s may be moved to next.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStatuses returns the statuses reachable from s in a single step.
+func (s OrderStatus) NextStatuses() []OrderStatus {
+	return append([]OrderStatus(nil), orderTransitions[s]...)
+}
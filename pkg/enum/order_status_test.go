@@ -0,0 +1,47 @@
+package enum
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestOrderStatus_CanTransitionTo(t *testing.T) {
+	assert.True(t, Pending.CanTransitionTo(Confirmed))
+	assert.True(t, Pending.CanTransitionTo(Paid))
+	assert.True(t, Paid.CanTransitionTo(Confirmed))
+	assert.True(t, Paid.CanTransitionTo(Cancelled))
+	assert.True(t, Confirmed.CanTransitionTo(Packed))
+	assert.True(t, Packed.CanTransitionTo(Shipping))
+	assert.True(t, Shipping.CanTransitionTo(Delivered))
+	assert.True(t, Delivered.CanTransitionTo(Complete))
+	assert.True(t, Delivered.CanTransitionTo(ReturnRequested))
+	assert.True(t, ReturnRequested.CanTransitionTo(Returned))
+
+	assert.False(t, Cancelled.CanTransitionTo(Complete))
+	assert.False(t, Pending.CanTransitionTo(Complete))
+	assert.False(t, Shipping.CanTransitionTo(Cancelled))
+	assert.False(t, Complete.CanTransitionTo(Pending))
+	assert.False(t, Paid.CanTransitionTo(Pending))
+}
+
+func TestOrderStatus_IsTerminal(t *testing.T) {
+	assert.True(t, Complete.IsTerminal())
+	assert.True(t, Cancelled.IsTerminal())
+	assert.True(t, Returned.IsTerminal())
+	assert.False(t, Pending.IsTerminal())
+	assert.False(t, Delivered.IsTerminal())
+}
+
+func TestOrderStatus_IsValid(t *testing.T) {
+	assert.True(t, Shipping.IsValid())
+	assert.False(t, OrderStatus("shipped").IsValid())
+	assert.False(t, OrderStatus("").IsValid())
+}
+
+func TestOrderStatus_IsStockReserved(t *testing.T) {
+	assert.True(t, Pending.IsStockReserved())
+	assert.True(t, Paid.IsStockReserved())
+	assert.True(t, Packed.IsStockReserved())
+	assert.False(t, Shipping.IsStockReserved())
+	assert.False(t, Cancelled.IsStockReserved())
+}
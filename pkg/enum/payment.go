@@ -0,0 +1,23 @@
+package enum
+
+type PaymentMethod string
+
+const (
+	CashOnDelivery PaymentMethod = "cod"
+	BankTransfer   PaymentMethod = "bank_transfer"
+	FakePayment    PaymentMethod = "fake"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentPending   PaymentStatus = "pending"
+	PaymentSucceeded PaymentStatus = "succeeded"
+	PaymentFailed    PaymentStatus = "failed"
+	PaymentCancelled PaymentStatus = "cancelled"
+)
+
+// IsFinal reports whether a payment in status s can no longer change.
+func (s PaymentStatus) IsFinal() bool {
+	return s != PaymentPending
+}
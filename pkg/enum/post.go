@@ -0,0 +1,32 @@
+package enum
+
+type PostType string
+
+const (
+	PostNews      PostType = "news"
+	PostGuide     PostType = "guide"
+	PostPromotion PostType = "promotion"
+)
+
+func (t PostType) IsValid() bool {
+	switch t {
+	case PostNews, PostGuide, PostPromotion:
+		return true
+	}
+	return false
+}
+
+// PostStatus is stored as the boolean status column of posts: true means published.
+type PostStatus string
+
+const (
+	Draft     PostStatus = "draft"
+	Published PostStatus = "published"
+)
+
+func ToPostStatus(status bool) PostStatus {
+	if status {
+		return Published
+	}
+	return Draft
+}
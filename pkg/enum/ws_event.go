@@ -3,5 +3,14 @@ package enum
 type WebsocketEvent string
 
 const (
-	ExampleEvent WebsocketEvent = "EXAMPLE"
+	ExampleEvent            WebsocketEvent = "EXAMPLE"
+	OrderCreatedEvent       WebsocketEvent = "ORDER_CREATED"
+	OrderStatusChangedEvent WebsocketEvent = "ORDER_STATUS_CHANGED"
+	StockLowEvent           WebsocketEvent = "STOCK_LOW"
+	PostPublishedEvent      WebsocketEvent = "POST_PUBLISHED"
+	// PongEvent answers a {"type":"ping"} sent by the client.
+	PongEvent WebsocketEvent = "PONG"
+	// ResyncEvent tells a resuming client that events were missed beyond what the
+	// server still remembers, so it should reload its state.
+	ResyncEvent WebsocketEvent = "RESYNC"
 )
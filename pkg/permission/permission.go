@@ -0,0 +1,145 @@
+// Package permission is the one place that says which role may do what. Routes ask
+// for a permission through middleware.Require; services check ownership of the
+// record at hand with CheckOwner.
+package permission
+
+import (
+	"SangXanh/pkg/auth"
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/enum"
+	"context"
+)
+
+// Resource is a kind of record the API acts on.
+type Resource string
+
+const (
+	Products        Resource = "products"
+	Categories      Resource = "categories"
+	ProductVariants Resource = "product_variants"
+	ProductOptions  Resource = "product_options"
+	Images          Resource = "images"
+	Orders          Resource = "orders"
+	Carts           Resource = "carts"
+	Payments        Resource = "payments"
+	Promotions      Resource = "promotions"
+	Posts           Resource = "posts"
+	Audit           Resource = "audit"
+	Inventory       Resource = "inventory"
+	Users           Resource = "users"
+)
+
+type Action string
+
+const (
+	Read   Action = "read"
+	Create Action = "create"
+	Update Action = "update"
+	Delete Action = "delete"
+	// Manage is the back-office work on a resource beyond plain writes: changing the
+	// status of an order, confirming a payment, assigning and publishing a post.
+	Manage Action = "manage"
+)
+
+// Scope is how far a permission reaches.
+type Scope int
+
+const (
+	// None is no permission at all.
+	None Scope = iota
+	// Own reaches the records that belong to the caller.
+	Own
+	// Any reaches every record.
+	Any
+)
+
+type grants map[string]Scope
+
+var (
+	adminOnly = grants{enum.Admin: Any}
+	staff     = grants{enum.Admin: Any, enum.Marketing: Any}
+	shoppers  = grants{enum.Admin: Own, enum.User: Own}
+)
+
+// matrix grants roles a scope per resource and action; anything missing is denied.
+// Reading the catalogue, published posts and slugs needs no permission.
+var matrix = map[Resource]map[Action]grants{
+	Products:        {Create: adminOnly, Update: adminOnly, Delete: adminOnly},
+	Categories:      {Create: adminOnly, Update: adminOnly, Delete: adminOnly},
+	ProductVariants: {Create: adminOnly, Update: adminOnly, Delete: adminOnly},
+	ProductOptions:  {Create: adminOnly, Update: adminOnly, Delete: adminOnly},
+	Images:          {Create: staff},
+	Orders: {
+		Read:   {enum.Admin: Any, enum.User: Own},
+		Create: shoppers,
+		Update: {enum.Admin: Any, enum.User: Own},
+		Delete: adminOnly,
+		// customers may only cancel their own orders, see OrderService.UpdateOrderStatus
+		Manage: {enum.Admin: Any, enum.User: Own},
+	},
+	Carts: {Read: shoppers, Create: shoppers, Update: shoppers, Delete: shoppers},
+	Payments: {
+		Read:   {enum.Admin: Any, enum.User: Own},
+		Create: {enum.Admin: Any, enum.User: Own},
+		Manage: adminOnly,
+	},
+	Promotions: {Read: staff, Create: staff, Update: staff, Delete: staff},
+	// marketing edits the posts assigned to them, or their own nobody was assigned to
+	Posts: {
+		Read:   staff,
+		Create: staff,
+		Update: {enum.Admin: Any, enum.Marketing: Own},
+		Delete: {enum.Admin: Any, enum.Marketing: Own},
+		Manage: {enum.Admin: Any, enum.Marketing: Own},
+	},
+	Audit:     {Read: adminOnly},
+	Inventory: {Read: adminOnly, Update: adminOnly},
+	Users: {
+		Read:   {enum.Admin: Any, enum.Marketing: Own, enum.User: Own},
+		Update: {enum.Admin: Any, enum.Marketing: Own, enum.User: Own},
+	},
+}
+
+// ScopeOf returns how far role may take action on resource.
+func ScopeOf(role string, resource Resource, action Action) Scope {
+	return matrix[resource][action][role]
+}
+
+type systemKey struct{}
+
+// AsSystem marks ctx as work the server does on its own account, like settling a
+// payment from a gateway webhook, which has every permission.
+func AsSystem(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemKey{}, true)
+}
+
+// Check returns the scope the caller has for action on resource, or an error when
+// there is none.
+func Check(ctx context.Context, resource Resource, action Action) (Scope, error) {
+	if system, _ := ctx.Value(systemKey{}).(bool); system {
+		return Any, nil
+	}
+	caller, err := auth.Authenticated(ctx)
+	if err != nil {
+		return None, err
+	}
+	scope := ScopeOf(caller.Role, resource, action)
+	if scope == None {
+		return None, errors.Forbidden("%s may not %s %s", caller.Role, action, resource).WithCode("permission_denied")
+	}
+	return scope, nil
+}
+
+// CheckOwner is Check for one record owned by ownerId: with scope Own the record
+// has to belong to the caller. Someone else's record is reported as not found, so
+// its existence does not leak.
+func CheckOwner(ctx context.Context, resource Resource, action Action, ownerId string) (Scope, error) {
+	scope, err := Check(ctx, resource, action)
+	if err != nil {
+		return None, err
+	}
+	if caller, _ := auth.FromContext(ctx); scope == Own && (ownerId == "" || ownerId != caller.Id) {
+		return None, errors.NotFound("record not found")
+	}
+	return scope, nil
+}
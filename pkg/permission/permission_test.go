@@ -0,0 +1,58 @@
+package permission
+
+import (
+	"SangXanh/pkg/auth"
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/enum"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func signedIn(id, role string) context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{Id: id, Role: role})
+}
+
+func errorCode(t *testing.T, err error) string {
+	var httpErr errors.HTTPError
+	require.True(t, errors.As(err, &httpErr), "not an HTTP error: %v", err)
+	return httpErr.ErrorCode()
+}
+
+func TestCheck(t *testing.T) {
+	scope, err := Check(signedIn("a1", enum.Admin), Products, Delete)
+	require.NoError(t, err)
+	assert.Equal(t, Any, scope)
+
+	scope, err = Check(signedIn("m1", enum.Marketing), Posts, Update)
+	require.NoError(t, err)
+	assert.Equal(t, Own, scope)
+
+	_, err = Check(signedIn("u1", enum.User), Products, Create)
+	assert.Equal(t, "permission_denied", errorCode(t, err))
+	_, err = Check(signedIn("m1", enum.Marketing), Orders, Read)
+	assert.Equal(t, "permission_denied", errorCode(t, err))
+
+	_, err = Check(context.Background(), Orders, Read)
+	var unauthorized *errors.UnauthorizedError
+	assert.True(t, errors.As(err, &unauthorized))
+
+	scope, err = Check(AsSystem(context.Background()), Orders, Manage)
+	require.NoError(t, err)
+	assert.Equal(t, Any, scope)
+}
+
+func TestCheckOwner(t *testing.T) {
+	_, err := CheckOwner(signedIn("u1", enum.User), Orders, Read, "u1")
+	assert.NoError(t, err)
+	_, err = CheckOwner(signedIn("a1", enum.Admin), Orders, Read, "u1")
+	assert.NoError(t, err)
+
+	// someone else's record is not there for the caller
+	_, err = CheckOwner(signedIn("u2", enum.User), Orders, Read, "u1")
+	var notFound *errors.NotFoundError
+	assert.True(t, errors.As(err, &notFound))
+	_, err = CheckOwner(signedIn("u2", enum.User), Users, Update, "")
+	assert.True(t, errors.As(err, &notFound))
+}
@@ -0,0 +1,27 @@
+package ratelimit
+
+import (
+	"SangXanh/pkg/config"
+	"fmt"
+	"github.com/samber/do/v2"
+)
+
+func Inject(di do.Injector) {
+	do.Provide(di, NewStore)
+	do.Provide(di, NewLimiter)
+}
+
+// NewStore returns the Store named by RATE_LIMIT_STORE.
+func NewStore(di do.Injector) (Store, error) {
+	conf, err := do.Invoke[config.RateLimit](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize rate limit Store: %w", err)
+	}
+	switch conf.Store {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "supabase":
+		return NewSupabaseStore(di)
+	}
+	return nil, fmt.Errorf("unknown rate limit store %q", conf.Store)
+}
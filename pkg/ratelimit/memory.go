@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// memorySweepSize is the number of keys above which idle ones are swept out.
+	memorySweepSize = 10000
+	// memoryMaxSize is the number of keys kept at most, idle or not, so a flood of
+	// distinct keys cannot exhaust the memory.
+	memoryMaxSize = 100000
+	// memorySweepPeriod spaces the sweeps out, as each one walks every key.
+	memorySweepPeriod = time.Second
+)
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	// full is when the bucket is full again and can be forgotten.
+	full time.Time
+}
+
+type failures struct {
+	Failures
+	expires time.Time
+}
+
+// MemoryStore keeps the counters of a single instance.
+type MemoryStore struct {
+	mu       sync.Mutex
+	buckets  map[string]bucket
+	failures map[string]failures
+	swept    time.Time
+	now      func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:  make(map[string]bucket),
+		failures: make(map[string]failures),
+		now:      time.Now,
+	}
+}
+
+// errMemoryFull is returned for a new key when every failure kept is still live.
+var errMemoryFull = errors.New("rate limit memory is full")
+
+// evictBucket makes room for a new key in a full map of buckets by dropping an
+// arbitrary one. A dropped bucket starts full again, so under a flood limits loosen
+// rather than lock out everybody.
+func (m *MemoryStore) evictBucket(key string) {
+	if _, ok := m.buckets[key]; ok || len(m.buckets) < memoryMaxSize {
+		return
+	}
+	for k := range m.buckets {
+		delete(m.buckets, k)
+		return
+	}
+}
+
+// roomForFailures makes room for a new key in a full map of failures by dropping
+// the expired ones. Live failures are never dropped, as that would lift a lockout;
+// with no room left the new key is refused instead.
+func (m *MemoryStore) roomForFailures(key string, now time.Time) error {
+	if _, ok := m.failures[key]; ok || len(m.failures) < memoryMaxSize {
+		return nil
+	}
+	for k, f := range m.failures {
+		if now.After(f.expires) {
+			delete(m.failures, k)
+		}
+	}
+	if len(m.failures) >= memoryMaxSize {
+		return errMemoryFull
+	}
+	return nil
+}
+
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.swept) < memorySweepPeriod {
+		return
+	}
+	m.swept = now
+	if len(m.buckets) >= memorySweepSize {
+		for key, b := range m.buckets {
+			if now.After(b.full) {
+				delete(m.buckets, key)
+			}
+		}
+	}
+	if len(m.failures) >= memorySweepSize {
+		for key, f := range m.failures {
+			if now.After(f.expires) {
+				delete(m.failures, key)
+			}
+		}
+	}
+}
+
+func (m *MemoryStore) Take(_ context.Context, key string, rate Rate) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.sweep(now)
+
+	burst, perSecond := float64(rate.Burst), rate.perSecond()
+	b, ok := m.buckets[key]
+	if !ok {
+		m.evictBucket(key)
+		b = bucket{tokens: burst, at: now}
+	}
+	b.tokens = min(burst, b.tokens+now.Sub(b.at).Seconds()*perSecond)
+	b.at = now
+	var wait time.Duration
+	if b.tokens >= 1 {
+		b.tokens--
+	} else {
+		wait = time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	}
+	b.full = now.Add(time.Duration((burst - b.tokens) / perSecond * float64(time.Second)))
+	m.buckets[key] = b
+	return wait, nil
+}
+
+func (m *MemoryStore) Failures(_ context.Context, key string) (Failures, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.failures[key].Failures, nil
+}
+
+func (m *MemoryStore) Fail(_ context.Context, key string, window time.Duration) (Failures, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.sweep(now)
+
+	if err := m.roomForFailures(key, now); err != nil {
+		return Failures{}, err
+	}
+	f := m.failures[key]
+	if now.After(f.expires) {
+		f = failures{}
+	}
+	f.Count++
+	f.Last = now
+	f.expires = now.Add(window)
+	m.failures[key] = f
+	return f.Failures, nil
+}
+
+func (m *MemoryStore) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.failures, key)
+	return nil
+}
@@ -0,0 +1,130 @@
+// Package ratelimit throttles requests with token buckets and locks accounts out
+// after repeated failed logins. The counters live in a Store: in memory for a single
+// instance, or in Postgres when several instances have to share them.
+package ratelimit
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/log"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"math"
+	"time"
+)
+
+// Rate allows Burst requests at once, refilled evenly over Per.
+type Rate struct {
+	Burst int
+	Per   time.Duration
+}
+
+func (r Rate) perSecond() float64 {
+	return float64(r.Burst) / r.Per.Seconds()
+}
+
+// Lockout locks a key out once it failed more than Free times. The first lockout
+// lasts Base and every failure after it doubles that, up to Max; failures are
+// forgotten Window after the last one.
+type Lockout struct {
+	Free   int
+	Base   time.Duration
+	Max    time.Duration
+	Window time.Duration
+}
+
+// until returns when a key with failures f may try again.
+func (l Lockout) until(f Failures) time.Time {
+	if f.Count <= l.Free {
+		return time.Time{}
+	}
+	d := l.Max
+	if shift := f.Count - l.Free - 1; shift < 32 {
+		d = min(l.Base<<shift, l.Max)
+	}
+	return f.Last.Add(d)
+}
+
+// Failures is what a Store remembers of the failed attempts of a key.
+type Failures struct {
+	Count int       `json:"failures"`
+	Last  time.Time `json:"last_failure_at"`
+}
+
+type Store interface {
+	// Take takes a token from the bucket of key. When the bucket is empty it returns
+	// how long until the next token.
+	Take(ctx context.Context, key string, rate Rate) (time.Duration, error)
+	Failures(ctx context.Context, key string) (Failures, error)
+	// Fail counts a failure of key, starting over when the last one is older than
+	// window.
+	Fail(ctx context.Context, key string, window time.Duration) (Failures, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// Limiter applies rates and lockouts to keys such as "login:ip:203.0.113.7". A Store
+// that cannot be reached lets requests through rather than lock everybody out.
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+func NewLimiter(di do.Injector) (*Limiter, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Limiter: %w", err)
+	}
+	return &Limiter{store: store, now: time.Now}, nil
+}
+
+func retryAfter(d time.Duration) map[string]any {
+	return map[string]any{"retry_after": int(math.Ceil(d.Seconds()))}
+}
+
+// Allow fails with 429 Too Many Requests when key went over rate.
+func (l *Limiter) Allow(ctx context.Context, key string, rate Rate) error {
+	wait, err := l.store.Take(ctx, key, rate)
+	if err != nil {
+		log.Errorw("failed to take from rate limit bucket", "key", key, "error", err)
+		return nil
+	}
+	if wait > 0 {
+		return errors.TooManyRequests("too many requests, please try again later").
+			WithCode("rate_limited").
+			WithDebug(retryAfter(wait))
+	}
+	return nil
+}
+
+// Locked fails with 429 Too Many Requests while key is locked out.
+func (l *Limiter) Locked(ctx context.Context, key string, lockout Lockout) error {
+	failures, err := l.store.Failures(ctx, key)
+	if err != nil {
+		log.Errorw("failed to read failed attempts", "key", key, "error", err)
+		return nil
+	}
+	now := l.now()
+	if now.Sub(failures.Last) > lockout.Window {
+		return nil
+	}
+	if until := lockout.until(failures); until.After(now) {
+		return errors.TooManyRequests("too many failed attempts, please try again later").
+			WithCode("locked_out").
+			WithDebug(retryAfter(until.Sub(now)))
+	}
+	return nil
+}
+
+// Fail counts a failed attempt of key towards its lockout.
+func (l *Limiter) Fail(ctx context.Context, key string, lockout Lockout) {
+	if _, err := l.store.Fail(ctx, key, lockout.Window); err != nil {
+		log.Errorw("failed to count failed attempt", "key", key, "error", err)
+	}
+}
+
+// Reset forgets the failed attempts of key, after it succeeded.
+func (l *Limiter) Reset(ctx context.Context, key string) {
+	if err := l.store.Reset(ctx, key); err != nil {
+		log.Errorw("failed to reset failed attempts", "key", key, "error", err)
+	}
+}
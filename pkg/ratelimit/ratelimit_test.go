@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/repository"
+	"context"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(t *testing.T) (*Limiter, *clock) {
+	c := &clock{t: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = c.now
+	di := do.New()
+	do.ProvideValue[Store](di, store)
+	limiter, err := NewLimiter(di)
+	require.NoError(t, err)
+	limiter.now = c.now
+	return limiter, c
+}
+
+func errorCode(err error) string {
+	var httpErr errors.HTTPError
+	if !errors.As(err, &httpErr) {
+		return ""
+	}
+	return httpErr.ErrorCode()
+}
+
+func TestAllow(t *testing.T) {
+	limiter, c := newTestLimiter(t)
+	ctx := context.Background()
+	rate := Rate{Burst: 3, Per: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, limiter.Allow(ctx, "login:ip:1", rate))
+	}
+	err := limiter.Allow(ctx, "login:ip:1", rate)
+	assert.Equal(t, "rate_limited", errorCode(err))
+	assert.Equal(t, map[string]any{"retry_after": 20}, err.(errors.HTTPError).DebugInfo())
+	assert.NoError(t, limiter.Allow(ctx, "login:ip:2", rate), "other keys have their own bucket")
+
+	// a token comes back every 20 seconds
+	c.advance(20 * time.Second)
+	assert.NoError(t, limiter.Allow(ctx, "login:ip:1", rate))
+	assert.Error(t, limiter.Allow(ctx, "login:ip:1", rate))
+	c.advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, limiter.Allow(ctx, "login:ip:1", rate), "never more than the burst")
+	}
+	assert.Error(t, limiter.Allow(ctx, "login:ip:1", rate))
+}
+
+func TestLockout(t *testing.T) {
+	limiter, c := newTestLimiter(t)
+	ctx := context.Background()
+	lockout := Lockout{Free: 3, Base: time.Minute, Max: 5 * time.Minute, Window: time.Hour}
+	key := "login:account:lan"
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, limiter.Locked(ctx, key, lockout))
+		limiter.Fail(ctx, key, lockout)
+	}
+	assert.NoError(t, limiter.Locked(ctx, key, lockout), "the free attempts do not lock")
+
+	// every failure after them doubles the lockout, up to the maximum
+	for _, d := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute} {
+		limiter.Fail(ctx, key, lockout)
+		assert.Equal(t, "locked_out", errorCode(limiter.Locked(ctx, key, lockout)))
+		c.advance(d - time.Second)
+		assert.Error(t, limiter.Locked(ctx, key, lockout))
+		c.advance(time.Second)
+		assert.NoError(t, limiter.Locked(ctx, key, lockout), "unlocked after %v", d)
+	}
+
+	// a success starts over
+	limiter.Reset(ctx, key)
+	limiter.Fail(ctx, key, lockout)
+	assert.NoError(t, limiter.Locked(ctx, key, lockout))
+
+	// and so does a quiet window
+	for i := 0; i < 3; i++ {
+		limiter.Fail(ctx, key, lockout)
+	}
+	assert.Error(t, limiter.Locked(ctx, key, lockout))
+	c.advance(2 * time.Hour)
+	limiter.Fail(ctx, key, lockout)
+	assert.NoError(t, limiter.Locked(ctx, key, lockout))
+}
+
+func TestMemoryStoreSize(t *testing.T) {
+	limiter, c := newTestLimiter(t)
+	store := limiter.store.(*MemoryStore)
+	ctx := context.Background()
+	rate := Rate{Burst: 1, Per: time.Hour}
+
+	for i := 0; i < memoryMaxSize+10; i++ {
+		require.NoError(t, limiter.Allow(ctx, "register:ip:"+strconv.Itoa(i), rate))
+		limiter.Fail(ctx, "login:account:"+strconv.Itoa(i), Lockout{Free: 1, Window: time.Hour})
+	}
+	assert.Len(t, store.buckets, memoryMaxSize, "no bucket was idle yet, others make room")
+	assert.Len(t, store.failures, memoryMaxSize, "live failures are never dropped, new keys are refused")
+	assert.Error(t, limiter.Locked(ctx, "login:account:0", Lockout{Free: 0, Base: time.Hour, Max: time.Hour, Window: time.Hour}),
+		"the first lockout outlived the flood")
+
+	c.advance(2 * time.Hour)
+	require.NoError(t, limiter.Allow(ctx, "register:ip:new", rate))
+	assert.Len(t, store.buckets, 1, "idle buckets are swept out")
+	limiter.Fail(ctx, "login:account:new", Lockout{Free: 1, Window: time.Hour})
+	assert.Contains(t, store.failures, "login:account:new", "expired failures make room")
+}
+
+func TestSupabaseStoreFallback(t *testing.T) {
+	di := do.New()
+	repository.InjectMemory(di)
+	store, err := NewSupabaseStore(di)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// the memory store has no database functions, so the counting happens in memory
+	failures, err := store.Fail(ctx, "login:account:lan@sangxanh.vn", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, failures.Count)
+	failures, err = store.Failures(ctx, "login:account:lan@sangxanh.vn")
+	require.NoError(t, err)
+	assert.Equal(t, 1, failures.Count)
+}
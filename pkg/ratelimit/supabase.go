@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"SangXanh/pkg/repository"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/samber/do/v2"
+	"time"
+)
+
+// supabaseStore shares the counters between instances through the rate_limit_*
+// functions (see supabase/migrations). While they are missing from the database it
+// falls back to counting in memory.
+type supabaseStore struct {
+	db       repository.Store
+	fallback *MemoryStore
+}
+
+func NewSupabaseStore(di do.Injector) (Store, error) {
+	db, err := do.Invoke[repository.Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize rate limit Store: %w", err)
+	}
+	return &supabaseStore{db: db, fallback: NewMemoryStore()}, nil
+}
+
+// rpc calls function, reporting false when it is not installed.
+func (s *supabaseStore) rpc(ctx context.Context, function string, params map[string]interface{}, out interface{}) (bool, error) {
+	err := s.db.Call(ctx, function, params, out)
+	if errors.Is(err, repository.ErrNoFunction) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *supabaseStore) Take(ctx context.Context, key string, rate Rate) (time.Duration, error) {
+	var wait float64
+	ok, err := s.rpc(ctx, "rate_limit_take", map[string]interface{}{
+		"bucket_key":  key,
+		"burst":       rate.Burst,
+		"per_seconds": rate.Per.Seconds(),
+	}, &wait)
+	if !ok {
+		if err != nil {
+			return 0, err
+		}
+		return s.fallback.Take(ctx, key, rate)
+	}
+	return time.Duration(wait * float64(time.Second)), nil
+}
+
+func (s *supabaseStore) Failures(ctx context.Context, key string) (Failures, error) {
+	var rows []Failures
+	ok, err := s.rpc(ctx, "rate_limit_failures", map[string]interface{}{"failure_key": key}, &rows)
+	if !ok {
+		if err != nil {
+			return Failures{}, err
+		}
+		return s.fallback.Failures(ctx, key)
+	}
+	if len(rows) == 0 {
+		return Failures{}, nil
+	}
+	return rows[0], nil
+}
+
+func (s *supabaseStore) Fail(ctx context.Context, key string, window time.Duration) (Failures, error) {
+	var rows []Failures
+	ok, err := s.rpc(ctx, "rate_limit_fail", map[string]interface{}{
+		"failure_key":    key,
+		"window_seconds": window.Seconds(),
+	}, &rows)
+	if !ok {
+		if err != nil {
+			return Failures{}, err
+		}
+		return s.fallback.Fail(ctx, key, window)
+	}
+	if len(rows) == 0 {
+		return Failures{}, nil
+	}
+	return rows[0], nil
+}
+
+func (s *supabaseStore) Reset(ctx context.Context, key string) error {
+	var deleted int
+	ok, err := s.rpc(ctx, "rate_limit_reset", map[string]interface{}{"failure_key": key}, &deleted)
+	if !ok && err == nil {
+		return s.fallback.Reset(ctx, key)
+	}
+	return err
+}
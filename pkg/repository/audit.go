@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"SangXanh/pkg/dto"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"time"
+)
+
+type AuditRepository interface {
+	// Snapshot returns every column of the row of table whose column equals value,
+	// or nil when there is none.
+	Snapshot(ctx context.Context, table, column, value string) (map[string]interface{}, error)
+	Create(ctx context.Context, trail map[string]interface{}) error
+	// List pages through the audit trails from (inclusive) to (exclusive), the newest
+	// first; a zero bound leaves that side open.
+	List(ctx context.Context, filter dto.AuditFilter, from, to time.Time) ([]dto.AuditTrail, error)
+	Count(ctx context.Context, filter dto.AuditFilter, from, to time.Time) (int, error)
+}
+
+type auditRepository struct {
+	store Store
+}
+
+func NewAuditRepository(di do.Injector) (AuditRepository, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AuditRepository: %w", err)
+	}
+	return &auditRepository{store: store}, nil
+}
+
+func auditQuery(filter dto.AuditFilter, from, to time.Time) Query {
+	q := Where(IsNull("deleted_at"))
+	if filter.EntityType != "" {
+		q = q.And(Eq("audit_type", string(filter.EntityType)))
+	}
+	if filter.EntityId != "" {
+		q = q.And(Eq("audit_id", filter.EntityId))
+	}
+	if filter.Actor != "" {
+		q = q.And(Eq("created_by", filter.Actor))
+	}
+	if !from.IsZero() {
+		q = q.And(Gte("created_at", from.Format(time.RFC3339)))
+	}
+	if !to.IsZero() {
+		q = q.And(Lt("created_at", to.Format(time.RFC3339)))
+	}
+	return q
+}
+
+func (r *auditRepository) Snapshot(ctx context.Context, table, column, value string) (map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	if err := r.store.Find(ctx, table, "*", Where(Eq(column, value)), &rows); err != nil {
+		return nil, fmt.Errorf("failed to snapshot %s %s=%s: %w", table, column, value, err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rows[0], nil
+}
+
+func (r *auditRepository) Create(ctx context.Context, trail map[string]interface{}) error {
+	if err := r.store.Insert(ctx, "audit_trails", trail, nil); err != nil {
+		return fmt.Errorf("failed to record audit trail: %w", err)
+	}
+	return nil
+}
+
+func (r *auditRepository) List(ctx context.Context, filter dto.AuditFilter, from, to time.Time) ([]dto.AuditTrail, error) {
+	var audits []dto.AuditTrail
+	q := auditQuery(filter, from, to).OrderBy("created_at", true).Page(int(filter.Limit), filter.Offset())
+	if err := r.store.Find(ctx, "audit_trails", "id,audit_id,audit_type,audit_content,created_by,metadata,created_at", q, &audits); err != nil {
+		return nil, fmt.Errorf("failed to fetch audit trails: %w", err)
+	}
+	return audits, nil
+}
+
+func (r *auditRepository) Count(ctx context.Context, filter dto.AuditFilter, from, to time.Time) (int, error) {
+	total, err := r.store.Count(ctx, "audit_trails", auditQuery(filter, from, to))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count audit trails: %w", err)
+	}
+	return total, nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"time"
+)
+
+type CartRepository interface {
+	// ListByUser returns the live cart rows of a user; with ids only those rows.
+	ListByUser(ctx context.Context, userId string, ids ...string) ([]dto.Cart, error)
+	Get(ctx context.Context, id, userId string) (dto.Cart, error)
+	Create(ctx context.Context, req dto.CartCreateRequest) (dto.Cart, error)
+	SetQuantity(ctx context.Context, id, userId string, quantity int) (dto.Cart, error)
+	SoftDelete(ctx context.Context, id string) error
+	// SoftDeleteMany removes checked-out rows of a user in one go.
+	SoftDeleteMany(ctx context.Context, userId string, ids []string) error
+}
+
+type cartRepository struct {
+	store Store
+}
+
+func NewCartRepository(di do.Injector) (CartRepository, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize CartRepository: %w", err)
+	}
+	return &cartRepository{store: store}, nil
+}
+
+const cartColumns = "id,user_id,product_option_id,quantity,created_at,updated_at"
+
+func (r *cartRepository) ListByUser(ctx context.Context, userId string, ids ...string) ([]dto.Cart, error) {
+	q := Where(Eq("user_id", userId), IsNull("deleted_at"))
+	if len(ids) > 0 {
+		q = q.And(In("id", ids))
+	}
+	var carts []dto.Cart
+	if err := r.store.Find(ctx, "carts", cartColumns, q, &carts); err != nil {
+		return nil, fmt.Errorf("failed to fetch carts for user %s: %w", userId, err)
+	}
+	return carts, nil
+}
+
+func (r *cartRepository) Get(ctx context.Context, id, userId string) (dto.Cart, error) {
+	var carts []dto.Cart
+	if err := r.store.Find(ctx, "carts", cartColumns, Where(Eq("id", id), Eq("user_id", userId), IsNull("deleted_at")), &carts); err != nil {
+		return dto.Cart{}, fmt.Errorf("failed to fetch cart: %w", err)
+	}
+	if len(carts) == 0 {
+		return dto.Cart{}, errors.NotFound("cart not found")
+	}
+	return carts[0], nil
+}
+
+func (r *cartRepository) Create(ctx context.Context, req dto.CartCreateRequest) (dto.Cart, error) {
+	var created []dto.Cart
+	if err := r.store.Insert(ctx, "carts", req, &created); err != nil {
+		return dto.Cart{}, fmt.Errorf("failed to create cart: %w", err)
+	}
+	return created[0], nil
+}
+
+func (r *cartRepository) SetQuantity(ctx context.Context, id, userId string, quantity int) (dto.Cart, error) {
+	var updated []dto.Cart
+	if err := r.store.Update(ctx, "carts",
+		Where(Eq("id", id), Eq("user_id", userId), IsNull("deleted_at")),
+		map[string]interface{}{"quantity": quantity, "updated_at": time.Now()},
+		&updated); err != nil {
+		return dto.Cart{}, fmt.Errorf("failed to update cart: %w", err)
+	}
+	if len(updated) == 0 {
+		return dto.Cart{}, errors.NotFound("cart not found")
+	}
+	return updated[0], nil
+}
+
+func (r *cartRepository) SoftDelete(ctx context.Context, id string) error {
+	if err := r.store.Update(ctx, "carts", Where(Eq("id", id)), map[string]interface{}{"deleted_at": time.Now()}, nil); err != nil {
+		return fmt.Errorf("failed to delete cart: %w", err)
+	}
+	return nil
+}
+
+func (r *cartRepository) SoftDeleteMany(ctx context.Context, userId string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := r.store.Update(ctx, "carts", Where(In("id", ids), Eq("user_id", userId)), map[string]interface{}{"deleted_at": time.Now()}, nil); err != nil {
+		return fmt.Errorf("failed to clear cart: %w", err)
+	}
+	return nil
+}
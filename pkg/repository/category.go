@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"slices"
+	"time"
+)
+
+type CategoryRepository interface {
+	// List returns the live categories in the requested order, oldest first by default.
+	List(ctx context.Context, filter dto.ListCategory) ([]dto.Category, error)
+	Get(ctx context.Context, id string) (dto.Category, error)
+	// Children returns the live children of a category in menu order.
+	Children(ctx context.Context, parentId string) ([]dto.Category, error)
+	// All returns every live category, unordered.
+	All(ctx context.Context) ([]dto.Category, error)
+	// Subtree returns the ids of the live categories among ids and of all their
+	// descendants.
+	Subtree(ctx context.Context, ids []string) ([]string, error)
+	// Path lists the live categories from the root down to id, id included; it stops
+	// short at a deleted ancestor.
+	Path(ctx context.Context, id string) ([]dto.CategoryProduct, error)
+	// Names maps the ids of categories to their names, deleted categories included.
+	Names(ctx context.Context, ids []string) (map[string]string, error)
+	Create(ctx context.Context, req dto.CategoryCreate) (dto.Category, error)
+	Update(ctx context.Context, id string, patch map[string]interface{}) (dto.Category, error)
+	SoftDelete(ctx context.Context, id string) error
+	// SoftDeleteMany deletes the categories among ids that are still live.
+	SoftDeleteMany(ctx context.Context, ids []string) error
+}
+
+type categoryRepository struct {
+	store Store
+}
+
+func NewCategoryRepository(di do.Injector) (CategoryRepository, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize CategoryRepository: %w", err)
+	}
+	return &categoryRepository{store: store}, nil
+}
+
+var categorySortable = []string{"name", "level", "created_at", "updated_at"}
+
+func (r *categoryRepository) List(ctx context.Context, filter dto.ListCategory) ([]dto.Category, error) {
+	q, err := Where(IsNull("deleted_at")).Sort(filter.Pagination, categorySortable, Order{Column: "created_at"})
+	if err != nil {
+		return nil, err
+	}
+	if filter.Name != "" {
+		q = q.And(Ilike("name", "*"+filter.Name+"*"))
+	}
+	if filter.IsDisplayHomepage {
+		q = q.And(Eq("is_display_homepage", "true"))
+	}
+	if filter.IsDisplayHeader {
+		q = q.And(Eq("is_display_header", "true"))
+	}
+	var categories []dto.Category
+	if err := r.store.Find(ctx, "categories", "*", q, &categories); err != nil {
+		return nil, fmt.Errorf("failed to fetch categories: %w", err)
+	}
+	return categories, nil
+}
+
+func (r *categoryRepository) Get(ctx context.Context, id string) (dto.Category, error) {
+	var categories []dto.Category
+	if err := r.store.Find(ctx, "categories", "*", Where(Eq("id", id), IsNull("deleted_at")), &categories); err != nil {
+		return dto.Category{}, fmt.Errorf("failed to fetch category: %w", err)
+	}
+	if len(categories) == 0 {
+		return dto.Category{}, errors.NotFound("category not found")
+	}
+	return categories[0], nil
+}
+
+func (r *categoryRepository) Children(ctx context.Context, parentId string) ([]dto.Category, error) {
+	var categories []dto.Category
+	if err := r.store.Find(ctx, "categories", "*", Where(Eq("parent_id", parentId), IsNull("deleted_at")).OrderBy("position", false).OrderBy("created_at", false), &categories); err != nil {
+		return nil, fmt.Errorf("failed to fetch child categories: %w", err)
+	}
+	return categories, nil
+}
+
+func (r *categoryRepository) All(ctx context.Context) ([]dto.Category, error) {
+	var categories []dto.Category
+	if err := r.store.Find(ctx, "categories", "*", Where(IsNull("deleted_at")), &categories); err != nil {
+		return nil, fmt.Errorf("failed to fetch categories: %w", err)
+	}
+	return categories, nil
+}
+
+func (r *categoryRepository) Subtree(ctx context.Context, ids []string) ([]string, error) {
+	categories, err := r.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return subtree(categories, ids), nil
+}
+
+func (r *categoryRepository) Path(ctx context.Context, id string) ([]dto.CategoryProduct, error) {
+	categories, err := r.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[string]dto.Category, len(categories))
+	for _, c := range categories {
+		byId[c.Id] = c
+	}
+	path := []dto.CategoryProduct{}
+	seen := make(map[string]bool)
+	for c, ok := byId[id]; ok && !seen[c.Id]; c, ok = byId[c.ParentId] {
+		seen[c.Id] = true
+		path = append(path, dto.CategoryProduct{Id: c.Id, Name: c.Name, Slug: c.Slug})
+	}
+	slices.Reverse(path)
+	return path, nil
+}
+
+// subtree walks down from the roots in ids; a category is visited once even when
+// the parent links run in a circle.
+func subtree(categories []dto.Category, ids []string) []string {
+	live := make(map[string]bool, len(categories))
+	children := make(map[string][]string)
+	for _, c := range categories {
+		live[c.Id] = true
+		children[c.ParentId] = append(children[c.ParentId], c.Id)
+	}
+	seen := make(map[string]bool)
+	var out []string
+	queue := append([]string(nil), ids...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] || !live[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+		queue = append(queue, children[id]...)
+	}
+	return out
+}
+
+func (r *categoryRepository) Names(ctx context.Context, ids []string) (map[string]string, error) {
+	names := make(map[string]string, len(ids))
+	ids = uniqueIds(ids)
+	if len(ids) == 0 {
+		return names, nil
+	}
+	var rows []struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := r.store.Find(ctx, "categories", "id,name", Where(In("id", ids)), &rows); err != nil {
+		return nil, fmt.Errorf("failed to fetch category names: %w", err)
+	}
+	for _, row := range rows {
+		names[row.Id] = row.Name
+	}
+	return names, nil
+}
+
+func (r *categoryRepository) Create(ctx context.Context, req dto.CategoryCreate) (dto.Category, error) {
+	var created []dto.Category
+	if err := r.store.Insert(ctx, "categories", req, &created); err != nil {
+		return dto.Category{}, fmt.Errorf("failed to insert category: %w", err)
+	}
+	return created[0], nil
+}
+
+func (r *categoryRepository) Update(ctx context.Context, id string, patch map[string]interface{}) (dto.Category, error) {
+	var updated []dto.Category
+	if err := r.store.Update(ctx, "categories", Where(Eq("id", id), IsNull("deleted_at")), patch, &updated); err != nil {
+		return dto.Category{}, fmt.Errorf("failed to update category: %w", err)
+	}
+	if len(updated) == 0 {
+		return dto.Category{}, errors.NotFound("category not found")
+	}
+	return updated[0], nil
+}
+
+func (r *categoryRepository) SoftDelete(ctx context.Context, id string) error {
+	if err := r.store.Update(ctx, "categories", Where(Eq("id", id)), map[string]interface{}{"deleted_at": time.Now()}, nil); err != nil {
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+	return nil
+}
+
+func (r *categoryRepository) SoftDeleteMany(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := r.store.Update(ctx, "categories", Where(In("id", ids), IsNull("deleted_at")), map[string]interface{}{"deleted_at": time.Now()}, nil); err != nil {
+		return fmt.Errorf("failed to delete categories: %w", err)
+	}
+	return nil
+}
+
+// uniqueIds drops empty and repeated ids so an IN (...) stays short.
+func uniqueIds(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out
+}
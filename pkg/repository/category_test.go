@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"SangXanh/pkg/dto"
+	"context"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCategoryPath(t *testing.T) {
+	di := do.New()
+	store := InjectMemory(di)
+	store.Seed("categories",
+		map[string]interface{}{"id": "root", "name": "Cây cảnh"},
+		map[string]interface{}{"id": "mid", "name": "Cây để bàn", "parent_id": "root"},
+		map[string]interface{}{"id": "leaf", "name": "Sen đá", "parent_id": "mid"},
+		map[string]interface{}{"id": "gone", "name": "Cũ", "parent_id": "root", "deleted_at": "2025-03-01T00:00:00Z"},
+		map[string]interface{}{"id": "orphan", "name": "Lạc", "parent_id": "gone"},
+	)
+	store.Seed("products", map[string]interface{}{"id": "p1", "name": "Sen đá nâu", "category_id": "leaf"})
+	categories := do.MustInvoke[CategoryRepository](di)
+	ctx := context.Background()
+
+	path, err := categories.Path(ctx, "leaf")
+	require.NoError(t, err)
+	assert.Equal(t, []dto.CategoryProduct{{Id: "root", Name: "Cây cảnh"}, {Id: "mid", Name: "Cây để bàn"}, {Id: "leaf", Name: "Sen đá"}}, path)
+	path, _ = categories.Path(ctx, "orphan")
+	assert.Equal(t, []dto.CategoryProduct{{Id: "orphan", Name: "Lạc"}}, path, "the path stops at a deleted parent")
+
+	ids, err := categories.Subtree(ctx, []string{"root"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"root", "mid", "leaf"}, ids, "deleted categories and what hangs below them are left out")
+
+	product, err := do.MustInvoke[ProductRepository](di).Get(ctx, "p1")
+	require.NoError(t, err)
+	assert.Len(t, product.Breadcrumb, 3)
+}
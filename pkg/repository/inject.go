@@ -0,0 +1,35 @@
+package repository
+
+import "github.com/samber/do/v2"
+
+func Inject(di do.Injector) {
+	do.Provide(di, NewSupabaseStore)
+	provideRepositories(di)
+	do.Provide(di, NewSupabaseProductSearcher)
+}
+
+// InjectMemory wires the repositories to a fresh MemoryStore and returns it, so a
+// test can seed tables and inspect them afterwards.
+func InjectMemory(di do.Injector) *MemoryStore {
+	store := NewMemoryStore()
+	do.ProvideValue[Store](di, store)
+	provideRepositories(di)
+	do.Provide(di, NewIndexProductSearcher)
+	return store
+}
+
+func provideRepositories(di do.Injector) {
+	do.Provide(di, NewCategoryRepository)
+	do.Provide(di, NewProductRepository)
+	do.Provide(di, NewProductOptionRepository)
+	do.Provide(di, NewProductVariantRepository)
+	do.Provide(di, NewOrderRepository)
+	do.Provide(di, NewCartRepository)
+	do.Provide(di, NewUserRepository)
+	do.Provide(di, NewSlugRepository)
+	do.Provide(di, NewSessionRepository)
+	do.Provide(di, NewPromotionRepository)
+	do.Provide(di, NewPaymentRepository)
+	do.Provide(di, NewPostRepository)
+	do.Provide(di, NewAuditRepository)
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/common/query"
+	"context"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Cursors point at the pages before and after a page of a list; an empty one means
+// there is no such page.
+type Cursors struct {
+	Next string
+	Prev string
+}
+
+// findPage reads one page of the sorted query q into out. With a cursor in p it seeks
+// past the cursor row on the sort columns instead of skipping rows, so a page costs
+// the same however deep it is and does not shift when rows are added in front of it.
+// Either way the cursors around the page are returned, which lets a client move on
+// to cursor paging from any offset page. The orders of q must end on a unique column
+// and columns must include every sort column.
+func findPage(ctx context.Context, store Store, table, columns string, q Query, p query.Pagination, out interface{}) (Cursors, error) {
+	order := orderString(q.Orders)
+	cursor, seek, err := p.DecodeCursor()
+	if err != nil {
+		return Cursors{}, err
+	}
+	limit := int(p.Limit)
+	page := q
+	if seek {
+		if cursor.Order != order || len(cursor.Values) != len(q.Orders) {
+			return Cursors{}, errors.BadRequest("the cursor was issued for another sort order")
+		}
+		if cursor.Before {
+			page.Orders = reversed(q.Orders)
+		}
+		page = page.And(after(page.Orders, cursor.Values)).Page(limit+1, 0)
+	} else {
+		page = page.Page(limit+1, p.Offset())
+	}
+
+	// one row more than the page tells whether there is anything beyond it
+	var rows []map[string]interface{}
+	if err := store.Find(ctx, table, columns, page, &rows); err != nil {
+		return Cursors{}, err
+	}
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+	hasNext, hasPrev := more, p.Offset() > 0
+	if seek {
+		hasNext, hasPrev = more || cursor.Before, !cursor.Before || more
+	}
+	if seek && cursor.Before {
+		slices.Reverse(rows)
+	}
+
+	var cursors Cursors
+	if len(rows) > 0 && hasNext {
+		cursors.Next = cursorAt(rows[len(rows)-1], q.Orders, order, false)
+	}
+	if len(rows) > 0 && hasPrev {
+		cursors.Prev = cursorAt(rows[0], q.Orders, order, true)
+	}
+	return cursors, decodeRows(rows, out)
+}
+
+func orderString(orders []Order) string {
+	parts := make([]string, 0, len(orders))
+	for _, o := range orders {
+		if o.Desc {
+			parts = append(parts, o.Column+".desc")
+		} else {
+			parts = append(parts, o.Column)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// reversed flips every order; the NULLs move along since they sort last ascending
+// and first descending.
+func reversed(orders []Order) []Order {
+	out := make([]Order, 0, len(orders))
+	for _, o := range orders {
+		out = append(out, Order{Column: o.Column, Desc: !o.Desc})
+	}
+	return out
+}
+
+func cursorAt(row map[string]interface{}, orders []Order, order string, before bool) string {
+	values := make([]*string, 0, len(orders))
+	for _, o := range orders {
+		values = append(values, cursorValue(row[o.Column]))
+	}
+	return query.Cursor{Order: order, Values: values, Before: before}.Encode()
+}
+
+// cursorValue keeps timestamps in UTC, a '+' in an offset would not make it through
+// the query string of a PostgREST request.
+func cursorValue(v interface{}) *string {
+	if v == nil {
+		return nil
+	}
+	s := valueString(v)
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		s = t.UTC().Format(time.RFC3339Nano)
+	}
+	return &s
+}
+
+// after matches the rows that sort after the given values of the sort columns: those
+// equal on the first columns and beyond on the next one, for every column in turn. The
+// last column is the unique one and never NULL.
+func after(orders []Order, values []*string) Filter {
+	var groups [][]Filter
+	for i, o := range orders {
+		prefix := make([]Filter, 0, i+1)
+		for j := 0; j < i; j++ {
+			if values[j] == nil {
+				prefix = append(prefix, IsNull(orders[j].Column))
+			} else {
+				prefix = append(prefix, Eq(orders[j].Column, *values[j]))
+			}
+		}
+		for _, f := range beyond(o, values[i], i < len(orders)-1) {
+			groups = append(groups, append(slices.Clone(prefix), f))
+		}
+	}
+	return Or(groups...)
+}
+
+// beyond lists the ways a value of the column can sort after v.
+func beyond(o Order, v *string, nullable bool) []Filter {
+	switch {
+	case v == nil && o.Desc:
+		return []Filter{NotNull(o.Column)}
+	case v == nil:
+		return nil
+	case o.Desc:
+		return []Filter{Lt(o.Column, *v)}
+	case !nullable:
+		return []Filter{Gt(o.Column, *v)}
+	default:
+		return []Filter{Gt(o.Column, *v), IsNull(o.Column)}
+	}
+}
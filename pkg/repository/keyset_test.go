@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/common/query"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFindPageCursor(t *testing.T) {
+	m := NewMemoryStore()
+	m.Seed("products",
+		map[string]interface{}{"id": "a", "price": 50},
+		map[string]interface{}{"id": "b", "price": nil},
+		map[string]interface{}{"id": "c", "price": 20},
+		map[string]interface{}{"id": "d", "price": 50},
+		map[string]interface{}{"id": "e", "price": nil},
+	)
+	ctx := context.Background()
+	q, _ := Query{}.Sort(query.Pagination{Sort: "price"}, []string{"price"})
+	ids := func(rows []row) (out []string) {
+		for _, r := range rows {
+			out = append(out, r.Id)
+		}
+		return out
+	}
+
+	// walk forward from the first offset page, ties on price and NULLs included
+	p := query.Pagination{Page: 1, Limit: 2, Sort: "price"}
+	var pages [][]string
+	var second, last Cursors
+	for {
+		var rows []row
+		cursors, err := findPage(ctx, m, "products", "id,price", q, p, &rows)
+		assert.NoError(t, err)
+		pages = append(pages, ids(rows))
+		if len(pages) == 1 {
+			second = cursors
+		}
+		last = cursors
+		if cursors.Next == "" {
+			break
+		}
+		p.Cursor = cursors.Next
+	}
+	assert.Equal(t, [][]string{{"c", "a"}, {"d", "b"}, {"e"}}, pages)
+
+	// and back again
+	var rows []row
+	p.Cursor = last.Prev
+	cursors, err := findPage(ctx, m, "products", "id,price", q, p, &rows)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"d", "b"}, ids(rows))
+	p.Cursor = cursors.Prev
+	cursors, err = findPage(ctx, m, "products", "id,price", q, p, &rows)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "a"}, ids(rows))
+	assert.Empty(t, cursors.Prev)
+	assert.NotEmpty(t, cursors.Next)
+
+	// a row added in front does not shift the cursor page
+	m.Seed("products", map[string]interface{}{"id": "0", "price": 10})
+	p.Cursor = second.Next
+	_, err = findPage(ctx, m, "products", "id,price", q, p, &rows)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"d", "b"}, ids(rows))
+
+	// a cursor only fits the order it was issued for
+	other, _ := Query{}.Sort(query.Pagination{Sort: "-price"}, []string{"price"})
+	_, err = findPage(ctx, m, "products", "id,price", other, p, &rows)
+	var badRequest *errors.BadRequestError
+	assert.True(t, errors.As(err, &badRequest))
+	p.Cursor = "not a cursor"
+	_, err = findPage(ctx, m, "products", "id,price", q, p, &rows)
+	assert.True(t, errors.As(err, &badRequest))
+}
@@ -0,0 +1,471 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps tables in memory so services can be tested without a database.
+// Rows are held the way they travel as JSON, and filters follow the PostgREST
+// rules the Supabase store relies on: a NULL never matches a comparison and sorts
+// last in ascending order. The views of memoryViews can be read like tables; there
+// are no database functions.
+type MemoryStore struct {
+	mu     sync.Mutex
+	tables map[string][]map[string]interface{}
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tables: make(map[string][]map[string]interface{})}
+}
+
+// Seed inserts rows the way Insert does; it panics on rows that cannot be encoded
+// and is meant for test setup only.
+func (m *MemoryStore) Seed(table string, rows ...interface{}) {
+	for _, row := range rows {
+		if err := m.Insert(context.Background(), table, row, nil); err != nil {
+			panic(fmt.Sprintf("failed to seed %s: %v", table, err))
+		}
+	}
+}
+
+// Rows returns a copy of every row of table, soft-deleted ones included.
+func (m *MemoryStore) Rows(table string) []map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]map[string]interface{}, 0, len(m.tables[table]))
+	for _, row := range m.tables[table] {
+		out = append(out, copyRow(row))
+	}
+	return out
+}
+
+func (m *MemoryStore) Find(_ context.Context, table, columns string, q Query, out interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var found []map[string]interface{}
+	joined := m.joined(q.Joins)
+	for _, row := range m.rows(table) {
+		if matchesAll(row, q.Filters) && related(table, row, q.Joins, joined) {
+			found = append(found, row)
+		}
+	}
+	sortRows(found, q.Orders)
+	if q.Offset > 0 {
+		found = found[min(q.Offset, len(found)):]
+	}
+	if q.Limit > 0 && len(found) > q.Limit {
+		found = found[:q.Limit]
+	}
+	return decodeRows(project(found, columns), out)
+}
+
+func (m *MemoryStore) Count(_ context.Context, table string, q Query) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	joined := m.joined(q.Joins)
+	for _, row := range m.rows(table) {
+		if matchesAll(row, q.Filters) && related(table, row, q.Joins, joined) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Insert fills in what the database would: an id, the timestamps and an empty deleted_at.
+func (m *MemoryStore) Insert(_ context.Context, table string, rows interface{}, out interface{}) error {
+	encoded, err := encodeRows(rows)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, row := range encoded {
+		if id, _ := row["id"].(string); id == "" {
+			row["id"] = uuid.NewString()
+		}
+		for _, column := range []string{"created_at", "updated_at"} {
+			if v, ok := row[column]; !ok || v == nil || v == "" {
+				row[column] = now
+			}
+		}
+		if _, ok := row["deleted_at"]; !ok {
+			row["deleted_at"] = nil
+		}
+		m.tables[table] = append(m.tables[table], row)
+	}
+	return decodeRows(encoded, out)
+}
+
+func (m *MemoryStore) Update(_ context.Context, table string, q Query, patch interface{}, out interface{}) error {
+	if len(q.Joins) > 0 {
+		return errJoinedWrite(table)
+	}
+	encoded, err := encodeRows(patch)
+	if err != nil {
+		return err
+	}
+	if len(encoded) != 1 {
+		return fmt.Errorf("update of %s needs exactly one patch", table)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var updated []map[string]interface{}
+	for _, row := range m.tables[table] {
+		if !matchesAll(row, q.Filters) {
+			continue
+		}
+		for k, v := range encoded[0] {
+			row[k] = v
+		}
+		updated = append(updated, row)
+	}
+	return decodeRows(updated, out)
+}
+
+func (m *MemoryStore) Delete(_ context.Context, table string, q Query) error {
+	if len(q.Joins) > 0 {
+		return errJoinedWrite(table)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.tables[table][:0]
+	for _, row := range m.tables[table] {
+		if !matchesAll(row, q.Filters) {
+			kept = append(kept, row)
+		}
+	}
+	m.tables[table] = kept
+	return nil
+}
+
+func (m *MemoryStore) Call(_ context.Context, function string, _ map[string]interface{}, _ interface{}) error {
+	return fmt.Errorf("%w: %s", ErrNoFunction, function)
+}
+
+/* ------------------------------------------------------------------
+   Views
+   ------------------------------------------------------------------*/
+
+// memoryViews compute the views of supabase/migrations from the tables; they must
+// be called with m.mu held.
+var memoryViews = map[string]func(tables map[string][]map[string]interface{}) []map[string]interface{}{
+	"product_variant_values": productVariantValues,
+}
+
+// rows returns the rows of a table or a view.
+func (m *MemoryStore) rows(table string) []map[string]interface{} {
+	if view, ok := memoryViews[table]; ok {
+		return view(m.tables)
+	}
+	return m.tables[table]
+}
+
+// productVariantValues has a row per value of a live product variant, its name and
+// value trimmed.
+func productVariantValues(tables map[string][]map[string]interface{}) []map[string]interface{} {
+	var rows []map[string]interface{}
+	for _, variant := range tables["product_variants"] {
+		if variant["deleted_at"] != nil {
+			continue
+		}
+		name, _ := variant["name"].(string)
+		details, _ := variant["detail"].([]interface{})
+		for _, d := range details {
+			detail, _ := d.(map[string]interface{})
+			value, ok := detail["name"].(string)
+			if !ok {
+				continue
+			}
+			rows = append(rows, map[string]interface{}{
+				"product_id": variant["product_id"],
+				"name":       strings.TrimSpace(name),
+				"value":      strings.TrimSpace(value),
+			})
+		}
+	}
+	return rows
+}
+
+/* ------------------------------------------------------------------
+   Encoding
+   ------------------------------------------------------------------*/
+
+// encodeRows turns a struct, a map or a slice of them into rows.
+func encodeRows(v interface{}) ([]map[string]interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	raw = []byte(strings.TrimSpace(string(raw)))
+	if len(raw) > 0 && raw[0] == '[' {
+		var rows []map[string]interface{}
+		err = json.Unmarshal(raw, &rows)
+		return rows, err
+	}
+	var row map[string]interface{}
+	if err := json.Unmarshal(raw, &row); err != nil {
+		return nil, err
+	}
+	return []map[string]interface{}{row}, nil
+}
+
+func decodeRows(rows []map[string]interface{}, out interface{}) error {
+	if out == nil {
+		return nil
+	}
+	if rows == nil {
+		rows = []map[string]interface{}{}
+	}
+	raw, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// project keeps the selected columns, so a row never carries more than the
+// database would have sent.
+func project(rows []map[string]interface{}, columns string) []map[string]interface{} {
+	if columns == "" || columns == "*" {
+		return rows
+	}
+	names := strings.Split(columns, ",")
+	out := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		projected := make(map[string]interface{}, len(names))
+		for _, name := range names {
+			name = strings.TrimSpace(name)
+			if v, ok := row[name]; ok {
+				projected[name] = v
+			}
+		}
+		out = append(out, projected)
+	}
+	return out
+}
+
+func copyRow(row map[string]interface{}) map[string]interface{} {
+	rows, _ := encodeRows(row)
+	return rows[0]
+}
+
+/* ------------------------------------------------------------------
+   Filtering and ordering
+   ------------------------------------------------------------------*/
+
+func matchesAll(row map[string]interface{}, filters []Filter) bool {
+	for _, f := range filters {
+		if !matches(row, f) {
+			return false
+		}
+	}
+	return true
+}
+
+// joined returns the rows of each joined table that match the filters of the join.
+func (m *MemoryStore) joined(joins []Join) [][]map[string]interface{} {
+	out := make([][]map[string]interface{}, 0, len(joins))
+	for _, j := range joins {
+		var rows []map[string]interface{}
+		for _, row := range m.rows(j.Table) {
+			if matchesAll(row, j.Filters) {
+				rows = append(rows, row)
+			}
+		}
+		out = append(out, rows)
+	}
+	return out
+}
+
+// related tells whether row of table has a related row among the joined rows of
+// every join. Without a schema to read the foreign keys from, a relation is taken
+// from the naming the tables follow: the referencing table has a column named after
+// the referenced one in the singular, e.g. product_options.product_id references
+// products.id.
+func related(table string, row map[string]interface{}, joins []Join, joined [][]map[string]interface{}) bool {
+	for i, j := range joins {
+		// either row references the joined table, e.g. an option its product,
+		own, other := singular(j.Table)+"_id", "id"
+		if _, ok := row[own]; !ok {
+			// or the joined table references table, e.g. a product its options
+			own, other = "id", singular(table)+"_id"
+		}
+		key := row[own]
+		if key == nil || !slices.ContainsFunc(joined[i], func(r map[string]interface{}) bool { return r[other] == key }) {
+			return false
+		}
+	}
+	return true
+}
+
+func singular(table string) string {
+	if strings.HasSuffix(table, "ies") {
+		return strings.TrimSuffix(table, "ies") + "y"
+	}
+	return strings.TrimSuffix(table, "s")
+}
+
+func matches(row map[string]interface{}, f Filter) bool {
+	if f.Operator == OpOr {
+		for _, group := range f.Groups {
+			if matchesAll(row, group) {
+				return true
+			}
+		}
+		return false
+	}
+	v := row[f.Column]
+	if f.Operator == OpIs {
+		var ok bool
+		switch f.Value {
+		case "null":
+			ok = v == nil
+		default:
+			ok = v != nil && valueString(v) == f.Value
+		}
+		return ok != f.Negate
+	}
+	// like in SQL, a comparison with NULL is never true, negated or not
+	if v == nil {
+		return false
+	}
+
+	var ok bool
+	switch f.Operator {
+	case OpEq:
+		ok = compareValue(v, f.Value) == 0
+	case OpNeq:
+		ok = compareValue(v, f.Value) != 0
+	case OpGt:
+		ok = compareValue(v, f.Value) > 0
+	case OpGte:
+		ok = compareValue(v, f.Value) >= 0
+	case OpLt:
+		ok = compareValue(v, f.Value) < 0
+	case OpLte:
+		ok = compareValue(v, f.Value) <= 0
+	case OpIn:
+		for _, value := range f.Values {
+			if compareValue(v, value) == 0 {
+				ok = true
+				break
+			}
+		}
+	case OpLike, OpIlike:
+		s, isString := v.(string)
+		ok = isString && likePattern(f.Value, f.Operator == OpIlike).MatchString(s)
+	}
+	return ok != f.Negate
+}
+
+// likePattern translates a LIKE pattern; '*' is accepted for '%' as PostgREST does.
+func likePattern(pattern string, insensitive bool) *regexp.Regexp {
+	var b strings.Builder
+	if insensitive {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '%', '*':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+func valueString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	}
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// compareValue compares a stored value with a filter value: numbers as numbers,
+// timestamps as instants and anything else as text.
+func compareValue(v interface{}, value string) int {
+	switch t := v.(type) {
+	case float64:
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			switch {
+			case t < n:
+				return -1
+			case t > n:
+				return 1
+			}
+			return 0
+		}
+	case string:
+		if a, ok := parseTime(t); ok {
+			if b, ok := parseTime(value); ok {
+				return a.Compare(b)
+			}
+		}
+	}
+	return strings.Compare(valueString(v), value)
+}
+
+func sortRows(rows []map[string]interface{}, orders []Order) {
+	if len(orders) == 0 {
+		return
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, o := range orders {
+			a, b := rows[i][o.Column], rows[j][o.Column]
+			var c int
+			switch {
+			case a == nil && b == nil:
+				c = 0
+			case a == nil:
+				// NULLS LAST when ascending, NULLS FIRST when descending
+				c = 1
+			case b == nil:
+				c = -1
+			default:
+				c = compareValue(a, valueString(b))
+			}
+			if o.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type row struct {
+	Id    string  `json:"id"`
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+func seedProducts() *MemoryStore {
+	m := NewMemoryStore()
+	m.Seed("products",
+		map[string]interface{}{"id": "1", "name": "Lúa giống", "price": 120, "discount": 10},
+		map[string]interface{}{"id": "2", "name": "Phân bón", "price": 80, "discount": nil},
+		map[string]interface{}{"id": "3", "name": "Lúa thơm", "price": 95, "deleted_at": "2025-03-01T00:00:00Z"},
+	)
+	return m
+}
+
+func TestMemoryStoreFind(t *testing.T) {
+	m := seedProducts()
+	ctx := context.Background()
+
+	var live []row
+	assert.NoError(t, m.Find(ctx, "products", "*", Where(IsNull("deleted_at")).OrderBy("price", true), &live))
+	assert.Equal(t, []row{{"1", "Lúa giống", 120}, {"2", "Phân bón", 80}}, live)
+
+	var cheap []row
+	assert.NoError(t, m.Find(ctx, "products", "*", Where(Lt("price", "100")).OrderBy("price", false), &cheap))
+	assert.Equal(t, []string{"2", "3"}, []string{cheap[0].Id, cheap[1].Id})
+
+	var rice []row
+	assert.NoError(t, m.Find(ctx, "products", "*", Where(Ilike("name", "*LÚA*"), In("id", []string{"1", "2", "3"})).Page(1, 1).OrderBy("id", false), &rice))
+	assert.Equal(t, "3", rice[0].Id)
+}
+
+func TestMemoryStoreNulls(t *testing.T) {
+	m := seedProducts()
+	ctx := context.Background()
+
+	// a NULL discount matches neither the comparison nor its negation
+	n, _ := m.Count(ctx, "products", Where(Gt("discount", "0")))
+	assert.Equal(t, 1, n)
+	n, _ = m.Count(ctx, "products", Where(Not(Gt("discount", "0"))))
+	assert.Equal(t, 0, n)
+	n, _ = m.Count(ctx, "products", Where(NotNull("discount")))
+	assert.Equal(t, 1, n)
+
+	var sorted []row
+	assert.NoError(t, m.Find(ctx, "products", "id", Query{}.OrderBy("discount", false), &sorted))
+	assert.Equal(t, "1", sorted[0].Id)
+	assert.Empty(t, sorted[0].Name, "columns outside the selection are not returned")
+}
+
+func TestMemoryStoreWrite(t *testing.T) {
+	m := NewMemoryStore()
+	ctx := context.Background()
+
+	var created []row
+	assert.NoError(t, m.Insert(ctx, "products", []row{{Name: "a", Price: 1}, {Name: "b", Price: 2}}, &created))
+	assert.Len(t, created, 2)
+	assert.NotEmpty(t, created[0].Id)
+	assert.Nil(t, m.Rows("products")[0]["deleted_at"])
+
+	var updated []row
+	assert.NoError(t, m.Update(ctx, "products", Where(Eq("name", "a")), map[string]interface{}{"price": 5}, &updated))
+	assert.Equal(t, 5.0, updated[0].Price)
+
+	assert.NoError(t, m.Delete(ctx, "products", Where(Eq("id", created[1].Id))))
+	assert.Len(t, m.Rows("products"), 1)
+}
+
+func TestMemoryStoreJoin(t *testing.T) {
+	m := seedProducts()
+	m.Seed("product_options",
+		map[string]interface{}{"id": "o1", "product_id": "1", "stock": 0},
+		map[string]interface{}{"id": "o2", "product_id": "2", "stock": 4},
+		map[string]interface{}{"id": "o3", "product_id": "3", "stock": 2},
+	)
+	ctx := context.Background()
+
+	// products through their options, and options through their product
+	var stocked []row
+	assert.NoError(t, m.Find(ctx, "products", "id", Query{}.Has("product_options", Gt("stock", "0")).OrderBy("id", false), &stocked))
+	assert.Equal(t, []row{{Id: "2"}, {Id: "3"}}, stocked)
+	n, _ := m.Count(ctx, "product_options", Query{}.Has("products", IsNull("deleted_at")))
+	assert.Equal(t, 2, n)
+	n, _ = m.Count(ctx, "products", Query{}.Has("product_options", Gt("stock", "0")).Has("product_options", Lt("stock", "3")))
+	assert.Equal(t, 1, n, "every join must match, each on a row of its own")
+
+	assert.Error(t, m.Update(ctx, "products", Query{}.Has("product_options"), map[string]interface{}{"price": 1}, nil))
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"time"
+)
+
+type OrderRepository interface {
+	// List pages through the live orders; filter.UserId is applied as given, so the
+	// caller decides whose orders may be seen. Pages go by offset or by cursor, the
+	// cursors around the page are returned with it.
+	List(ctx context.Context, filter dto.OrderListFilter) ([]dto.Order, Cursors, error)
+	Count(ctx context.Context, filter dto.OrderListFilter) (int, error)
+	Get(ctx context.Context, id string) (dto.Order, error)
+	Create(ctx context.Context, order map[string]interface{}) (dto.Order, error)
+	Update(ctx context.Context, id string, patch map[string]interface{}) error
+	// SetStatus moves a live order from one status to another, and reports false when
+	// the order was no longer in status from.
+	SetStatus(ctx context.Context, id string, from, to enum.OrderStatus) (dto.Order, bool, error)
+	// SoftDelete marks the order and its lines deleted.
+	SoftDelete(ctx context.Context, id string) error
+	// Discard removes the order, its lines and its history for good.
+	Discard(ctx context.Context, id string) error
+
+	Details(ctx context.Context, orderId string) ([]dto.OrderDetail, error)
+	// AddDetails inserts lines and returns them as stored.
+	AddDetails(ctx context.Context, rows []map[string]interface{}) ([]dto.OrderDetail, error)
+	// SoftDeleteDetails marks the lines with the given ids deleted.
+	SoftDeleteDetails(ctx context.Context, ids []string) error
+
+	AddHistory(ctx context.Context, entry dto.OrderStatusHistory) error
+	// History lists the status changes of an order, oldest first.
+	History(ctx context.Context, orderId string) ([]dto.OrderStatusHistory, error)
+}
+
+type orderRepository struct {
+	store Store
+}
+
+func NewOrderRepository(di do.Injector) (OrderRepository, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize OrderRepository: %w", err)
+	}
+	return &orderRepository{store: store}, nil
+}
+
+const (
+	orderColumns       = "id,created_at,updated_at,user_id,address,status,subtotal,discount_total,promotion_code,promotion_discount,grand_total,metadata"
+	orderDetailColumns = "id,order_id,product_option_id,quantity,unit_price,discount,discount_type,discount_amount,line_total,metadata"
+)
+
+var orderSortable = []string{"status", "grand_total", "created_at", "updated_at"}
+
+func orderQuery(filter dto.OrderListFilter) Query {
+	q := Where(IsNull("deleted_at"))
+	if filter.Status != "" {
+		q = q.And(Eq("status", string(filter.Status)))
+	}
+	if filter.UserId != "" {
+		q = q.And(Eq("user_id", filter.UserId))
+	}
+	return q
+}
+
+func (r *orderRepository) List(ctx context.Context, filter dto.OrderListFilter) ([]dto.Order, Cursors, error) {
+	var orders []dto.Order
+	q, err := orderQuery(filter).Sort(filter.Pagination, orderSortable, Order{Column: "created_at", Desc: true})
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+	cursors, err := findPage(ctx, r.store, "orders", orderColumns, q, filter.Pagination, &orders)
+	if err != nil {
+		return nil, Cursors{}, fmt.Errorf("failed to fetch orders: %w", err)
+	}
+	return orders, cursors, nil
+}
+
+func (r *orderRepository) Count(ctx context.Context, filter dto.OrderListFilter) (int, error) {
+	total, err := r.store.Count(ctx, "orders", orderQuery(filter))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count orders: %w", err)
+	}
+	return total, nil
+}
+
+func (r *orderRepository) Get(ctx context.Context, id string) (dto.Order, error) {
+	var orders []dto.Order
+	if err := r.store.Find(ctx, "orders", orderColumns, Where(Eq("id", id), IsNull("deleted_at")), &orders); err != nil {
+		return dto.Order{}, fmt.Errorf("failed to fetch order: %w", err)
+	}
+	if len(orders) == 0 {
+		return dto.Order{}, errors.NotFound("order not found")
+	}
+	return orders[0], nil
+}
+
+func (r *orderRepository) Create(ctx context.Context, order map[string]interface{}) (dto.Order, error) {
+	var created []dto.Order
+	if err := r.store.Insert(ctx, "orders", order, &created); err != nil {
+		return dto.Order{}, fmt.Errorf("failed to create order: %w", err)
+	}
+	return created[0], nil
+}
+
+func (r *orderRepository) Update(ctx context.Context, id string, patch map[string]interface{}) error {
+	if err := r.store.Update(ctx, "orders", Where(Eq("id", id)), patch, nil); err != nil {
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+	return nil
+}
+
+func (r *orderRepository) SetStatus(ctx context.Context, id string, from, to enum.OrderStatus) (dto.Order, bool, error) {
+	var updated []dto.Order
+	if err := r.store.Update(ctx, "orders",
+		Where(Eq("id", id), Eq("status", string(from)), IsNull("deleted_at")),
+		map[string]interface{}{"status": to, "updated_at": time.Now()},
+		&updated); err != nil {
+		return dto.Order{}, false, fmt.Errorf("failed to update order status: %w", err)
+	}
+	if len(updated) == 0 {
+		return dto.Order{}, false, nil
+	}
+	return updated[0], true, nil
+}
+
+func (r *orderRepository) SoftDelete(ctx context.Context, id string) error {
+	deleted := map[string]interface{}{"deleted_at": time.Now()}
+	if err := r.store.Update(ctx, "orders", Where(Eq("id", id)), deleted, nil); err != nil {
+		return fmt.Errorf("failed to delete order: %w", err)
+	}
+	if err := r.store.Update(ctx, "order_details", Where(Eq("order_id", id)), deleted, nil); err != nil {
+		return fmt.Errorf("failed to delete order details: %w", err)
+	}
+	return nil
+}
+
+func (r *orderRepository) Discard(ctx context.Context, id string) error {
+	if err := r.store.Delete(ctx, "order_status_history", Where(Eq("order_id", id))); err != nil {
+		return fmt.Errorf("failed to discard order history: %w", err)
+	}
+	if err := r.store.Delete(ctx, "order_details", Where(Eq("order_id", id))); err != nil {
+		return fmt.Errorf("failed to discard order details: %w", err)
+	}
+	if err := r.store.Delete(ctx, "orders", Where(Eq("id", id))); err != nil {
+		return fmt.Errorf("failed to discard order: %w", err)
+	}
+	return nil
+}
+
+func (r *orderRepository) Details(ctx context.Context, orderId string) ([]dto.OrderDetail, error) {
+	var details []dto.OrderDetail
+	if err := r.store.Find(ctx, "order_details", orderDetailColumns, Where(Eq("order_id", orderId), IsNull("deleted_at")), &details); err != nil {
+		return nil, fmt.Errorf("failed to fetch order details: %w", err)
+	}
+	return details, nil
+}
+
+func (r *orderRepository) AddDetails(ctx context.Context, rows []map[string]interface{}) ([]dto.OrderDetail, error) {
+	var created []dto.OrderDetail
+	if err := r.store.Insert(ctx, "order_details", rows, &created); err != nil {
+		return nil, fmt.Errorf("failed to insert order details: %w", err)
+	}
+	return created, nil
+}
+
+func (r *orderRepository) SoftDeleteDetails(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := r.store.Update(ctx, "order_details", Where(In("id", ids), IsNull("deleted_at")), map[string]interface{}{"deleted_at": time.Now()}, nil); err != nil {
+		return fmt.Errorf("failed to clear order details: %w", err)
+	}
+	return nil
+}
+
+// AddHistory leaves out an empty from status or author, both are NULL in the table.
+func (r *orderRepository) AddHistory(ctx context.Context, entry dto.OrderStatusHistory) error {
+	row := map[string]interface{}{
+		"order_id":  entry.OrderId,
+		"to_status": entry.ToStatus,
+		"note":      entry.Note,
+	}
+	if entry.FromStatus != "" {
+		row["from_status"] = entry.FromStatus
+	}
+	if entry.CreatedBy != "" {
+		row["created_by"] = entry.CreatedBy
+	}
+	if err := r.store.Insert(ctx, "order_status_history", row, nil); err != nil {
+		return fmt.Errorf("failed to record order status history: %w", err)
+	}
+	return nil
+}
+
+func (r *orderRepository) History(ctx context.Context, orderId string) ([]dto.OrderStatusHistory, error) {
+	var history []dto.OrderStatusHistory
+	q := Where(Eq("order_id", orderId)).OrderBy("created_at", false)
+	if err := r.store.Find(ctx, "order_status_history", "id,order_id,from_status,to_status,note,created_by,created_at", q, &history); err != nil {
+		return nil, fmt.Errorf("failed to fetch order history: %w", err)
+	}
+	return history, nil
+}
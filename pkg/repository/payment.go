@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"time"
+)
+
+type PaymentRepository interface {
+	// ListByOrder returns every payment of an order, the newest first.
+	ListByOrder(ctx context.Context, orderId string) ([]dto.Payment, error)
+	Get(ctx context.Context, id string) (dto.Payment, error)
+	GetByReference(ctx context.Context, reference string) (dto.Payment, error)
+	Create(ctx context.Context, payment dto.Payment) (dto.Payment, error)
+	// CancelPending cancels the payments of an order that are still pending.
+	CancelPending(ctx context.Context, orderId string) error
+	// Settle applies patch to a pending payment, and reports false when the payment
+	// was no longer pending.
+	Settle(ctx context.Context, id string, patch map[string]interface{}) (dto.Payment, bool, error)
+
+	// EventSeen tells whether a gateway callback was recorded before.
+	EventSeen(ctx context.Context, method enum.PaymentMethod, eventId string) (bool, error)
+	AddEvent(ctx context.Context, paymentId string, method enum.PaymentMethod, event dto.PaymentEvent, payload map[string]interface{}) error
+}
+
+type paymentRepository struct {
+	store Store
+}
+
+func NewPaymentRepository(di do.Injector) (PaymentRepository, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PaymentRepository: %w", err)
+	}
+	return &paymentRepository{store: store}, nil
+}
+
+const paymentColumns = "id,order_id,user_id,method,status,amount,reference,transaction_id,instructions,paid_at,created_at,updated_at"
+
+func (r *paymentRepository) ListByOrder(ctx context.Context, orderId string) ([]dto.Payment, error) {
+	var payments []dto.Payment
+	if err := r.store.Find(ctx, "payments", paymentColumns, Where(Eq("order_id", orderId)).OrderBy("created_at", true), &payments); err != nil {
+		return nil, fmt.Errorf("failed to fetch payments: %w", err)
+	}
+	return payments, nil
+}
+
+func (r *paymentRepository) find(ctx context.Context, column, value string) (dto.Payment, error) {
+	var payments []dto.Payment
+	if err := r.store.Find(ctx, "payments", paymentColumns, Where(Eq(column, value)), &payments); err != nil {
+		return dto.Payment{}, fmt.Errorf("failed to fetch payment: %w", err)
+	}
+	if len(payments) == 0 {
+		return dto.Payment{}, errors.NotFound("payment not found")
+	}
+	return payments[0], nil
+}
+
+func (r *paymentRepository) Get(ctx context.Context, id string) (dto.Payment, error) {
+	return r.find(ctx, "id", id)
+}
+
+func (r *paymentRepository) GetByReference(ctx context.Context, reference string) (dto.Payment, error) {
+	return r.find(ctx, "reference", reference)
+}
+
+func (r *paymentRepository) Create(ctx context.Context, payment dto.Payment) (dto.Payment, error) {
+	row := map[string]interface{}{
+		"order_id":     payment.OrderId,
+		"user_id":      payment.UserId,
+		"method":       payment.Method,
+		"status":       payment.Status,
+		"amount":       payment.Amount,
+		"reference":    payment.Reference,
+		"instructions": payment.Instructions,
+	}
+	var created []dto.Payment
+	if err := r.store.Insert(ctx, "payments", row, &created); err != nil {
+		return dto.Payment{}, fmt.Errorf("failed to create payment: %w", err)
+	}
+	return created[0], nil
+}
+
+func (r *paymentRepository) CancelPending(ctx context.Context, orderId string) error {
+	if err := r.store.Update(ctx, "payments",
+		Where(Eq("order_id", orderId), Eq("status", string(enum.PaymentPending))),
+		map[string]interface{}{"status": enum.PaymentCancelled, "updated_at": time.Now()},
+		nil); err != nil {
+		return fmt.Errorf("failed to cancel previous payments: %w", err)
+	}
+	return nil
+}
+
+func (r *paymentRepository) Settle(ctx context.Context, id string, patch map[string]interface{}) (dto.Payment, bool, error) {
+	var updated []dto.Payment
+	if err := r.store.Update(ctx, "payments", Where(Eq("id", id), Eq("status", string(enum.PaymentPending))), patch, &updated); err != nil {
+		return dto.Payment{}, false, fmt.Errorf("failed to update payment: %w", err)
+	}
+	if len(updated) == 0 {
+		return dto.Payment{}, false, nil
+	}
+	return updated[0], true, nil
+}
+
+func (r *paymentRepository) EventSeen(ctx context.Context, method enum.PaymentMethod, eventId string) (bool, error) {
+	n, err := r.store.Count(ctx, "payment_events", Where(Eq("method", string(method)), Eq("event_id", eventId)))
+	if err != nil {
+		return false, fmt.Errorf("failed to check payment event: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *paymentRepository) AddEvent(ctx context.Context, paymentId string, method enum.PaymentMethod, event dto.PaymentEvent, payload map[string]interface{}) error {
+	row := map[string]interface{}{
+		"payment_id": paymentId,
+		"method":     method,
+		"event_id":   event.EventId,
+		"status":     event.Status,
+		"payload":    payload,
+	}
+	if err := r.store.Insert(ctx, "payment_events", row, nil); err != nil {
+		return fmt.Errorf("failed to record payment event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"strconv"
+	"time"
+)
+
+type PostRepository interface {
+	// List pages through the live posts, the newest first; with publishedOnly the
+	// published posts by publication date, and the filter cannot pick by assignee.
+	List(ctx context.Context, filter dto.PostFilter, publishedOnly bool) ([]dto.Post, error)
+	Count(ctx context.Context, filter dto.PostFilter, publishedOnly bool) (int, error)
+	Get(ctx context.Context, id string, publishedOnly bool) (dto.Post, error)
+	Create(ctx context.Context, post map[string]interface{}) (dto.Post, error)
+	Update(ctx context.Context, id string, patch map[string]interface{}) (dto.Post, error)
+	SoftDelete(ctx context.Context, id string) error
+}
+
+type postRepository struct {
+	store Store
+}
+
+func NewPostRepository(di do.Injector) (PostRepository, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PostRepository: %w", err)
+	}
+	return &postRepository{store: store}, nil
+}
+
+const postColumns = "id,title,slug,content,thumbnail,type,status,assignee,created_by,metadata,published_at,created_at,updated_at"
+
+func postQuery(filter dto.PostFilter, publishedOnly bool) Query {
+	q := Where(IsNull("deleted_at"))
+	if filter.Type != "" {
+		q = q.And(Eq("type", string(filter.Type)))
+	}
+	if filter.Title != "" {
+		q = q.And(Ilike("title", "*"+filter.Title+"*"))
+	}
+	if publishedOnly || filter.Status != "" {
+		q = q.And(Eq("status", strconv.FormatBool(publishedOnly || filter.Status == enum.Published)))
+	}
+	if !publishedOnly && filter.Assignee != "" {
+		q = q.And(Eq("assignee", filter.Assignee))
+	}
+	return q
+}
+
+func (r *postRepository) List(ctx context.Context, filter dto.PostFilter, publishedOnly bool) ([]dto.Post, error) {
+	order := "created_at"
+	if publishedOnly {
+		order = "published_at"
+	}
+	var posts []dto.Post
+	q := postQuery(filter, publishedOnly).OrderBy(order, true).Page(int(filter.Limit), filter.Offset())
+	if err := r.store.Find(ctx, "posts", postColumns, q, &posts); err != nil {
+		return nil, fmt.Errorf("failed to fetch posts: %w", err)
+	}
+	return posts, nil
+}
+
+func (r *postRepository) Count(ctx context.Context, filter dto.PostFilter, publishedOnly bool) (int, error) {
+	total, err := r.store.Count(ctx, "posts", postQuery(filter, publishedOnly))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count posts: %w", err)
+	}
+	return total, nil
+}
+
+func (r *postRepository) Get(ctx context.Context, id string, publishedOnly bool) (dto.Post, error) {
+	q := Where(Eq("id", id), IsNull("deleted_at"))
+	if publishedOnly {
+		q = q.And(Eq("status", "true"))
+	}
+	var posts []dto.Post
+	if err := r.store.Find(ctx, "posts", postColumns, q, &posts); err != nil {
+		return dto.Post{}, fmt.Errorf("failed to fetch post: %w", err)
+	}
+	if len(posts) == 0 {
+		return dto.Post{}, errors.NotFound("post not found")
+	}
+	return posts[0], nil
+}
+
+func (r *postRepository) Create(ctx context.Context, post map[string]interface{}) (dto.Post, error) {
+	var created []dto.Post
+	if err := r.store.Insert(ctx, "posts", post, &created); err != nil {
+		return dto.Post{}, fmt.Errorf("failed to create post: %w", err)
+	}
+	return created[0], nil
+}
+
+func (r *postRepository) Update(ctx context.Context, id string, patch map[string]interface{}) (dto.Post, error) {
+	var updated []dto.Post
+	if err := r.store.Update(ctx, "posts", Where(Eq("id", id), IsNull("deleted_at")), patch, &updated); err != nil {
+		return dto.Post{}, fmt.Errorf("failed to update post: %w", err)
+	}
+	if len(updated) == 0 {
+		return dto.Post{}, errors.NotFound("post not found")
+	}
+	return updated[0], nil
+}
+
+func (r *postRepository) SoftDelete(ctx context.Context, id string) error {
+	if err := r.store.Update(ctx, "posts", Where(Eq("id", id)), map[string]interface{}{"deleted_at": time.Now()}, nil); err != nil {
+		return fmt.Errorf("failed to delete post: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type ProductRepository interface {
+	// List reads one page of products, by offset or by cursor, and the cursors around it.
+	List(ctx context.Context, filter dto.ProductFilter) ([]dto.ProductList, Cursors, error)
+	Count(ctx context.Context, filter dto.ProductFilter) (int, error)
+	// Facets counts the products matching filter per category, variant value, price
+	// bucket and stock.
+	Facets(ctx context.Context, filter dto.ProductFilter) (dto.ProductFacets, error)
+	// ListByIds returns the live products among ids in the order of ids.
+	ListByIds(ctx context.Context, ids []string) ([]dto.ProductList, error)
+	// Get returns a live product with its category and the path to it; options and
+	// variants are left empty.
+	Get(ctx context.Context, id string) (dto.ProductDetail, error)
+	Exists(ctx context.Context, id string) (bool, error)
+	Create(ctx context.Context, req dto.ProductCreated) (dto.Product, error)
+	Update(ctx context.Context, id string, patch map[string]interface{}) (dto.Product, error)
+	SoftDelete(ctx context.Context, id string) error
+	// CountInCategories counts the live products filed under any of the categories.
+	CountInCategories(ctx context.Context, categoryIds []string) (int, error)
+	// MoveToCategory files the live products of the categories from under category to.
+	MoveToCategory(ctx context.Context, from []string, to string) error
+	// SoftDeleteInCategories deletes the live products filed under any of the categories.
+	SoftDeleteInCategories(ctx context.Context, categoryIds []string) error
+}
+
+type productRepository struct {
+	store      Store
+	categories CategoryRepository
+}
+
+func NewProductRepository(di do.Injector) (ProductRepository, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductRepository: %w", err)
+	}
+	categories, err := do.Invoke[CategoryRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductRepository: %w", err)
+	}
+	return &productRepository{store: store, categories: categories}, nil
+}
+
+const (
+	productListColumns   = "id,name,slug,price,content,image_detail,category_id,thumbnail,discount,discount_type,created_at,updated_at"
+	productDetailColumns = "id,name,slug,price,content,image_detail,description,product_code,category_id,thumbnail,discount,discount_type,meta_title,meta_description,canonical_image,created_at,updated_at"
+)
+
+var productSortable = []string{"name", "price", "created_at", "updated_at"}
+
+func productQuery(filter dto.ProductFilter) Query {
+	q := Where(IsNull("deleted_at"))
+	if filter.Name != "" {
+		q = q.And(Ilike("name", "*"+filter.Name+"*"))
+	}
+	// with its descendants the category is resolved like the category_ids facet
+	if filter.CategoryId != "" && !filter.IncludeDescendants {
+		q = q.And(Eq("category_id", filter.CategoryId))
+	}
+	if filter.IsDiscount {
+		q = q.And(NotNull("discount"))
+	}
+	if filter.GreaterThan > 0 {
+		q = q.And(Gt("price", strconv.FormatFloat(filter.GreaterThan, 'f', -1, 64)))
+	}
+	if filter.SmallerThan > 0 {
+		q = q.And(Lt("price", strconv.FormatFloat(filter.SmallerThan, 'f', -1, 64)))
+	}
+	return q
+}
+
+// productListRow is a product list entry as it is read, before the category name is
+// filled in.
+type productListRow struct {
+	dto.ProductList
+	CategoryId string `json:"category_id"`
+}
+
+func (r *productRepository) List(ctx context.Context, filter dto.ProductFilter) ([]dto.ProductList, Cursors, error) {
+	var rows []productListRow
+	q, err := r.query(ctx, filter)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+	q, err = q.Sort(filter.Pagination, productSortable, Order{Column: "created_at", Desc: true})
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+	cursors, err := findPage(ctx, r.store, "products", productListColumns, q, filter.Pagination, &rows)
+	if err != nil {
+		return nil, Cursors{}, fmt.Errorf("failed to fetch products: %w", err)
+	}
+
+	products, err := r.withCategories(ctx, rows)
+	if err != nil {
+		return nil, Cursors{}, err
+	}
+	return products, cursors, nil
+}
+
+func (r *productRepository) ListByIds(ctx context.Context, ids []string) ([]dto.ProductList, error) {
+	if len(ids) == 0 {
+		return []dto.ProductList{}, nil
+	}
+	var rows []productListRow
+	if err := r.store.Find(ctx, "products", productListColumns, Where(In("id", ids), IsNull("deleted_at")), &rows); err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	position := make(map[string]int, len(ids))
+	for i, id := range ids {
+		position[id] = i
+	}
+	slices.SortFunc(rows, func(a, b productListRow) int { return position[a.Id] - position[b.Id] })
+	return r.withCategories(ctx, rows)
+}
+
+func (r *productRepository) withCategories(ctx context.Context, rows []productListRow) ([]dto.ProductList, error) {
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.CategoryId)
+	}
+	names, err := r.categories.Names(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]dto.ProductList, 0, len(rows))
+	for _, row := range rows {
+		p := row.ProductList
+		p.Category = dto.CategoryProduct{Id: row.CategoryId, Name: names[row.CategoryId]}
+		products = append(products, p)
+	}
+	return products, nil
+}
+
+func (r *productRepository) Count(ctx context.Context, filter dto.ProductFilter) (int, error) {
+	q, err := r.query(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	total, err := r.store.Count(ctx, "products", q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count products: %w", err)
+	}
+	return total, nil
+}
+
+func (r *productRepository) Get(ctx context.Context, id string) (dto.ProductDetail, error) {
+	var rows []struct {
+		dto.ProductDetail
+		CategoryId string `json:"category_id"`
+		dto.Seo
+	}
+	if err := r.store.Find(ctx, "products", productDetailColumns, Where(Eq("id", id), IsNull("deleted_at")), &rows); err != nil {
+		return dto.ProductDetail{}, fmt.Errorf("failed to fetch product: %w", err)
+	}
+	if len(rows) == 0 {
+		return dto.ProductDetail{}, errors.NotFound("product not found")
+	}
+
+	names, err := r.categories.Names(ctx, []string{rows[0].CategoryId})
+	if err != nil {
+		return dto.ProductDetail{}, err
+	}
+	product := rows[0].ProductDetail
+	product.Seo = rows[0].Seo.Or(product.Name, product.Description, product.Thumbnail)
+	product.CategoryProduct = dto.CategoryProduct{Id: rows[0].CategoryId, Name: names[rows[0].CategoryId]}
+	if product.Breadcrumb, err = r.categories.Path(ctx, rows[0].CategoryId); err != nil {
+		return dto.ProductDetail{}, err
+	}
+	return product, nil
+}
+
+func (r *productRepository) Exists(ctx context.Context, id string) (bool, error) {
+	n, err := r.store.Count(ctx, "products", Where(Eq("id", id), IsNull("deleted_at")))
+	if err != nil {
+		return false, fmt.Errorf("failed to find product: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *productRepository) Create(ctx context.Context, req dto.ProductCreated) (dto.Product, error) {
+	var created []dto.Product
+	if err := r.store.Insert(ctx, "products", req, &created); err != nil {
+		return dto.Product{}, fmt.Errorf("failed to create product: %w", err)
+	}
+	return created[0], nil
+}
+
+func (r *productRepository) Update(ctx context.Context, id string, patch map[string]interface{}) (dto.Product, error) {
+	var updated []dto.Product
+	if err := r.store.Update(ctx, "products", Where(Eq("id", id), IsNull("deleted_at")), patch, &updated); err != nil {
+		return dto.Product{}, fmt.Errorf("failed to update product: %w", err)
+	}
+	if len(updated) == 0 {
+		return dto.Product{}, errors.NotFound("product not found")
+	}
+	return updated[0], nil
+}
+
+func (r *productRepository) SoftDelete(ctx context.Context, id string) error {
+	if err := r.store.Update(ctx, "products", Where(Eq("id", id)), map[string]interface{}{"deleted_at": time.Now()}, nil); err != nil {
+		return fmt.Errorf("failed to soft delete product: %w", err)
+	}
+	return nil
+}
+
+func (r *productRepository) CountInCategories(ctx context.Context, categoryIds []string) (int, error) {
+	if len(categoryIds) == 0 {
+		return 0, nil
+	}
+	count, err := r.store.Count(ctx, "products", Where(In("category_id", categoryIds), IsNull("deleted_at")))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count products: %w", err)
+	}
+	return count, nil
+}
+
+func (r *productRepository) MoveToCategory(ctx context.Context, from []string, to string) error {
+	if len(from) == 0 {
+		return nil
+	}
+	patch := map[string]interface{}{"category_id": to, "updated_at": time.Now()}
+	if err := r.store.Update(ctx, "products", Where(In("category_id", from), IsNull("deleted_at")), patch, nil); err != nil {
+		return fmt.Errorf("failed to move products: %w", err)
+	}
+	return nil
+}
+
+func (r *productRepository) SoftDeleteInCategories(ctx context.Context, categoryIds []string) error {
+	if len(categoryIds) == 0 {
+		return nil
+	}
+	patch := map[string]interface{}{"deleted_at": time.Now()}
+	if err := r.store.Update(ctx, "products", Where(In("category_id", categoryIds), IsNull("deleted_at")), patch, nil); err != nil {
+		return fmt.Errorf("failed to soft delete products: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,434 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// facetFallbackLimit is the number of live products above which the facets are not
+// counted in the API while product_facets is missing, as that reads whole tables.
+const facetFallbackLimit = 1000
+
+// facetFilter holds the facet filters of a product list once parsed.
+type facetFilter struct {
+	categories []string
+	// variants maps a variant name to the values asked for, in the order the names came
+	variants map[string][]string
+	names    []string
+	prices   []priceRange
+	inStock  bool
+}
+
+type priceRange struct {
+	value    string
+	min, max float64
+	bounded  bool
+}
+
+func (p priceRange) contains(price float64) bool {
+	return price >= p.min && (!p.bounded || price < p.max)
+}
+
+func parsePriceRange(value string) (priceRange, error) {
+	from, to, ok := strings.Cut(value, "-")
+	p := priceRange{value: value}
+	var err error
+	if from != "" {
+		p.min, err = strconv.ParseFloat(from, 64)
+	}
+	if err == nil && to != "" {
+		p.max, err = strconv.ParseFloat(to, 64)
+		p.bounded = true
+	}
+	if !ok || err != nil || (p.bounded && p.max <= p.min) {
+		return p, errors.BadRequest("price range %q is not min-max", value)
+	}
+	return p, nil
+}
+
+func parseFacets(filter dto.ProductFilter) (facetFilter, error) {
+	f := facetFilter{variants: make(map[string][]string), inStock: filter.InStock}
+	var categories []string
+	if filter.CategoryId != "" && filter.IncludeDescendants {
+		categories = append(categories, filter.CategoryId)
+	}
+	for _, ids := range filter.CategoryIds {
+		for _, id := range strings.Split(ids, ",") {
+			categories = append(categories, strings.TrimSpace(id))
+		}
+	}
+	f.categories = uniqueIds(categories)
+	for _, v := range filter.Variants {
+		name, value, ok := strings.Cut(v, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			return f, errors.BadRequest("variant filter %q is not name:value", v)
+		}
+		if _, seen := f.variants[name]; !seen {
+			f.names = append(f.names, name)
+		}
+		f.variants[name] = append(f.variants[name], value)
+	}
+	for _, value := range filter.PriceRanges {
+		p, err := parsePriceRange(value)
+		if err != nil {
+			return f, err
+		}
+		f.prices = append(f.prices, p)
+	}
+	return f, nil
+}
+
+func (f facetFilter) priceMatches(price float64) bool {
+	if len(f.prices) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(f.prices, func(p priceRange) bool { return p.contains(price) })
+}
+
+// variantMatches tells whether a product with the given variant values has one of
+// the values asked for of every variant named, leaving out the variant skip.
+func (f facetFilter) variantMatches(values map[string]map[string]bool, skip string) bool {
+	for _, name := range f.names {
+		if name == skip {
+			continue
+		}
+		if !slices.ContainsFunc(f.variants[name], func(v string) bool { return values[name][v] }) {
+			return false
+		}
+	}
+	return true
+}
+
+// query is productQuery with the facet filters added; categories are widened to
+// their subtrees, and the variant and stock filters need a related row: a live
+// option in stock, and for every variant named a value asked for.
+func (r *productRepository) query(ctx context.Context, filter dto.ProductFilter) (Query, error) {
+	q := productQuery(filter)
+	f, err := parseFacets(filter)
+	if err != nil {
+		return q, err
+	}
+	if len(f.categories) > 0 {
+		ids, err := r.categories.Subtree(ctx, f.categories)
+		if err != nil {
+			return q, err
+		}
+		q = q.And(In("category_id", ids))
+	}
+	if len(f.prices) > 0 {
+		groups := make([][]Filter, 0, len(f.prices))
+		for _, p := range f.prices {
+			group := []Filter{Gte("price", strconv.FormatFloat(p.min, 'f', -1, 64))}
+			if p.bounded {
+				group = append(group, Lt("price", strconv.FormatFloat(p.max, 'f', -1, 64)))
+			}
+			groups = append(groups, group)
+		}
+		q = q.And(Or(groups...))
+	}
+	if f.inStock {
+		q = q.Has("product_options", Gt("stock", "0"), IsNull("deleted_at"))
+	}
+	for _, name := range f.names {
+		q = q.Has("product_variant_values", Eq("name", name), In("value", f.variants[name]))
+	}
+	return q, nil
+}
+
+// facetCounts are the counts behind dto.ProductFacets; categories counts the
+// products of each category itself, before they are rolled up to the parents.
+type facetCounts struct {
+	categories map[string]int
+	prices     []int
+	variants   map[string]map[string]int
+	inStock    int
+}
+
+func priceBuckets() []priceRange {
+	buckets := make([]priceRange, 0, len(dto.PriceBuckets))
+	for _, value := range dto.PriceBuckets {
+		p, _ := parsePriceRange(value)
+		buckets = append(buckets, p)
+	}
+	return buckets
+}
+
+// Facets counts the products for every facet value with the filters of the other
+// facets applied. The counting is done by the product_facets function (see
+// supabase/migrations); while it is missing the rows are read and counted here, as
+// long as there are no more than facetFallbackLimit products.
+func (r *productRepository) Facets(ctx context.Context, filter dto.ProductFilter) (dto.ProductFacets, error) {
+	f, err := parseFacets(filter)
+	if err != nil {
+		return dto.ProductFacets{}, err
+	}
+	categories, err := r.categories.All(ctx)
+	if err != nil {
+		return dto.ProductFacets{}, err
+	}
+	var selected []string
+	if len(f.categories) > 0 {
+		selected = subtree(categories, f.categories)
+	}
+	buckets := priceBuckets()
+
+	counts, err := r.countFacetsInDB(ctx, filter, f, selected, buckets)
+	if errors.Is(err, ErrNoFunction) {
+		counts, err = r.countFacets(ctx, filter, f, selected, buckets)
+	}
+	if err != nil {
+		return dto.ProductFacets{}, err
+	}
+	return counts.facets(categories, buckets), nil
+}
+
+// rangeBounds lists the lower and upper bounds of ranges for product_facets; an
+// unbounded upper end is a NULL.
+func rangeBounds(ranges []priceRange) ([]float64, []*float64) {
+	mins, maxs := make([]float64, 0, len(ranges)), make([]*float64, 0, len(ranges))
+	for _, p := range ranges {
+		mins = append(mins, p.min)
+		if p.bounded {
+			maxs = append(maxs, &p.max)
+		} else {
+			maxs = append(maxs, nil)
+		}
+	}
+	return mins, maxs
+}
+
+func (r *productRepository) countFacetsInDB(ctx context.Context, filter dto.ProductFilter, f facetFilter, selected []string, buckets []priceRange) (facetCounts, error) {
+	params := map[string]interface{}{
+		"is_discount": filter.IsDiscount,
+		"in_stock":    f.inStock,
+		"variants":    f.variants,
+	}
+	if filter.Name != "" {
+		params["name_filter"] = filter.Name
+	}
+	if filter.CategoryId != "" && !filter.IncludeDescendants {
+		params["category_id"] = filter.CategoryId
+	}
+	if filter.GreaterThan > 0 {
+		params["greater_than"] = filter.GreaterThan
+	}
+	if filter.SmallerThan > 0 {
+		params["smaller_than"] = filter.SmallerThan
+	}
+	if len(f.categories) > 0 {
+		params["category_ids"] = selected
+	}
+	if len(f.prices) > 0 {
+		params["price_min"], params["price_max"] = rangeBounds(f.prices)
+	}
+	params["bucket_min"], params["bucket_max"] = rangeBounds(buckets)
+
+	var rows []struct {
+		Facet string `json:"facet"`
+		Name  string `json:"name"`
+		Value string `json:"value"`
+		Count int    `json:"count"`
+	}
+	if err := r.store.Call(ctx, "product_facets", params, &rows); err != nil {
+		return facetCounts{}, err
+	}
+	counts := facetCounts{
+		categories: make(map[string]int),
+		prices:     make([]int, len(buckets)),
+		variants:   make(map[string]map[string]int),
+	}
+	for _, row := range rows {
+		switch row.Facet {
+		case "category":
+			counts.categories[row.Value] = row.Count
+		case "price":
+			// buckets are numbered from 1 in the order they were given
+			if i, err := strconv.Atoi(row.Value); err == nil && i >= 1 && i <= len(buckets) {
+				counts.prices[i-1] = row.Count
+			}
+		case "variant":
+			if counts.variants[row.Name] == nil {
+				counts.variants[row.Name] = make(map[string]int)
+			}
+			counts.variants[row.Name][row.Value] = row.Count
+		case "in_stock":
+			counts.inStock = row.Count
+		}
+	}
+	return counts, nil
+}
+
+// variantValues maps product ids to the values of their live variants by variant
+// name.
+func (r *productRepository) variantValues(ctx context.Context) (map[string]map[string]map[string]bool, error) {
+	var rows []struct {
+		ProductId string                     `json:"product_id"`
+		Name      string                     `json:"name"`
+		Detail    []dto.ProductVariantDetail `json:"detail"`
+	}
+	if err := r.store.Find(ctx, "product_variants", "product_id,name,detail", Where(IsNull("deleted_at")), &rows); err != nil {
+		return nil, fmt.Errorf("failed to fetch product variants: %w", err)
+	}
+	values := make(map[string]map[string]map[string]bool)
+	for _, row := range rows {
+		if values[row.ProductId] == nil {
+			values[row.ProductId] = make(map[string]map[string]bool)
+		}
+		name := strings.TrimSpace(row.Name)
+		if values[row.ProductId][name] == nil {
+			values[row.ProductId][name] = make(map[string]bool)
+		}
+		for _, d := range row.Detail {
+			values[row.ProductId][name][strings.TrimSpace(d.Name)] = true
+		}
+	}
+	return values, nil
+}
+
+// stocked returns the ids of the products with a live option in stock.
+func (r *productRepository) stocked(ctx context.Context) (map[string]bool, error) {
+	var rows []struct {
+		ProductId string `json:"product_id"`
+	}
+	if err := r.store.Find(ctx, "product_options", "product_id", Where(Gt("stock", "0"), IsNull("deleted_at")), &rows); err != nil {
+		return nil, fmt.Errorf("failed to fetch product stock: %w", err)
+	}
+	stocked := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		stocked[row.ProductId] = true
+	}
+	return stocked, nil
+}
+
+// countFacets does what product_facets does over every product, variant and option
+// read from the store.
+func (r *productRepository) countFacets(ctx context.Context, filter dto.ProductFilter, f facetFilter, selected []string, buckets []priceRange) (facetCounts, error) {
+	total, err := r.store.Count(ctx, "products", Where(IsNull("deleted_at")))
+	if err != nil {
+		return facetCounts{}, fmt.Errorf("failed to count products: %w", err)
+	}
+	if total > facetFallbackLimit {
+		return facetCounts{}, fmt.Errorf("product_facets is not installed and %d products are too many to count without it", total)
+	}
+	var products []struct {
+		Id         string  `json:"id"`
+		CategoryId string  `json:"category_id"`
+		Price      float64 `json:"price"`
+	}
+	if err := r.store.Find(ctx, "products", "id,category_id,price", productQuery(filter), &products); err != nil {
+		return facetCounts{}, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	values, err := r.variantValues(ctx)
+	if err != nil {
+		return facetCounts{}, err
+	}
+	stocked, err := r.stocked(ctx)
+	if err != nil {
+		return facetCounts{}, err
+	}
+
+	inSelected := make(map[string]bool, len(selected))
+	for _, id := range selected {
+		inSelected[id] = true
+	}
+	counts := facetCounts{
+		categories: make(map[string]int),
+		prices:     make([]int, len(buckets)),
+		variants:   make(map[string]map[string]int),
+	}
+	for _, p := range products {
+		inCategory := len(f.categories) == 0 || inSelected[p.CategoryId]
+		inPrice := f.priceMatches(p.Price)
+		inVariants := f.variantMatches(values[p.Id], "")
+		inStockFilter := !f.inStock || stocked[p.Id]
+
+		if inPrice && inVariants && inStockFilter {
+			counts.categories[p.CategoryId]++
+		}
+		if inCategory && inVariants && inStockFilter {
+			for i, b := range buckets {
+				if b.contains(p.Price) {
+					counts.prices[i]++
+				}
+			}
+		}
+		if inCategory && inPrice && inStockFilter {
+			for name, vals := range values[p.Id] {
+				if !f.variantMatches(values[p.Id], name) {
+					continue
+				}
+				if counts.variants[name] == nil {
+					counts.variants[name] = make(map[string]int)
+				}
+				for v := range vals {
+					counts.variants[name][v]++
+				}
+			}
+		}
+		if inCategory && inPrice && inVariants && stocked[p.Id] {
+			counts.inStock++
+		}
+	}
+	return counts, nil
+}
+
+// facets labels and sorts the counts; a product counts for its category and every
+// category above it.
+func (c facetCounts) facets(categories []dto.Category, buckets []priceRange) dto.ProductFacets {
+	parent := make(map[string]string, len(categories))
+	label := make(map[string]string, len(categories))
+	for _, cat := range categories {
+		parent[cat.Id], label[cat.Id] = cat.ParentId, cat.Name
+	}
+	rolled := make(map[string]int)
+	for own, n := range c.categories {
+		seen := make(map[string]bool)
+		for id := own; id != "" && !seen[id] && label[id] != ""; id = parent[id] {
+			seen[id] = true
+			rolled[id] += n
+		}
+	}
+
+	facets := dto.ProductFacets{
+		Categories: make([]dto.FacetCount, 0, len(rolled)),
+		Variants:   make([]dto.VariantFacet, 0, len(c.variants)),
+		Prices:     make([]dto.FacetCount, 0, len(buckets)),
+		InStock:    c.inStock,
+	}
+	for id, n := range rolled {
+		facets.Categories = append(facets.Categories, dto.FacetCount{Value: id, Label: label[id], Count: n})
+	}
+	sortFacetCounts(facets.Categories)
+	for name, counts := range c.variants {
+		facet := dto.VariantFacet{Name: name, Values: make([]dto.FacetCount, 0, len(counts))}
+		for v, n := range counts {
+			facet.Values = append(facet.Values, dto.FacetCount{Value: v, Count: n})
+		}
+		sortFacetCounts(facet.Values)
+		facets.Variants = append(facets.Variants, facet)
+	}
+	slices.SortFunc(facets.Variants, func(a, b dto.VariantFacet) int { return strings.Compare(a.Name, b.Name) })
+	for i, b := range buckets {
+		facets.Prices = append(facets.Prices, dto.FacetCount{Value: b.value, Count: c.prices[i]})
+	}
+	return facets
+}
+
+// sortFacetCounts puts the most frequent values first, ties by label and value.
+func sortFacetCounts(counts []dto.FacetCount) {
+	slices.SortFunc(counts, func(a, b dto.FacetCount) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		if c := strings.Compare(a.Label, b.Label); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Value, b.Value)
+	})
+}
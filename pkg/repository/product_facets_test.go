@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"SangXanh/pkg/dto"
+	"context"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func TestProductFacets(t *testing.T) {
+	di := do.New()
+	store := InjectMemory(di)
+	store.Seed("categories",
+		map[string]interface{}{"id": "plants", "name": "Cây cảnh"},
+		map[string]interface{}{"id": "succulents", "name": "Sen đá", "parent_id": "plants"},
+		map[string]interface{}{"id": "pots", "name": "Chậu"},
+	)
+	store.Seed("products",
+		map[string]interface{}{"id": "p1", "name": "Kim tiền", "price": 150000, "category_id": "plants"},
+		map[string]interface{}{"id": "p2", "name": "Sen đá nâu", "price": 50000, "category_id": "succulents"},
+		map[string]interface{}{"id": "p3", "name": "Sen đá kim cương", "price": 80000, "category_id": "succulents"},
+		map[string]interface{}{"id": "p4", "name": "Chậu gốm", "price": 250000, "category_id": "pots"},
+	)
+	store.Seed("product_variants",
+		map[string]interface{}{"product_id": "p1", "name": "Kích thước", "detail": []map[string]interface{}{{"name": "Lớn"}, {"name": "Nhỏ"}}},
+		map[string]interface{}{"product_id": "p2", "name": "Kích thước", "detail": []map[string]interface{}{{"name": "Nhỏ"}}},
+		map[string]interface{}{"product_id": "p4", "name": "Kích thước", "detail": []map[string]interface{}{{"name": "Lớn"}}},
+	)
+	store.Seed("product_options",
+		map[string]interface{}{"product_id": "p1", "stock": 0},
+		map[string]interface{}{"product_id": "p2", "stock": 4},
+		map[string]interface{}{"product_id": "p4", "stock": 1},
+	)
+	products := do.MustInvoke[ProductRepository](di)
+	ctx := context.Background()
+	list := func(filter dto.ProductFilter) []string {
+		filter.Page, filter.Limit = 1, 20
+		found, _, err := products.List(ctx, filter)
+		require.NoError(t, err)
+		ids := make([]string, 0, len(found))
+		for _, p := range found {
+			ids = append(ids, p.Id)
+		}
+		return ids
+	}
+
+	assert.ElementsMatch(t, []string{"p1", "p2", "p3"}, list(dto.ProductFilter{CategoryIds: []string{"plants"}}), "subcategories are included")
+	assert.ElementsMatch(t, []string{"p2", "p3", "p4"}, list(dto.ProductFilter{CategoryIds: []string{"succulents,pots"}}))
+	assert.ElementsMatch(t, []string{"p1"}, list(dto.ProductFilter{CategoryId: "plants"}))
+	assert.ElementsMatch(t, []string{"p1", "p2", "p3"}, list(dto.ProductFilter{CategoryId: "plants", IncludeDescendants: true}))
+	assert.ElementsMatch(t, []string{"p1", "p2"}, list(dto.ProductFilter{Variants: []string{"Kích thước:Nhỏ"}}))
+	assert.ElementsMatch(t, []string{"p2", "p3", "p4"}, list(dto.ProductFilter{PriceRanges: []string{"0-100000", "200000-"}}))
+	assert.ElementsMatch(t, []string{"p2"}, list(dto.ProductFilter{InStock: true, CategoryIds: []string{"plants"}}))
+	_, _, err := products.List(ctx, dto.ProductFilter{Variants: []string{"Kích thước"}})
+	assert.Error(t, err)
+
+	facets, err := products.Facets(ctx, dto.ProductFilter{CategoryIds: []string{"plants"}, Variants: []string{"Kích thước:Nhỏ"}})
+	require.NoError(t, err)
+	// categories are counted without the category filter, rolled up to their parents
+	assert.Equal(t, []dto.FacetCount{
+		{Value: "plants", Label: "Cây cảnh", Count: 2},
+		{Value: "succulents", Label: "Sen đá", Count: 1},
+	}, facets.Categories)
+	// variant values are counted without the filter on the same variant
+	assert.Equal(t, []dto.VariantFacet{{Name: "Kích thước", Values: []dto.FacetCount{
+		{Value: "Nhỏ", Count: 2},
+		{Value: "Lớn", Count: 1},
+	}}}, facets.Variants)
+	assert.Equal(t, dto.FacetCount{Value: "0-100000", Count: 1}, facets.Prices[0])
+	assert.Equal(t, dto.FacetCount{Value: "100000-200000", Count: 1}, facets.Prices[1])
+	assert.Equal(t, 1, facets.InStock)
+}
+
+func TestProductFacetsFallbackLimit(t *testing.T) {
+	di := do.New()
+	store := InjectMemory(di)
+	for i := 0; i <= facetFallbackLimit; i++ {
+		store.Seed("products", map[string]interface{}{"id": strconv.Itoa(i), "name": "Lúa", "price": 100})
+	}
+	products := do.MustInvoke[ProductRepository](di)
+
+	_, err := products.Facets(context.Background(), dto.ProductFilter{})
+	assert.Error(t, err, "too many products to read without product_facets")
+}
+
+// rpcStore answers every function call with rows, remembering the params.
+type rpcStore struct {
+	*MemoryStore
+	rows   []map[string]interface{}
+	params map[string]interface{}
+}
+
+func (s *rpcStore) Call(_ context.Context, _ string, params map[string]interface{}, out interface{}) error {
+	s.params = params
+	return decodeRows(s.rows, out)
+}
+
+func TestProductFacetsInDB(t *testing.T) {
+	di := do.New()
+	memory := InjectMemory(di)
+	store := &rpcStore{MemoryStore: memory, rows: []map[string]interface{}{
+		{"facet": "category", "value": "succulents", "count": 2},
+		{"facet": "category", "value": "plants", "count": 1},
+		{"facet": "category", "value": "deleted", "count": 5},
+		{"facet": "price", "value": "1", "count": 3},
+		{"facet": "price", "value": "5", "count": 1},
+		{"facet": "variant", "name": "Kích thước", "value": "Nhỏ", "count": 2},
+		{"facet": "in_stock", "count": 4},
+	}}
+	do.OverrideValue[Store](di, store)
+	memory.Seed("categories",
+		map[string]interface{}{"id": "plants", "name": "Cây cảnh"},
+		map[string]interface{}{"id": "succulents", "name": "Sen đá", "parent_id": "plants"},
+	)
+	products := do.MustInvoke[ProductRepository](di)
+
+	facets, err := products.Facets(context.Background(), dto.ProductFilter{
+		CategoryIds: []string{"plants"},
+		Variants:    []string{"Kích thước:Nhỏ"},
+		PriceRanges: []string{"0-100000", "200000-"},
+		InStock:     true,
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"plants", "succulents"}, store.params["category_ids"], "the subtree is resolved before the call")
+	assert.Equal(t, map[string][]string{"Kích thước": {"Nhỏ"}}, store.params["variants"])
+	assert.Equal(t, []float64{0, 200000}, store.params["price_min"])
+	assert.Len(t, store.params["bucket_max"], len(dto.PriceBuckets))
+	assert.Equal(t, true, store.params["in_stock"])
+
+	assert.Equal(t, []dto.FacetCount{
+		{Value: "plants", Label: "Cây cảnh", Count: 3},
+		{Value: "succulents", Label: "Sen đá", Count: 2},
+	}, facets.Categories, "rolled up, without the categories that are gone")
+	assert.Equal(t, []dto.VariantFacet{{Name: "Kích thước", Values: []dto.FacetCount{{Value: "Nhỏ", Count: 2}}}}, facets.Variants)
+	assert.Equal(t, 3, facets.Prices[0].Count)
+	assert.Equal(t, 1, facets.Prices[4].Count)
+	assert.Equal(t, 4, facets.InStock)
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"strconv"
+	"time"
+)
+
+type ProductOptionRepository interface {
+	ListByProduct(ctx context.Context, productId string) ([]dto.ProductOption, error)
+	// Get returns a live option; withDeleted also finds soft-deleted ones.
+	Get(ctx context.Context, id string, withDeleted bool) (dto.ProductOption, error)
+	// Create takes a single dto.ProductOptionCreate or a slice of them.
+	Create(ctx context.Context, options interface{}) ([]dto.ProductOption, error)
+	// Update changes one option; a non-empty productId must match as well.
+	Update(ctx context.Context, id, productId string, patch map[string]interface{}) (dto.ProductOption, error)
+	SoftDelete(ctx context.Context, ids ...string) error
+	SoftDeleteByProduct(ctx context.Context, productId string) error
+	// SetStock moves the stock from current to next, and reports false when the stock
+	// was no longer current.
+	SetStock(ctx context.Context, id string, current, next int) (bool, error)
+	// Prices returns the options among ids that can still be ordered; options that are
+	// deleted or whose product is deleted are left out.
+	Prices(ctx context.Context, ids []string) ([]dto.ProductOptionPrice, error)
+	// LowStock returns a page of the live options of live products with at most
+	// threshold items left, lowest stock first, and how many there are in all.
+	LowStock(ctx context.Context, threshold, limit, offset int) ([]dto.LowStockItem, int, error)
+}
+
+type productOptionRepository struct {
+	store Store
+}
+
+func NewProductOptionRepository(di do.Injector) (ProductOptionRepository, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductOptionRepository: %w", err)
+	}
+	return &productOptionRepository{store: store}, nil
+}
+
+const productOptionColumns = "id,name,product_id,price,stock,detail,metadata,created_at,updated_at"
+
+func (r *productOptionRepository) ListByProduct(ctx context.Context, productId string) ([]dto.ProductOption, error) {
+	var options []dto.ProductOption
+	if err := r.store.Find(ctx, "product_options", productOptionColumns, Where(Eq("product_id", productId), IsNull("deleted_at")), &options); err != nil {
+		return nil, fmt.Errorf("failed to fetch product options: %w", err)
+	}
+	return options, nil
+}
+
+func (r *productOptionRepository) Get(ctx context.Context, id string, withDeleted bool) (dto.ProductOption, error) {
+	q := Where(Eq("id", id))
+	if !withDeleted {
+		q = q.And(IsNull("deleted_at"))
+	}
+	var options []dto.ProductOption
+	if err := r.store.Find(ctx, "product_options", productOptionColumns, q, &options); err != nil {
+		return dto.ProductOption{}, fmt.Errorf("failed to fetch product option: %w", err)
+	}
+	if len(options) == 0 {
+		return dto.ProductOption{}, errors.NotFound("product option %s not found", id)
+	}
+	return options[0], nil
+}
+
+func (r *productOptionRepository) Create(ctx context.Context, options interface{}) ([]dto.ProductOption, error) {
+	var created []dto.ProductOption
+	if err := r.store.Insert(ctx, "product_options", options, &created); err != nil {
+		return nil, fmt.Errorf("failed to create product options: %w", err)
+	}
+	return created, nil
+}
+
+func (r *productOptionRepository) Update(ctx context.Context, id, productId string, patch map[string]interface{}) (dto.ProductOption, error) {
+	q := Where(Eq("id", id), IsNull("deleted_at"))
+	if productId != "" {
+		q = q.And(Eq("product_id", productId))
+	}
+	var updated []dto.ProductOption
+	if err := r.store.Update(ctx, "product_options", q, patch, &updated); err != nil {
+		return dto.ProductOption{}, fmt.Errorf("failed to update product option %s: %w", id, err)
+	}
+	if len(updated) == 0 {
+		return dto.ProductOption{}, errors.NotFound("product option %s not found", id)
+	}
+	return updated[0], nil
+}
+
+func (r *productOptionRepository) SoftDelete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := r.store.Update(ctx, "product_options", Where(In("id", ids), IsNull("deleted_at")), map[string]interface{}{"deleted_at": time.Now()}, nil); err != nil {
+		return fmt.Errorf("failed to delete product options: %w", err)
+	}
+	return nil
+}
+
+func (r *productOptionRepository) SoftDeleteByProduct(ctx context.Context, productId string) error {
+	if err := r.store.Update(ctx, "product_options", Where(Eq("product_id", productId), IsNull("deleted_at")), map[string]interface{}{"deleted_at": time.Now()}, nil); err != nil {
+		return fmt.Errorf("failed to delete product options of product %s: %w", productId, err)
+	}
+	return nil
+}
+
+func (r *productOptionRepository) SetStock(ctx context.Context, id string, current, next int) (bool, error) {
+	var updated []struct {
+		Id string `json:"id"`
+	}
+	if err := r.store.Update(ctx, "product_options",
+		Where(Eq("id", id), Eq("stock", strconv.Itoa(current))),
+		map[string]interface{}{"stock": next, "updated_at": time.Now()},
+		&updated); err != nil {
+		return false, fmt.Errorf("failed to update stock of product option %s: %w", id, err)
+	}
+	return len(updated) > 0, nil
+}
+
+// liveProducts loads the live products among ids, keyed by id.
+func (r *productOptionRepository) liveProducts(ctx context.Context, ids []string) (map[string]dto.ProductOptionPrice, error) {
+	out := make(map[string]dto.ProductOptionPrice)
+	ids = uniqueIds(ids)
+	if len(ids) == 0 {
+		return out, nil
+	}
+	var products []struct {
+		Id           string            `json:"id"`
+		CategoryId   string            `json:"category_id"`
+		Price        float64           `json:"price"`
+		Discount     float64           `json:"discount"`
+		DiscountType enum.DiscountType `json:"discount_type"`
+	}
+	if err := r.store.Find(ctx, "products", "id,category_id,price,discount,discount_type", Where(In("id", ids), IsNull("deleted_at")), &products); err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	for _, p := range products {
+		out[p.Id] = dto.ProductOptionPrice{
+			ProductId:    p.Id,
+			CategoryId:   p.CategoryId,
+			ProductPrice: p.Price,
+			Discount:     p.Discount,
+			DiscountType: p.DiscountType,
+		}
+	}
+	return out, nil
+}
+
+func (r *productOptionRepository) Prices(ctx context.Context, ids []string) ([]dto.ProductOptionPrice, error) {
+	ids = uniqueIds(ids)
+	if len(ids) == 0 {
+		return []dto.ProductOptionPrice{}, nil
+	}
+	var options []struct {
+		Id        string  `json:"id"`
+		ProductId string  `json:"product_id"`
+		Price     float64 `json:"price"`
+	}
+	if err := r.store.Find(ctx, "product_options", "id,product_id,price", Where(In("id", ids), IsNull("deleted_at")), &options); err != nil {
+		return nil, fmt.Errorf("failed to fetch product options: %w", err)
+	}
+
+	productIds := make([]string, 0, len(options))
+	for _, o := range options {
+		productIds = append(productIds, o.ProductId)
+	}
+	products, err := r.liveProducts(ctx, productIds)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make([]dto.ProductOptionPrice, 0, len(options))
+	for _, o := range options {
+		p, ok := products[o.ProductId]
+		if !ok {
+			continue
+		}
+		p.Id = o.Id
+		p.Price = o.Price
+		prices = append(prices, p)
+	}
+	return prices, nil
+}
+
+func (r *productOptionRepository) LowStock(ctx context.Context, threshold, limit, offset int) ([]dto.LowStockItem, int, error) {
+	q := Where(Lte("stock", strconv.Itoa(threshold)), IsNull("deleted_at")).Has("products", IsNull("deleted_at"))
+	total, err := r.store.Count(ctx, "product_options", q)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count low stock options: %w", err)
+	}
+	var items []dto.LowStockItem
+	q = q.OrderBy("stock", false).OrderBy("id", false).Page(limit, offset)
+	if err := r.store.Find(ctx, "product_options", "id,name,product_id,price,stock", q, &items); err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch low stock options: %w", err)
+	}
+
+	productIds := make([]string, 0, len(items))
+	for _, item := range items {
+		productIds = append(productIds, item.ProductId)
+	}
+	var products []dto.CategoryProduct
+	if len(productIds) > 0 {
+		if err := r.store.Find(ctx, "products", "id,name", Where(In("id", uniqueIds(productIds))), &products); err != nil {
+			return nil, 0, fmt.Errorf("failed to fetch products: %w", err)
+		}
+	}
+	names := make(map[string]string, len(products))
+	for _, p := range products {
+		names[p.Id] = p.Name
+	}
+	for i, item := range items {
+		items[i].Product = dto.CategoryProduct{Id: item.ProductId, Name: names[item.ProductId]}
+	}
+	return items, total, nil
+}
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/log"
+	"SangXanh/pkg/search"
+	"context"
+	"fmt"
+	"github.com/nedpals/supabase-go"
+	postgrest "github.com/nedpals/supabase-go/postgrest/pkg"
+	"github.com/samber/do/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type ProductSearcher interface {
+	// Search ranks the live products against req.Q and returns the page of hits asked
+	// for, best first, with the number of products that matched.
+	Search(ctx context.Context, req dto.ProductSearch) ([]dto.ProductSearchHit, int, error)
+	// Invalidate drops what the searcher cached about the products; call it after a
+	// product changed.
+	Invalidate()
+}
+
+// productSearchFields are the searched fields, the product name weighing most.
+var productSearchFields = []search.Field{
+	{Name: "name", Weight: 4},
+	{Name: "product_code", Weight: 3},
+	{Name: "category", Weight: 2},
+	{Name: "description", Weight: 1, Snippet: 160},
+}
+
+const (
+	// productIndexTTL bounds how long the in-process index misses changes made
+	// elsewhere, e.g. a renamed category or another instance.
+	productIndexTTL = 5 * time.Minute
+	// searchMaxResults caps the matches ranked by the database per search.
+	searchMaxResults = 1000
+)
+
+/* ------------------------------------------------------------------
+   In-process index
+   ------------------------------------------------------------------*/
+
+// indexSearcher searches an in-process index of every live product, which it builds
+// from the store on first use and again once it is older than productIndexTTL.
+type indexSearcher struct {
+	store      Store
+	products   ProductRepository
+	categories CategoryRepository
+
+	mu    sync.Mutex
+	index *search.Index
+	built time.Time
+}
+
+func NewIndexProductSearcher(di do.Injector) (ProductSearcher, error) {
+	return newIndexSearcher(di)
+}
+
+func newIndexSearcher(di do.Injector) (*indexSearcher, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductSearcher: %w", err)
+	}
+	products, err := do.Invoke[ProductRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductSearcher: %w", err)
+	}
+	categories, err := do.Invoke[CategoryRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductSearcher: %w", err)
+	}
+	return &indexSearcher{store: store, products: products, categories: categories}, nil
+}
+
+func (s *indexSearcher) Search(ctx context.Context, req dto.ProductSearch) ([]dto.ProductSearchHit, int, error) {
+	index, err := s.load(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	hits := index.Search(req.Q)
+	from := min(max(req.Offset(), 0), len(hits))
+	to := min(from+int(req.Limit), len(hits))
+
+	page := make(map[string]search.Hit, to-from)
+	ids := make([]string, 0, to-from)
+	for _, hit := range hits[from:to] {
+		page[hit.Id] = hit
+		ids = append(ids, hit.Id)
+	}
+	products, err := s.products.ListByIds(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	results := make([]dto.ProductSearchHit, 0, len(products))
+	for _, p := range products {
+		hit := page[p.Id]
+		results = append(results, dto.ProductSearchHit{ProductList: p, Score: hit.Score, Highlights: hit.Highlights})
+	}
+	return results, len(hits), nil
+}
+
+func (s *indexSearcher) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.index = nil
+}
+
+func (s *indexSearcher) load(ctx context.Context) (*search.Index, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index != nil && time.Since(s.built) < productIndexTTL {
+		return s.index, nil
+	}
+	docs, err := searchDocuments(ctx, s.store, s.categories, nil)
+	if err != nil {
+		return nil, err
+	}
+	index := search.NewIndex(productSearchFields...)
+	for id, doc := range docs {
+		index.Put(id, doc)
+	}
+	s.index, s.built = index, time.Now()
+	return index, nil
+}
+
+// searchDocuments reads the searched fields of the live products, all of them or
+// only those among ids, keyed by product id.
+func searchDocuments(ctx context.Context, store Store, categories CategoryRepository, ids []string) (map[string]map[string]string, error) {
+	q := Where(IsNull("deleted_at"))
+	if ids != nil {
+		if len(ids) == 0 {
+			return map[string]map[string]string{}, nil
+		}
+		q = q.And(In("id", ids))
+	}
+	var rows []struct {
+		Id          string `json:"id"`
+		Name        string `json:"name"`
+		ProductCode string `json:"product_code"`
+		Description string `json:"description"`
+		CategoryId  string `json:"category_id"`
+	}
+	if err := store.Find(ctx, "products", "id,name,product_code,description,category_id", q, &rows); err != nil {
+		return nil, fmt.Errorf("failed to load products for search: %w", err)
+	}
+	categoryIds := make([]string, 0, len(rows))
+	for _, row := range rows {
+		categoryIds = append(categoryIds, row.CategoryId)
+	}
+	names, err := categories.Names(ctx, categoryIds)
+	if err != nil {
+		return nil, err
+	}
+	docs := make(map[string]map[string]string, len(rows))
+	for _, row := range rows {
+		docs[row.Id] = map[string]string{
+			"name":         row.Name,
+			"product_code": row.ProductCode,
+			"description":  row.Description,
+			"category":     names[row.CategoryId],
+		}
+	}
+	return docs, nil
+}
+
+/* ------------------------------------------------------------------
+   Database
+   ------------------------------------------------------------------*/
+
+// supabaseSearcher ranks in Postgres through the search_products function (see
+// supabase/migrations). While the function is missing from the database it falls
+// back to the in-process index.
+type supabaseSearcher struct {
+	db         *supabase.Client
+	store      Store
+	products   ProductRepository
+	categories CategoryRepository
+	fallback   *indexSearcher
+	missing    atomic.Bool
+}
+
+func NewSupabaseProductSearcher(di do.Injector) (ProductSearcher, error) {
+	db, err := do.Invoke[*supabase.Client](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductSearcher: %w", err)
+	}
+	fallback, err := newIndexSearcher(di)
+	if err != nil {
+		return nil, err
+	}
+	return &supabaseSearcher{
+		db:         db,
+		store:      fallback.store,
+		products:   fallback.products,
+		categories: fallback.categories,
+		fallback:   fallback,
+	}, nil
+}
+
+func (s *supabaseSearcher) Search(ctx context.Context, req dto.ProductSearch) ([]dto.ProductSearchHit, int, error) {
+	if s.missing.Load() {
+		return s.fallback.Search(ctx, req)
+	}
+	var ranked []struct {
+		Id   string  `json:"id"`
+		Rank float64 `json:"rank"`
+	}
+	err := s.db.DB.Rpc("search_products", map[string]interface{}{
+		"search":      req.Q,
+		"max_results": searchMaxResults,
+	}).ExecuteWithContext(ctx, &ranked)
+	var reqErr *postgrest.RequestError
+	if errors.As(err, &reqErr) && reqErr.Code == "PGRST202" {
+		log.Errorw("search_products is not installed, searching the in-process index", "error", err)
+		s.missing.Store(true)
+		return s.fallback.Search(ctx, req)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search products: %w", err)
+	}
+
+	from := min(max(req.Offset(), 0), len(ranked))
+	to := min(from+int(req.Limit), len(ranked))
+	ids := make([]string, 0, to-from)
+	rank := make(map[string]float64, to-from)
+	for _, r := range ranked[from:to] {
+		ids = append(ids, r.Id)
+		rank[r.Id] = r.Rank
+	}
+	products, err := s.products.ListByIds(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	// the function ranks on unaccented text, highlighting is done here so it looks
+	// the same as with the index
+	docs, err := searchDocuments(ctx, s.store, s.categories, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	terms := search.Terms(req.Q)
+	results := make([]dto.ProductSearchHit, 0, len(products))
+	for _, p := range products {
+		results = append(results, dto.ProductSearchHit{
+			ProductList: p,
+			Score:       rank[p.Id],
+			Highlights:  search.HighlightFields(productSearchFields, terms, docs[p.Id]),
+		})
+	}
+	return results, len(ranked), nil
+}
+
+func (s *supabaseSearcher) Invalidate() {
+	s.fallback.Invalidate()
+}
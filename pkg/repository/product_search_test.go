@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"SangXanh/pkg/dto"
+	"context"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIndexProductSearcher(t *testing.T) {
+	di := do.New()
+	store := InjectMemory(di)
+	store.Seed("categories", map[string]interface{}{"id": "c1", "name": "Hạt giống"})
+	store.Seed("products",
+		map[string]interface{}{"id": "p1", "name": "Lúa ST25", "product_code": "LUA-ST25", "category_id": "c1"},
+		map[string]interface{}{"id": "p2", "name": "Cây xanh mini", "description": "Trồng từ hạt giống chọn lọc", "category_id": "c1"},
+		map[string]interface{}{"id": "p3", "name": "Hạt giống cũ", "deleted_at": "2025-03-01T00:00:00Z"},
+	)
+	searcher := do.MustInvoke[ProductSearcher](di)
+	ctx := context.Background()
+
+	req := dto.ProductSearch{Q: "hat giong"}
+	req.Limit = 10
+	hits, total, err := searcher.Search(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total, "deleted products are not found")
+	assert.Equal(t, "p2", hits[0].Id, "the description match ranks above the category match")
+	assert.Equal(t, "Hạt giống", hits[1].Category.Name)
+	assert.Equal(t, "<mark>Hạt</mark> <mark>giống</mark>", hits[1].Highlights["category"])
+
+	// the index is only rebuilt once invalidated
+	store.Seed("products", map[string]interface{}{"id": "p4", "name": "Hạt giống rau"})
+	_, total, _ = searcher.Search(ctx, req)
+	assert.Equal(t, 2, total)
+	searcher.Invalidate()
+	hits, total, _ = searcher.Search(ctx, req)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, "p4", hits[0].Id)
+}
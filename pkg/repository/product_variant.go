@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"time"
+)
+
+type ProductVariantRepository interface {
+	ListByProduct(ctx context.Context, productId string) ([]dto.ProductVariant, error)
+	Get(ctx context.Context, id string) (dto.ProductVariant, error)
+	// Names maps the ids of live variants of a product to their names; ids that are
+	// unknown or belong to another product are missing from the map.
+	Names(ctx context.Context, productId string, ids []string) (map[string]string, error)
+	// Create takes a single dto.ProductVariantCreate or a slice of them.
+	Create(ctx context.Context, variants interface{}) ([]dto.ProductVariant, error)
+	// Update changes one variant; a non-empty productId must match as well.
+	Update(ctx context.Context, id, productId string, patch map[string]interface{}) (dto.ProductVariant, error)
+	SoftDelete(ctx context.Context, ids ...string) error
+}
+
+type productVariantRepository struct {
+	store Store
+}
+
+func NewProductVariantRepository(di do.Injector) (ProductVariantRepository, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductVariantRepository: %w", err)
+	}
+	return &productVariantRepository{store: store}, nil
+}
+
+const productVariantColumns = "id,name,product_id,detail,metadata,created_at,updated_at"
+
+func (r *productVariantRepository) ListByProduct(ctx context.Context, productId string) ([]dto.ProductVariant, error) {
+	var variants []dto.ProductVariant
+	if err := r.store.Find(ctx, "product_variants", productVariantColumns, Where(Eq("product_id", productId), IsNull("deleted_at")), &variants); err != nil {
+		return nil, fmt.Errorf("failed to fetch product variants: %w", err)
+	}
+	return variants, nil
+}
+
+func (r *productVariantRepository) Get(ctx context.Context, id string) (dto.ProductVariant, error) {
+	var variants []dto.ProductVariant
+	if err := r.store.Find(ctx, "product_variants", productVariantColumns, Where(Eq("id", id), IsNull("deleted_at")), &variants); err != nil {
+		return dto.ProductVariant{}, fmt.Errorf("failed to fetch variant: %w", err)
+	}
+	if len(variants) == 0 {
+		return dto.ProductVariant{}, errors.NotFound("variant not found")
+	}
+	return variants[0], nil
+}
+
+func (r *productVariantRepository) Names(ctx context.Context, productId string, ids []string) (map[string]string, error) {
+	names := make(map[string]string, len(ids))
+	ids = uniqueIds(ids)
+	if len(ids) == 0 {
+		return names, nil
+	}
+	var rows []struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := r.store.Find(ctx, "product_variants", "id,name", Where(Eq("product_id", productId), In("id", ids), IsNull("deleted_at")), &rows); err != nil {
+		return nil, fmt.Errorf("failed to load variant names: %w", err)
+	}
+	for _, row := range rows {
+		names[row.Id] = row.Name
+	}
+	return names, nil
+}
+
+func (r *productVariantRepository) Create(ctx context.Context, variants interface{}) ([]dto.ProductVariant, error) {
+	var created []dto.ProductVariant
+	if err := r.store.Insert(ctx, "product_variants", variants, &created); err != nil {
+		return nil, fmt.Errorf("failed to create product variants: %w", err)
+	}
+	return created, nil
+}
+
+func (r *productVariantRepository) Update(ctx context.Context, id, productId string, patch map[string]interface{}) (dto.ProductVariant, error) {
+	q := Where(Eq("id", id), IsNull("deleted_at"))
+	if productId != "" {
+		q = q.And(Eq("product_id", productId))
+	}
+	var updated []dto.ProductVariant
+	if err := r.store.Update(ctx, "product_variants", q, patch, &updated); err != nil {
+		return dto.ProductVariant{}, fmt.Errorf("failed to update variant %s: %w", id, err)
+	}
+	if len(updated) == 0 {
+		return dto.ProductVariant{}, errors.NotFound("variant %s not found", id)
+	}
+	return updated[0], nil
+}
+
+func (r *productVariantRepository) SoftDelete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := r.store.Update(ctx, "product_variants", Where(In("id", ids), IsNull("deleted_at")), map[string]interface{}{"deleted_at": time.Now()}, nil); err != nil {
+		return fmt.Errorf("failed to delete product variants: %w", err)
+	}
+	return nil
+}
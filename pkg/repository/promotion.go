@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"strconv"
+	"time"
+)
+
+type PromotionRepository interface {
+	// List pages through the live promotions, the newest first.
+	List(ctx context.Context, filter dto.PromotionFilter) ([]dto.Promotion, error)
+	Count(ctx context.Context, filter dto.PromotionFilter) (int, error)
+	Get(ctx context.Context, id string) (dto.Promotion, error)
+	// GetByCode looks a live promotion up by its code, which is stored upper case.
+	GetByCode(ctx context.Context, code string) (dto.Promotion, error)
+	Create(ctx context.Context, promotion map[string]interface{}) (dto.Promotion, error)
+	Update(ctx context.Context, id string, patch map[string]interface{}) (dto.Promotion, error)
+	SoftDelete(ctx context.Context, id string) error
+	// SetUsedCount moves used_count from current to next, and reports false when it
+	// was no longer current.
+	SetUsedCount(ctx context.Context, id string, current, next int) (bool, error)
+
+	// CountUsages counts the live usages of a promotion by a user, leaving out those
+	// of the order excludeOrderId when it is given.
+	CountUsages(ctx context.Context, promotionId, userId, excludeOrderId string) (int, error)
+	// Usages returns the live usages of an order, of a single promotion when
+	// promotionId is given.
+	Usages(ctx context.Context, orderId, promotionId string) ([]dto.PromotionUsage, error)
+	AddUsage(ctx context.Context, usage dto.PromotionUsage) (dto.PromotionUsage, error)
+	SetUsageDiscount(ctx context.Context, id string, discount float64) error
+	SoftDeleteUsage(ctx context.Context, id string) error
+}
+
+type promotionRepository struct {
+	store Store
+}
+
+func NewPromotionRepository(di do.Injector) (PromotionRepository, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PromotionRepository: %w", err)
+	}
+	return &promotionRepository{store: store}, nil
+}
+
+const (
+	promotionColumns      = "id,code,description,discount_type,discount_value,max_discount,min_order_value,starts_at,ends_at,usage_limit,usage_limit_per_user,used_count,category_ids,product_ids,status,created_by,created_at,updated_at"
+	promotionUsageColumns = "id,promotion_id,order_id,user_id,discount"
+)
+
+func promotionQuery(filter dto.PromotionFilter) Query {
+	q := Where(IsNull("deleted_at"))
+	if filter.Code != "" {
+		q = q.And(Like("code", "*"+filter.Code+"*"))
+	}
+	if filter.Active {
+		q = q.And(Eq("status", "true"))
+	}
+	return q
+}
+
+func (r *promotionRepository) List(ctx context.Context, filter dto.PromotionFilter) ([]dto.Promotion, error) {
+	var promotions []dto.Promotion
+	q := promotionQuery(filter).OrderBy("created_at", true).Page(int(filter.Limit), filter.Offset())
+	if err := r.store.Find(ctx, "promotions", promotionColumns, q, &promotions); err != nil {
+		return nil, fmt.Errorf("failed to fetch promotions: %w", err)
+	}
+	return promotions, nil
+}
+
+func (r *promotionRepository) Count(ctx context.Context, filter dto.PromotionFilter) (int, error) {
+	total, err := r.store.Count(ctx, "promotions", promotionQuery(filter))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count promotions: %w", err)
+	}
+	return total, nil
+}
+
+func (r *promotionRepository) find(ctx context.Context, column, value string) (dto.Promotion, error) {
+	var promotions []dto.Promotion
+	if err := r.store.Find(ctx, "promotions", promotionColumns, Where(Eq(column, value), IsNull("deleted_at")), &promotions); err != nil {
+		return dto.Promotion{}, fmt.Errorf("failed to fetch promotion: %w", err)
+	}
+	if len(promotions) == 0 {
+		return dto.Promotion{}, errors.NotFound("promotion not found")
+	}
+	return promotions[0], nil
+}
+
+func (r *promotionRepository) Get(ctx context.Context, id string) (dto.Promotion, error) {
+	return r.find(ctx, "id", id)
+}
+
+func (r *promotionRepository) GetByCode(ctx context.Context, code string) (dto.Promotion, error) {
+	return r.find(ctx, "code", code)
+}
+
+func (r *promotionRepository) Create(ctx context.Context, promotion map[string]interface{}) (dto.Promotion, error) {
+	var created []dto.Promotion
+	if err := r.store.Insert(ctx, "promotions", promotion, &created); err != nil {
+		return dto.Promotion{}, fmt.Errorf("failed to create promotion: %w", err)
+	}
+	return created[0], nil
+}
+
+func (r *promotionRepository) Update(ctx context.Context, id string, patch map[string]interface{}) (dto.Promotion, error) {
+	var updated []dto.Promotion
+	if err := r.store.Update(ctx, "promotions", Where(Eq("id", id), IsNull("deleted_at")), patch, &updated); err != nil {
+		return dto.Promotion{}, fmt.Errorf("failed to update promotion: %w", err)
+	}
+	if len(updated) == 0 {
+		return dto.Promotion{}, errors.NotFound("promotion not found")
+	}
+	return updated[0], nil
+}
+
+func (r *promotionRepository) SoftDelete(ctx context.Context, id string) error {
+	if err := r.store.Update(ctx, "promotions", Where(Eq("id", id)), map[string]interface{}{"deleted_at": time.Now()}, nil); err != nil {
+		return fmt.Errorf("failed to delete promotion: %w", err)
+	}
+	return nil
+}
+
+func (r *promotionRepository) SetUsedCount(ctx context.Context, id string, current, next int) (bool, error) {
+	var updated []struct {
+		Id string `json:"id"`
+	}
+	if err := r.store.Update(ctx, "promotions",
+		Where(Eq("id", id), Eq("used_count", strconv.Itoa(current))),
+		map[string]interface{}{"used_count": next},
+		&updated); err != nil {
+		return false, fmt.Errorf("failed to update usage count of promotion %s: %w", id, err)
+	}
+	return len(updated) > 0, nil
+}
+
+func (r *promotionRepository) CountUsages(ctx context.Context, promotionId, userId, excludeOrderId string) (int, error) {
+	q := Where(Eq("promotion_id", promotionId), Eq("user_id", userId), IsNull("deleted_at"))
+	if excludeOrderId != "" {
+		q = q.And(Neq("order_id", excludeOrderId))
+	}
+	used, err := r.store.Count(ctx, "promotion_usages", q)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count promotion usage: %w", err)
+	}
+	return used, nil
+}
+
+func (r *promotionRepository) Usages(ctx context.Context, orderId, promotionId string) ([]dto.PromotionUsage, error) {
+	q := Where(Eq("order_id", orderId), IsNull("deleted_at"))
+	if promotionId != "" {
+		q = q.And(Eq("promotion_id", promotionId))
+	}
+	var usages []dto.PromotionUsage
+	if err := r.store.Find(ctx, "promotion_usages", promotionUsageColumns, q, &usages); err != nil {
+		return nil, fmt.Errorf("failed to fetch promotion usage: %w", err)
+	}
+	return usages, nil
+}
+
+func (r *promotionRepository) AddUsage(ctx context.Context, usage dto.PromotionUsage) (dto.PromotionUsage, error) {
+	row := map[string]interface{}{
+		"promotion_id": usage.PromotionId,
+		"order_id":     usage.OrderId,
+		"user_id":      usage.UserId,
+		"discount":     usage.Discount,
+	}
+	var created []dto.PromotionUsage
+	if err := r.store.Insert(ctx, "promotion_usages", row, &created); err != nil {
+		return dto.PromotionUsage{}, fmt.Errorf("failed to record promotion usage: %w", err)
+	}
+	if len(created) == 0 {
+		return dto.PromotionUsage{}, fmt.Errorf("failed to record promotion usage: no row returned")
+	}
+	return created[0], nil
+}
+
+func (r *promotionRepository) SetUsageDiscount(ctx context.Context, id string, discount float64) error {
+	if err := r.store.Update(ctx, "promotion_usages", Where(Eq("id", id)), map[string]interface{}{"discount": discount}, nil); err != nil {
+		return fmt.Errorf("failed to update promotion usage: %w", err)
+	}
+	return nil
+}
+
+func (r *promotionRepository) SoftDeleteUsage(ctx context.Context, id string) error {
+	if err := r.store.Update(ctx, "promotion_usages", Where(Eq("id", id)), map[string]interface{}{"deleted_at": time.Now()}, nil); err != nil {
+		return fmt.Errorf("failed to release promotion usage: %w", err)
+	}
+	return nil
+}
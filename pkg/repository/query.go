@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/common/query"
+	"slices"
+	"strings"
+)
+
+// Operators understood by every Store; they carry the PostgREST names.
+const (
+	OpEq    = "eq"
+	OpNeq   = "neq"
+	OpGt    = "gt"
+	OpGte   = "gte"
+	OpLt    = "lt"
+	OpLte   = "lte"
+	OpLike  = "like"
+	OpIlike = "ilike"
+	OpIn    = "in"
+	OpIs    = "is"
+	OpOr    = "or"
+)
+
+// Filter is one condition on a column. Values are given as strings the way they
+// would appear in a PostgREST query, e.g. "true" or "12.5".
+type Filter struct {
+	Column   string
+	Operator string
+	Value    string
+	Values   []string
+	Negate   bool
+	// Groups are the alternatives of an OpOr filter, each one a list of filters that
+	// must all match.
+	Groups [][]Filter
+}
+
+func Eq(column, value string) Filter   { return Filter{Column: column, Operator: OpEq, Value: value} }
+func Neq(column, value string) Filter  { return Filter{Column: column, Operator: OpNeq, Value: value} }
+func Gt(column, value string) Filter   { return Filter{Column: column, Operator: OpGt, Value: value} }
+func Gte(column, value string) Filter  { return Filter{Column: column, Operator: OpGte, Value: value} }
+func Lt(column, value string) Filter   { return Filter{Column: column, Operator: OpLt, Value: value} }
+func Lte(column, value string) Filter  { return Filter{Column: column, Operator: OpLte, Value: value} }
+func Like(column, value string) Filter { return Filter{Column: column, Operator: OpLike, Value: value} }
+
+// Ilike matches case-insensitively; '*' and '%' both stand for any run of characters.
+func Ilike(column, value string) Filter {
+	return Filter{Column: column, Operator: OpIlike, Value: value}
+}
+
+func In(column string, values []string) Filter {
+	return Filter{Column: column, Operator: OpIn, Values: values}
+}
+
+func IsNull(column string) Filter  { return Filter{Column: column, Operator: OpIs, Value: "null"} }
+func NotNull(column string) Filter { return Not(IsNull(column)) }
+
+// Or matches a row when all filters of at least one group match; it has no column
+// of its own and cannot be negated.
+func Or(groups ...[]Filter) Filter {
+	return Filter{Operator: OpOr, Groups: groups}
+}
+
+func Not(f Filter) Filter {
+	f.Negate = !f.Negate
+	return f
+}
+
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// Join requires a related row of Table matching Filters. The relation is the
+// foreign key between the two tables, in either direction.
+type Join struct {
+	Table   string
+	Filters []Filter
+}
+
+// Query selects rows of one table. The zero value matches every row.
+type Query struct {
+	Filters []Filter
+	Joins   []Join
+	Orders  []Order
+	Limit   int
+	Offset  int
+}
+
+func Where(filters ...Filter) Query {
+	return Query{Filters: filters}
+}
+
+// And returns a copy of q with more filters; q itself is left alone so a base
+// query can be shared between a count and a page.
+func (q Query) And(filters ...Filter) Query {
+	q.Filters = append(append([]Filter(nil), q.Filters...), filters...)
+	return q
+}
+
+// Has returns a copy of q keeping only the rows with at least one related row of
+// table that matches filters; the same table may be joined more than once.
+func (q Query) Has(table string, filters ...Filter) Query {
+	q.Joins = append(append([]Join(nil), q.Joins...), Join{Table: table, Filters: filters})
+	return q
+}
+
+func (q Query) OrderBy(column string, desc bool) Query {
+	q.Orders = append(append([]Order(nil), q.Orders...), Order{Column: column, Desc: desc})
+	return q
+}
+
+// Sort orders q by the sort parameter of a list request. Only the sortable fields may
+// be used, anything else is a bad request rather than being ignored; without a sort
+// parameter the fallback order applies. The id is always added last so rows that tie
+// keep the same order from one page to the next.
+func (q Query) Sort(p query.Pagination, sortable []string, fallback ...Order) (Query, error) {
+	orders := fallback
+	if fields := p.SortFields(); len(fields) > 0 {
+		orders = make([]Order, 0, len(fields))
+		for _, f := range fields {
+			if !slices.Contains(sortable, f.Field) {
+				return q, errors.BadRequest("cannot sort by %q, sortable fields are %s", f.Field, strings.Join(sortable, ", "))
+			}
+			orders = append(orders, Order{Column: f.Field, Desc: f.Desc})
+		}
+	}
+	if !slices.ContainsFunc(orders, func(o Order) bool { return o.Column == "id" }) {
+		orders = append(orders, Order{Column: "id"})
+	}
+	q.Orders = append(append([]Order(nil), q.Orders...), orders...)
+	return q, nil
+}
+
+// Page limits the result to limit rows starting at offset; a limit of 0 means all rows.
+func (q Query) Page(limit, offset int) Query {
+	q.Limit, q.Offset = limit, offset
+	return q
+}
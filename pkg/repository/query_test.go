@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/common/query"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestQuerySort(t *testing.T) {
+	sortable := []string{"name", "price"}
+
+	q, err := Query{}.Sort(query.Pagination{Sort: "-price,name"}, sortable)
+	assert.NoError(t, err)
+	assert.Equal(t, []Order{{Column: "price", Desc: true}, {Column: "name"}, {Column: "id"}}, q.Orders)
+
+	q, err = Query{}.Sort(query.Pagination{}, sortable, Order{Column: "created_at", Desc: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []Order{{Column: "created_at", Desc: true}, {Column: "id"}}, q.Orders)
+
+	_, err = Query{}.Sort(query.Pagination{Sort: "-password"}, sortable)
+	var badRequest *errors.BadRequestError
+	assert.True(t, errors.As(err, &badRequest))
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"time"
+)
+
+type SessionRepository interface {
+	// Get returns a session, revoked or not.
+	Get(ctx context.Context, id string) (dto.Session, error)
+	Create(ctx context.Context, session dto.Session) error
+	// ListActive returns the sessions of a user that were not revoked, the most
+	// recently used first.
+	ListActive(ctx context.Context, userId string) ([]dto.Session, error)
+	Touch(ctx context.Context, id string, at time.Time) error
+	Revoke(ctx context.Context, id string, at time.Time) error
+	// RevokeUser revokes every session of a user and every token issued to them
+	// before at, including those of sessions not seen yet; it returns the ids of the
+	// sessions it revoked.
+	RevokeUser(ctx context.Context, userId string, at time.Time) ([]string, error)
+	// RevokedBefore returns when RevokeUser was last called for a user.
+	RevokedBefore(ctx context.Context, userId string) (time.Time, error)
+}
+
+type sessionRepository struct {
+	store Store
+}
+
+func NewSessionRepository(di do.Injector) (SessionRepository, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize SessionRepository: %w", err)
+	}
+	return &sessionRepository{store: store}, nil
+}
+
+const sessionColumns = "id,user_id,device,user_agent,ip,created_at,last_seen_at,revoked_at"
+
+func (r *sessionRepository) Get(ctx context.Context, id string) (dto.Session, error) {
+	var sessions []dto.Session
+	if err := r.store.Find(ctx, "user_sessions", sessionColumns, Where(Eq("id", id)), &sessions); err != nil {
+		return dto.Session{}, fmt.Errorf("failed to fetch session: %w", err)
+	}
+	if len(sessions) == 0 {
+		return dto.Session{}, errors.NotFound("session not found")
+	}
+	return sessions[0], nil
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session dto.Session) error {
+	row := map[string]interface{}{
+		"id":           session.Id,
+		"user_id":      session.UserId,
+		"device":       session.Device,
+		"user_agent":   session.UserAgent,
+		"ip":           session.Ip,
+		"last_seen_at": session.LastSeenAt,
+		"revoked_at":   session.RevokedAt,
+	}
+	if err := r.store.Insert(ctx, "user_sessions", row, nil); err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) ListActive(ctx context.Context, userId string) ([]dto.Session, error) {
+	q := Where(Eq("user_id", userId), IsNull("revoked_at")).OrderBy("last_seen_at", true)
+	var sessions []dto.Session
+	if err := r.store.Find(ctx, "user_sessions", sessionColumns, q, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to fetch sessions of user %s: %w", userId, err)
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	if err := r.store.Update(ctx, "user_sessions", Where(Eq("id", id)), map[string]interface{}{"last_seen_at": at}, nil); err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	if err := r.store.Update(ctx, "user_sessions", Where(Eq("id", id), IsNull("revoked_at")), map[string]interface{}{"revoked_at": at}, nil); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeUser(ctx context.Context, userId string, at time.Time) ([]string, error) {
+	if err := r.store.Update(ctx, "users", Where(Eq("id", userId)), map[string]interface{}{"sessions_revoked_at": at}, nil); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	var revoked []struct {
+		Id string `json:"id"`
+	}
+	if err := r.store.Update(ctx, "user_sessions", Where(Eq("user_id", userId), IsNull("revoked_at")), map[string]interface{}{"revoked_at": at}, &revoked); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	ids := make([]string, 0, len(revoked))
+	for _, s := range revoked {
+		ids = append(ids, s.Id)
+	}
+	return ids, nil
+}
+
+func (r *sessionRepository) RevokedBefore(ctx context.Context, userId string) (time.Time, error) {
+	var users []struct {
+		SessionsRevokedAt *time.Time `json:"sessions_revoked_at"`
+	}
+	if err := r.store.Find(ctx, "users", "sessions_revoked_at", Where(Eq("id", userId)), &users); err != nil {
+		return time.Time{}, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if len(users) == 0 || users[0].SessionsRevokedAt == nil {
+		return time.Time{}, nil
+	}
+	return *users[0].SessionsRevokedAt, nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/util"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"strconv"
+)
+
+// SlugRepository hands out the slugs of the tables addressed by slug (products,
+// categories and posts) and remembers the slugs they had before, so old links keep
+// working. A slug is unique among the live rows of a table and its history.
+type SlugRepository interface {
+	// Unique returns a free slug for a row of table: requested when given, which must
+	// then be free, otherwise one derived from name and numbered until it is free.
+	// exceptId is the row the slug is for, whose own slugs do not count as taken.
+	Unique(ctx context.Context, table, requested, name, exceptId string) (string, error)
+	// Retire keeps slug pointing at row id of table after the row moved to another.
+	Retire(ctx context.Context, table, id, slug string) error
+	// Resolve finds the live row of table at slug, now or in the past, and returns
+	// its id and current slug.
+	Resolve(ctx context.Context, table, slug string) (id, current string, err error)
+}
+
+type slugRepository struct {
+	store Store
+}
+
+func NewSlugRepository(di do.Injector) (SlugRepository, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize SlugRepository: %w", err)
+	}
+	return &slugRepository{store: store}, nil
+}
+
+// slugFallback is the base slug of a row whose name has no letters or digits.
+var slugFallback = map[string]string{"products": "product", "categories": "category", "posts": "post"}
+
+func (r *slugRepository) taken(ctx context.Context, table, slug, exceptId string) (bool, error) {
+	live := Where(Eq("slug", slug), IsNull("deleted_at"))
+	past := Where(Eq("entity", table), Eq("slug", slug))
+	if exceptId != "" {
+		live = live.And(Neq("id", exceptId))
+		past = past.And(Neq("entity_id", exceptId))
+	}
+	n, err := r.store.Count(ctx, table, live)
+	if err != nil {
+		return false, fmt.Errorf("failed to check slug: %w", err)
+	}
+	if n > 0 {
+		return true, nil
+	}
+	if n, err = r.store.Count(ctx, "slug_history", past); err != nil {
+		return false, fmt.Errorf("failed to check slug history: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *slugRepository) Unique(ctx context.Context, table, requested, name, exceptId string) (string, error) {
+	if requested != "" {
+		slug := util.Slugify(requested)
+		if slug == "" {
+			return "", errors.BadRequest("invalid slug %q", requested)
+		}
+		taken, err := r.taken(ctx, table, slug, exceptId)
+		if err != nil {
+			return "", err
+		}
+		if taken {
+			return "", errors.Conflict("slug %s is already in use", slug).WithCode("slug_taken")
+		}
+		return slug, nil
+	}
+
+	base := util.Slugify(name)
+	if base == "" {
+		base = slugFallback[table]
+	}
+	slug := base
+	for i := 2; ; i++ {
+		taken, err := r.taken(ctx, table, slug, exceptId)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+		slug = base + "-" + strconv.Itoa(i)
+	}
+}
+
+func (r *slugRepository) Retire(ctx context.Context, table, id, slug string) error {
+	// a row may get an old slug of its own back and give it up again
+	if err := r.store.Delete(ctx, "slug_history", Where(Eq("entity", table), Eq("slug", slug))); err != nil {
+		return fmt.Errorf("failed to retire slug: %w", err)
+	}
+	row := map[string]interface{}{"entity": table, "entity_id": id, "slug": slug}
+	if err := r.store.Insert(ctx, "slug_history", row, nil); err != nil {
+		return fmt.Errorf("failed to retire slug: %w", err)
+	}
+	return nil
+}
+
+func (r *slugRepository) Resolve(ctx context.Context, table, slug string) (string, string, error) {
+	type row struct {
+		Id   string `json:"id"`
+		Slug string `json:"slug"`
+	}
+	var rows []row
+	if err := r.store.Find(ctx, table, "id,slug", Where(Eq("slug", slug), IsNull("deleted_at")), &rows); err != nil {
+		return "", "", fmt.Errorf("failed to resolve slug: %w", err)
+	}
+	if len(rows) == 0 {
+		var past []struct {
+			EntityId string `json:"entity_id"`
+		}
+		if err := r.store.Find(ctx, "slug_history", "entity_id", Where(Eq("entity", table), Eq("slug", slug)), &past); err != nil {
+			return "", "", fmt.Errorf("failed to resolve slug: %w", err)
+		}
+		if len(past) > 0 {
+			if err := r.store.Find(ctx, table, "id,slug", Where(Eq("id", past[0].EntityId), IsNull("deleted_at")), &rows); err != nil {
+				return "", "", fmt.Errorf("failed to resolve slug: %w", err)
+			}
+		}
+	}
+	if len(rows) == 0 {
+		return "", "", errors.NotFound("%s not found", slugFallback[table])
+	}
+	return rows[0].Id, rows[0].Slug, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSlugs(t *testing.T) {
+	di := do.New()
+	store := InjectMemory(di)
+	store.Seed("products",
+		map[string]interface{}{"id": "p1", "name": "Cây xanh", "slug": "cay-xanh"},
+		map[string]interface{}{"id": "p2", "name": "Cây xanh", "slug": "cay-xanh-2"},
+		map[string]interface{}{"id": "p3", "name": "Chậu", "slug": "chau", "deleted_at": "2025-03-01T00:00:00Z"},
+	)
+	slugs := do.MustInvoke[SlugRepository](di)
+	ctx := context.Background()
+
+	slug, err := slugs.Unique(ctx, "products", "", "Cây Xanh", "")
+	require.NoError(t, err)
+	assert.Equal(t, "cay-xanh-3", slug)
+	slug, _ = slugs.Unique(ctx, "products", "", "Cây xanh", "p1")
+	assert.Equal(t, "cay-xanh", slug, "a row's own slug is free for it")
+	slug, _ = slugs.Unique(ctx, "products", "", "Chậu", "")
+	assert.Equal(t, "chau", slug, "deleted rows give their slug up")
+	slug, _ = slugs.Unique(ctx, "products", "", "!!!", "")
+	assert.Equal(t, "product", slug)
+	_, err = slugs.Unique(ctx, "products", "Cay Xanh 2", "", "")
+	assert.Error(t, err)
+
+	// p1 is renamed; its old slug redirects and is not handed out again
+	require.NoError(t, slugs.Retire(ctx, "products", "p1", "cay-xanh"))
+	require.NoError(t, store.Update(ctx, "products", Where(Eq("id", "p1")), map[string]interface{}{"slug": "cay-canh"}, nil))
+	id, current, err := slugs.Resolve(ctx, "products", "cay-xanh")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"p1", "cay-canh"}, []string{id, current})
+	slug, _ = slugs.Unique(ctx, "products", "", "Cây xanh", "")
+	assert.Equal(t, "cay-xanh-3", slug)
+	slug, _ = slugs.Unique(ctx, "products", "cay-xanh", "", "p1")
+	assert.Equal(t, "cay-xanh", slug, "a row can take an old slug of its own back")
+
+	_, _, err = slugs.Resolve(ctx, "products", "chau")
+	assert.Error(t, err)
+	_, _, err = slugs.Resolve(ctx, "categories", "cay-xanh")
+	assert.Error(t, err, "history is kept per table")
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoFunction is returned by Store.Call for a function the database does not have,
+// e.g. before its migration ran; callers are expected to fall back to reading rows.
+var ErrNoFunction = errors.New("database function is not installed")
+
+// Store is the table level access the repositories are written against. out is
+// always a pointer to a slice and receives the affected rows as JSON would decode
+// them; it may be nil when the rows are not needed. Only Find and Count take a
+// query with joins.
+type Store interface {
+	Find(ctx context.Context, table, columns string, q Query, out interface{}) error
+	Count(ctx context.Context, table string, q Query) (int, error)
+	// Insert takes a single row or a slice of rows.
+	Insert(ctx context.Context, table string, rows interface{}, out interface{}) error
+	Update(ctx context.Context, table string, q Query, patch interface{}, out interface{}) error
+	Delete(ctx context.Context, table string, q Query) error
+	// Call runs a database function with named params and decodes what it returns
+	// into out.
+	Call(ctx context.Context, function string, params map[string]interface{}, out interface{}) error
+}
+
+func errJoinedWrite(table string) error {
+	return fmt.Errorf("cannot write to %s through a query with joins", table)
+}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"SangXanh/pkg/log"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nedpals/supabase-go"
+	postgrest "github.com/nedpals/supabase-go/postgrest/pkg"
+	"github.com/samber/do/v2"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// supabaseStore runs queries through PostgREST.
+type supabaseStore struct {
+	db *supabase.Client
+	// missing holds the functions PostgREST did not find, so they are not asked for
+	// on every call
+	missing sync.Map
+}
+
+func NewSupabaseStore(di do.Injector) (Store, error) {
+	db, err := do.Invoke[*supabase.Client](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Store: %w", err)
+	}
+	return &supabaseStore{db: db}, nil
+}
+
+// applyFilters adds the filters to the query string of b; every builder method
+// only appends a parameter, so it does not matter which builder they go through.
+func applyFilters(b *postgrest.FilterRequestBuilder, filters []Filter) {
+	applyFiltersTo(b, "", filters)
+}
+
+// applyFiltersTo adds filters on the embedded resource named by alias, or on the
+// table itself when alias is empty.
+func applyFiltersTo(b *postgrest.FilterRequestBuilder, alias string, filters []Filter) {
+	key := func(column string) string {
+		if alias == "" {
+			return column
+		}
+		return alias + "." + column
+	}
+	for _, f := range filters {
+		if f.Operator == OpOr {
+			// the client has no builder for or=(...), but Filter only joins operator and
+			// criteria with a dot, so the list goes in split at its last one
+			list := orList(f.Groups)
+			dot := strings.LastIndex(list, ".")
+			b.Filter(key(OpOr), list[:dot], list[dot+1:])
+			continue
+		}
+		if f.Negate {
+			b.Not()
+		}
+		switch f.Operator {
+		case OpIn:
+			b.In(key(f.Column), f.Values)
+		case OpIs:
+			b.Filter(key(f.Column), OpIs, f.Value)
+		case OpLike, OpIlike:
+			// '%' would not survive the unescaping of the query string, '*' is the same to PostgREST
+			b.Filter(key(f.Column), f.Operator, postgrest.SanitizePatternParam(f.Value))
+		default:
+			b.Filter(key(f.Column), f.Operator, postgrest.SanitizeParam(f.Value))
+		}
+	}
+}
+
+// joinAlias names the embedding of the i-th join, so a table can be joined twice.
+func joinAlias(i int) string {
+	return "j" + strconv.Itoa(i)
+}
+
+// joinColumns adds an inner embedding without columns per join to columns: it
+// drops the rows without a matching related row but adds nothing to the result.
+func joinColumns(columns string, joins []Join) string {
+	for i, j := range joins {
+		columns += "," + joinAlias(i) + ":" + j.Table + "!inner()"
+	}
+	return columns
+}
+
+func applyJoins(b *postgrest.FilterRequestBuilder, joins []Join) {
+	for i, j := range joins {
+		applyFiltersTo(b, joinAlias(i), j.Filters)
+	}
+}
+
+// orList renders the groups of an OpOr filter as PostgREST's (a,and(b,c)) syntax.
+func orList(groups [][]Filter) string {
+	alternatives := make([]string, 0, len(groups))
+	for _, group := range groups {
+		conditions := make([]string, 0, len(group))
+		for _, f := range group {
+			conditions = append(conditions, condition(f))
+		}
+		if len(conditions) == 1 {
+			alternatives = append(alternatives, conditions[0])
+			continue
+		}
+		alternatives = append(alternatives, "and("+strings.Join(conditions, ",")+")")
+	}
+	return "(" + strings.Join(alternatives, ",") + ")"
+}
+
+// condition renders one filter the way it is written inside a logical operator.
+func condition(f Filter) string {
+	operator := f.Operator
+	if f.Negate {
+		operator = "not." + operator
+	}
+	var criteria string
+	switch f.Operator {
+	case OpIn:
+		values := make([]string, 0, len(f.Values))
+		for _, v := range f.Values {
+			values = append(values, postgrest.SanitizeParam(v))
+		}
+		criteria = "(" + strings.Join(values, ",") + ")"
+	case OpIs:
+		criteria = f.Value
+	case OpLike, OpIlike:
+		criteria = postgrest.SanitizePatternParam(f.Value)
+	default:
+		criteria = postgrest.SanitizeParam(f.Value)
+	}
+	return f.Column + "." + operator + "." + criteria
+}
+
+func applyOrders(b *postgrest.SelectRequestBuilder, orders []Order) {
+	if len(orders) == 0 {
+		return
+	}
+	direction := func(o Order) string {
+		if o.Desc {
+			return "desc"
+		}
+		return "asc"
+	}
+	// OrderBy takes a single column, but PostgREST accepts a list in the same parameter
+	column := ""
+	for _, o := range orders[:len(orders)-1] {
+		column += o.Column + "." + direction(o) + ","
+	}
+	last := orders[len(orders)-1]
+	b.OrderBy(column+last.Column, direction(last))
+}
+
+func (s *supabaseStore) Find(ctx context.Context, table, columns string, q Query, out interface{}) error {
+	b := s.db.DB.From(table).Select(joinColumns(columns, q.Joins))
+	applyOrders(b, q.Orders)
+	if q.Limit > 0 {
+		b.LimitWithOffset(q.Limit, q.Offset)
+	}
+	applyFilters(&b.FilterRequestBuilder, q.Filters)
+	applyJoins(&b.FilterRequestBuilder, q.Joins)
+	return b.ExecuteWithContext(ctx, out)
+}
+
+func (s *supabaseStore) Count(ctx context.Context, table string, q Query) (int, error) {
+	return exactCount(ctx, s.db, table, joinColumns("id", q.Joins), func(b *postgrest.FilterRequestBuilder) {
+		applyFilters(b, q.Filters)
+		applyJoins(b, q.Joins)
+	})
+}
+
+// exactCount counts the rows of table that match the filters added by where. It sends
+// a single HEAD request with Prefer: count=exact and reads the total from the
+// Content-Range header, so no rows are transferred and no page limit applies.
+func exactCount(ctx context.Context, db *supabase.Client, table, columns string, where func(b *postgrest.FilterRequestBuilder)) (int, error) {
+	b := db.DB.From(table).Select(columns).Count()
+	if where != nil {
+		where(&b.FilterRequestBuilder)
+	}
+	var total int
+	if err := b.ExecuteWithContext(ctx, &total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (s *supabaseStore) Insert(ctx context.Context, table string, rows interface{}, out interface{}) error {
+	return s.db.DB.From(table).Insert(rows).ExecuteWithContext(ctx, out)
+}
+
+func (s *supabaseStore) Update(ctx context.Context, table string, q Query, patch interface{}, out interface{}) error {
+	if len(q.Joins) > 0 {
+		return errJoinedWrite(table)
+	}
+	b := s.db.DB.From(table).Update(patch)
+	applyFilters(b, q.Filters)
+	return b.ExecuteWithContext(ctx, out)
+}
+
+func (s *supabaseStore) Delete(ctx context.Context, table string, q Query) error {
+	if len(q.Joins) > 0 {
+		return errJoinedWrite(table)
+	}
+	b := s.db.DB.From(table).Delete()
+	applyFilters(b, q.Filters)
+	return b.ExecuteWithContext(ctx, nil)
+}
+
+func (s *supabaseStore) Call(ctx context.Context, function string, params map[string]interface{}, out interface{}) error {
+	if _, ok := s.missing.Load(function); ok {
+		return fmt.Errorf("%w: %s", ErrNoFunction, function)
+	}
+	err := s.db.DB.Rpc(function, params).ExecuteWithContext(ctx, out)
+	var reqErr *postgrest.RequestError
+	if errors.As(err, &reqErr) && reqErr.Code == "PGRST202" {
+		log.Errorw("database function is not installed", "function", function, "error", err)
+		s.missing.Store(function, true)
+		return fmt.Errorf("%w: %s", ErrNoFunction, function)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", function, err)
+	}
+	return nil
+}
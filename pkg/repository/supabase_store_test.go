@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/nedpals/supabase-go"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSupabaseStoreCount(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Header().Set("Content-Range", "0-19/57")
+		w.WriteHeader(http.StatusPartialContent)
+	}))
+	defer server.Close()
+
+	store := &supabaseStore{db: supabase.CreateClient(server.URL, "key")}
+	total, err := store.Count(context.Background(), "orders", Where(IsNull("deleted_at"), Eq("user_id", "u1")))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 57, total)
+	assert.Equal(t, http.MethodHead, got.Method)
+	assert.Equal(t, "count=exact", got.Header.Get("Prefer"))
+	assert.Empty(t, got.Header.Get("Range"), "a count must not be limited to a page")
+	assert.Equal(t, "eq.u1", got.URL.Query().Get("user_id"))
+}
+
+func TestSupabaseStoreOr(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	store := &supabaseStore{db: supabase.CreateClient(server.URL, "key")}
+	keyset := after([]Order{{Column: "price"}, {Column: "id"}}, []*string{ptr("9.5"), ptr("p1")})
+	err := store.Find(context.Background(), "products", "id", Where(IsNull("deleted_at"), keyset), &[]row{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `(price.gt."9.5",price.is.null,and(price.eq."9.5",id.gt.p1))`, got.URL.Query().Get("or"))
+	assert.Equal(t, "is.null", got.URL.Query().Get("deleted_at"))
+}
+
+func TestSupabaseStoreJoin(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	store := &supabaseStore{db: supabase.CreateClient(server.URL, "key")}
+	q := Where(IsNull("deleted_at")).
+		Has("product_options", Gt("stock", "0")).
+		Has("product_variants", Eq("name", "Size"), Or([]Filter{Eq("value", "S")}, []Filter{Eq("value", "M")}))
+	err := store.Find(context.Background(), "products", "id", q, &[]row{})
+
+	assert.NoError(t, err)
+	params := got.URL.Query()
+	assert.Equal(t, "id,j0:product_options!inner(),j1:product_variants!inner()", params.Get("select"))
+	assert.Equal(t, "gt.0", params.Get("j0.stock"))
+	assert.Equal(t, "eq.Size", params.Get("j1.name"))
+	assert.Equal(t, "(value.eq.S,value.eq.M)", params.Get("j1.or"))
+	assert.Equal(t, "is.null", params.Get("deleted_at"))
+}
+
+func TestSupabaseStoreCall(t *testing.T) {
+	calls := map[string]int{}
+	var params map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls[r.URL.Path]++
+		if r.URL.Path == "/rest/v1/rpc/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"PGRST202","message":"Could not find the function"}`))
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&params)
+		w.Write([]byte(`[{"n":3}]`))
+	}))
+	defer server.Close()
+
+	store := &supabaseStore{db: supabase.CreateClient(server.URL, "key")}
+	var rows []struct {
+		N int `json:"n"`
+	}
+	assert.NoError(t, store.Call(context.Background(), "found", map[string]interface{}{"limit": 2}, &rows))
+	assert.Equal(t, 3, rows[0].N)
+	assert.Equal(t, map[string]interface{}{"limit": 2.0}, params)
+
+	for i := 0; i < 2; i++ {
+		err := store.Call(context.Background(), "missing", nil, &rows)
+		assert.ErrorIs(t, err, ErrNoFunction)
+	}
+	assert.Equal(t, 1, calls["/rest/v1/rpc/missing"], "a missing function is not asked for again")
+}
+
+func ptr(s string) *string { return &s }
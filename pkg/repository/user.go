@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+)
+
+type UserRepository interface {
+	// List pages through the live users without their password hashes.
+	List(ctx context.Context, filter dto.ListUser) ([]dto.User, error)
+	Count(ctx context.Context, filter dto.ListUser) (int, error)
+	// Get returns a live user including the password hash.
+	Get(ctx context.Context, id string) (dto.User, error)
+	// Info returns the public profile of a live user.
+	Info(ctx context.Context, id string) (dto.UserInfo, error)
+	GetByUsername(ctx context.Context, username string) (dto.User, error)
+	// UsernameTaken also counts deleted users, their usernames are not given out again.
+	UsernameTaken(ctx context.Context, username string) (bool, error)
+	// Update returns the changed user without the password hash.
+	Update(ctx context.Context, id string, patch map[string]interface{}) (dto.User, error)
+}
+
+type userRepository struct {
+	store Store
+}
+
+func NewUserRepository(di do.Injector) (UserRepository, error) {
+	store, err := do.Invoke[Store](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize UserRepository: %w", err)
+	}
+	return &userRepository{store: store}, nil
+}
+
+const (
+	userListColumns = "id,username,email,role,avatar,phone,basic_address,metadata,created_at,updated_at"
+	userInfoColumns = "id,username,role,address,basic_address,full_name,avatar,phone,email"
+)
+
+var userSortable = []string{"username", "role", "created_at", "updated_at"}
+
+func userQuery(filter dto.ListUser) Query {
+	q := Where(IsNull("deleted_at"))
+	if filter.Name != "" {
+		q = q.And(Ilike("username", "*"+filter.Name+"*"))
+	}
+	if filter.Role != "" {
+		q = q.And(Eq("role", string(filter.Role)))
+	}
+	if filter.Status != "" {
+		q = q.And(Eq("status", string(filter.Status)))
+	}
+	return q
+}
+
+func (r *userRepository) List(ctx context.Context, filter dto.ListUser) ([]dto.User, error) {
+	var users []dto.User
+	q, err := userQuery(filter).Sort(filter.Pagination, userSortable, Order{Column: "created_at", Desc: true})
+	if err != nil {
+		return nil, err
+	}
+	q = q.Page(int(filter.Limit), filter.Offset())
+	if err := r.store.Find(ctx, "users", userListColumns, q, &users); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
+}
+
+func (r *userRepository) Count(ctx context.Context, filter dto.ListUser) (int, error) {
+	total, err := r.store.Count(ctx, "users", userQuery(filter))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return total, nil
+}
+
+func (r *userRepository) Get(ctx context.Context, id string) (dto.User, error) {
+	var users []dto.User
+	if err := r.store.Find(ctx, "users", "*", Where(Eq("id", id), IsNull("deleted_at")), &users); err != nil {
+		return dto.User{}, fmt.Errorf("failed to find user: %w", err)
+	}
+	if len(users) == 0 {
+		return dto.User{}, errors.NotFound("user not found")
+	}
+	return users[0], nil
+}
+
+func (r *userRepository) Info(ctx context.Context, id string) (dto.UserInfo, error) {
+	var users []dto.UserInfo
+	if err := r.store.Find(ctx, "users", userInfoColumns, Where(Eq("id", id), IsNull("deleted_at")), &users); err != nil {
+		return dto.UserInfo{}, fmt.Errorf("failed to find user: %w", err)
+	}
+	if len(users) == 0 {
+		return dto.UserInfo{}, errors.NotFound("user not found")
+	}
+	return users[0], nil
+}
+
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (dto.User, error) {
+	var users []dto.User
+	if err := r.store.Find(ctx, "users", "*", Where(Eq("username", username), IsNull("deleted_at")), &users); err != nil {
+		return dto.User{}, fmt.Errorf("failed to find user: %w", err)
+	}
+	if len(users) == 0 {
+		return dto.User{}, errors.NotFound("user not found")
+	}
+	return users[0], nil
+}
+
+func (r *userRepository) UsernameTaken(ctx context.Context, username string) (bool, error) {
+	n, err := r.store.Count(ctx, "users", Where(Eq("username", username)))
+	if err != nil {
+		return false, fmt.Errorf("failed to check existing user: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *userRepository) Update(ctx context.Context, id string, patch map[string]interface{}) (dto.User, error) {
+	var updated []dto.User
+	if err := r.store.Update(ctx, "users", Where(Eq("id", id), IsNull("deleted_at")), patch, &updated); err != nil {
+		return dto.User{}, fmt.Errorf("failed to update user: %w", err)
+	}
+	if len(updated) == 0 {
+		return dto.User{}, errors.NotFound("user not found")
+	}
+	updated[0].Password = ""
+	return updated[0], nil
+}
@@ -0,0 +1,88 @@
+package search
+
+import (
+	"html"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+	ellipsis  = "…"
+)
+
+// Highlight wraps the words of text that match a query term in <mark> tags; the rest
+// of the text is HTML-escaped. With a positive width only a snippet of about that many
+// runes around the first match is kept. It returns "" when nothing matches.
+func Highlight(terms []string, text string, width int) string {
+	runes := []rune(norm.NFC.String(text))
+	tokens := tokenize(text)
+	var marks []token
+	for _, t := range tokens {
+		for _, term := range terms {
+			if Match(term, t.term) > 0 {
+				marks = append(marks, t)
+				break
+			}
+		}
+	}
+	if len(marks) == 0 {
+		return ""
+	}
+
+	from, to := 0, len(runes)
+	if width > 0 && len(runes) > width {
+		from = max(0, marks[0].start-width/4)
+		to = min(len(runes), from+width)
+		from = max(0, min(from, to-width))
+		// start and end on whole words
+		for _, t := range tokens {
+			if t.start >= from {
+				from = t.start
+				break
+			}
+		}
+		end := from
+		for _, t := range tokens {
+			if t.start >= from && t.end <= to {
+				end = t.end
+			}
+		}
+		to = max(end, marks[0].end)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString(ellipsis)
+	}
+	at := from
+	for _, m := range marks {
+		if m.start < from || m.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[at:m.start])))
+		b.WriteString(markOpen)
+		b.WriteString(html.EscapeString(string(runes[m.start:m.end])))
+		b.WriteString(markClose)
+		at = m.end
+	}
+	b.WriteString(html.EscapeString(string(runes[at:to])))
+	if to < len(runes) {
+		b.WriteString(ellipsis)
+	}
+	return b.String()
+}
+
+// HighlightFields highlights every field of doc that matches a query term, each with
+// the snippet width of its field.
+func HighlightFields(fields []Field, terms []string, doc map[string]string) map[string]string {
+	highlights := make(map[string]string)
+	for _, f := range fields {
+		if h := Highlight(terms, doc[f.Name], f.Snippet); h != "" {
+			highlights[f.Name] = h
+		}
+	}
+	return highlights
+}
@@ -0,0 +1,155 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Field is a searchable field of the documents in an Index; a match in a field with
+// a higher weight ranks higher.
+type Field struct {
+	Name   string
+	Weight float64
+	// Snippet cuts the highlight of a long field down to about this many runes; 0
+	// highlights the whole text.
+	Snippet int
+}
+
+type Hit struct {
+	Id    string
+	Score float64
+	// Highlights holds the highlighted text of every field that matched.
+	Highlights map[string]string
+}
+
+// Index is an in-process full-text index. Words are folded, so matching ignores case
+// and diacritics, and a query word also finds the words it is a prefix of or a typo
+// away from. It is safe for concurrent use.
+type Index struct {
+	mu     sync.RWMutex
+	fields []Field
+	docs   map[string]map[string]string
+	// postings maps a word to the documents containing it and their weighted frequency
+	postings map[string]map[string]float64
+}
+
+func NewIndex(fields ...Field) *Index {
+	return &Index{
+		fields:   fields,
+		docs:     make(map[string]map[string]string),
+		postings: make(map[string]map[string]float64),
+	}
+}
+
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+// Put adds a document or replaces the one with the same id; fields the index does not
+// know are ignored.
+func (ix *Index) Put(id string, fields map[string]string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+
+	doc := make(map[string]string, len(ix.fields))
+	for _, f := range ix.fields {
+		text := fields[f.Name]
+		doc[f.Name] = text
+		tokens := tokenize(text)
+		if len(tokens) == 0 {
+			continue
+		}
+		// a word weighs less in a long text than in a short one
+		weight := f.Weight / math.Sqrt(float64(len(tokens)))
+		for _, t := range tokens {
+			if ix.postings[t.term] == nil {
+				ix.postings[t.term] = make(map[string]float64)
+			}
+			ix.postings[t.term][id] += weight
+		}
+	}
+	ix.docs[id] = doc
+}
+
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id string) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for _, text := range doc {
+		for _, t := range tokenize(text) {
+			delete(ix.postings[t.term], id)
+			if len(ix.postings[t.term]) == 0 {
+				delete(ix.postings, t.term)
+			}
+		}
+	}
+	delete(ix.docs, id)
+}
+
+// Search returns the documents matching every word of query, best first. A word
+// scores by how well it matched, how rare it is and the weight of the fields it was
+// found in; documents holding the whole query as a phrase score extra.
+func (ix *Index) Search(query string) []Hit {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var scores map[string]float64
+	for _, term := range terms {
+		best := make(map[string]float64)
+		for word, docs := range ix.postings {
+			quality := Match(term, word)
+			if quality == 0 {
+				continue
+			}
+			idf := math.Log(1 + float64(len(ix.docs))/float64(len(docs)))
+			for id, weight := range docs {
+				best[id] = max(best[id], quality*weight*idf)
+			}
+		}
+		if scores == nil {
+			scores = best
+			continue
+		}
+		for id, score := range scores {
+			if extra, ok := best[id]; ok {
+				scores[id] = score + extra
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	phrase := strings.Join(terms, " ")
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		for _, f := range ix.fields {
+			if len(terms) > 1 && strings.Contains(strings.Join(Terms(ix.docs[id][f.Name]), " "), phrase) {
+				score += f.Weight
+			}
+		}
+		hits = append(hits, Hit{Id: id, Score: score, Highlights: HighlightFields(ix.fields, terms, ix.docs[id])})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Id < hits[j].Id
+	})
+	return hits
+}
@@ -0,0 +1,57 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFold(t *testing.T) {
+	assert.Equal(t, "cay xanh dep", Fold("Cây Xanh Đẹp"))
+	assert.Equal(t, []string{"hat", "giong", "lua", "st25"}, Terms("  Hạt giống: LÚA (ST25)"))
+	// decomposed input folds the same as precomposed
+	assert.Equal(t, Fold("Cây"), Fold("Cầy"))
+}
+
+func TestMatch(t *testing.T) {
+	assert.Equal(t, 1.0, Match("xanh", "xanh"))
+	assert.Equal(t, 0.8, Match("xan", "xanh"))
+	assert.Equal(t, 0.6, Match("giongg", "giong"))
+	assert.Equal(t, 0.4, Match("phanbonn", "phanbo"))
+	assert.Zero(t, Match("cay", "cat"), "short words need to be spelled right")
+	assert.Zero(t, Match("giong", "xanh"))
+}
+
+func TestIndexSearch(t *testing.T) {
+	fields := []Field{{Name: "name", Weight: 4}, {Name: "description", Weight: 1, Snippet: 30}}
+	ix := NewIndex(fields...)
+	ix.Put("1", map[string]string{"name": "Cây xanh mini", "description": "Dễ chăm sóc"})
+	ix.Put("2", map[string]string{"name": "Chậu gốm", "description": "Chậu cho cây xanh để bàn, men bóng, nhiều kích thước"})
+	ix.Put("3", map[string]string{"name": "Phân bón hữu cơ", "description": "Bón cho cây"})
+
+	hits := ix.Search("cay xanh")
+	assert.Equal(t, []string{"1", "2"}, ids(hits), "a match in the name ranks first")
+	assert.Equal(t, "<mark>Cây</mark> <mark>xanh</mark> mini", hits[0].Highlights["name"])
+	assert.Equal(t, "…cho <mark>cây</mark> <mark>xanh</mark> để bàn, men…", hits[1].Highlights["description"])
+	assert.NotContains(t, hits[1].Highlights, "name")
+
+	assert.Equal(t, []string{"3"}, ids(ix.Search("phan bonn huu co")), "typo tolerance")
+	assert.Empty(t, ix.Search("cay xanh lon"), "every word has to match")
+
+	ix.Remove("1")
+	ix.Put("3", map[string]string{"name": "Phân trùn quế"})
+	assert.Equal(t, []string{"2"}, ids(ix.Search("cay")))
+	assert.Equal(t, 2, ix.Len())
+}
+
+func TestHighlightEscapes(t *testing.T) {
+	assert.Equal(t, "<mark>Lúa</mark> &lt;b&gt;", Highlight([]string{"lua"}, "Lúa <b>", 0))
+	assert.Empty(t, Highlight([]string{"ngo"}, "Lúa", 0))
+}
+
+func ids(hits []Hit) []string {
+	out := make([]string, 0, len(hits))
+	for _, h := range hits {
+		out = append(out, h.Id)
+	}
+	return out
+}
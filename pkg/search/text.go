@@ -0,0 +1,132 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Fold lowercases s and drops the Vietnamese diacritics, đ included, so "Cây Xanh"
+// and "cay xanh" compare equal. Every rune of the NFC form of s folds to exactly one
+// rune, which keeps positions in the folded text valid in the original.
+func Fold(s string) string {
+	runes := []rune(norm.NFC.String(s))
+	for i, r := range runes {
+		runes[i] = foldRune(r)
+	}
+	return string(runes)
+}
+
+func foldRune(r rune) rune {
+	if r < unicode.MaxASCII {
+		return unicode.ToLower(r)
+	}
+	if r == 'đ' || r == 'Đ' {
+		return 'd'
+	}
+	// the base letter comes first in the canonical decomposition, the marks after it
+	if base := []rune(norm.NFD.String(string(r))); len(base) > 0 {
+		r = base[0]
+	}
+	return unicode.ToLower(r)
+}
+
+// token is one word of a text; start and end are rune offsets into the NFC form.
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits text into folded words of letters and digits.
+func tokenize(text string) []token {
+	runes := []rune(norm.NFC.String(text))
+	var tokens []token
+	start := -1
+	for i := 0; i <= len(runes); i++ {
+		word := i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]))
+		switch {
+		case word && start < 0:
+			start = i
+		case !word && start >= 0:
+			tokens = append(tokens, token{term: Fold(string(runes[start:i])), start: start, end: i})
+			start = -1
+		}
+	}
+	return tokens
+}
+
+// Terms returns the folded words of a query in the order they were typed.
+func Terms(query string) []string {
+	tokens := tokenize(query)
+	terms := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		terms = append(terms, t.term)
+	}
+	return terms
+}
+
+// Match rates how well a word of a text matches a query term: 1 for the same word,
+// less for a word that only starts with the term or is a typo away from it, and 0
+// for no match. Longer terms allow more typos.
+func Match(term, word string) float64 {
+	if term == word {
+		return 1
+	}
+	tl := len([]rune(term))
+	if tl >= 2 && strings.HasPrefix(word, term) {
+		return 0.8
+	}
+	edits := maxEdits(tl)
+	if edits == 0 {
+		return 0
+	}
+	switch d := distance(term, word, edits); {
+	case d > edits:
+		return 0
+	case d == 1:
+		return 0.6
+	}
+	return 0.4
+}
+
+func maxEdits(length int) int {
+	switch {
+	case length < 4:
+		return 0
+	case length < 8:
+		return 1
+	}
+	return 2
+}
+
+// distance is the Levenshtein distance of a and b, or max+1 once it is clear the
+// distance is larger than max.
+func distance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		best := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			best = min(best, cur[j])
+		}
+		if best > max {
+			return max + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
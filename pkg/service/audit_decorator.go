@@ -0,0 +1,246 @@
+package service
+
+import (
+	"SangXanh/pkg/auth"
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/samber/do/v2"
+)
+
+// withAudit wraps the constructor of a service so that what it provides is the
+// audited decorator instead of the plain service.
+func withAudit[T any](newService func(do.Injector) (T, error), decorate func(T, AuditService) T) func(do.Injector) (T, error) {
+	return func(di do.Injector) (T, error) {
+		inner, err := newService(di)
+		if err != nil {
+			return inner, err
+		}
+		audit, err := do.Invoke[AuditService](di)
+		if err != nil {
+			return inner, fmt.Errorf("failed to initialize audit: %w", err)
+		}
+		return decorate(inner, audit), nil
+	}
+}
+
+// audited snapshots the row of an entity around a write and records the difference.
+// A create passes an empty id; the id is then taken from the response.
+func audited(ctx context.Context, audit AuditService, entity enum.AuditEntity, table, id string, action enum.AuditAction, write func() (api.Response, error)) (api.Response, error) {
+	before := audit.Snapshot(ctx, table, "id", id)
+	resp, err := write()
+	if err != nil {
+		return resp, err
+	}
+	if id == "" {
+		id = responseId(resp)
+	}
+	audit.Record(ctx, entity, id, action, before, audit.Snapshot(ctx, table, "id", id))
+	return resp, nil
+}
+
+// responseId digs the id out of the data of a success response.
+func responseId(resp api.Response) string {
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return ""
+	}
+	var body struct {
+		Data struct {
+			Id string `json:"id"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(raw, &body)
+	return body.Data.Id
+}
+
+/* ------------------------------------------------------------------
+   Products
+   ------------------------------------------------------------------*/
+
+type auditedProductService struct {
+	ProductService
+	audit AuditService
+}
+
+func auditProductService(inner ProductService, audit AuditService) ProductService {
+	return &auditedProductService{ProductService: inner, audit: audit}
+}
+
+func (s *auditedProductService) CreateProduct(ctx context.Context, req dto.ProductCreated) (api.Response, error) {
+	return audited(ctx, s.audit, enum.AuditProduct, "products", "", enum.AuditCreate, func() (api.Response, error) {
+		return s.ProductService.CreateProduct(ctx, req)
+	})
+}
+
+func (s *auditedProductService) UpdateProduct(ctx context.Context, req dto.ProductUpdated) (api.Response, error) {
+	return audited(ctx, s.audit, enum.AuditProduct, "products", req.Id, enum.AuditUpdate, func() (api.Response, error) {
+		return s.ProductService.UpdateProduct(ctx, req)
+	})
+}
+
+func (s *auditedProductService) DeleteProduct(ctx context.Context, id string) (api.Response, error) {
+	return audited(ctx, s.audit, enum.AuditProduct, "products", id, enum.AuditDelete, func() (api.Response, error) {
+		return s.ProductService.DeleteProduct(ctx, id)
+	})
+}
+
+/* ------------------------------------------------------------------
+   Categories
+   ------------------------------------------------------------------*/
+
+type auditedCategoryService struct {
+	CategoryService
+	audit AuditService
+}
+
+func auditCategoryService(inner CategoryService, audit AuditService) CategoryService {
+	return &auditedCategoryService{CategoryService: inner, audit: audit}
+}
+
+func (s *auditedCategoryService) CreateCategory(ctx context.Context, req dto.CategoryCreate) (api.Response, error) {
+	return audited(ctx, s.audit, enum.AuditCategory, "categories", "", enum.AuditCreate, func() (api.Response, error) {
+		return s.CategoryService.CreateCategory(ctx, req)
+	})
+}
+
+func (s *auditedCategoryService) UpdateCategory(ctx context.Context, req dto.CategoryUpdate) (api.Response, error) {
+	return audited(ctx, s.audit, enum.AuditCategory, "categories", req.Id, enum.AuditUpdate, func() (api.Response, error) {
+		return s.CategoryService.UpdateCategory(ctx, req)
+	})
+}
+
+func (s *auditedCategoryService) DeleteCategory(ctx context.Context, req dto.CategoryDelete) (api.Response, error) {
+	if req.DryRun {
+		return s.CategoryService.DeleteCategory(ctx, req)
+	}
+	return audited(ctx, s.audit, enum.AuditCategory, "categories", req.Id, enum.AuditDelete, func() (api.Response, error) {
+		return s.CategoryService.DeleteCategory(ctx, req)
+	})
+}
+
+func (s *auditedCategoryService) MoveCategory(ctx context.Context, req dto.CategoryMove) (api.Response, error) {
+	return audited(ctx, s.audit, enum.AuditCategory, "categories", req.Id, enum.AuditUpdate, func() (api.Response, error) {
+		return s.CategoryService.MoveCategory(ctx, req)
+	})
+}
+
+// ReorderCategories records every category the request moved; siblings that only
+// shifted along are not recorded.
+func (s *auditedCategoryService) ReorderCategories(ctx context.Context, req dto.CategoryReorder) (api.Response, error) {
+	before := make(map[string]map[string]interface{}, len(req.Items))
+	for _, item := range req.Items {
+		before[item.Id] = s.audit.Snapshot(ctx, "categories", "id", item.Id)
+	}
+	resp, err := s.CategoryService.ReorderCategories(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	for id, snapshot := range before {
+		s.audit.Record(ctx, enum.AuditCategory, id, enum.AuditUpdate, snapshot, s.audit.Snapshot(ctx, "categories", "id", id))
+	}
+	return resp, nil
+}
+
+/* ------------------------------------------------------------------
+   Orders
+   ------------------------------------------------------------------*/
+
+type auditedOrderService struct {
+	OrderService
+	audit AuditService
+}
+
+func auditOrderService(inner OrderService, audit AuditService) OrderService {
+	return &auditedOrderService{OrderService: inner, audit: audit}
+}
+
+func (s *auditedOrderService) CreateOrder(ctx context.Context, req dto.OrderCreate) (api.Response, error) {
+	return audited(ctx, s.audit, enum.AuditOrder, "orders", "", enum.AuditCreate, func() (api.Response, error) {
+		return s.OrderService.CreateOrder(ctx, req)
+	})
+}
+
+func (s *auditedOrderService) PlaceOrder(ctx context.Context, req dto.OrderCreate) (string, error) {
+	orderId, err := s.OrderService.PlaceOrder(ctx, req)
+	if err != nil {
+		return orderId, err
+	}
+	s.audit.Record(ctx, enum.AuditOrder, orderId, enum.AuditCreate, nil, s.audit.Snapshot(ctx, "orders", "id", orderId))
+	return orderId, nil
+}
+
+func (s *auditedOrderService) DiscardOrder(ctx context.Context, id string) error {
+	before := s.audit.Snapshot(ctx, "orders", "id", id)
+	if err := s.OrderService.DiscardOrder(ctx, id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, enum.AuditOrder, id, enum.AuditDelete, before, nil)
+	return nil
+}
+
+func (s *auditedOrderService) UpdateOrder(ctx context.Context, req dto.OrderUpdate) (api.Response, error) {
+	return audited(ctx, s.audit, enum.AuditOrder, "orders", req.Id, enum.AuditUpdate, func() (api.Response, error) {
+		return s.OrderService.UpdateOrder(ctx, req)
+	})
+}
+
+func (s *auditedOrderService) DeleteOrder(ctx context.Context, id string) (api.Response, error) {
+	return audited(ctx, s.audit, enum.AuditOrder, "orders", id, enum.AuditDelete, func() (api.Response, error) {
+		return s.OrderService.DeleteOrder(ctx, id)
+	})
+}
+
+func (s *auditedOrderService) UpdateOrderStatus(ctx context.Context, req dto.OrderStatusUpdate) (api.Response, error) {
+	return audited(ctx, s.audit, enum.AuditOrder, "orders", req.OrderId, enum.AuditUpdate, func() (api.Response, error) {
+		return s.OrderService.UpdateOrderStatus(ctx, req)
+	})
+}
+
+/* ------------------------------------------------------------------
+   Users
+   ------------------------------------------------------------------*/
+
+type auditedUserService struct {
+	UserService
+	audit AuditService
+}
+
+func auditUserService(inner UserService, audit AuditService) UserService {
+	return &auditedUserService{UserService: inner, audit: audit}
+}
+
+// Register answers with the sign-up credentials rather than the users row, so the
+// new row is looked up by its username.
+func (s *auditedUserService) Register(ctx context.Context, req dto.UserRegisterRequest) (api.Response, error) {
+	resp, err := s.UserService.Register(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	after := s.audit.Snapshot(ctx, "users", "username", req.Username)
+	id, _ := after["id"].(string)
+	s.audit.Record(ctx, enum.AuditUser, id, enum.AuditCreate, nil, after)
+	return resp, nil
+}
+
+func (s *auditedUserService) UpdateUser(ctx context.Context, req dto.UserUpdateRequest) (api.Response, error) {
+	return audited(ctx, s.audit, enum.AuditUser, "users", req.Id, enum.AuditUpdate, func() (api.Response, error) {
+		return s.UserService.UpdateUser(ctx, req)
+	})
+}
+
+func (s *auditedUserService) UpdateUserAddress(ctx context.Context, req dto.UserUpdateAddressRequest) (api.Response, error) {
+	return audited(ctx, s.audit, enum.AuditUser, "users", req.Id, enum.AuditUpdate, func() (api.Response, error) {
+		return s.UserService.UpdateUserAddress(ctx, req)
+	})
+}
+
+func (s *auditedUserService) ChangePassword(ctx context.Context, req dto.ChangePassword) (api.Response, error) {
+	caller, _ := auth.FromContext(ctx)
+	return audited(ctx, s.audit, enum.AuditUser, "users", caller.Id, enum.AuditUpdate, func() (api.Response, error) {
+		return s.UserService.ChangePassword(ctx, req)
+	})
+}
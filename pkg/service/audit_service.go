@@ -0,0 +1,150 @@
+package service
+
+import (
+	"SangXanh/pkg/auth"
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"SangXanh/pkg/log"
+	"SangXanh/pkg/repository"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"reflect"
+	"sort"
+	"time"
+)
+
+type AuditService interface {
+	// Snapshot returns the row of table whose column equals value, or nil.
+	Snapshot(ctx context.Context, table, column, value string) map[string]interface{}
+	// Record stores the difference between two snapshots of an entity. Failures are
+	// logged only: the change itself has already happened.
+	Record(ctx context.Context, entity enum.AuditEntity, id string, action enum.AuditAction, before, after map[string]interface{})
+	ListAudits(ctx context.Context, filter dto.AuditFilter) (api.Response, error)
+}
+
+type auditService struct {
+	audits repository.AuditRepository
+}
+
+func NewAuditService(di do.Injector) (AuditService, error) {
+	audits, err := do.Invoke[repository.AuditRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AuditService: %w", err)
+	}
+	return &auditService{audits: audits}, nil
+}
+
+// columns that change on every write and would only add noise to a diff
+var auditIgnoredFields = map[string]bool{"created_at": true, "updated_at": true}
+
+// columns whose values must never end up in the audit log
+var auditRedactedFields = map[string]bool{"password": true}
+
+const redacted = "[redacted]"
+
+// diffSnapshots lists the columns that differ between before and after. A nil
+// snapshot stands for a row that does not exist (yet or any more).
+func diffSnapshots(before, after map[string]interface{}) []dto.AuditChange {
+	fields := make(map[string]bool, len(before)+len(after))
+	for k := range before {
+		fields[k] = true
+	}
+	for k := range after {
+		fields[k] = true
+	}
+
+	changes := make([]dto.AuditChange, 0)
+	for field := range fields {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		b, a := before[field], after[field]
+		if reflect.DeepEqual(b, a) {
+			continue
+		}
+		if auditRedactedFields[field] {
+			b, a = redacted, redacted
+		}
+		changes = append(changes, dto.AuditChange{Field: field, Before: b, After: a})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func (s *auditService) Snapshot(ctx context.Context, table, column, value string) map[string]interface{} {
+	if value == "" {
+		return nil
+	}
+	row, err := s.audits.Snapshot(ctx, table, column, value)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	return row
+}
+
+func (s *auditService) Record(ctx context.Context, entity enum.AuditEntity, id string, action enum.AuditAction, before, after map[string]interface{}) {
+	changes := diffSnapshots(before, after)
+	if len(changes) == 0 && action == enum.AuditUpdate {
+		return
+	}
+	row := map[string]interface{}{
+		"audit_id":      id,
+		"audit_type":    entity,
+		"audit_content": changes,
+		"metadata":      []map[string]interface{}{{"action": action}},
+	}
+	if caller, ok := auth.FromContext(ctx); ok {
+		row["created_by"] = caller.Id
+	}
+	if err := s.audits.Create(ctx, row); err != nil {
+		log.Errorf("failed to record audit of %s %s: %v", entity, id, err)
+	}
+}
+
+// parseAuditTime accepts an RFC 3339 timestamp or a plain date; endOfDay moves a
+// plain date to the start of the following day so the bound covers all of it.
+func parseAuditTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return t, errors.BadRequest("invalid date %q, expected RFC 3339 or YYYY-MM-DD", value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func (s *auditService) ListAudits(ctx context.Context, filter dto.AuditFilter) (api.Response, error) {
+	filter.Correct()
+	var from, to time.Time
+	var err error
+	if filter.From != "" {
+		if from, err = parseAuditTime(filter.From, false); err != nil {
+			return nil, err
+		}
+	}
+	if filter.To != "" {
+		if to, err = parseAuditTime(filter.To, true); err != nil {
+			return nil, err
+		}
+	}
+
+	total, err := s.audits.Count(ctx, filter, from, to)
+	if err != nil {
+		return nil, err
+	}
+	audits, err := s.audits.List(ctx, filter, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.SetTotal(int64(total))
+	return api.SuccessPagination(audits, &filter.Pagination), nil
+}
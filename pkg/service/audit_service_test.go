@@ -0,0 +1,80 @@
+package service
+
+import (
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"SangXanh/pkg/repository"
+	"context"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	before := map[string]interface{}{"id": "1", "name": "Lúa", "price": 100.0, "updated_at": "a", "password": "x"}
+	after := map[string]interface{}{"id": "1", "name": "Lúa giống", "price": 100.0, "updated_at": "b", "password": "y"}
+
+	assert.Equal(t, []dto.AuditChange{
+		{Field: "name", Before: "Lúa", After: "Lúa giống"},
+		{Field: "password", Before: redacted, After: redacted},
+	}, diffSnapshots(before, after))
+}
+
+func TestDiffSnapshots_CreateAndDelete(t *testing.T) {
+	row := map[string]interface{}{"id": "1", "name": "Lúa"}
+
+	assert.Equal(t, []dto.AuditChange{
+		{Field: "id", Before: nil, After: "1"},
+		{Field: "name", Before: nil, After: "Lúa"},
+	}, diffSnapshots(nil, row))
+	assert.Len(t, diffSnapshots(row, nil), 2)
+	assert.Empty(t, diffSnapshots(row, row))
+}
+
+func TestParseAuditTime(t *testing.T) {
+	from, err := parseAuditTime("2025-03-01", false)
+	assert.NoError(t, err)
+	to, err := parseAuditTime("2025-03-01", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 24.0, to.Sub(from).Hours())
+
+	_, err = parseAuditTime("01/03/2025", false)
+	assert.Error(t, err)
+}
+
+func TestAuditTrail(t *testing.T) {
+	di := do.New()
+	store := repository.InjectMemory(di)
+	do.Provide(di, NewAuditService)
+	audits := do.MustInvoke[AuditService](di)
+	store.Seed("products", map[string]interface{}{"id": "p1", "name": "Lúa", "price": 100})
+	ctx := signedIn("a1", enum.Admin)
+
+	before := audits.Snapshot(ctx, "products", "id", "p1")
+	require.NotNil(t, before)
+	assert.Nil(t, audits.Snapshot(ctx, "products", "id", "p2"))
+	audits.Record(ctx, enum.AuditProduct, "p1", enum.AuditUpdate, before, before)
+	after := map[string]interface{}{}
+	for k, v := range before {
+		after[k] = v
+	}
+	after["name"] = "Lúa giống"
+	audits.Record(ctx, enum.AuditProduct, "p1", enum.AuditUpdate, before, after)
+
+	filter := dto.AuditFilter{EntityType: enum.AuditProduct, EntityId: "p1"}
+	filter.Page, filter.Limit = 1, 10
+	resp, err := audits.ListAudits(context.Background(), filter)
+	require.NoError(t, err)
+	var trails []dto.AuditTrail
+	responseData(t, resp, &trails)
+	require.Len(t, trails, 1, "an update that changes nothing is not recorded")
+	assert.Equal(t, "a1", trails[0].CreatedBy)
+	assert.Equal(t, []dto.AuditChange{{Field: "name", Before: "Lúa", After: "Lúa giống"}}, trails[0].AuditContent)
+
+	filter.To = "2000-01-01"
+	resp, err = audits.ListAudits(context.Background(), filter)
+	require.NoError(t, err)
+	responseData(t, resp, &trails)
+	assert.Empty(t, trails)
+}
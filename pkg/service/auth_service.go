@@ -1,59 +1,115 @@
 package service
 
 import (
+	"SangXanh/pkg/auth"
 	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
 	"SangXanh/pkg/dto"
 	"SangXanh/pkg/log"
+	"SangXanh/pkg/ratelimit"
+	"SangXanh/pkg/repository"
 	"context"
 	"fmt"
-	"github.com/labstack/echo/v4"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/nedpals/supabase-go"
 	"github.com/samber/do/v2"
-	"net/http"
+	"strings"
+	"time"
 )
 
+var (
+	// loginPerIp bounds the guesses made from one address over many accounts.
+	loginPerIp      = ratelimit.Rate{Burst: 20, Per: time.Minute}
+	loginPerAccount = ratelimit.Rate{Burst: 5, Per: time.Minute}
+	// loginLockout locks an account for a minute after its fifth failed login in a
+	// row, then twice as long after every further one.
+	loginLockout = ratelimit.Lockout{Free: 5, Base: time.Minute, Max: time.Hour, Window: 24 * time.Hour}
+)
+
+// errInvalidCredentials is the answer to every failed login, so it does not tell an
+// unknown username from a wrong password.
+func errInvalidCredentials() error {
+	return errors.Unauthorized("invalid username, email or password").WithCode("invalid_credentials")
+}
+
 type AuthService interface {
 	Login(ctx context.Context, req dto.LoginRequest) (api.Response, error)
 	Refresh(ctx context.Context, req dto.RefreshTokenRequest) (api.Response, error)
 	GetCurrentUser(ctx context.Context) (api.Response, error)
+	// ListSessions lists the devices the caller is signed in on.
+	ListSessions(ctx context.Context) (api.Response, error)
+	// Logout ends the session of the caller's token, LogoutAll every session of the
+	// caller.
+	Logout(ctx context.Context) (api.Response, error)
+	LogoutAll(ctx context.Context) (api.Response, error)
 }
 
 type authService struct {
-	db *supabase.Client
+	identity IdentityProvider
+	users    repository.UserRepository
+	sessions SessionService
+	limiter  *ratelimit.Limiter
 }
 
 func NewAuthService(di do.Injector) (AuthService, error) {
-	db, err := do.Invoke[*supabase.Client](di)
+	identity, err := do.Invoke[IdentityProvider](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AuthService: %w", err)
+	}
+	users, err := do.Invoke[repository.UserRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AuthService: %w", err)
+	}
+	sessions, err := do.Invoke[SessionService](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AuthService: %w", err)
+	}
+	limiter, err := do.Invoke[*ratelimit.Limiter](di)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize AuthService: %w", err)
 	}
-	return &authService{db: db}, nil
+	return &authService{identity: identity, users: users, sessions: sessions, limiter: limiter}, nil
 }
 
 func (a *authService) Login(ctx context.Context, req dto.LoginRequest) (api.Response, error) {
 	if req.Password == "" || (req.Email == "" && req.Username == "") {
-		return nil, fmt.Errorf("email/username and password are required")
+		return nil, errors.BadRequest("email/username and password are required")
 	}
 
-	// If login with username, you must first find the user's email
-	email := req.Email
-	if email == "" {
-		var users []dto.User
-		err := a.db.DB.From("users").Select("*").Eq("username", req.Username).Execute(&users)
-		if err != nil || len(users) == 0 {
-			return nil, fmt.Errorf("user not found: %v", err)
+	if req.Client.Ip != "" {
+		if err := a.limiter.Allow(ctx, "login:ip:"+req.Client.Ip, loginPerIp); err != nil {
+			return nil, err
 		}
-		log.Info(users[0].Username)
-		email = users[0].Email
-		log.Info(email)
+	}
+	// The account is keyed on its email, so a username and the email it stands for
+	// count against one budget and one lockout.
+	email, err := a.loginEmail(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	account := "login:account:" + strings.ToLower(strings.TrimSpace(email))
+	if err := a.limiter.Allow(ctx, account, loginPerAccount); err != nil {
+		return nil, err
+	}
+	if err := a.limiter.Locked(ctx, account, loginLockout); err != nil {
+		return nil, err
 	}
 
-	session, err := a.db.Auth.SignIn(ctx, supabase.UserCredentials{
+	session, err := a.identity.SignIn(ctx, supabase.UserCredentials{
 		Email:    email,
 		Password: req.Password,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("login failed: %v", err)
+		log.Infow("login failed", "error", err)
+		a.limiter.Fail(ctx, account, loginLockout)
+		return nil, errInvalidCredentials()
+	}
+	a.limiter.Reset(ctx, account)
+
+	client := req.Client
+	client.Device = req.Device
+	if err := a.sessions.Start(ctx, tokenPrincipal(session.AccessToken), client); err != nil {
+		return nil, err
 	}
 
 	resp := dto.AuthResponse{
@@ -64,16 +120,43 @@ func (a *authService) Login(ctx context.Context, req dto.LoginRequest) (api.Resp
 	return api.Success(resp), nil
 }
 
+// unknownLoginEmail stands in for the email of a username no one has. It is under a
+// reserved domain, so no account can have it.
+const unknownLoginEmail = "unknown@sangxanh.invalid"
+
+// loginEmail finds the email a login is for. An unknown username gets
+// unknownLoginEmail plus the username, whose sign-in is still tried, so that it
+// takes about as long to fail as a wrong password and does not give away which
+// usernames exist.
+func (a *authService) loginEmail(ctx context.Context, req dto.LoginRequest) (string, error) {
+	if req.Email != "" {
+		return req.Email, nil
+	}
+	user, err := a.users.GetByUsername(ctx, req.Username)
+	var notFound *errors.NotFoundError
+	if errors.As(err, &notFound) {
+		return strings.Replace(unknownLoginEmail, "@", "+"+req.Username+"@", 1), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return user.Email, nil
+}
+
 func (a *authService) Refresh(ctx context.Context, req dto.RefreshTokenRequest) (api.Response, error) {
 	if req.RefreshToken == "" {
-		return nil, fmt.Errorf("refresh token is required")
+		return nil, errors.BadRequest("refresh token is required")
 	}
 
-	authDetails, err := a.db.Auth.RefreshUser(ctx, "", req.RefreshToken)
+	authDetails, err := a.identity.RefreshUser(ctx, "", req.RefreshToken)
 	if err != nil {
 		log.Errorf("failed to refresh session: %v", err)
 		return nil, fmt.Errorf("failed to refresh token")
 	}
+	// a session that was logged out must not get new tokens
+	if err := a.sessions.Verify(ctx, tokenPrincipal(authDetails.AccessToken), req.Client); err != nil {
+		return nil, err
+	}
 
 	resp := dto.AuthResponse{
 		AccessToken:  authDetails.AccessToken,
@@ -84,20 +167,71 @@ func (a *authService) Refresh(ctx context.Context, req dto.RefreshTokenRequest)
 }
 
 func (a *authService) GetCurrentUser(ctx context.Context) (api.Response, error) {
-	userID, ok := ctx.Value("user_id").(string)
-	if !ok || userID == "" {
-		return nil, echo.NewHTTPError(http.StatusUnauthorized, "User ID not found in context")
+	caller, err := auth.Authenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.users.Info(ctx, caller.Id)
+	if err != nil {
+		return nil, err
+	}
+	return api.Success(user), nil
+}
+
+// tokenPrincipal reads the caller from a token Supabase has just issued, which needs
+// no verification.
+func tokenPrincipal(accessToken string) auth.Principal {
+	var claims jwt.MapClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(accessToken, &claims); err != nil {
+		log.Errorf("failed to read issued token: %v", err)
+		return auth.Principal{}
+	}
+	return auth.FromClaims(claims, accessToken)
+}
+
+func (a *authService) ListSessions(ctx context.Context) (api.Response, error) {
+	caller, err := auth.Authenticated(ctx)
+	if err != nil {
+		return nil, err
 	}
+	sessions, err := a.sessions.List(ctx, caller.Id)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]dto.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		resp = append(resp, dto.SessionResponse{Session: session, Current: session.Id == caller.SessionId})
+	}
+	return api.Success(resp), nil
+}
 
-	userRole, ok := ctx.Value("user_role").(string)
-	if !ok || userRole == "" {
-		return nil, echo.NewHTTPError(http.StatusUnauthorized, "User role not found in context")
+func (a *authService) Logout(ctx context.Context) (api.Response, error) {
+	caller, err := auth.Authenticated(ctx)
+	if err != nil {
+		return nil, err
 	}
+	if caller.SessionId == "" {
+		return nil, errors.Unprocessable("token is not bound to a session")
+	}
+	if err := a.sessions.Revoke(ctx, caller.SessionId); err != nil {
+		return nil, err
+	}
+	return api.Success(dto.LogoutResponse{Revoked: 1}), nil
+}
 
-	var user []dto.UserInfo
-	err := a.db.DB.From("users").Select("*").Eq("id", userID).Execute(&user)
+func (a *authService) LogoutAll(ctx context.Context) (api.Response, error) {
+	caller, err := auth.Authenticated(ctx)
 	if err != nil {
-		return nil, echo.NewHTTPError(http.StatusUnauthorized, "User not found")
+		return nil, err
+	}
+	revoked, err := a.sessions.RevokeAll(ctx, caller.Id)
+	if err != nil {
+		return nil, err
+	}
+	// Supabase signs the user out everywhere too, so no refresh token is left
+	if err := a.identity.SignOut(ctx, caller.Token); err != nil {
+		log.Errorf("failed to sign out user %s at Supabase: %v", caller.Id, err)
 	}
-	return api.Success(user[0]), nil
+	return api.Success(dto.LogoutResponse{Revoked: revoked}), nil
 }
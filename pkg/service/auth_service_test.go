@@ -0,0 +1,85 @@
+package service
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/ratelimit"
+	"SangXanh/pkg/repository"
+	"SangXanh/pkg/ws"
+	"context"
+	"github.com/nedpals/supabase-go"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// fakeIdentity knows a single account and refuses everything else.
+type fakeIdentity struct {
+	IdentityProvider
+	email, password string
+	signIns         []string
+}
+
+func (f *fakeIdentity) SignIn(_ context.Context, credentials supabase.UserCredentials) (*supabase.AuthenticatedDetails, error) {
+	f.signIns = append(f.signIns, credentials.Email)
+	if credentials.Email != f.email || credentials.Password != f.password {
+		return nil, errors.New("invalid login credentials")
+	}
+	return &supabase.AuthenticatedDetails{AccessToken: "access", RefreshToken: "refresh"}, nil
+}
+
+func TestLoginFailure(t *testing.T) {
+	di := do.New()
+	store := repository.InjectMemory(di)
+	identity := &fakeIdentity{email: "lan@sangxanh.vn", password: "secret"}
+	do.ProvideValue[IdentityProvider](di, identity)
+	do.ProvideValue[ratelimit.Store](di, ratelimit.NewMemoryStore())
+	do.Provide(di, ratelimit.NewLimiter)
+	do.ProvideValue(di, ws.New())
+	do.Provide(di, NewSessionService)
+	do.Provide(di, NewAuthService)
+	store.Seed("users", map[string]interface{}{"id": "u1", "username": "lan", "email": "lan@sangxanh.vn"})
+	auths := do.MustInvoke[AuthService](di)
+	ctx := context.Background()
+
+	for _, req := range []dto.LoginRequest{
+		{Username: "mai", Password: "secret"},
+		{Username: "lan", Password: "wrong"},
+		{Email: "lan@sangxanh.vn", Password: "wrong"},
+	} {
+		_, err := auths.Login(ctx, req)
+		var httpErr errors.HTTPError
+		require.True(t, errors.As(err, &httpErr), "not an HTTP error: %v", err)
+		assert.Equal(t, "invalid_credentials", httpErr.ErrorCode(), "a failed login does not say why")
+	}
+	assert.Equal(t, []string{"unknown+mai@sangxanh.invalid", "lan@sangxanh.vn", "lan@sangxanh.vn"}, identity.signIns,
+		"a username signs in with its email, an unknown one is tried all the same")
+}
+
+func TestLoginLockoutSharedByUsernameAndEmail(t *testing.T) {
+	di := do.New()
+	store := repository.InjectMemory(di)
+	do.ProvideValue[IdentityProvider](di, &fakeIdentity{email: "lan@sangxanh.vn", password: "secret"})
+	do.ProvideValue[ratelimit.Store](di, ratelimit.NewMemoryStore())
+	do.Provide(di, ratelimit.NewLimiter)
+	do.ProvideValue(di, ws.New())
+	do.Provide(di, NewSessionService)
+	do.Provide(di, NewAuthService)
+	store.Seed("users", map[string]interface{}{"id": "u1", "username": "lan", "email": "lan@sangxanh.vn"})
+	auths := do.MustInvoke[AuthService](di)
+	ctx := context.Background()
+
+	for i := 0; i < loginLockout.Free; i++ {
+		req := dto.LoginRequest{Username: "lan", Password: "wrong"}
+		if i%2 == 1 {
+			req = dto.LoginRequest{Email: "LAN@sangxanh.vn", Password: "wrong"}
+		}
+		_, err := auths.Login(ctx, req)
+		require.Error(t, err)
+	}
+	_, err := auths.Login(ctx, dto.LoginRequest{Email: "lan@sangxanh.vn", Password: "secret"})
+	var httpErr errors.HTTPError
+	require.True(t, errors.As(err, &httpErr), "not an HTTP error: %v", err)
+	assert.NotEqual(t, "invalid_credentials", httpErr.ErrorCode(), "the account is locked whichever way it was guessed")
+}
@@ -1,85 +1,104 @@
 package service
 
 import (
+	"SangXanh/pkg/auth"
 	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
 	"SangXanh/pkg/dto"
+	"SangXanh/pkg/log"
+	"SangXanh/pkg/repository"
 	"context"
+	"encoding/json"
 	"fmt"
-	"github.com/nedpals/supabase-go"
 	"github.com/samber/do/v2"
-	"time"
+	"github.com/samber/lo"
 )
 
 type CartService interface {
-	CreateCart(ctx context.Context, req dto.CartCreateRequest, userID string) (api.Response, error)
-	GetCartsByUserID(ctx context.Context, userID string) (api.Response, error)
-	UpdateCart(ctx context.Context, req dto.CartUpdate, userID string) (api.Response, error)
+	// every method works on the cart of the signed-in user
+	CreateCart(ctx context.Context, req dto.CartCreateRequest) (api.Response, error)
+	ListCarts(ctx context.Context) (api.Response, error)
+	UpdateCart(ctx context.Context, req dto.CartUpdate) (api.Response, error)
 	DeleteCart(ctx context.Context, id string) (api.Response, error)
+	Checkout(ctx context.Context, req dto.CartCheckout) (api.Response, error)
 }
 
 type cartService struct {
-	db *supabase.Client
+	carts        repository.CartRepository
+	options      repository.ProductOptionRepository
+	users        repository.UserRepository
+	orderService OrderService
+	inventory    InventoryService
 }
 
 func NewCartService(di do.Injector) (CartService, error) {
-	db, err := do.Invoke[*supabase.Client](di)
+	carts, err := do.Invoke[repository.CartRepository](di)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize CartService: %w", err)
 	}
-	return &cartService{db: db}, nil
+	options, err := do.Invoke[repository.ProductOptionRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize CartService: %w", err)
+	}
+	users, err := do.Invoke[repository.UserRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize CartService: %w", err)
+	}
+	orderService, err := do.Invoke[OrderService](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize CartService: %w", err)
+	}
+	inventory, err := do.Invoke[InventoryService](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize CartService: %w", err)
+	}
+	return &cartService{carts: carts, options: options, users: users, orderService: orderService, inventory: inventory}, nil
 }
 
-func (s *cartService) CreateCart(ctx context.Context, req dto.CartCreateRequest, userID string) (api.Response, error) {
-	var created []dto.Cart
-	req.UserID = userID
+func (s *cartService) CreateCart(ctx context.Context, req dto.CartCreateRequest) (api.Response, error) {
+	caller, err := auth.Authenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.UserID = caller.Id
 
-	if err := s.db.DB.
-		From("carts").
-		Insert(req).
-		Execute(&created); err != nil {
-		return nil, fmt.Errorf("failed to create cart: %w", err)
+	if err := s.inventory.CheckAvailable(ctx, req.ProductOptionID, req.Quantity); err != nil {
+		return nil, err
 	}
-	if err := s.db.DB.
-		From("product_options").
-		Select("id,name,price,detail,created_at,updated_at").
-		Eq("id", req.ProductOptionID).
-		IsNull("deleted_at").
-		Execute(nil); err != nil {
-		return nil, fmt.Errorf("failed to fetch product option for cart %s: %w", req.ProductOptionID, err)
+	created, err := s.carts.Create(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 	// Return the created cart
-	return api.Success(created[0]), nil
+	return api.Success(created), nil
 }
 
-func (s *cartService) GetCartsByUserID(ctx context.Context, userID string) (api.Response, error) {
-	// Query to get carts for a specific user
-	var carts []dto.Cart
-	if err := s.db.DB.
-		From("carts").
-		Select("id,user_id,product_option_id,quantity,created_at,updated_at").
-		Eq("user_id", userID).
-		IsNull("deleted_at").
-		Execute(&carts); err != nil {
-		return nil, fmt.Errorf("failed to fetch carts for user %s: %w", userID, err)
+func (s *cartService) ListCarts(ctx context.Context) (api.Response, error) {
+	caller, err := auth.Authenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	carts, err := s.carts.ListByUser(ctx, caller.Id)
+	if err != nil {
+		return nil, err
 	}
 
-	// Now query the product_options table to get ProductOption data for each cart
+	// Now look up the ProductOption of each cart; rows whose option was deleted are left out
 	var cartResponses []dto.CartResponse
 	for _, cart := range carts {
-		var productOption []dto.ProductOption
-		if err := s.db.DB.
-			From("product_options").
-			Select("id,name,price,detail,created_at,updated_at").
-			Eq("id", cart.ProductOptionID).
-			IsNull("deleted_at").
-			Execute(&productOption); err != nil {
+		productOption, err := s.options.Get(ctx, cart.ProductOptionID, false)
+		var notFound *errors.NotFoundError
+		if errors.As(err, &notFound) {
+			continue
+		}
+		if err != nil {
 			return nil, fmt.Errorf("failed to fetch product option for cart %s: %w", cart.ID, err)
 		}
 
 		// Combine cart and product option in CartResponse
 		cartResponse := dto.CartResponse{
 			Cart:          cart,
-			ProductOption: productOption[0],
+			ProductOption: productOption,
 		}
 		cartResponses = append(cartResponses, cartResponse)
 	}
@@ -88,55 +107,138 @@ func (s *cartService) GetCartsByUserID(ctx context.Context, userID string) (api.
 	return api.Success(cartResponses), nil
 }
 
-func (s *cartService) UpdateCart(ctx context.Context, req dto.CartUpdate, userID string) (api.Response, error) {
-	updateData := map[string]interface{}{
-		"quantity":   req.Quantity,
-		"updated_at": time.Now(),
+func (s *cartService) UpdateCart(ctx context.Context, req dto.CartUpdate) (api.Response, error) {
+	caller, err := auth.Authenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cart, err := s.carts.Get(ctx, req.ID, caller.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.inventory.CheckAvailable(ctx, cart.ProductOptionID, req.Quantity); err != nil {
+		return nil, err
 	}
 
-	var updated []dto.Cart
-	if err := s.db.DB.
-		From("carts").
-		Update(updateData).
-		Eq("id", req.ID).
-		Eq("user_id", userID).
-		Execute(&updated); err != nil {
-		return nil, fmt.Errorf("failed to update cart: %w", err)
+	updated, err := s.carts.SetQuantity(ctx, req.ID, caller.Id, req.Quantity)
+	if err != nil {
+		return nil, err
 	}
 
-	// Fetch the updated cart and return with ProductOption
-	var productOption []dto.ProductOption
-	if err := s.db.DB.
-		From("product_options").
-		Select("id,name,price,detail,created_at,updated_at").
-		Eq("id", updated[0].ProductOptionID).
-		IsNull("deleted_at").
-		Execute(&productOption); err != nil {
-		return nil, fmt.Errorf("failed to fetch product option for cart %s: %w", updated[0].ID, err)
+	// Return the updated cart with its ProductOption
+	productOption, err := s.options.Get(ctx, updated.ProductOptionID, false)
+	if err != nil {
+		return nil, err
 	}
 
 	cartResponse := dto.CartResponse{
-		Cart:          updated[0],
-		ProductOption: productOption[0],
+		Cart:          updated,
+		ProductOption: productOption,
 	}
 
 	return api.Success(cartResponse), nil
 }
 
 func (s *cartService) DeleteCart(ctx context.Context, id string) (api.Response, error) {
-	updateData := map[string]interface{}{
-		"deleted_at": time.Now(),
+	caller, err := auth.Authenticated(ctx)
+	if err != nil {
+		return nil, err
 	}
-
-	var updated []dto.Cart
-	if err := s.db.DB.
-		From("carts").
-		Update(updateData).
-		Eq("id", id).
-		Execute(&updated); err != nil {
-		return nil, fmt.Errorf("failed to delete cart: %w", err)
+	// only the owner's rows are found, anyone else's cart is not there for them
+	if _, err := s.carts.Get(ctx, id, caller.Id); err != nil {
+		return nil, err
+	}
+	if err := s.carts.SoftDelete(ctx, id); err != nil {
+		return nil, err
 	}
 
 	// Return a success message
 	return api.Success("Cart deleted successfully"), nil
 }
+
+// Checkout places an order for the selected cart rows of the current user and
+// removes them from the cart. If the cart cannot be cleared the order is discarded
+// again so the user never ends up with both an order and the same items in the cart.
+func (s *cartService) Checkout(ctx context.Context, req dto.CartCheckout) (api.Response, error) {
+	caller, err := auth.Authenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := caller.Id
+
+	// 1) the cart rows being checked out ------------------------------------
+	// an id sent twice is still one row
+	req.CartIds = lo.Uniq(req.CartIds)
+	carts, err := s.carts.ListByUser(ctx, userID, req.CartIds...)
+	if err != nil {
+		return nil, err
+	}
+	if len(carts) == 0 {
+		return nil, errors.BadRequest("cart is empty")
+	}
+	if len(req.CartIds) > 0 && len(carts) != len(req.CartIds) {
+		return nil, errors.NotFound("one or more cart items not found")
+	}
+
+	// 2) shipping address ---------------------------------------------------
+	address, err := s.resolveAddress(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3) place the order (validates every product option) -------------------
+	order := dto.OrderCreate{
+		UserId:        userID,
+		Address:       address,
+		PromotionCode: req.PromotionCode,
+		Metadata:      req.Metadata,
+	}
+	cartIds := make([]string, 0, len(carts))
+	for _, cart := range carts {
+		cartIds = append(cartIds, cart.ID)
+		order.OrderDetails = append(order.OrderDetails, dto.OrderDetailBase{
+			ProductOptionId: cart.ProductOptionID,
+			Quantity:        cart.Quantity,
+		})
+	}
+	orderId, err := s.orderService.PlaceOrder(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4) clear the checked-out rows, undo the order if that fails -----------
+	if err := s.carts.SoftDeleteMany(ctx, userID, cartIds); err != nil {
+		if rbErr := s.orderService.DiscardOrder(ctx, orderId); rbErr != nil {
+			log.Errorf("failed to roll back order %s after checkout failure: %v", orderId, rbErr)
+		}
+		return nil, err
+	}
+
+	return s.orderService.GetOrderById(ctx, orderId)
+}
+
+// resolveAddress returns the explicit address or the saved address picked by index,
+// encoded as JSON so the order keeps the recipient name and phone as well.
+func (s *cartService) resolveAddress(ctx context.Context, userID string, req dto.CartCheckout) (string, error) {
+	if req.AddressIndex == nil {
+		if req.Address == "" {
+			return "", errors.BadRequest("address or address_index is required")
+		}
+		return req.Address, nil
+	}
+
+	user, err := s.users.Info(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	idx := *req.AddressIndex
+	if idx < 0 || idx >= len(user.Address) {
+		return "", errors.BadRequest("address_index %d is out of range", idx)
+	}
+
+	address, err := json.Marshal(user.Address[idx])
+	if err != nil {
+		return "", fmt.Errorf("failed to encode address: %w", err)
+	}
+	return string(address), nil
+}
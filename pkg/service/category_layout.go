@@ -0,0 +1,124 @@
+package service
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"github.com/google/uuid"
+	"slices"
+	"strings"
+	"time"
+)
+
+// categoryLayout is the tree of the live categories while it is being rearranged.
+// Moves only change the in-memory tree; changes then tells which rows to write.
+type categoryLayout struct {
+	byId     map[string]dto.Category
+	parent   map[string]string
+	children map[string][]string // "" holds the top level
+}
+
+func newCategoryLayout(categories []dto.Category) *categoryLayout {
+	l := &categoryLayout{
+		byId:     make(map[string]dto.Category, len(categories)),
+		parent:   make(map[string]string, len(categories)),
+		children: make(map[string][]string),
+	}
+	for _, c := range categories {
+		l.byId[c.Id] = c
+	}
+	sorted := slices.Clone(categories)
+	slices.SortStableFunc(sorted, compareSiblings)
+	for _, c := range sorted {
+		p := topLevel(c.ParentId)
+		// like BuildCategoryTree, a category whose parent is gone sits at the top, and
+		// is written back as such once its siblings change
+		if _, ok := l.byId[p]; !ok {
+			p = ""
+		}
+		l.parent[c.Id] = p
+		l.children[p] = append(l.children[p], c.Id)
+	}
+	return l
+}
+
+// compareSiblings orders by position; categories from before positions existed all
+// have 0 and keep their creation order.
+func compareSiblings(a, b dto.Category) int {
+	if a.Position != b.Position {
+		return a.Position - b.Position
+	}
+	if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Id, b.Id)
+}
+
+func topLevel(parentId string) string {
+	if parentId == uuid.Nil.String() {
+		return ""
+	}
+	return parentId
+}
+
+// subtree lists id and every category below it, parents before their children.
+func (l *categoryLayout) subtree(id string) []string {
+	ids := []string{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, l.children[ids[i]]...)
+	}
+	return ids
+}
+
+func (l *categoryLayout) move(m dto.CategoryMove) error {
+	if _, ok := l.byId[m.Id]; !ok {
+		return errors.NotFound("category %s not found", m.Id)
+	}
+	parent := topLevel(m.ParentId)
+	if parent != "" {
+		if _, ok := l.byId[parent]; !ok {
+			return errors.NotFound("parent category %s not found", parent)
+		}
+		seen := make(map[string]bool)
+		for id := parent; id != "" && !seen[id]; id = l.parent[id] {
+			if id == m.Id {
+				return errors.Unprocessable("category %s cannot be moved under itself or one of its subcategories", m.Id).WithCode("category_cycle")
+			}
+			seen[id] = true
+		}
+	}
+
+	old := l.parent[m.Id]
+	l.children[old] = slices.DeleteFunc(slices.Clone(l.children[old]), func(id string) bool { return id == m.Id })
+	siblings := l.children[parent]
+	l.children[parent] = slices.Insert(slices.Clone(siblings), min(m.Position, len(siblings)), m.Id)
+	l.parent[m.Id] = parent
+	return nil
+}
+
+// changes numbers the siblings from 0, derives the levels from the top and returns
+// the patch of every category whose parent, position or level is now different.
+func (l *categoryLayout) changes() map[string]map[string]interface{} {
+	patches := make(map[string]map[string]interface{})
+	now := time.Now()
+	var walk func(parent string, level int)
+	walk = func(parent string, level int) {
+		for position, id := range l.children[parent] {
+			c := l.byId[id]
+			if topLevel(c.ParentId) != parent || c.Position != position || c.Level != level {
+				var parentId interface{}
+				if parent != "" {
+					parentId = parent
+				}
+				patches[id] = map[string]interface{}{
+					"parent_id":  parentId,
+					"position":   position,
+					"level":      level,
+					"updated_at": now,
+				}
+			}
+			walk(id, level+1)
+		}
+	}
+	walk("", 0)
+	return patches
+}
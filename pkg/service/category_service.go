@@ -2,16 +2,18 @@ package service
 
 import (
 	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
 	"SangXanh/pkg/dto"
 	"SangXanh/pkg/enum"
 	"SangXanh/pkg/log"
+	"SangXanh/pkg/repository"
 	"context"
 	"fmt"
 	"github.com/google/uuid"
-	"github.com/nedpals/supabase-go"
 	"github.com/samber/do/v2"
 	"github.com/samber/lo"
-	"net/url"
+	"math"
+	"slices"
 	"sort"
 	"time"
 )
@@ -20,58 +22,67 @@ type CategoryService interface {
 	CreateCategory(ctx context.Context, req dto.CategoryCreate) (api.Response, error)
 	ListCategories(ctx context.Context, req dto.ListCategory) (api.Response, error)
 	UpdateCategory(ctx context.Context, req dto.CategoryUpdate) (api.Response, error)
-	DeleteCategory(ctx context.Context, categoryId string) (api.Response, error)
+	// DeleteCategory deletes a category and deals with its subcategories and products
+	// as req.Mode says, or only tells what it would do on a dry run.
+	DeleteCategory(ctx context.Context, req dto.CategoryDelete) (api.Response, error)
 	ListCategoryById(ctx context.Context, categoryId string) (api.Response, error)
+	// GetCategoryBySlug returns the category like ListCategoryById, or redirects to
+	// the current slug when slug is one the category had before.
+	GetCategoryBySlug(ctx context.Context, slug string) (api.Response, error)
+	// MoveCategory places one category under a new parent and/or at a new position.
+	MoveCategory(ctx context.Context, req dto.CategoryMove) (api.Response, error)
+	// ReorderCategories applies a batch of moves and returns the resulting tree.
+	ReorderCategories(ctx context.Context, req dto.CategoryReorder) (api.Response, error)
 }
 
 type categoryService struct {
-	db *supabase.Client
+	categories repository.CategoryRepository
+	products   repository.ProductRepository
+	searcher   repository.ProductSearcher
+	slugs      repository.SlugRepository
 }
 
 func NewCategoryService(di do.Injector) (CategoryService, error) {
-	db, err := do.Invoke[*supabase.Client](di)
+	categories, err := do.Invoke[repository.CategoryRepository](di)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize UserService: %w", err)
+		return nil, fmt.Errorf("failed to initialize CategoryService: %w", err)
+	}
+	products, err := do.Invoke[repository.ProductRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize CategoryService: %w", err)
+	}
+	searcher, err := do.Invoke[repository.ProductSearcher](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize CategoryService: %w", err)
+	}
+	slugs, err := do.Invoke[repository.SlugRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize CategoryService: %w", err)
 	}
 
-	return &categoryService{db: db}, nil
+	return &categoryService{categories: categories, products: products, searcher: searcher, slugs: slugs}, nil
 }
 
 func (u *categoryService) ListCategoryById(ctx context.Context, categoryId string) (api.Response, error) {
 	// 1. Fetch the category that matches the given ID and has not been soft‑deleted
-	var categories []dto.Category
-	err := u.db.DB.
-		From("categories").
-		Select("*").
-		Eq("id", categoryId).
-		IsNull("deleted_at").
-		Execute(&categories)
-
+	cat, err := u.categories.Get(ctx, categoryId)
 	if err != nil {
 		log.Errorf("failed to fetch category %s: %v", categoryId, err)
-		return nil, fmt.Errorf("failed to fetch category")
+		return nil, err
 	}
-	if len(categories) == 0 {
-		return nil, fmt.Errorf("category not found")
+	childCategories, err := u.categories.Children(ctx, categoryId)
+	if err != nil {
+		return nil, err
+	}
+	breadcrumb, err := u.categories.Path(ctx, categoryId)
+	if err != nil {
+		return nil, err
 	}
-	cat := categories[0]
-	var childCategories []dto.Category
-	err = u.db.DB.From("categories").Select("*").Eq("parent_id", categoryId).IsNull("deleted_at").Execute(&childCategories)
-	log.Info("category", categoryId, "childCategories", childCategories)
 
 	// 2. Build the response payload (same fields you return elsewhere)
-	categoryResponse := dto.CategoryResponse{
-		Id:          cat.Id,
-		Name:        cat.Name,
-		Thumbnail:   cat.Thumbnail,
-		Level:       cat.Level,
-		Description: cat.Description,
-		Status:      enum.ToStatus(cat.Status),
-		Categories:  childCategories,
-		Metadata:    cat.Metadata,
-		CreatedAt:   cat.CreatedAt,
-		UpdatedAt:   cat.UpdatedAt,
-	}
+	categoryResponse := dto.GetResponse(&cat)
+	categoryResponse.Categories = childCategories
+	categoryResponse.Breadcrumb = breadcrumb
 
 	return api.Success(categoryResponse), nil
 }
@@ -84,56 +95,45 @@ func (u *categoryService) CreateCategory(ctx context.Context, req dto.CategoryCr
 		Thumbnail:         req.Thumbnail,
 		Description:       req.Description,
 		IsDisplayHomepage: req.IsDisplayHomepage,
+		Seo:               req.Seo,
 	}
 
-	var parentCategory []dto.Category
 	if req.ParentId != uuid.Nil.String() && req.ParentId != "" {
-		err := u.db.DB.From("categories").
-			Select("*").
-			Eq("id", req.ParentId).
-			Execute(&parentCategory)
-
+		parentCategory, err := u.categories.Get(ctx, req.ParentId)
 		if err != nil {
 			log.Errorf("Parent category with ID %s does not exist: %v", req.ParentId, err)
 			return nil, err
 		}
 
 		createCategory.ParentId = req.ParentId
-		createCategory.Level = parentCategory[0].Level + 1 // Set child category level
+		createCategory.Level = parentCategory.Level + 1 // Set child category level
 	} else {
 		createCategory.ParentId = ""
 		createCategory.IsDisplayHeader = req.IsDisplayHeader
 	}
+	categories, err := u.categories.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// a new category goes after its siblings
+	createCategory.Position = len(newCategoryLayout(categories).children[createCategory.ParentId])
+	if createCategory.Slug, err = u.slugs.Unique(ctx, "categories", req.Slug, req.Name, ""); err != nil {
+		return nil, err
+	}
 
-	var category []dto.Category
-	err := u.db.DB.From("categories").Insert(createCategory).Execute(&category)
+	category, err := u.categories.Create(ctx, createCategory)
 	if err != nil {
 		log.Errorf("failed to insert category: %v", err)
 		return nil, err
 	}
 	log.Info("category created", category)
-	categoryResponse := dto.GetResponse(&category[0])
+	categoryResponse := dto.GetResponse(&category)
 	return api.Success(categoryResponse), nil
 }
 
 func (u *categoryService) ListCategories(ctx context.Context, req dto.ListCategory) (api.Response, error) {
-	// Step 1: Fetch all categories from Supabase
-	var categories []dto.Category
-	query := u.db.DB.From("categories").Select("*").OrderBy("created_at", "asc").IsNull("deleted_at")
-	if req.Name != "" {
-		encoded := url.QueryEscape("%" + req.Name + "%")
-		query = query.Like("name", encoded)
-	}
-	if req.IsDisplayHomepage {
-		query = query.Eq("is_display_homepage", "true")
-	}
-
-	if req.IsDisplayHeader {
-		log.Info("==========")
-		query = query.Eq("is_display_header", "true")
-	}
-
-	err := query.Execute(&categories)
+	// Step 1: Fetch all categories
+	categories, err := u.categories.List(ctx, req)
 	if err != nil {
 		log.Errorf("failed to fetch categories: %v", err)
 		return nil, err
@@ -144,22 +144,27 @@ func (u *categoryService) ListCategories(ctx context.Context, req dto.ListCatego
 	}
 
 	categoryResponses := BuildCategoryTree(categories)
-
-	if int(req.Limit) == 0 && int(req.Page) == 0 {
-		return api.Success(categoryResponses), nil
+	if req.Sort == "" {
+		// without an explicit sort the top level follows the menu order, and among
+		// categories placed alike the newest come first
+		sort.SliceStable(categoryResponses, func(i, j int) bool {
+			a, b := categoryResponses[i], categoryResponses[j]
+			if a.Position != b.Position {
+				return a.Position < b.Position
+			}
+			return a.CreatedAt.After(b.CreatedAt)
+		})
 	}
 
-	if int(req.Limit) >= len(categoryResponses) {
+	if int(req.Limit) == 0 && int(req.Page) == 0 {
 		return api.Success(categoryResponses), nil
 	}
 
-	if int(req.Limit*(req.Page-1)) > len(categoryResponses) {
-		return api.SuccessPagination(nil, &req.Pagination), nil
-	}
-
-	req.Pagination.Total = int64(len(categoryResponses))
-
-	categoryResponsesPage := categoryResponses[req.Limit*(req.Page-1) : req.Limit*req.Page]
+	req.Correct()
+	req.SetTotal(int64(len(categoryResponses)))
+	start := min(req.Offset(), len(categoryResponses))
+	end := min(start+int(req.Limit), len(categoryResponses))
+	categoryResponsesPage := categoryResponses[start:end]
 
 	return api.SuccessPagination(categoryResponsesPage, &req.Pagination), nil
 }
@@ -170,10 +175,12 @@ func buildCategoryResponse(category dto.Category) dto.CategoryListResponse {
 	return dto.CategoryListResponse{
 		Id:                category.Id,
 		Name:              category.Name,
+		Slug:              category.Slug,
 		Thumbnail:         category.Thumbnail,
 		Level:             category.Level,
 		Description:       category.Description,
 		ParentId:          category.ParentId,
+		Position:          category.Position,
 		Status:            enum.ToStatus(category.Status),
 		Metadata:          category.Metadata,
 		IsDisplayHomepage: category.IsDisplayHomepage,
@@ -185,93 +192,223 @@ func buildCategoryResponse(category dto.Category) dto.CategoryListResponse {
 
 func (u *categoryService) UpdateCategory(ctx context.Context, req dto.CategoryUpdate) (api.Response, error) {
 	// Check if the category exists
-	var existingCategory []dto.Category
-	err := u.db.DB.From("categories").Select("*").Eq("id", req.Id).Execute(&existingCategory)
+	current, err := u.categories.Get(ctx, req.Id)
 	if err != nil {
 		log.Errorf("Category with ID %s not found: %v", req.Id, err)
-		return nil, fmt.Errorf("category not found")
+		return nil, err
+	}
+	slug, err := nextSlug(ctx, u.slugs, "categories", req.Id, current.Slug, req.Slug, req.Name, req.Name != current.Name)
+	if err != nil {
+		return nil, err
 	}
 
 	updateData := map[string]interface{}{
 		"name":                req.Name,
+		"slug":                slug,
 		"thumbnail":           req.Thumbnail,
 		"status":              req.Status,
 		"metadata":            req.Metadata,
 		"description":         req.Description,
 		"is_display_homepage": req.IsDisplayHomepage,
+		"meta_title":          req.MetaTitle,
+		"meta_description":    req.MetaDescription,
+		"canonical_image":     req.CanonicalImage,
 		"updated_at":          time.Now(),
 	}
 
-	if req.ParentId != uuid.Nil.String() && req.ParentId != "" {
-		// Prepare updated fields
-		updateData = map[string]interface{}{
-			"name":                req.Name,
-			"thumbnail":           req.Thumbnail,
-			"status":              req.Status,
-			"metadata":            req.Metadata,
-			"description":         req.Description,
-			"is_display_homepage": req.IsDisplayHomepage,
-			"updated_at":          time.Now(),
-			"parent_id":           req.ParentId,
-		}
-	} else if req.IsDisplayHeader {
-		updateData = map[string]interface{}{
-			"name":                req.Name,
-			"thumbnail":           req.Thumbnail,
-			"status":              req.Status,
-			"metadata":            req.Metadata,
-			"description":         req.Description,
-			"is_display_homepage": req.IsDisplayHomepage,
-			"updated_at":          time.Now(),
-			"is_display_header":   req.IsDisplayHeader,
+	// only top-level categories show in the header
+	if (req.ParentId == uuid.Nil.String() || req.ParentId == "") && req.IsDisplayHeader {
+		updateData["is_display_header"] = req.IsDisplayHeader
+	}
+
+	// a new parent is a move to the end of its children, checked like any other move
+	// before anything is written
+	var layout *categoryLayout
+	if req.ParentId != uuid.Nil.String() && req.ParentId != "" && req.ParentId != current.ParentId {
+		if layout, err = u.layout(ctx, []dto.CategoryMove{{Id: req.Id, ParentId: req.ParentId, Position: math.MaxInt}}); err != nil {
+			return nil, err
 		}
 	}
 
 	// Perform the update
-	var updateCategory []dto.Category
-	err = u.db.DB.From("categories").Update(updateData).Eq("id", req.Id).Execute(&updateCategory)
+	updateCategory, err := u.categories.Update(ctx, req.Id, updateData)
 	if err != nil {
 		log.Errorf("Failed to update category %s: %v", req.Id, err)
-		return nil, fmt.Errorf("failed to update category")
+		return nil, err
+	}
+	if err := retireSlug(ctx, u.slugs, "categories", req.Id, current.Slug, slug); err != nil {
+		return nil, err
+	}
+
+	if layout != nil {
+		if err := u.applyLayout(ctx, layout); err != nil {
+			return nil, err
+		}
+		if updateCategory, err = u.categories.Get(ctx, req.Id); err != nil {
+			return nil, err
+		}
 	}
 
-	return api.Success(updateCategory[0]), nil
+	return api.Success(updateCategory), nil
 }
 
-func (u *categoryService) DeleteCategory(ctx context.Context, categoryId string) (api.Response, error) {
-	// Check if the category exists
-	var category []dto.Category
-	err := u.db.DB.From("categories").Select("*").Eq("id", categoryId).Execute(&category)
+func (u *categoryService) GetCategoryBySlug(ctx context.Context, slug string) (api.Response, error) {
+	id, current, err := u.slugs.Resolve(ctx, "categories", slug)
+	if err != nil {
+		return nil, err
+	}
+	if current != slug {
+		return api.Redirect(current), nil
+	}
+	return u.ListCategoryById(ctx, id)
+}
+
+func (u *categoryService) MoveCategory(ctx context.Context, req dto.CategoryMove) (api.Response, error) {
+	if err := u.rearrange(ctx, []dto.CategoryMove{req}); err != nil {
+		return nil, err
+	}
+	category, err := u.categories.Get(ctx, req.Id)
 	if err != nil {
-		log.Errorf("Category with ID %s not found: %v", categoryId, err)
-		return nil, fmt.Errorf("category not found")
+		return nil, err
 	}
+	return api.Success(category), nil
+}
 
-	// Check if the category has child categories
-	var childCategories []dto.Category
-	err = u.db.DB.From("categories").Select("id").Eq("parent_id", categoryId).IsNull("deleted_at").Execute(&childCategories)
+func (u *categoryService) ReorderCategories(ctx context.Context, req dto.CategoryReorder) (api.Response, error) {
+	moves := slices.Clone(req.Items)
+	slices.SortStableFunc(moves, func(a, b dto.CategoryMove) int { return a.Position - b.Position })
+	if err := u.rearrange(ctx, moves); err != nil {
+		return nil, err
+	}
+	categories, err := u.categories.All(ctx)
 	if err != nil {
-		log.Errorf("Failed to check child categories for %s: %v", categoryId, err)
-		return nil, fmt.Errorf("failed to verify child categories")
+		return nil, err
 	}
+	return api.Success(BuildCategoryTree(categories)), nil
+}
 
-	if len(childCategories) > 0 {
-		log.Errorf("Cannot delete category %s as it has child categories", categoryId)
-		return nil, fmt.Errorf("category has child categories and cannot be deleted")
+// rearrange applies the moves to the current tree, and only writes anything once all
+// of them turned out valid.
+func (u *categoryService) rearrange(ctx context.Context, moves []dto.CategoryMove) error {
+	layout, err := u.layout(ctx, moves)
+	if err != nil {
+		return err
 	}
+	return u.applyLayout(ctx, layout)
+}
 
-	updateData := map[string]interface{}{
-		"deleted_at": time.Now(),
+// layout makes the moves on the current tree without writing them.
+func (u *categoryService) layout(ctx context.Context, moves []dto.CategoryMove) (*categoryLayout, error) {
+	categories, err := u.categories.All(ctx)
+	if err != nil {
+		return nil, err
 	}
-	// Delete the category
-	err = u.db.DB.From("categories").Update(updateData).Eq("id", categoryId).Execute(&category)
+	layout := newCategoryLayout(categories)
+	for _, m := range moves {
+		if err := layout.move(m); err != nil {
+			return nil, err
+		}
+	}
+	return layout, nil
+}
+
+func (u *categoryService) applyLayout(ctx context.Context, layout *categoryLayout) error {
+	for id, patch := range layout.changes() {
+		if _, err := u.categories.Update(ctx, id, patch); err != nil {
+			log.Errorf("Failed to rearrange category %s: %v", id, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *categoryService) DeleteCategory(ctx context.Context, req dto.CategoryDelete) (api.Response, error) {
+	mode := req.Mode
+	if mode == "" {
+		mode = enum.CategoryDeleteRestrict
+	}
+	if !mode.IsValid() {
+		return nil, errors.BadRequest("delete mode %q is not one of restrict, cascade or reassign", req.Mode)
+	}
+	// Check if the category exists
+	if _, err := u.categories.Get(ctx, req.Id); err != nil {
+		log.Errorf("Category with ID %s not found: %v", req.Id, err)
+		return nil, err
+	}
+	categories, err := u.categories.All(ctx)
 	if err != nil {
-		log.Errorf("Failed to delete category %s: %v", categoryId, err)
-		return nil, fmt.Errorf("failed to delete category")
+		return nil, err
+	}
+	layout := newCategoryLayout(categories)
+	children := layout.children[req.Id]
+	deleted := []string{req.Id}
+	result := dto.CategoryDeleteResult{Mode: mode, DryRun: req.DryRun, DeletedCategories: 1}
+
+	switch mode {
+	case enum.CategoryDeleteRestrict:
+		products, err := u.products.CountInCategories(ctx, deleted)
+		if err != nil {
+			return nil, err
+		}
+		if len(children) > 0 || products > 0 {
+			return nil, errors.Conflict("category has %d subcategories and %d products, delete it with mode cascade or reassign", len(children), products).WithCode("category_not_empty")
+		}
+	case enum.CategoryDeleteCascade:
+		deleted = layout.subtree(req.Id)
+		if result.DeletedProducts, err = u.products.CountInCategories(ctx, deleted); err != nil {
+			return nil, err
+		}
+		result.DeletedCategories = len(deleted)
+	case enum.CategoryDeleteReassign:
+		if req.TargetId == "" {
+			return nil, errors.BadRequest("target_id is required to reassign the subcategories and products")
+		}
+		if _, ok := layout.byId[req.TargetId]; !ok {
+			return nil, errors.NotFound("category %s not found", req.TargetId)
+		}
+		if slices.Contains(layout.subtree(req.Id), req.TargetId) {
+			return nil, errors.Unprocessable("category %s is deleted with %s and cannot take over its subcategories and products", req.TargetId, req.Id).WithCode("category_cycle")
+		}
+		if result.MovedProducts, err = u.products.CountInCategories(ctx, deleted); err != nil {
+			return nil, err
+		}
+		result.MovedCategories, result.TargetId = len(children), req.TargetId
+	}
+	if req.DryRun {
+		return api.Success(result), nil
 	}
 
-	log.Infof("Category %s deleted successfully", categoryId)
-	return api.Success("Category deleted successfully"), nil
+	// the products and subcategories are taken care of first, so a failure halfway
+	// leaves nothing pointing at a deleted category
+	if mode == enum.CategoryDeleteReassign {
+		if err := u.products.MoveToCategory(ctx, deleted, req.TargetId); err != nil {
+			return nil, err
+		}
+		moves := make([]dto.CategoryMove, 0, len(children))
+		for _, id := range children {
+			moves = append(moves, dto.CategoryMove{Id: id, ParentId: req.TargetId, Position: math.MaxInt})
+		}
+		if err := u.rearrange(ctx, moves); err != nil {
+			return nil, err
+		}
+	}
+	if mode == enum.CategoryDeleteCascade {
+		if err := u.products.SoftDeleteInCategories(ctx, deleted); err != nil {
+			return nil, err
+		}
+	}
+	// children are deleted before their parents
+	slices.Reverse(deleted)
+	if err := u.categories.SoftDeleteMany(ctx, deleted); err != nil {
+		log.Errorf("Failed to delete category %s: %v", req.Id, err)
+		return nil, err
+	}
+	if result.DeletedProducts > 0 || result.MovedProducts > 0 {
+		u.searcher.Invalidate()
+	}
+
+	log.Infof("Category %s deleted in %s mode", req.Id, mode)
+	return api.Success(result), nil
 }
 
 type node struct {
@@ -279,7 +416,12 @@ type node struct {
 	kids                      []*node
 }
 
+// BuildCategoryTree nests the categories under their parents; roots and children are
+// ordered by position and otherwise keep the order in which they were given.
 func BuildCategoryTree(categories []dto.Category) []dto.CategoryListResponse {
+	categories = slices.Clone(categories)
+	slices.SortStableFunc(categories, func(a, b dto.Category) int { return a.Position - b.Position })
+
 	var nilID = uuid.Nil.String()
 	var cateIds []string
 
@@ -296,7 +438,8 @@ func BuildCategoryTree(categories []dto.Category) []dto.CategoryListResponse {
 	// ----- Phase 2: wire children -------------------------------------------
 	var roots []*node
 
-	for _, n := range nodes {
+	for _, c := range categories {
+		n := nodes[c.Id]
 		pid := n.ParentId
 		if pid == "" || pid == nilID || !lo.Contains(cateIds, pid) {
 			roots = append(roots, n)
@@ -307,10 +450,6 @@ func BuildCategoryTree(categories []dto.Category) []dto.CategoryListResponse {
 		}
 	}
 
-	sort.SliceStable(roots, func(i, j int) bool {
-		return roots[i].CreatedAt.After(roots[j].CreatedAt)
-	})
-
 	// ----- Phase 3: deep‑clone to DTO values --------------------------------
 	var result []dto.CategoryListResponse
 	for _, r := range roots {
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"SangXanh/pkg/repository"
+	"context"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newCategoryTest(t *testing.T) (CategoryService, repository.CategoryRepository) {
+	categories, repo, _ := newCategoryStoreTest(t)
+	return categories, repo
+}
+
+func newCategoryStoreTest(t *testing.T) (CategoryService, repository.CategoryRepository, *repository.MemoryStore) {
+	di := do.New()
+	store := repository.InjectMemory(di)
+	do.Provide(di, NewCategoryService)
+	store.Seed("categories",
+		map[string]interface{}{"id": "plants", "name": "Cây", "level": 0, "position": 0},
+		map[string]interface{}{"id": "indoor", "name": "Trong nhà", "parent_id": "plants", "level": 1, "position": 0},
+		map[string]interface{}{"id": "succulents", "name": "Sen đá", "parent_id": "indoor", "level": 2, "position": 0},
+		map[string]interface{}{"id": "outdoor", "name": "Ngoài trời", "parent_id": "plants", "level": 1, "position": 1},
+		map[string]interface{}{"id": "pots", "name": "Chậu", "level": 0, "position": 1},
+	)
+	store.Seed("products",
+		map[string]interface{}{"id": "aloe", "name": "Nha đam", "category_id": "succulents"},
+		map[string]interface{}{"id": "fern", "name": "Dương xỉ", "category_id": "indoor"},
+		map[string]interface{}{"id": "old", "name": "Cũ", "category_id": "indoor", "deleted_at": "2025-03-01T00:00:00Z"},
+	)
+	categories, err := do.Invoke[CategoryService](di)
+	require.NoError(t, err)
+	return categories, do.MustInvoke[repository.CategoryRepository](di), store
+}
+
+func TestMoveCategory(t *testing.T) {
+	categories, repo := newCategoryTest(t)
+	ctx := context.Background()
+
+	_, err := categories.MoveCategory(ctx, dto.CategoryMove{Id: "plants", ParentId: "succulents"})
+	var httpErr errors.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, "category_cycle", httpErr.ErrorCode())
+	_, err = categories.MoveCategory(ctx, dto.CategoryMove{Id: "indoor", ParentId: "indoor"})
+	assert.Error(t, err, "a category cannot become its own parent")
+
+	// the subtree goes along and every level below it is recomputed
+	_, err = categories.MoveCategory(ctx, dto.CategoryMove{Id: "indoor", ParentId: "pots", Position: 5})
+	require.NoError(t, err)
+	indoor, _ := repo.Get(ctx, "indoor")
+	succulents, _ := repo.Get(ctx, "succulents")
+	outdoor, _ := repo.Get(ctx, "outdoor")
+	assert.Equal(t, []any{"pots", 1, 0}, []any{indoor.ParentId, indoor.Level, indoor.Position})
+	assert.Equal(t, 2, succulents.Level)
+	assert.Equal(t, 0, outdoor.Position, "the siblings left behind close the gap")
+
+	_, err = categories.MoveCategory(ctx, dto.CategoryMove{Id: "indoor"})
+	require.NoError(t, err)
+	indoor, _ = repo.Get(ctx, "indoor")
+	succulents, _ = repo.Get(ctx, "succulents")
+	assert.Equal(t, []any{"", 0, 0}, []any{indoor.ParentId, indoor.Level, indoor.Position})
+	assert.Equal(t, 1, succulents.Level)
+}
+
+func TestReorderCategories(t *testing.T) {
+	categories, _ := newCategoryTest(t)
+	ctx := context.Background()
+
+	resp, err := categories.ReorderCategories(ctx, dto.CategoryReorder{Items: []dto.CategoryMove{
+		{Id: "pots", Position: 0},
+		{Id: "outdoor", ParentId: "plants", Position: 0},
+	}})
+	require.NoError(t, err)
+	var tree []dto.CategoryListResponse
+	responseData(t, resp, &tree)
+	require.Len(t, tree, 2)
+	assert.Equal(t, []string{"pots", "plants"}, []string{tree[0].Id, tree[1].Id})
+	require.Len(t, tree[1].Categories, 2)
+	assert.Equal(t, []string{"outdoor", "indoor"}, []string{tree[1].Categories[0].Id, tree[1].Categories[1].Id})
+	assert.Equal(t, 1, tree[1].Position)
+
+	_, err = categories.ReorderCategories(ctx, dto.CategoryReorder{Items: []dto.CategoryMove{
+		{Id: "pots", Position: 1},
+		{Id: "plants", ParentId: "succulents"},
+	}})
+	assert.Error(t, err)
+	listed, _ := categories.ListCategories(ctx, dto.ListCategory{})
+	responseData(t, listed, &tree)
+	assert.Equal(t, "pots", tree[0].Id, "nothing is written when one move fails")
+}
+
+func TestDeleteCategory(t *testing.T) {
+	categories, repo, store := newCategoryStoreTest(t)
+	ctx := context.Background()
+	productCategory := func(id string) interface{} {
+		for _, row := range store.Rows("products") {
+			if row["id"] == id {
+				return row["category_id"]
+			}
+		}
+		return nil
+	}
+
+	_, err := categories.DeleteCategory(ctx, dto.CategoryDelete{Id: "indoor"})
+	var httpErr errors.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, "category_not_empty", httpErr.ErrorCode())
+	_, err = categories.DeleteCategory(ctx, dto.CategoryDelete{Id: "indoor", Mode: "purge"})
+	assert.Error(t, err)
+	_, err = categories.DeleteCategory(ctx, dto.CategoryDelete{Id: "indoor", Mode: enum.CategoryDeleteReassign, TargetId: "succulents"})
+	assert.Error(t, err, "the target cannot be deleted along")
+
+	resp, err := categories.DeleteCategory(ctx, dto.CategoryDelete{Id: "plants", Mode: enum.CategoryDeleteCascade, DryRun: true})
+	require.NoError(t, err)
+	var result dto.CategoryDeleteResult
+	responseData(t, resp, &result)
+	assert.Equal(t, dto.CategoryDeleteResult{Mode: enum.CategoryDeleteCascade, DryRun: true, DeletedCategories: 4, DeletedProducts: 2}, result)
+	_, err = repo.Get(ctx, "plants")
+	assert.NoError(t, err, "a dry run writes nothing")
+
+	resp, err = categories.DeleteCategory(ctx, dto.CategoryDelete{Id: "indoor", Mode: enum.CategoryDeleteReassign, TargetId: "pots"})
+	require.NoError(t, err)
+	responseData(t, resp, &result)
+	assert.Equal(t, dto.CategoryDeleteResult{Mode: enum.CategoryDeleteReassign, DeletedCategories: 1, MovedCategories: 1, MovedProducts: 1, TargetId: "pots"}, result)
+	_, err = repo.Get(ctx, "indoor")
+	assert.Error(t, err)
+	succulents, _ := repo.Get(ctx, "succulents")
+	assert.Equal(t, []any{"pots", 1}, []any{succulents.ParentId, succulents.Level})
+	assert.Equal(t, "pots", productCategory("fern"))
+	assert.Equal(t, "indoor", productCategory("old"), "deleted products stay where they were")
+
+	_, err = categories.DeleteCategory(ctx, dto.CategoryDelete{Id: "pots", Mode: enum.CategoryDeleteCascade})
+	require.NoError(t, err)
+	_, err = repo.Get(ctx, "succulents")
+	assert.Error(t, err)
+	live, _ := store.Count(ctx, "products", repository.Where(repository.IsNull("deleted_at")))
+	assert.Zero(t, live, "the products of the subtree go along")
+}
+
+func TestCategorySlugRedirect(t *testing.T) {
+	categories, repo := newCategoryTest(t)
+	ctx := context.Background()
+
+	resp, err := categories.CreateCategory(ctx, dto.CategoryCreate{Name: "Hạt giống", Description: "Hạt giống rau, hoa và cây ăn trái."})
+	require.NoError(t, err)
+	var created dto.CategoryResponse
+	responseData(t, resp, &created)
+	assert.Equal(t, "hat-giong", created.Slug)
+	assert.Equal(t, dto.Seo{MetaTitle: "Hạt giống", MetaDescription: "Hạt giống rau, hoa và cây ăn trái."}, created.Seo)
+
+	_, err = categories.UpdateCategory(ctx, dto.CategoryUpdate{Id: created.Id, Name: "Hạt giống rau"})
+	require.NoError(t, err)
+	updated, _ := repo.Get(ctx, created.Id)
+	assert.Equal(t, "hat-giong-rau", updated.Slug)
+
+	resp, err = categories.GetCategoryBySlug(ctx, "hat-giong")
+	require.NoError(t, err)
+	assert.Equal(t, api.Redirect("hat-giong-rau"), resp)
+	resp, err = categories.GetCategoryBySlug(ctx, "hat-giong-rau")
+	require.NoError(t, err)
+	var found dto.CategoryResponse
+	responseData(t, resp, &found)
+	assert.Equal(t, created.Id, found.Id)
+	assert.Equal(t, "hat-giong-rau", found.Slug)
+	assert.Equal(t, dto.Seo{MetaTitle: "Hạt giống rau"}, found.Seo)
+}
+
+func TestUpdateCategoryParent(t *testing.T) {
+	categories, repo := newCategoryTest(t)
+	ctx := context.Background()
+
+	_, err := categories.UpdateCategory(ctx, dto.CategoryUpdate{Id: "plants", Name: "Cây cảnh", ParentId: "succulents"})
+	var httpErr errors.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, "category_cycle", httpErr.ErrorCode())
+	plants, _ := repo.Get(ctx, "plants")
+	assert.Equal(t, "Cây", plants.Name, "nothing is written when the move is refused")
+
+	_, err = categories.UpdateCategory(ctx, dto.CategoryUpdate{Id: "outdoor", Name: "Sân vườn", ParentId: "pots"})
+	require.NoError(t, err)
+	outdoor, _ := repo.Get(ctx, "outdoor")
+	assert.Equal(t, []any{"Sân vườn", "pots", 1}, []any{outdoor.Name, outdoor.ParentId, outdoor.Level})
+}
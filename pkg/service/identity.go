@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/nedpals/supabase-go"
+	"github.com/samber/do/v2"
+)
+
+// IdentityProvider keeps the accounts themselves: passwords, tokens and sign-in
+// links. It is the part of the Supabase Auth client the services use, so tests can
+// provide their own.
+type IdentityProvider interface {
+	SignUp(ctx context.Context, credentials supabase.UserCredentials) (*supabase.User, error)
+	SignIn(ctx context.Context, credentials supabase.UserCredentials) (*supabase.AuthenticatedDetails, error)
+	RefreshUser(ctx context.Context, userToken string, refreshToken string) (*supabase.AuthenticatedDetails, error)
+	UpdateUser(ctx context.Context, userToken string, updateData map[string]interface{}) (*supabase.User, error)
+	SendMagicLink(ctx context.Context, email string) error
+	SignOut(ctx context.Context, userToken string) error
+}
+
+func NewSupabaseIdentity(di do.Injector) (IdentityProvider, error) {
+	db, err := do.Invoke[*supabase.Client](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize IdentityProvider: %w", err)
+	}
+	return db.Auth, nil
+}
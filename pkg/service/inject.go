@@ -3,13 +3,20 @@ package service
 import "github.com/samber/do/v2"
 
 func Inject(di do.Injector) {
-	do.Provide(di, NewUserService)
-	do.Provide(di, NewCategoryService)
-	do.Provide(di, NewProductService)
+	do.Provide(di, NewSupabaseIdentity)
+	do.Provide(di, NewAuditService)
+	do.Provide(di, withAudit(NewUserService, auditUserService))
+	do.Provide(di, withAudit(NewCategoryService, auditCategoryService))
+	do.Provide(di, withAudit(NewProductService, auditProductService))
 	do.Provide(di, NewProductVariantService)
 	do.Provide(di, NewProductOptionService)
 	do.Provide(di, NewImageService)
 	do.Provide(di, NewAuthService)
+	do.Provide(di, NewSessionService)
 	do.Provide(di, NewCartService)
-	do.Provide(di, NewOrderService)
+	do.Provide(di, withAudit(NewOrderService, auditOrderService))
+	do.Provide(di, NewInventoryService)
+	do.Provide(di, NewPromotionService)
+	do.Provide(di, NewPaymentService)
+	do.Provide(di, NewPostService)
 }
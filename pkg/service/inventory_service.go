@@ -0,0 +1,173 @@
+package service
+
+import (
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"SangXanh/pkg/log"
+	"SangXanh/pkg/repository"
+	"SangXanh/pkg/ws"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+)
+
+const (
+	defaultLowStockThreshold = 5
+	maxStockRetries          = 3
+)
+
+type InventoryService interface {
+	CheckAvailable(ctx context.Context, productOptionId string, quantity int) error
+	Reserve(ctx context.Context, lines []dto.StockLine) error
+	Release(ctx context.Context, lines []dto.StockLine) error
+	// Adjust changes the stock of an option by hand, without losing the reservations
+	// made in the meantime.
+	Adjust(ctx context.Context, req dto.StockAdjust) (api.Response, error)
+	ListLowStock(ctx context.Context, filter dto.LowStockFilter) (api.Response, error)
+}
+
+type inventoryService struct {
+	options repository.ProductOptionRepository
+	events  ws.Publisher
+}
+
+func NewInventoryService(di do.Injector) (InventoryService, error) {
+	options, err := do.Invoke[repository.ProductOptionRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize InventoryService: %w", err)
+	}
+	hub, err := do.Invoke[*ws.Hub](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize InventoryService: %w", err)
+	}
+	return &inventoryService{options: options, events: hub}, nil
+}
+
+// merge lines of the same option so every option is adjusted exactly once
+func groupStockLines(lines []dto.StockLine) []dto.StockLine {
+	index := make(map[string]int, len(lines))
+	var out []dto.StockLine
+	for _, l := range lines {
+		if i, ok := index[l.ProductOptionId]; ok {
+			out[i].Quantity += l.Quantity
+			continue
+		}
+		index[l.ProductOptionId] = len(out)
+		out = append(out, l)
+	}
+	return out
+}
+
+func (s *inventoryService) CheckAvailable(ctx context.Context, productOptionId string, quantity int) error {
+	if quantity <= 0 {
+		return errors.BadRequest("quantity must be greater than 0")
+	}
+	option, err := s.findOption(ctx, productOptionId, false)
+	if err != nil {
+		return err
+	}
+	if option.Stock < quantity {
+		return errors.Unprocessable("not enough stock for product option %s: %d left", productOptionId, option.Stock).WithCode("insufficient_stock")
+	}
+	return nil
+}
+
+// Reserve takes stock for every line, or for none of them if any line cannot be served.
+func (s *inventoryService) Reserve(ctx context.Context, lines []dto.StockLine) error {
+	grouped := groupStockLines(lines)
+	for i, l := range grouped {
+		if err := s.adjust(ctx, l.ProductOptionId, -l.Quantity, true); err != nil {
+			if rbErr := s.Release(ctx, grouped[:i]); rbErr != nil {
+				log.Errorf("failed to release stock after reservation failure: %v", rbErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Release hands reserved stock back, also for options that were deleted in the meantime.
+func (s *inventoryService) Release(ctx context.Context, lines []dto.StockLine) error {
+	for _, l := range groupStockLines(lines) {
+		if err := s.adjust(ctx, l.ProductOptionId, l.Quantity, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *inventoryService) Adjust(ctx context.Context, req dto.StockAdjust) (api.Response, error) {
+	if req.Delta == 0 {
+		return nil, errors.BadRequest("delta must not be 0")
+	}
+	if err := s.adjust(ctx, req.ProductOptionId, req.Delta, true); err != nil {
+		return nil, err
+	}
+	option, err := s.findOption(ctx, req.ProductOptionId, false)
+	if err != nil {
+		return nil, err
+	}
+	return api.Success(option), nil
+}
+
+// findOption loads an option for a stock change; a missing option is reported the same
+// way as missing stock, since both mean the line cannot be served.
+func (s *inventoryService) findOption(ctx context.Context, productOptionId string, withDeleted bool) (dto.ProductOption, error) {
+	option, err := s.options.Get(ctx, productOptionId, withDeleted)
+	var notFound *errors.NotFoundError
+	if errors.As(err, &notFound) {
+		return option, errors.Unprocessable("product option %s not found or deleted", productOptionId).WithCode("product_option_not_found")
+	}
+	return option, err
+}
+
+// adjust changes the stock of one option by delta. The update is guarded on the stock
+// value that was read, so concurrent orders cannot both take the last item.
+func (s *inventoryService) adjust(ctx context.Context, productOptionId string, delta int, activeOnly bool) error {
+	for attempt := 0; attempt < maxStockRetries; attempt++ {
+		option, err := s.findOption(ctx, productOptionId, !activeOnly)
+		if err != nil {
+			return err
+		}
+
+		current := option.Stock
+		next := current + delta
+		if next < 0 {
+			return errors.Unprocessable("not enough stock for product option %s: %d left", productOptionId, current).WithCode("insufficient_stock")
+		}
+
+		updated, err := s.options.SetStock(ctx, productOptionId, current, next)
+		if err != nil {
+			return err
+		}
+		if updated {
+			// warn once, when the stock drops to the threshold, not on every later sale
+			if current > defaultLowStockThreshold && next <= defaultLowStockThreshold {
+				s.events.Publish(enum.StockLowEvent, dto.StockEvent{
+					ProductOptionId: productOptionId,
+					Stock:           next,
+				}, ws.AdminRoom)
+			}
+			return nil
+		}
+	}
+	return errors.Conflict("stock of product option %s is changing too fast, please retry", productOptionId)
+}
+
+func (s *inventoryService) ListLowStock(ctx context.Context, filter dto.LowStockFilter) (api.Response, error) {
+	filter.Correct()
+	threshold := filter.Threshold
+	if threshold <= 0 {
+		threshold = defaultLowStockThreshold
+	}
+
+	items, total, err := s.options.LowStock(ctx, threshold, int(filter.Limit), filter.Offset())
+	if err != nil {
+		return nil, err
+	}
+
+	filter.SetTotal(int64(total))
+	return api.SuccessPagination(items, &filter.Pagination), nil
+}
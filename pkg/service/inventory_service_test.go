@@ -0,0 +1,81 @@
+package service
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/repository"
+	"SangXanh/pkg/ws"
+	"context"
+	"encoding/json"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAdjustStock(t *testing.T) {
+	di := do.New()
+	store := repository.InjectMemory(di)
+	do.ProvideValue(di, ws.New())
+	do.Provide(di, NewInventoryService)
+	do.Provide(di, NewProductOptionService)
+	store.Seed("products", map[string]interface{}{"id": "p1", "name": "Lúa giống"})
+	store.Seed("product_options", map[string]interface{}{"id": "o1", "product_id": "p1", "name": "5kg", "price": 20, "stock": 7})
+	inventory := do.MustInvoke[InventoryService](di)
+	options := do.MustInvoke[ProductOptionService](di)
+	ctx := context.Background()
+
+	// an order reserves stock while an admin has the option open for editing
+	require.NoError(t, inventory.Reserve(ctx, []dto.StockLine{{ProductOptionId: "o1", Quantity: 2}}))
+	_, err := options.UpdateProductOption(ctx, dto.ProductOptionUpdate{Id: "o1", ProductId: "p1", Name: "5kg", Price: 25, Stock: 7})
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, optionStock(store, "o1"), "editing an option keeps the stock")
+
+	_, err = inventory.Adjust(ctx, dto.StockAdjust{ProductOptionId: "o1", Delta: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 15.0, optionStock(store, "o1"))
+
+	_, err = inventory.Adjust(ctx, dto.StockAdjust{ProductOptionId: "o1", Delta: -20})
+	var httpErr errors.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, "insufficient_stock", httpErr.ErrorCode())
+	assert.Equal(t, 15.0, optionStock(store, "o1"))
+}
+
+func TestListLowStock(t *testing.T) {
+	di := do.New()
+	store := repository.InjectMemory(di)
+	do.ProvideValue(di, ws.New())
+	do.Provide(di, NewInventoryService)
+	store.Seed("products",
+		map[string]interface{}{"id": "p1", "name": "Lúa giống"},
+		map[string]interface{}{"id": "p2", "name": "Phân bón", "deleted_at": "2026-10-01T00:00:00Z"},
+	)
+	store.Seed("product_options",
+		map[string]interface{}{"id": "o1", "product_id": "p1", "name": "5kg", "stock": 3},
+		map[string]interface{}{"id": "o2", "product_id": "p1", "name": "10kg", "stock": 1},
+		map[string]interface{}{"id": "o3", "product_id": "p1", "name": "20kg", "stock": 9},
+		map[string]interface{}{"id": "o4", "product_id": "p1", "name": "50kg", "stock": 0, "deleted_at": "2026-10-01T00:00:00Z"},
+		map[string]interface{}{"id": "o5", "product_id": "p2", "name": "1kg", "stock": 0},
+	)
+	inventory := do.MustInvoke[InventoryService](di)
+
+	filter := dto.LowStockFilter{}
+	filter.Page, filter.Limit = 2, 1
+	resp, err := inventory.ListLowStock(context.Background(), filter)
+	require.NoError(t, err)
+	var page struct {
+		Meta struct {
+			Total int64 `json:"total"`
+		} `json:"meta"`
+		Data []dto.LowStockItem `json:"data"`
+	}
+	raw, err := json.Marshal(resp)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(raw, &page))
+	// options of deleted products are left out of both the page and the total
+	assert.Equal(t, int64(2), page.Meta.Total)
+	require.Len(t, page.Data, 1)
+	assert.Equal(t, "o1", page.Data[0].Id)
+	assert.Equal(t, "Lúa giống", page.Data[0].Product.Name)
+}
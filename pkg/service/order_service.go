@@ -1,13 +1,19 @@
 package service
 
 import (
+	"SangXanh/pkg/auth"
 	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
 	"SangXanh/pkg/dto"
 	"SangXanh/pkg/enum"
+	"SangXanh/pkg/log"
+	"SangXanh/pkg/permission"
+	"SangXanh/pkg/repository"
+	"SangXanh/pkg/ws"
 	"context"
 	"fmt"
-	"github.com/nedpals/supabase-go"
 	"github.com/samber/do/v2"
+	"math"
 	"time"
 )
 
@@ -15,74 +21,209 @@ type OrderService interface {
 	ListOrders(ctx context.Context, filter dto.OrderListFilter) (api.Response, error)
 	GetOrderById(ctx context.Context, id string) (api.Response, error)
 	CreateOrder(ctx context.Context, req dto.OrderCreate) (api.Response, error)
+	PlaceOrder(ctx context.Context, req dto.OrderCreate) (string, error)
+	DiscardOrder(ctx context.Context, id string) error
 	UpdateOrder(ctx context.Context, req dto.OrderUpdate) (api.Response, error)
 	DeleteOrder(ctx context.Context, id string) (api.Response, error)
-	UpdateOrderStatus(ctx context.Context, id string, status enum.OrderStatus) (api.Response, error)
+	UpdateOrderStatus(ctx context.Context, req dto.OrderStatusUpdate) (api.Response, error)
+	GetOrderHistory(ctx context.Context, id string) (api.Response, error)
 }
 
 type orderService struct {
-	db *supabase.Client
+	orders     repository.OrderRepository
+	options    repository.ProductOptionRepository
+	inventory  InventoryService
+	promotions PromotionService
+	payments   repository.PaymentRepository
+	events     ws.Publisher
 }
 
 func NewOrderService(di do.Injector) (OrderService, error) {
-	db, err := do.Invoke[*supabase.Client](di)
+	orders, err := do.Invoke[repository.OrderRepository](di)
 	if err != nil {
 		return nil, fmt.Errorf("failed to init OrderService: %w", err)
 	}
-	return &orderService{db: db}, nil
+	options, err := do.Invoke[repository.ProductOptionRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init OrderService: %w", err)
+	}
+	inventory, err := do.Invoke[InventoryService](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init OrderService: %w", err)
+	}
+	promotions, err := do.Invoke[PromotionService](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init OrderService: %w", err)
+	}
+	payments, err := do.Invoke[repository.PaymentRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init OrderService: %w", err)
+	}
+	hub, err := do.Invoke[*ws.Hub](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init OrderService: %w", err)
+	}
+	return &orderService{orders: orders, options: options, inventory: inventory, promotions: promotions, payments: payments, events: hub}, nil
 }
 
 /* ------------------------------------------------------------------
    Helpers
    ------------------------------------------------------------------*/
 
-func (s *orderService) countOrders(ctx context.Context, filter dto.OrderListFilter) (int, error) {
-	userId := ctx.Value("user_id")
-	userRole := ctx.Value("user_role")
-	q := s.db.DB.
-		From("orders").
-		Select("*").
-		LimitWithOffset(int(filter.Limit), int((filter.Page-1)*filter.Limit)).
-		IsNull("deleted_at")
-
-	if filter.Status != "" {
-		q = q.Eq("status", string(filter.Status))
+// scopeOrders decides whose orders a caller may list: whoever may read any order may
+// pick a user, everyone else sees their own orders.
+func scopeOrders(ctx context.Context, filter dto.OrderListFilter) (dto.OrderListFilter, error) {
+	scope, err := permission.Check(ctx, permission.Orders, permission.Read)
+	if err != nil {
+		return filter, err
+	}
+	if scope == permission.Own || filter.UserId == "" {
+		caller, _ := auth.FromContext(ctx)
+		filter.UserId = caller.Id
 	}
-	if userRole == enum.Admin && filter.UserId != "" {
-		q = q.Eq("user_id", filter.UserId)
+	return filter, nil
+}
+
+// pricedLine is an order line with its price and discount resolved from the catalogue
+type pricedLine struct {
+	dto.OrderDetailBase
+	ProductId      string
+	CategoryId     string
+	UnitPrice      float64
+	Discount       float64
+	DiscountType   enum.DiscountType
+	DiscountAmount float64
+	LineTotal      float64
+}
+
+type orderTotals struct {
+	Subtotal      float64
+	DiscountTotal float64
+	GrandTotal    float64
+}
+
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// priceOrderLines makes sure every option referenced in an order still exists and
+// snapshots its current price and product discount. Nothing price related is taken
+// from the request.
+func priceOrderLines(ctx context.Context, options repository.ProductOptionRepository, details []dto.OrderDetailBase) ([]pricedLine, orderTotals, error) {
+	var totals orderTotals
+	if len(details) == 0 {
+		return nil, totals, errors.BadRequest("order must contain at least one product option")
 	}
 
-	if userRole == enum.User || (userRole == enum.Admin && filter.UserId == "") {
-		q = q.Eq("user_id", userId.(string))
+	var ids []string
+	for _, od := range details {
+		if od.Quantity <= 0 {
+			return nil, totals, errors.BadRequest("quantity of product option %s must be greater than 0", od.ProductOptionId)
+		}
+		ids = append(ids, od.ProductOptionId)
 	}
 
-	var tmp []struct{}
-	if err := q.Execute(&tmp); err != nil {
-		return 0, fmt.Errorf("failed to count orders: %w", err)
+	found, err := options.Prices(ctx, ids)
+	if err != nil {
+		return nil, totals, fmt.Errorf("failed to validate product options: %w", err)
+	}
+
+	byId := make(map[string]int, len(found))
+	for i, f := range found {
+		byId[f.Id] = i
+	}
+
+	lines := make([]pricedLine, 0, len(details))
+	for _, od := range details {
+		i, ok := byId[od.ProductOptionId]
+		if !ok {
+			return nil, totals, errors.Unprocessable("product option %s not found or deleted", od.ProductOptionId).WithCode("product_option_not_found")
+		}
+		opt := found[i]
+
+		// same rule as the product page: option price is added on top of the product base price
+		unitPrice := roundMoney(opt.ProductPrice + opt.Price)
+		unitDiscount := opt.DiscountType.Amount(unitPrice, opt.Discount)
+		line := pricedLine{
+			OrderDetailBase: od,
+			ProductId:       opt.ProductId,
+			CategoryId:      opt.CategoryId,
+			UnitPrice:       unitPrice,
+			Discount:        opt.Discount,
+			DiscountType:    opt.DiscountType,
+			DiscountAmount:  roundMoney(unitDiscount * float64(od.Quantity)),
+		}
+		line.LineTotal = roundMoney(unitPrice*float64(od.Quantity) - line.DiscountAmount)
+
+		totals.Subtotal += unitPrice * float64(od.Quantity)
+		totals.DiscountTotal += line.DiscountAmount
+		lines = append(lines, line)
+	}
+
+	totals.Subtotal = roundMoney(totals.Subtotal)
+	totals.DiscountTotal = roundMoney(totals.DiscountTotal)
+	totals.GrandTotal = roundMoney(totals.Subtotal - totals.DiscountTotal)
+	return lines, totals, nil
+}
+
+func orderDetailRows(orderId string, lines []pricedLine) []map[string]interface{} {
+	rows := make([]map[string]interface{}, 0, len(lines))
+	for _, l := range lines {
+		rows = append(rows, map[string]interface{}{
+			"order_id":          orderId,
+			"product_option_id": l.ProductOptionId,
+			"quantity":          l.Quantity,
+			"unit_price":        l.UnitPrice,
+			"discount":          l.Discount,
+			"discount_type":     l.DiscountType,
+			"discount_amount":   l.DiscountAmount,
+			"line_total":        l.LineTotal,
+			"metadata":          l.Metadata,
+		})
 	}
-	return len(tmp), nil
+	return rows
 }
 
-// make sure every option referenced in an order still exists
-func (s *orderService) validateOptionIds(ids []string) error {
-	if len(ids) == 0 {
-		return fmt.Errorf("order must contain at least one product option")
+func pricedPromotionLines(lines []pricedLine) []dto.PromotionLine {
+	out := make([]dto.PromotionLine, 0, len(lines))
+	for _, l := range lines {
+		out = append(out, dto.PromotionLine{ProductId: l.ProductId, CategoryId: l.CategoryId, LineTotal: l.LineTotal})
 	}
-	var found []struct {
-		Id string `json:"id"`
+	return out
+}
+
+func pricedStockLines(lines []pricedLine) []dto.StockLine {
+	out := make([]dto.StockLine, 0, len(lines))
+	for _, l := range lines {
+		out = append(out, dto.StockLine{ProductOptionId: l.ProductOptionId, Quantity: l.Quantity})
 	}
-	if err := s.db.DB.
-		From("product_options").
-		Select("id").
-		In("id", ids).
-		IsNull("deleted_at").
-		Execute(&found); err != nil {
-		return fmt.Errorf("failed to validate product options: %w", err)
+	return out
+}
+
+// the stock currently held by the live lines of an order
+func (s *orderService) orderStockLines(ctx context.Context, orderId string) ([]dto.StockLine, error) {
+	details, err := s.orders.Details(ctx, orderId)
+	if err != nil {
+		return nil, err
 	}
-	if len(found) != len(ids) {
-		return fmt.Errorf("one or more product options not found or deleted")
+	lines := make([]dto.StockLine, 0, len(details))
+	for _, d := range details {
+		lines = append(lines, dto.StockLine{ProductOptionId: d.ProductOptionId, Quantity: d.Quantity})
 	}
-	return nil
+	return lines, nil
+}
+
+// insert one row into order_status_history; from is empty for a freshly created order
+func (s *orderService) recordStatusChange(ctx context.Context, orderId string, from, to enum.OrderStatus, note string) error {
+	// empty when the server changes the status itself, like on a payment callback
+	caller, _ := auth.FromContext(ctx)
+	return s.orders.AddHistory(ctx, dto.OrderStatusHistory{
+		OrderId:    orderId,
+		FromStatus: from,
+		ToStatus:   to,
+		Note:       note,
+		CreatedBy:  caller.Id,
+	})
 }
 
 /* ------------------------------------------------------------------
@@ -90,35 +231,22 @@ func (s *orderService) validateOptionIds(ids []string) error {
    ------------------------------------------------------------------*/
 
 func (s *orderService) ListOrders(ctx context.Context, filter dto.OrderListFilter) (api.Response, error) {
-	total, err := s.countOrders(ctx, filter)
+	filter.Correct()
+	filter, err := scopeOrders(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
-	userId := ctx.Value("user_id")
-	userRole := ctx.Value("user_role")
-	var orders []dto.Order
-	q := s.db.DB.
-		From("orders").
-		Select("*").
-		LimitWithOffset(int(filter.Limit), int((filter.Page-1)*filter.Limit)).
-		IsNull("deleted_at")
-
-	if filter.Status != "" {
-		q = q.Eq("status", string(filter.Status))
-	}
-	if userRole == enum.Admin && filter.UserId != "" {
-		q = q.Eq("user_id", filter.UserId)
-	}
-
-	if userRole == enum.User || (userRole == enum.Admin && filter.UserId == "") {
-		q = q.Eq("user_id", userId.(string))
+	total, err := s.orders.Count(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
-
-	if err := q.Execute(&orders); err != nil {
-		return nil, fmt.Errorf("failed to fetch orders: %w", err)
+	orders, cursors, err := s.orders.List(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	filter.Total = int64(total)
+	filter.SetTotal(int64(total))
+	filter.NextCursor, filter.PrevCursor = cursors.Next, cursors.Prev
 	return api.SuccessPagination(orders, &filter.Pagination), nil
 }
 
@@ -128,32 +256,22 @@ func (s *orderService) ListOrders(ctx context.Context, filter dto.OrderListFilte
 
 func (s *orderService) GetOrderById(ctx context.Context, id string) (api.Response, error) {
 	// 1) base order ---------------------------------------------------------
-	var orders []dto.Order
-	if err := s.db.DB.
-		From("orders").
-		Select("id,created_at,updated_at,user_id,address,status,metadata").
-		Eq("id", id).
-		IsNull("deleted_at").
-		Execute(&orders); err != nil {
-		return nil, fmt.Errorf("failed to fetch order: %w", err)
+	order, err := s.orders.Get(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	if len(orders) == 0 {
-		return nil, fmt.Errorf("order not found")
+	if _, err := permission.CheckOwner(ctx, permission.Orders, permission.Read, order.UserId); err != nil {
+		return nil, err
 	}
 
 	// 2) details ------------------------------------------------------------
-	var details []dto.OrderDetail
-	if err := s.db.DB.
-		From("order_details").
-		Select("id,order_id,product_option_id,quantity,discount,discount_type,metadata").
-		Eq("order_id", id).
-		IsNull("deleted_at").
-		Execute(&details); err != nil {
-		return nil, fmt.Errorf("failed to fetch order details: %w", err)
+	details, err := s.orders.Details(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 
 	resp := dto.OrderDetailResponse{
-		Order:       orders[0],
+		Order:       order,
 		OrderDetail: details,
 	}
 	return api.Success(resp), nil
@@ -164,49 +282,129 @@ func (s *orderService) GetOrderById(ctx context.Context, id string) (api.Respons
    ------------------------------------------------------------------*/
 
 func (s *orderService) CreateOrder(ctx context.Context, req dto.OrderCreate) (api.Response, error) {
-	// 1) validate options exist
-	var optionIds []string
-	for _, od := range req.OrderDetails {
-		optionIds = append(optionIds, od.ProductOptionId)
-	}
-	if err := s.validateOptionIds(optionIds); err != nil {
+	orderId, err := s.PlaceOrder(ctx, req)
+	if err != nil {
 		return nil, err
 	}
+	return s.GetOrderById(ctx, orderId)
+}
+
+// PlaceOrder inserts the order with its priced lines and returns the new order id.
+// Callers that have more work to do after placing the order can undo it with DiscardOrder.
+func (s *orderService) PlaceOrder(ctx context.Context, req dto.OrderCreate) (string, error) {
+	caller, err := auth.Authenticated(ctx)
+	if err != nil {
+		return "", err
+	}
+	userId := caller.Id
+
+	// 1) validate options exist and price every line
+	lines, totals, err := priceOrderLines(ctx, s.options, req.OrderDetails)
+	if err != nil {
+		return "", err
+	}
+	var promotion dto.AppliedPromotion
+	if req.PromotionCode != "" {
+		promotion, err = s.promotions.Evaluate(ctx, req.PromotionCode, userId, "", pricedPromotionLines(lines))
+		if err != nil {
+			return "", err
+		}
+		totals.GrandTotal = roundMoney(totals.GrandTotal - promotion.Discount)
+	}
+
+	stock := pricedStockLines(lines)
+	if err := s.inventory.Reserve(ctx, stock); err != nil {
+		return "", err
+	}
 
-	userId := ctx.Value("user_id")
 	// 2) insert into orders --------------------------------------------------
 	orderBody := map[string]interface{}{
-		"user_id":  userId.(string),
-		"address":  req.Address,
-		"status":   enum.Pending,
-		"metadata": req.Metadata,
-	}
-	var createdOrders []dto.Order
-	if err := s.db.DB.From("orders").Insert(orderBody).Execute(&createdOrders); err != nil {
-		return nil, fmt.Errorf("failed to create order: %v", err)
+		"user_id":            userId,
+		"address":            req.Address,
+		"status":             enum.Pending,
+		"subtotal":           totals.Subtotal,
+		"discount_total":     totals.DiscountTotal,
+		"promotion_code":     promotion.Code,
+		"promotion_discount": promotion.Discount,
+		"grand_total":        totals.GrandTotal,
+		"metadata":           req.Metadata,
+	}
+	createdOrder, err := s.orders.Create(ctx, orderBody)
+	if err != nil {
+		s.releaseStock(ctx, stock)
+		return "", err
 	}
-	orderId := createdOrders[0].Id
+	orderId := createdOrder.Id
 
 	// 3) insert order_details -----------------------------------------------
-	var odRows []map[string]interface{}
-	for _, od := range req.OrderDetails {
-		odRows = append(odRows, map[string]interface{}{
-			"order_id":          orderId,
-			"product_option_id": od.ProductOptionId,
-			"quantity":          od.Quantity,
-			"discount":          od.Discount,
-			"discount_type":     od.DiscountType,
-			"metadata":          od.Metadata,
-		})
+	if _, err := s.orders.AddDetails(ctx, orderDetailRows(orderId, lines)); err != nil {
+		// best-effort rollback
+		_ = s.orders.Discard(ctx, orderId)
+		s.releaseStock(ctx, stock)
+		return "", err
 	}
 
-	if err := s.db.DB.From("order_details").Insert(odRows).Execute(nil); err != nil {
-		// best-effort rollback
-		_ = s.db.DB.From("orders").Delete().Eq("id", orderId).Execute(nil)
-		return nil, fmt.Errorf("failed to insert order details: %v", err)
+	if promotion.PromotionId != "" {
+		if err := s.promotions.Redeem(ctx, promotion, orderId, userId); err != nil {
+			if rbErr := s.DiscardOrder(ctx, orderId); rbErr != nil {
+				log.Errorf("failed to discard order %s after promotion redemption failure: %v", orderId, rbErr)
+			}
+			return "", err
+		}
 	}
 
-	return s.GetOrderById(ctx, orderId)
+	if err := s.recordStatusChange(ctx, orderId, "", enum.Pending, ""); err != nil {
+		log.Errorf("order %s created without initial history: %v", orderId, err)
+	}
+
+	s.events.Publish(enum.OrderCreatedEvent, dto.OrderEvent{
+		OrderId:    orderId,
+		UserId:     userId,
+		Status:     enum.Pending,
+		GrandTotal: totals.GrandTotal,
+	}, ws.UserRoom(userId), ws.AdminRoom)
+
+	return orderId, nil
+}
+
+// DiscardOrder hard-deletes an order placed moments ago together with its lines and
+// history. It is only meant for rolling back a PlaceOrder whose surrounding work failed.
+func (s *orderService) DiscardOrder(ctx context.Context, id string) error {
+	stock, err := s.orderStockLines(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.promotions.ReleaseRedemption(ctx, id); err != nil {
+		return err
+	}
+	if err := s.orders.Discard(ctx, id); err != nil {
+		return err
+	}
+	return s.inventory.Release(ctx, stock)
+}
+
+// releaseStock is used on rollback paths where the original error is what the caller
+// needs to see, so a failing release is only logged.
+func (s *orderService) releaseStock(ctx context.Context, lines []dto.StockLine) {
+	if err := s.inventory.Release(ctx, lines); err != nil {
+		log.Errorf("failed to release stock %v: %v", lines, err)
+	}
+}
+
+// releaseRedemption hands a promotion usage back once the order itself is gone;
+// the order change has already happened, so a failure is only logged.
+func (s *orderService) releaseRedemption(ctx context.Context, orderId string) {
+	if err := s.promotions.ReleaseRedemption(ctx, orderId); err != nil {
+		log.Errorf("failed to release promotion usage of order %s: %v", orderId, err)
+	}
+}
+
+// updateRedemption records the discount an edited order now gets from its promotion;
+// like releaseRedemption it runs after the order changed, so a failure is only logged.
+func (s *orderService) updateRedemption(ctx context.Context, applied dto.AppliedPromotion, orderId string) {
+	if err := s.promotions.UpdateRedemption(ctx, applied, orderId); err != nil {
+		log.Errorf("failed to update promotion usage of order %s: %v", orderId, err)
+	}
 }
 
 /* ------------------------------------------------------------------
@@ -214,52 +412,118 @@ func (s *orderService) CreateOrder(ctx context.Context, req dto.OrderCreate) (ap
    ------------------------------------------------------------------*/
 
 func (s *orderService) UpdateOrder(ctx context.Context, req dto.OrderUpdate) (api.Response, error) {
-	var optionIds []string
-	for _, od := range req.OrderDetails {
-		optionIds = append(optionIds, od.ProductOptionId)
+	// only orders that have not been confirmed yet may change their lines
+	order, err := s.orders.Get(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := permission.CheckOwner(ctx, permission.Orders, permission.Update, order.UserId); err != nil {
+		return nil, err
+	}
+	if order.Status != enum.Pending {
+		return nil, errors.Conflict("order in status %s can no longer be updated", order.Status)
+	}
+
+	lines, totals, err := priceOrderLines(ctx, s.options, req.OrderDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	// the code stays on the order, but it must still hold for the new lines
+	var promotion dto.AppliedPromotion
+	if order.PromotionCode != "" {
+		promotion, err = s.promotions.Evaluate(ctx, order.PromotionCode, order.UserId, req.Id, pricedPromotionLines(lines))
+		if err != nil {
+			return nil, err
+		}
+		totals.GrandTotal = roundMoney(totals.GrandTotal - promotion.Discount)
+	}
+
+	// swap the stock held by the old lines for the new ones
+	oldDetails, err := s.orders.Details(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	oldStock := make([]dto.StockLine, 0, len(oldDetails))
+	oldIds := make([]string, 0, len(oldDetails))
+	for _, d := range oldDetails {
+		oldStock = append(oldStock, dto.StockLine{ProductOptionId: d.ProductOptionId, Quantity: d.Quantity})
+		oldIds = append(oldIds, d.Id)
+	}
+	newStock := pricedStockLines(lines)
+	if err := s.inventory.Release(ctx, oldStock); err != nil {
+		return nil, err
 	}
-	if err := s.validateOptionIds(optionIds); err != nil {
+	if err := s.inventory.Reserve(ctx, newStock); err != nil {
+		if rbErr := s.inventory.Reserve(ctx, oldStock); rbErr != nil {
+			log.Errorf("failed to restore stock of order %s: %v", req.Id, rbErr)
+		}
 		return nil, err
 	}
 
-	userId := ctx.Value("user_id")
-	// 3) update order
+	// 3) update order; it stays with the customer who placed it, whoever edits it
 	updateBody := map[string]interface{}{
-		"user_id":    userId.(string),
-		"address":    req.Address,
-		"metadata":   req.Metadata,
-		"updated_at": time.Now(),
-	}
-	if err := s.db.DB.
-		From("orders").
-		Update(updateBody).
-		Eq("id", req.Id).
-		Execute(nil); err != nil {
-		return nil, fmt.Errorf("failed to update order: %v", err)
-	}
-
-	// 4) replace order_details – simplest approach: soft-delete old rows & re-insert
-	if err := s.db.DB.
-		From("order_details").
-		Update(map[string]interface{}{"deleted_at": time.Now()}).
-		Eq("order_id", req.Id).
-		IsNull("deleted_at").
-		Execute(nil); err != nil {
-		return nil, fmt.Errorf("failed to clear old order details: %v", err)
-	}
-	var newRows []map[string]interface{}
-	for _, od := range req.OrderDetails {
-		newRows = append(newRows, map[string]interface{}{
-			"order_id":          req.Id,
-			"product_option_id": od.ProductOptionId,
-			"quantity":          od.Quantity,
-			"discount":          od.Discount,
-			"discount_type":     od.DiscountType,
-			"metadata":          od.Metadata,
-		})
+		"address":            req.Address,
+		"metadata":           req.Metadata,
+		"subtotal":           totals.Subtotal,
+		"discount_total":     totals.DiscountTotal,
+		"promotion_discount": promotion.Discount,
+		"grand_total":        totals.GrandTotal,
+		"updated_at":         time.Now(),
+	}
+	restoreStock := func() {
+		s.releaseStock(ctx, newStock)
+		if rbErr := s.inventory.Reserve(ctx, oldStock); rbErr != nil {
+			log.Errorf("failed to restore stock of order %s: %v", req.Id, rbErr)
+		}
+	}
+	if err := s.orders.Update(ctx, req.Id, updateBody); err != nil {
+		restoreStock()
+		return nil, err
+	}
+	// best-effort rollback of everything above once the lines cannot be replaced
+	restore := func() {
+		restoreStock()
+		if rbErr := s.orders.Update(ctx, req.Id, map[string]interface{}{
+			"address":            order.Address,
+			"metadata":           order.Metadata,
+			"subtotal":           order.Subtotal,
+			"discount_total":     order.DiscountTotal,
+			"promotion_discount": order.PromotionDiscount,
+			"grand_total":        order.GrandTotal,
+			"updated_at":         time.Now(),
+		}); rbErr != nil {
+			log.Errorf("failed to restore totals of order %s: %v", req.Id, rbErr)
+		}
+	}
+
+	// 4) replace order_details: the new lines go in before the old ones go, so the
+	// order is never left without lines
+	added, err := s.orders.AddDetails(ctx, orderDetailRows(req.Id, lines))
+	if err != nil {
+		restore()
+		return nil, err
 	}
-	if err := s.db.DB.From("order_details").Insert(newRows).Execute(nil); err != nil {
-		return nil, fmt.Errorf("failed to add new order details: %v", err)
+	if err := s.orders.SoftDeleteDetails(ctx, oldIds); err != nil {
+		addedIds := make([]string, 0, len(added))
+		for _, d := range added {
+			addedIds = append(addedIds, d.Id)
+		}
+		if rbErr := s.orders.SoftDeleteDetails(ctx, addedIds); rbErr != nil {
+			log.Errorf("failed to remove new lines of order %s: %v", req.Id, rbErr)
+		}
+		restore()
+		return nil, err
+	}
+
+	if order.PromotionCode != "" && promotion.Discount != order.PromotionDiscount {
+		s.updateRedemption(ctx, promotion, req.Id)
+	}
+	// a payment started for the old total must not settle the new one
+	if roundMoney(totals.GrandTotal) != roundMoney(order.GrandTotal) {
+		if err := s.payments.CancelPending(ctx, req.Id); err != nil {
+			return nil, err
+		}
 	}
 
 	return s.GetOrderById(ctx, req.Id)
@@ -270,45 +534,122 @@ func (s *orderService) UpdateOrder(ctx context.Context, req dto.OrderUpdate) (ap
    ------------------------------------------------------------------*/
 
 func (s *orderService) DeleteOrder(ctx context.Context, id string) (api.Response, error) {
-	now := time.Now()
-	if err := s.db.DB.
-		From("orders").
-		Update(map[string]interface{}{"deleted_at": now}).
-		Eq("id", id).
-		Execute(nil); err != nil {
-		return nil, fmt.Errorf("failed to delete order: %v", err)
-	}
-	if err := s.db.DB.
-		From("order_details").
-		Update(map[string]interface{}{"deleted_at": now}).
-		Eq("order_id", id).
-		Execute(nil); err != nil {
-		return nil, fmt.Errorf("failed to delete order details: %v", err)
+	order, err := s.orders.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := permission.CheckOwner(ctx, permission.Orders, permission.Delete, order.UserId); err != nil {
+		return nil, err
+	}
+	stock, err := s.orderStockLines(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.orders.SoftDelete(ctx, id); err != nil {
+		return nil, err
+	}
+	if order.Status.IsStockReserved() {
+		s.releaseStock(ctx, stock)
+		s.releaseRedemption(ctx, id)
 	}
 	return api.Success("Order deleted successfully"), nil
 }
 
-func (s *orderService) UpdateOrderStatus(ctx context.Context, id string, status enum.OrderStatus) (api.Response, error) {
-	// Validate the incoming status
-	if status != enum.Pending &&
-		status != enum.Complete &&
-		status != enum.Cancelled {
-		return nil, fmt.Errorf("invalid status")
+/* ------------------------------------------------------------------
+   Status transitions
+   ------------------------------------------------------------------*/
+
+func (s *orderService) UpdateOrderStatus(ctx context.Context, req dto.OrderStatusUpdate) (api.Response, error) {
+	if !req.Status.IsValid() {
+		return nil, errors.BadRequest("invalid status %q", req.Status)
 	}
 
-	updateBody := map[string]interface{}{
-		"status":     status,
-		"updated_at": time.Now(),
+	order, err := s.orders.Get(ctx, req.OrderId)
+	if err != nil {
+		return nil, err
 	}
-	var order []dto.Order
-	if err := s.db.DB.
-		From("orders").
-		Update(updateBody).
-		Eq("id", id).
-		IsNull("deleted_at").
-		Execute(order); err != nil {
-		return nil, fmt.Errorf("failed to update order status: %v", err)
+	scope, err := permission.CheckOwner(ctx, permission.Orders, permission.Manage, order.UserId)
+	if err != nil {
+		return nil, err
 	}
+	current := order.Status
+	if !current.CanTransitionTo(req.Status) {
+		return nil, errors.Conflict("cannot change order status from %s to %s", current, req.Status).
+			WithCode("invalid_status_transition").
+			WithDebug(map[string]any{"allowed": current.NextStatuses()})
+	}
+	// customers may call off their own order, the rest of the workflow is the shop's
+	if scope == permission.Own && req.Status != enum.Cancelled {
+		return nil, errors.Forbidden("only the shop may set an order to %s", req.Status).WithCode("permission_denied")
+	}
+
+	// cancelled or returned items go back on the shelf
+	var released []dto.StockLine
+	if req.Status == enum.Cancelled || req.Status == enum.Returned {
+		stock, err := s.orderStockLines(ctx, req.OrderId)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.inventory.Release(ctx, stock); err != nil {
+			return nil, err
+		}
+		released = stock
+	}
+	restock := func() {
+		if len(released) == 0 {
+			return
+		}
+		if err := s.inventory.Reserve(ctx, released); err != nil {
+			log.Errorf("failed to take back stock of order %s: %v", req.OrderId, err)
+		}
+	}
+
+	// guard on the current status so two concurrent transitions cannot both win
+	updated, ok, err := s.orders.SetStatus(ctx, req.OrderId, current, req.Status)
+	if err != nil {
+		restock()
+		return nil, err
+	}
+	if !ok {
+		restock()
+		return nil, errors.Conflict("order status was changed by another request, please retry")
+	}
+
+	if err := s.recordStatusChange(ctx, req.OrderId, current, req.Status, req.Note); err != nil {
+		// best-effort rollback so the status never changes without a history row
+		_ = s.orders.Update(ctx, req.OrderId, map[string]interface{}{"status": current})
+		restock()
+		return nil, err
+	}
+
+	// a cancelled or returned order gives its promotion usage back
+	if req.Status == enum.Cancelled || req.Status == enum.Returned {
+		s.releaseRedemption(ctx, req.OrderId)
+	}
+
+	s.events.Publish(enum.OrderStatusChangedEvent, dto.OrderEvent{
+		OrderId:    req.OrderId,
+		UserId:     order.UserId,
+		FromStatus: current,
+		Status:     req.Status,
+	}, ws.UserRoom(order.UserId), ws.AdminRoom)
+
+	return api.Success(updated), nil
+}
 
-	return api.Success(order[0]), nil
+func (s *orderService) GetOrderHistory(ctx context.Context, id string) (api.Response, error) {
+	order, err := s.orders.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := permission.CheckOwner(ctx, permission.Orders, permission.Read, order.UserId); err != nil {
+		return nil, err
+	}
+
+	history, err := s.orders.History(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return api.Success(history), nil
 }
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"SangXanh/pkg/auth"
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"SangXanh/pkg/repository"
+	"SangXanh/pkg/ws"
+	"context"
+	"encoding/json"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// noPromotions stands in for the promotion service, orders in these tests carry no code.
+type noPromotions struct {
+	PromotionService
+}
+
+func (noPromotions) Evaluate(context.Context, string, string, string, []dto.PromotionLine) (dto.AppliedPromotion, error) {
+	return dto.AppliedPromotion{}, errors.Unprocessable("promotion code is not valid")
+}
+
+func (noPromotions) Redeem(context.Context, dto.AppliedPromotion, string, string) error { return nil }
+
+func (noPromotions) UpdateRedemption(context.Context, dto.AppliedPromotion, string) error {
+	return nil
+}
+
+func (noPromotions) ReleaseRedemption(context.Context, string) error { return nil }
+
+func newOrderTest(t *testing.T) (OrderService, *repository.MemoryStore) {
+	di := do.New()
+	store := repository.InjectMemory(di)
+	do.ProvideValue(di, ws.New())
+	do.ProvideValue[PromotionService](di, noPromotions{})
+	do.Provide(di, NewInventoryService)
+	do.Provide(di, NewOrderService)
+
+	store.Seed("products", map[string]interface{}{"id": "p1", "name": "Lúa giống", "price": 100, "discount": 10, "discount_type": enum.Percent})
+	store.Seed("product_options",
+		map[string]interface{}{"id": "o1", "product_id": "p1", "name": "5kg", "price": 20, "stock": 7},
+		map[string]interface{}{"id": "o2", "product_id": "p1", "name": "10kg", "price": 50, "stock": 1},
+	)
+
+	orders, err := do.Invoke[OrderService](di)
+	require.NoError(t, err)
+	return orders, store
+}
+
+func optionStock(store *repository.MemoryStore, id string) float64 {
+	for _, row := range store.Rows("product_options") {
+		if row["id"] == id {
+			return row["stock"].(float64)
+		}
+	}
+	return -1
+}
+
+// responseData decodes the data of a response the way a client would see it.
+func responseData(t *testing.T, resp api.Response, out any) {
+	raw, err := json.Marshal(resp)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(raw, &struct {
+		Data any `json:"data"`
+	}{Data: out}))
+}
+
+func signedIn(id, role string) context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{Id: id, Role: role})
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	orders, store := newOrderTest(t)
+	ctx := signedIn("u1", enum.User)
+
+	orderId, err := orders.PlaceOrder(ctx, dto.OrderCreate{
+		Address:      "Cần Thơ",
+		OrderDetails: []dto.OrderDetailBase{{ProductOptionId: "o1", Quantity: 2}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, optionStock(store, "o1"))
+
+	resp, err := orders.GetOrderById(ctx, orderId)
+	require.NoError(t, err)
+	var order dto.OrderDetailResponse
+	responseData(t, resp, &order)
+	assert.Equal(t, 240.0, order.Subtotal)
+	assert.Equal(t, 24.0, order.DiscountTotal)
+	assert.Equal(t, 216.0, order.GrandTotal)
+	assert.Len(t, order.OrderDetail, 1)
+
+	_, err = orders.UpdateOrderStatus(ctx, dto.OrderStatusUpdate{OrderId: orderId, Status: enum.Cancelled})
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, optionStock(store, "o1"))
+	assert.Len(t, store.Rows("order_status_history"), 2)
+
+	_, err = orders.UpdateOrderStatus(ctx, dto.OrderStatusUpdate{OrderId: orderId, Status: enum.Pending})
+	var httpErr errors.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, "invalid_status_transition", httpErr.ErrorCode())
+}
+
+func TestPlaceOrderOutOfStock(t *testing.T) {
+	orders, store := newOrderTest(t)
+	ctx := signedIn("u1", enum.User)
+
+	_, err := orders.PlaceOrder(ctx, dto.OrderCreate{
+		OrderDetails: []dto.OrderDetailBase{{ProductOptionId: "o1", Quantity: 1}, {ProductOptionId: "o2", Quantity: 2}},
+	})
+	var httpErr errors.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, "insufficient_stock", httpErr.ErrorCode())
+
+	// nothing was taken and no order was left behind
+	assert.Equal(t, 7.0, optionStock(store, "o1"))
+	assert.Empty(t, store.Rows("orders"))
+
+	_, err = orders.PlaceOrder(context.Background(), dto.OrderCreate{
+		OrderDetails: []dto.OrderDetailBase{{ProductOptionId: "o1", Quantity: 1}},
+	})
+	var unauthorized *errors.UnauthorizedError
+	assert.True(t, errors.As(err, &unauthorized), "an anonymous order is refused, not a panic")
+
+	_, err = orders.PlaceOrder(ctx, dto.OrderCreate{
+		OrderDetails: []dto.OrderDetailBase{{ProductOptionId: "missing", Quantity: 1}},
+	})
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, "product_option_not_found", httpErr.ErrorCode())
+}
+
+func TestListOrdersTotal(t *testing.T) {
+	orders, store := newOrderTest(t)
+	for _, userId := range []string{"u1", "u1", "u1", "u2"} {
+		store.Seed("orders", map[string]interface{}{"user_id": userId, "status": enum.Pending})
+	}
+	ctx := signedIn("u1", enum.User)
+
+	type listBody struct {
+		Meta struct {
+			Total      int64  `json:"total"`
+			TotalPage  int64  `json:"total_page"`
+			NextCursor string `json:"next_cursor"`
+			PrevCursor string `json:"prev_cursor"`
+		} `json:"meta"`
+		Data []dto.Order `json:"data"`
+	}
+	list := func(filter dto.OrderListFilter) listBody {
+		resp, err := orders.ListOrders(ctx, filter)
+		require.NoError(t, err)
+		raw, err := json.Marshal(resp)
+		require.NoError(t, err)
+		var body listBody
+		require.NoError(t, json.Unmarshal(raw, &body))
+		return body
+	}
+
+	filter := dto.OrderListFilter{}
+	filter.Limit = 2
+	body := list(filter)
+	assert.Len(t, body.Data, 2)
+	assert.Equal(t, int64(3), body.Meta.Total)
+	assert.Equal(t, int64(2), body.Meta.TotalPage)
+	assert.Empty(t, body.Meta.PrevCursor)
+
+	// the next cursor carries on where the offset page ended
+	filter.Cursor = body.Meta.NextCursor
+	rest := list(filter)
+	assert.Len(t, rest.Data, 1)
+	assert.NotContains(t, []string{body.Data[0].Id, body.Data[1].Id}, rest.Data[0].Id)
+	assert.Empty(t, rest.Meta.NextCursor)
+	assert.NotEmpty(t, rest.Meta.PrevCursor)
+}
+
+func TestOrderOwnership(t *testing.T) {
+	orders, _ := newOrderTest(t)
+	customer := signedIn("u1", enum.User)
+	other := signedIn("u2", enum.User)
+	admin := signedIn("a1", enum.Admin)
+
+	orderId, err := orders.PlaceOrder(customer, dto.OrderCreate{
+		OrderDetails: []dto.OrderDetailBase{{ProductOptionId: "o1", Quantity: 1}},
+	})
+	require.NoError(t, err)
+
+	_, err = orders.GetOrderById(other, orderId)
+	var notFound *errors.NotFoundError
+	assert.True(t, errors.As(err, &notFound), "another customer's order is not there for them")
+	_, err = orders.UpdateOrderStatus(other, dto.OrderStatusUpdate{OrderId: orderId, Status: enum.Cancelled})
+	assert.True(t, errors.As(err, &notFound))
+
+	_, err = orders.UpdateOrderStatus(customer, dto.OrderStatusUpdate{OrderId: orderId, Status: enum.Confirmed})
+	var httpErr errors.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, "permission_denied", httpErr.ErrorCode())
+
+	_, err = orders.UpdateOrderStatus(admin, dto.OrderStatusUpdate{OrderId: orderId, Status: enum.Confirmed})
+	require.NoError(t, err)
+	resp, err := orders.GetOrderById(admin, orderId)
+	require.NoError(t, err)
+	var order dto.OrderDetailResponse
+	responseData(t, resp, &order)
+	assert.Equal(t, enum.Confirmed, order.Status)
+	assert.Equal(t, "u1", order.UserId)
+}
+
+// failingStore fails the inserts into one table once failTable is set.
+type failingStore struct {
+	*repository.MemoryStore
+	failTable string
+}
+
+func (s *failingStore) Insert(ctx context.Context, table string, rows interface{}, out interface{}) error {
+	if table == s.failTable {
+		return errors.New("insert into %s failed", table)
+	}
+	return s.MemoryStore.Insert(ctx, table, rows, out)
+}
+
+func TestUpdateOrderRollback(t *testing.T) {
+	di := do.New()
+	store := &failingStore{MemoryStore: repository.InjectMemory(di)}
+	do.OverrideValue[repository.Store](di, store)
+	do.ProvideValue(di, ws.New())
+	do.ProvideValue[PromotionService](di, noPromotions{})
+	do.Provide(di, NewInventoryService)
+	do.Provide(di, NewOrderService)
+	store.Seed("products", map[string]interface{}{"id": "p1", "name": "Lúa giống", "price": 100})
+	store.Seed("product_options", map[string]interface{}{"id": "o1", "product_id": "p1", "name": "5kg", "price": 20, "stock": 7})
+	orders := do.MustInvoke[OrderService](di)
+	ctx := signedIn("u1", enum.User)
+
+	orderId, err := orders.PlaceOrder(ctx, dto.OrderCreate{OrderDetails: []dto.OrderDetailBase{{ProductOptionId: "o1", Quantity: 1}}})
+	require.NoError(t, err)
+	store.failTable = "order_details"
+	_, err = orders.UpdateOrder(ctx, dto.OrderUpdate{Id: orderId, OrderDetails: []dto.OrderDetailBase{{ProductOptionId: "o1", Quantity: 3}}})
+	require.Error(t, err)
+
+	assert.Equal(t, 6.0, optionStock(store.MemoryStore, "o1"), "the stock of the old line is held again")
+	assert.Equal(t, 120.0, store.Rows("orders")[0]["grand_total"])
+	resp, err := orders.GetOrderById(ctx, orderId)
+	require.NoError(t, err)
+	var order dto.OrderDetailResponse
+	responseData(t, resp, &order)
+	require.Len(t, order.OrderDetail, 1, "the old line is still there")
+	assert.Equal(t, 1, order.OrderDetail[0].Quantity)
+}
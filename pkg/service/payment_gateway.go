@@ -0,0 +1,147 @@
+package service
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/config"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of the raw webhook body.
+const SignatureHeader = "X-Signature"
+
+// PaymentGateway is one way of collecting money for an order.
+type PaymentGateway interface {
+	Method() enum.PaymentMethod
+	// Initiate returns what the customer needs to complete the payment,
+	// e.g. the bank account and transfer content.
+	Initiate(ctx context.Context, payment dto.Payment) (map[string]interface{}, error)
+	// ParseWebhook verifies a callback of the gateway and decodes it.
+	ParseWebhook(header http.Header, body []byte) (dto.PaymentEvent, error)
+}
+
+func webhookMAC(secret string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// SignWebhook returns the SignatureHeader value a gateway sends along with body.
+func SignWebhook(secret string, body []byte) string {
+	return hex.EncodeToString(webhookMAC(secret, body))
+}
+
+func parseSignedEvent(secret string, header http.Header, body []byte) (dto.PaymentEvent, error) {
+	var event dto.PaymentEvent
+	if secret == "" {
+		return event, errors.Forbidden("webhooks are not configured")
+	}
+	signature, err := hex.DecodeString(header.Get(SignatureHeader))
+	if err != nil || !hmac.Equal(signature, webhookMAC(secret, body)) {
+		return event, errors.Unauthorized("invalid webhook signature")
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return event, errors.BadRequest("invalid webhook payload: %v", err)
+	}
+	if event.EventId == "" || event.Reference == "" {
+		return event, errors.BadRequest("webhook payload misses id or reference")
+	}
+	switch event.Status {
+	case enum.PaymentSucceeded, enum.PaymentFailed, enum.PaymentCancelled:
+	default:
+		return event, errors.BadRequest("invalid payment status %q", event.Status)
+	}
+	return event, nil
+}
+
+/* ------------------------------------------------------------------
+   Cash on delivery
+   ------------------------------------------------------------------*/
+
+// codGateway has no callbacks; the payment is confirmed by staff once the cash is in.
+type codGateway struct{}
+
+func (codGateway) Method() enum.PaymentMethod { return enum.CashOnDelivery }
+
+func (codGateway) Initiate(ctx context.Context, payment dto.Payment) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"amount": payment.Amount,
+		"note":   "pay the courier on delivery",
+	}, nil
+}
+
+func (codGateway) ParseWebhook(header http.Header, body []byte) (dto.PaymentEvent, error) {
+	return dto.PaymentEvent{}, errors.BadRequest("cash on delivery has no webhook")
+}
+
+/* ------------------------------------------------------------------
+   Bank transfer
+   ------------------------------------------------------------------*/
+
+// bankTransferGateway asks the customer to transfer with the payment reference as
+// content; the bank hub reports matching transfers through the signed webhook.
+type bankTransferGateway struct {
+	conf config.Payment
+}
+
+func (g bankTransferGateway) Method() enum.PaymentMethod { return enum.BankTransfer }
+
+func (g bankTransferGateway) Initiate(ctx context.Context, payment dto.Payment) (map[string]interface{}, error) {
+	if g.conf.BankAccount == "" {
+		return nil, errors.Unprocessable("bank transfer is not available")
+	}
+	return map[string]interface{}{
+		"bank_name":    g.conf.BankName,
+		"account":      g.conf.BankAccount,
+		"account_name": g.conf.BankAccountName,
+		"amount":       payment.Amount,
+		"content":      payment.Reference,
+	}, nil
+}
+
+func (g bankTransferGateway) ParseWebhook(header http.Header, body []byte) (dto.PaymentEvent, error) {
+	return parseSignedEvent(g.conf.WebhookSecret, header, body)
+}
+
+/* ------------------------------------------------------------------
+   Fake
+   ------------------------------------------------------------------*/
+
+// FakeGateway accepts every payment and remembers what it was asked to collect.
+// Tests sign callbacks for it with SignWebhook and its Secret.
+type FakeGateway struct {
+	Secret string
+
+	mu        sync.Mutex
+	initiated []dto.Payment
+}
+
+func NewFakeGateway(secret string) *FakeGateway {
+	return &FakeGateway{Secret: secret}
+}
+
+func (g *FakeGateway) Method() enum.PaymentMethod { return enum.FakePayment }
+
+func (g *FakeGateway) Initiate(ctx context.Context, payment dto.Payment) (map[string]interface{}, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.initiated = append(g.initiated, payment)
+	return map[string]interface{}{"reference": payment.Reference}, nil
+}
+
+func (g *FakeGateway) ParseWebhook(header http.Header, body []byte) (dto.PaymentEvent, error) {
+	return parseSignedEvent(g.Secret, header, body)
+}
+
+func (g *FakeGateway) Initiated() []dto.Payment {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]dto.Payment(nil), g.initiated...)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"SangXanh/pkg/config"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func signedHeader(secret string, body []byte) http.Header {
+	h := http.Header{}
+	h.Set(SignatureHeader, SignWebhook(secret, body))
+	return h
+}
+
+func TestFakeGateway_ParseWebhook(t *testing.T) {
+	g := NewFakeGateway("secret")
+	body := []byte(`{"id":"evt_1","reference":"SXABC","transaction_id":"tx_1","amount":150000,"status":"succeeded"}`)
+
+	event, err := g.ParseWebhook(signedHeader("secret", body), body)
+	assert.NoError(t, err)
+	assert.Equal(t, dto.PaymentEvent{
+		EventId:       "evt_1",
+		Reference:     "SXABC",
+		TransactionId: "tx_1",
+		Amount:        150000,
+		Status:        enum.PaymentSucceeded,
+	}, event)
+
+	_, err = g.ParseWebhook(signedHeader("other", body), body)
+	assert.Error(t, err)
+
+	_, err = g.ParseWebhook(http.Header{}, body)
+	assert.Error(t, err)
+}
+
+func TestFakeGateway_ParseWebhookRejectsBadPayload(t *testing.T) {
+	g := NewFakeGateway("secret")
+	for _, body := range [][]byte{
+		[]byte(`not json`),
+		[]byte(`{"reference":"SXABC","status":"succeeded"}`),
+		[]byte(`{"id":"evt_1","reference":"SXABC","status":"pending"}`),
+	} {
+		_, err := g.ParseWebhook(signedHeader("secret", body), body)
+		assert.Error(t, err, string(body))
+	}
+}
+
+func TestFakeGateway_Initiate(t *testing.T) {
+	g := NewFakeGateway("secret")
+	_, err := g.Initiate(context.Background(), dto.Payment{Reference: "SXABC", Amount: 10})
+	assert.NoError(t, err)
+	assert.Len(t, g.Initiated(), 1)
+}
+
+func TestBankTransferGateway(t *testing.T) {
+	body := []byte(`{"id":"evt_1","reference":"SXABC","amount":10,"status":"succeeded"}`)
+
+	unconfigured := bankTransferGateway{}
+	_, err := unconfigured.ParseWebhook(signedHeader("", body), body)
+	assert.Error(t, err)
+	_, err = unconfigured.Initiate(context.Background(), dto.Payment{})
+	assert.Error(t, err)
+
+	g := bankTransferGateway{conf: config.Payment{WebhookSecret: "secret", BankAccount: "0123456789"}}
+	instructions, err := g.Initiate(context.Background(), dto.Payment{Reference: "SXABC", Amount: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, "SXABC", instructions["content"])
+	_, err = g.ParseWebhook(signedHeader("secret", body), body)
+	assert.NoError(t, err)
+}
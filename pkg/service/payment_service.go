@@ -0,0 +1,325 @@
+package service
+
+import (
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/config"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"SangXanh/pkg/log"
+	"SangXanh/pkg/permission"
+	"SangXanh/pkg/repository"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"github.com/samber/do/v2"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type PaymentService interface {
+	CreatePayment(ctx context.Context, req dto.PaymentCreate) (api.Response, error)
+	GetOrderPayments(ctx context.Context, orderId string) (api.Response, error)
+	ConfirmPayment(ctx context.Context, req dto.PaymentConfirm) (api.Response, error)
+	HandleWebhook(ctx context.Context, method enum.PaymentMethod, header http.Header, body []byte) (api.Response, error)
+}
+
+type paymentService struct {
+	payments repository.PaymentRepository
+	orders   repository.OrderRepository
+	statuses OrderService
+	gateways map[enum.PaymentMethod]PaymentGateway
+	prefix   string
+}
+
+func NewPaymentService(di do.Injector) (PaymentService, error) {
+	payments, err := do.Invoke[repository.PaymentRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PaymentService: %w", err)
+	}
+	orders, err := do.Invoke[repository.OrderRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PaymentService: %w", err)
+	}
+	statuses, err := do.Invoke[OrderService](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PaymentService: %w", err)
+	}
+	conf, err := do.Invoke[config.Payment](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PaymentService: %w", err)
+	}
+
+	gateways := []PaymentGateway{codGateway{}, bankTransferGateway{conf: conf}}
+	// the fake gateway only exists where a test provided one
+	if fake, err := do.Invoke[*FakeGateway](di); err == nil {
+		gateways = append(gateways, fake)
+	}
+
+	s := &paymentService{
+		payments: payments,
+		orders:   orders,
+		statuses: statuses,
+		gateways: make(map[enum.PaymentMethod]PaymentGateway, len(gateways)),
+		prefix:   conf.ReferencePrefix,
+	}
+	for _, g := range gateways {
+		s.gateways[g.Method()] = g
+	}
+	return s, nil
+}
+
+// referenceAlphabet leaves out characters that are easily mistyped in a transfer content
+const referenceAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func (s *paymentService) newReference() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate payment reference: %w", err)
+	}
+	var b strings.Builder
+	b.WriteString(s.prefix)
+	for _, c := range buf {
+		b.WriteByte(referenceAlphabet[int(c)%len(referenceAlphabet)])
+	}
+	return b.String(), nil
+}
+
+func (s *paymentService) gateway(method enum.PaymentMethod) (PaymentGateway, error) {
+	g, ok := s.gateways[method]
+	if !ok {
+		return nil, errors.BadRequest("unsupported payment method %q", method)
+	}
+	return g, nil
+}
+
+// fetchOrder returns the order payments are about when the caller may take action on
+// its payments.
+func (s *paymentService) fetchOrder(ctx context.Context, orderId string, action permission.Action) (dto.Order, error) {
+	order, err := s.orders.Get(ctx, orderId)
+	if err != nil {
+		return dto.Order{}, err
+	}
+	if _, err := permission.CheckOwner(ctx, permission.Payments, action, order.UserId); err != nil {
+		return dto.Order{}, err
+	}
+	return order, nil
+}
+
+/* ------------------------------------------------------------------
+   Create
+   ------------------------------------------------------------------*/
+
+// CreatePayment starts paying a pending order. Asking again with the same method
+// returns the payment that is already under way instead of opening a second one.
+func (s *paymentService) CreatePayment(ctx context.Context, req dto.PaymentCreate) (api.Response, error) {
+	g, err := s.gateway(req.Method)
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.fetchOrder(ctx, req.OrderId, permission.Create)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != enum.Pending {
+		return nil, errors.Conflict("order in status %s cannot be paid", order.Status)
+	}
+
+	existing, err := s.payments.ListByOrder(ctx, order.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range existing {
+		if p.Status == enum.PaymentSucceeded {
+			return nil, errors.Conflict("order %s has already been paid", order.Id)
+		}
+		if p.Status == enum.PaymentPending && p.Method == req.Method && p.Amount == order.GrandTotal {
+			return api.Success(p), nil
+		}
+	}
+
+	// only one payment may be open per order
+	if err := s.payments.CancelPending(ctx, order.Id); err != nil {
+		return nil, err
+	}
+
+	reference, err := s.newReference()
+	if err != nil {
+		return nil, err
+	}
+	payment := dto.Payment{
+		OrderId:   order.Id,
+		UserId:    order.UserId,
+		Method:    req.Method,
+		Status:    enum.PaymentPending,
+		Amount:    order.GrandTotal,
+		Reference: reference,
+	}
+	if payment.Instructions, err = g.Initiate(ctx, payment); err != nil {
+		return nil, err
+	}
+
+	created, err := s.payments.Create(ctx, payment)
+	if err != nil {
+		return nil, err
+	}
+	return api.Success(created), nil
+}
+
+func (s *paymentService) GetOrderPayments(ctx context.Context, orderId string) (api.Response, error) {
+	if _, err := s.fetchOrder(ctx, orderId, permission.Read); err != nil {
+		return nil, err
+	}
+	payments, err := s.payments.ListByOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+	return api.Success(payments), nil
+}
+
+/* ------------------------------------------------------------------
+   Settlement
+   ------------------------------------------------------------------*/
+
+// ConfirmPayment is how staff settle payments without callbacks, such as cash
+// collected on delivery or a transfer checked by hand.
+func (s *paymentService) ConfirmPayment(ctx context.Context, req dto.PaymentConfirm) (api.Response, error) {
+	payment, err := s.payments.Get(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status.IsFinal() {
+		return nil, errors.Conflict("payment is already %s", payment.Status)
+	}
+	event := dto.PaymentEvent{
+		Reference:     payment.Reference,
+		TransactionId: req.TransactionId,
+		Amount:        payment.Amount,
+		Status:        enum.PaymentSucceeded,
+	}
+	settled, err := s.settle(ctx, payment, event)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.markOrderPaid(ctx, settled); err != nil {
+		return nil, err
+	}
+	return api.Success(settled), nil
+}
+
+// HandleWebhook applies a gateway callback. Gateways deliver at least once, so a
+// callback that was seen before, or that targets a payment that is already final,
+// is acknowledged without doing anything.
+func (s *paymentService) HandleWebhook(ctx context.Context, method enum.PaymentMethod, header http.Header, body []byte) (api.Response, error) {
+	g, err := s.gateway(method)
+	if err != nil {
+		return nil, err
+	}
+	event, err := g.ParseWebhook(header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	seen, err := s.payments.EventSeen(ctx, method, event.EventId)
+	if err != nil {
+		return nil, err
+	}
+	if seen {
+		return api.Success("duplicate event ignored"), nil
+	}
+
+	payment, err := s.payments.GetByReference(ctx, event.Reference)
+	var notFound *errors.NotFoundError
+	if errors.As(err, &notFound) || (err == nil && payment.Method != method) {
+		return nil, errors.NotFound("unknown payment reference %s", event.Reference)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !payment.Status.IsFinal() {
+		if payment, err = s.settle(ctx, payment, event); err != nil {
+			return nil, err
+		}
+	}
+	// also done for a payment that is final already, in case an earlier delivery
+	// failed between settling the payment and updating the order; nobody is signed
+	// in on a callback, the server moves the order on its own account
+	if err := s.markOrderPaid(permission.AsSystem(ctx), payment); err != nil {
+		return nil, err
+	}
+
+	if err := s.recordEvent(ctx, payment.Id, method, event, body); err != nil {
+		// the payment is settled already; a redelivery is still caught by the final status
+		log.Errorf("failed to record payment event %s: %v", event.EventId, err)
+	}
+	return api.Success(payment), nil
+}
+
+// settle moves a pending payment to the status reported in event.
+func (s *paymentService) settle(ctx context.Context, payment dto.Payment, event dto.PaymentEvent) (dto.Payment, error) {
+	if event.Status == enum.PaymentSucceeded && roundMoney(event.Amount) < roundMoney(payment.Amount) {
+		return payment, errors.Unprocessable("paid amount %v is less than %v", event.Amount, payment.Amount)
+	}
+
+	body := map[string]interface{}{
+		"status":     event.Status,
+		"updated_at": time.Now(),
+	}
+	if event.TransactionId != "" {
+		body["transaction_id"] = event.TransactionId
+	}
+	if event.Status == enum.PaymentSucceeded {
+		body["paid_at"] = time.Now()
+	}
+
+	// guarded on pending so a concurrent duplicate cannot settle the payment twice
+	updated, ok, err := s.payments.Settle(ctx, payment.Id, body)
+	if err != nil {
+		return payment, err
+	}
+	if !ok {
+		return s.payments.Get(ctx, payment.Id)
+	}
+	return updated, nil
+}
+
+// markOrderPaid moves the order of a succeeded payment from pending to paid, as long
+// as the payment covers what the order costs now.
+func (s *paymentService) markOrderPaid(ctx context.Context, payment dto.Payment) error {
+	if payment.Status != enum.PaymentSucceeded {
+		return nil
+	}
+	order, err := s.orders.Get(ctx, payment.OrderId)
+	var notFound *errors.NotFoundError
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if order.Status != enum.Pending {
+		// already paid, or e.g. a cash on delivery order confirmed before the cash came in
+		return nil
+	}
+	if roundMoney(payment.Amount) < roundMoney(order.GrandTotal) {
+		return errors.Unprocessable("payment %s of %v does not cover the order total of %v", payment.Reference, payment.Amount, order.GrandTotal).
+			WithCode("payment_short")
+	}
+	_, err = s.statuses.UpdateOrderStatus(ctx, dto.OrderStatusUpdate{
+		OrderId: payment.OrderId,
+		Status:  enum.Paid,
+		Note:    "payment " + payment.Reference,
+	})
+	return err
+}
+
+func (s *paymentService) recordEvent(ctx context.Context, paymentId string, method enum.PaymentMethod, event dto.PaymentEvent, body []byte) error {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return err
+	}
+	return s.payments.AddEvent(ctx, paymentId, method, event, payload)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/config"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"SangXanh/pkg/repository"
+	"SangXanh/pkg/ws"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newPaymentTest(t *testing.T) (OrderService, PaymentService, *FakeGateway, *repository.MemoryStore) {
+	di := do.New()
+	store := repository.InjectMemory(di)
+	do.ProvideValue(di, ws.New())
+	do.ProvideValue[PromotionService](di, noPromotions{})
+	do.ProvideValue(di, config.Payment{ReferencePrefix: "SX"})
+	gateway := NewFakeGateway("secret")
+	do.ProvideValue(di, gateway)
+	do.Provide(di, NewInventoryService)
+	do.Provide(di, NewOrderService)
+	do.Provide(di, NewPaymentService)
+	store.Seed("products", map[string]interface{}{"id": "p1", "name": "Lúa giống", "price": 100})
+	store.Seed("product_options", map[string]interface{}{"id": "o1", "product_id": "p1", "name": "5kg", "price": 20, "stock": 7})
+	return do.MustInvoke[OrderService](di), do.MustInvoke[PaymentService](di), gateway, store
+}
+
+func TestPaymentWebhook(t *testing.T) {
+	orders, payments, gateway, store := newPaymentTest(t)
+	ctx := signedIn("u1", enum.User)
+
+	orderId, err := orders.PlaceOrder(ctx, dto.OrderCreate{OrderDetails: []dto.OrderDetailBase{{ProductOptionId: "o1", Quantity: 1}}})
+	require.NoError(t, err)
+	_, err = payments.CreatePayment(signedIn("u2", enum.User), dto.PaymentCreate{OrderId: orderId, Method: enum.FakePayment})
+	var notFound *errors.NotFoundError
+	assert.True(t, errors.As(err, &notFound), "the order of someone else is not there to pay")
+
+	resp, err := payments.CreatePayment(ctx, dto.PaymentCreate{OrderId: orderId, Method: enum.FakePayment})
+	require.NoError(t, err)
+	var payment dto.Payment
+	responseData(t, resp, &payment)
+	assert.Equal(t, enum.PaymentPending, payment.Status)
+	assert.Equal(t, 120.0, payment.Amount)
+	assert.Equal(t, payment.Reference, payment.Instructions["reference"])
+	require.Len(t, gateway.Initiated(), 1)
+
+	body := []byte(fmt.Sprintf(`{"id":"evt_1","reference":%q,"transaction_id":"tx_1","amount":120,"status":"succeeded"}`, payment.Reference))
+	for i := 0; i < 2; i++ {
+		_, err = payments.HandleWebhook(context.Background(), enum.FakePayment, signedHeader("secret", body), body)
+		require.NoError(t, err, "delivery %d", i+1)
+	}
+	assert.Len(t, store.Rows("payment_events"), 1, "a redelivered callback is recorded once")
+	assert.Len(t, store.Rows("order_status_history"), 2)
+
+	resp, err = payments.GetOrderPayments(ctx, orderId)
+	require.NoError(t, err)
+	var listed []dto.Payment
+	responseData(t, resp, &listed)
+	require.Len(t, listed, 1)
+	assert.Equal(t, enum.PaymentSucceeded, listed[0].Status)
+	assert.Equal(t, "tx_1", listed[0].TransactionId)
+	for _, row := range store.Rows("orders") {
+		assert.Equal(t, string(enum.Paid), row["status"])
+	}
+
+	_, err = payments.CreatePayment(ctx, dto.PaymentCreate{OrderId: orderId, Method: enum.FakePayment})
+	var conflict *errors.ConflictError
+	assert.True(t, errors.As(err, &conflict), "a paid order is not paid twice")
+}
+
+func TestPaymentAfterOrderEdit(t *testing.T) {
+	orders, payments, _, store := newPaymentTest(t)
+	ctx := signedIn("u1", enum.User)
+	webhook := func(payment dto.Payment, event string) error {
+		body := []byte(fmt.Sprintf(`{"id":%q,"reference":%q,"amount":%v,"status":"succeeded"}`, event, payment.Reference, payment.Amount))
+		_, err := payments.HandleWebhook(context.Background(), enum.FakePayment, signedHeader("secret", body), body)
+		return err
+	}
+	orderStatus := func() interface{} { return store.Rows("orders")[0]["status"] }
+
+	orderId, err := orders.PlaceOrder(ctx, dto.OrderCreate{OrderDetails: []dto.OrderDetailBase{{ProductOptionId: "o1", Quantity: 1}}})
+	require.NoError(t, err)
+	resp, err := payments.CreatePayment(ctx, dto.PaymentCreate{OrderId: orderId, Method: enum.FakePayment})
+	require.NoError(t, err)
+	var stale dto.Payment
+	responseData(t, resp, &stale)
+
+	_, err = orders.UpdateOrder(ctx, dto.OrderUpdate{Id: orderId, OrderDetails: []dto.OrderDetailBase{{ProductOptionId: "o1", Quantity: 2}}})
+	require.NoError(t, err)
+	require.NoError(t, webhook(stale, "evt_1"))
+	assert.Equal(t, string(enum.PaymentCancelled), store.Rows("payments")[0]["status"], "the edit cancelled the payment of the old total")
+	assert.Equal(t, string(enum.Pending), orderStatus())
+
+	// a payment that was not cancelled in time does not pay for more than it covers
+	resp, err = payments.CreatePayment(ctx, dto.PaymentCreate{OrderId: orderId, Method: enum.FakePayment})
+	require.NoError(t, err)
+	var current dto.Payment
+	responseData(t, resp, &current)
+	assert.Equal(t, 240.0, current.Amount)
+	current.Amount = 120
+	require.NoError(t, store.Update(context.Background(), "payments", repository.Where(repository.Eq("id", current.Id)), map[string]interface{}{"amount": 120}, nil))
+	err = webhook(current, "evt_2")
+	var httpErr errors.HTTPError
+	require.True(t, errors.As(err, &httpErr), "not an HTTP error: %v", err)
+	assert.Equal(t, "payment_short", httpErr.ErrorCode())
+	assert.Equal(t, string(enum.Pending), orderStatus())
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"SangXanh/pkg/auth"
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"SangXanh/pkg/permission"
+	"SangXanh/pkg/repository"
+	"SangXanh/pkg/ws"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"time"
+)
+
+type PostService interface {
+	ListPublishedPosts(ctx context.Context, filter dto.PostFilter) (api.Response, error)
+	ListPosts(ctx context.Context, filter dto.PostFilter) (api.Response, error)
+	GetPostById(ctx context.Context, id string) (api.Response, error)
+	GetPostBySlug(ctx context.Context, slug string) (api.Response, error)
+	CreatePost(ctx context.Context, req dto.PostCreate) (api.Response, error)
+	UpdatePost(ctx context.Context, req dto.PostUpdate) (api.Response, error)
+	DeletePost(ctx context.Context, id string) (api.Response, error)
+	AssignPost(ctx context.Context, req dto.PostAssign) (api.Response, error)
+	PublishPost(ctx context.Context, req dto.PostPublish) (api.Response, error)
+}
+
+type postService struct {
+	posts  repository.PostRepository
+	users  repository.UserRepository
+	events ws.Publisher
+	slugs  repository.SlugRepository
+}
+
+func NewPostService(di do.Injector) (PostService, error) {
+	posts, err := do.Invoke[repository.PostRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PostService: %w", err)
+	}
+	users, err := do.Invoke[repository.UserRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PostService: %w", err)
+	}
+	hub, err := do.Invoke[*ws.Hub](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PostService: %w", err)
+	}
+	slugs, err := do.Invoke[repository.SlugRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PostService: %w", err)
+	}
+	return &postService{posts: posts, users: users, events: hub, slugs: slugs}, nil
+}
+
+/* ------------------------------------------------------------------
+   Helpers
+   ------------------------------------------------------------------*/
+
+// postOwner is who answers for a post: the one it is assigned to, or whoever wrote it
+// while nobody is.
+func postOwner(post dto.Post) string {
+	if post.Assignee != "" {
+		return post.Assignee
+	}
+	return post.CreatedBy
+}
+
+// editablePost fetches a post the caller may take action on. Every staff member can
+// read any post, so someone else's post is refused rather than hidden.
+func (s *postService) editablePost(ctx context.Context, id string, action permission.Action) (dto.Post, error) {
+	scope, err := permission.Check(ctx, permission.Posts, action)
+	if err != nil {
+		return dto.Post{}, err
+	}
+	post, err := s.posts.Get(ctx, id, false)
+	if err != nil {
+		return dto.Post{}, err
+	}
+	if caller, _ := auth.FromContext(ctx); scope == permission.Own && postOwner(post) != caller.Id {
+		return dto.Post{}, errors.Forbidden("post %s is assigned to someone else", id).WithCode("permission_denied")
+	}
+	return post, nil
+}
+
+// validAssignee makes sure posts are only handed to marketing staff or admins
+func (s *postService) validAssignee(ctx context.Context, userId string) error {
+	user, err := s.users.Info(ctx, userId)
+	var notFound *errors.NotFoundError
+	if errors.As(err, &notFound) {
+		return errors.Unprocessable("assignee %s not found", userId)
+	}
+	if err != nil {
+		return err
+	}
+	if user.Role != enum.Marketing && user.Role != enum.Admin {
+		return errors.Unprocessable("posts can only be assigned to marketing or admin users")
+	}
+	return nil
+}
+
+func (s *postService) validatePost(ctx context.Context, req dto.PostCreate) error {
+	if !req.Type.IsValid() {
+		return errors.BadRequest("invalid post type %q", req.Type)
+	}
+	if req.Assignee != "" {
+		return s.validAssignee(ctx, req.Assignee)
+	}
+	return nil
+}
+
+/* ------------------------------------------------------------------
+   List & lookup
+   ------------------------------------------------------------------*/
+
+func (s *postService) listPosts(ctx context.Context, filter dto.PostFilter, publishedOnly bool) (api.Response, error) {
+	filter.Correct()
+	total, err := s.posts.Count(ctx, filter, publishedOnly)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := s.posts.List(ctx, filter, publishedOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.PostResponse, 0, len(posts))
+	for i := range posts {
+		responses = append(responses, dto.GetPostResponse(&posts[i]))
+	}
+	filter.SetTotal(int64(total))
+	return api.SuccessPagination(responses, &filter.Pagination), nil
+}
+
+func (s *postService) ListPublishedPosts(ctx context.Context, filter dto.PostFilter) (api.Response, error) {
+	return s.listPosts(ctx, filter, true)
+}
+
+func (s *postService) ListPosts(ctx context.Context, filter dto.PostFilter) (api.Response, error) {
+	return s.listPosts(ctx, filter, false)
+}
+
+func (s *postService) GetPostById(ctx context.Context, id string) (api.Response, error) {
+	post, err := s.posts.Get(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	return api.Success(dto.GetPostResponse(&post)), nil
+}
+
+// GetPostBySlug redirects to the current slug when slug is one the post had before.
+func (s *postService) GetPostBySlug(ctx context.Context, slug string) (api.Response, error) {
+	id, current, err := s.slugs.Resolve(ctx, "posts", slug)
+	if err != nil {
+		return nil, err
+	}
+	if current != slug {
+		return api.Redirect(current), nil
+	}
+	post, err := s.posts.Get(ctx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	return api.Success(dto.GetPostResponse(&post)), nil
+}
+
+/* ------------------------------------------------------------------
+   Write
+   ------------------------------------------------------------------*/
+
+// CreatePost always starts as a draft; publishing is a separate step.
+func (s *postService) CreatePost(ctx context.Context, req dto.PostCreate) (api.Response, error) {
+	if err := s.validatePost(ctx, req); err != nil {
+		return nil, err
+	}
+	slug, err := s.slugs.Unique(ctx, "posts", req.Slug, req.Title, "")
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"title":     req.Title,
+		"slug":      slug,
+		"content":   req.Content,
+		"thumbnail": req.Thumbnail,
+		"type":      req.Type,
+		"status":    false,
+		"metadata":  req.Metadata,
+	}
+	if req.Assignee != "" {
+		body["assignee"] = req.Assignee
+	}
+	if caller, ok := auth.FromContext(ctx); ok {
+		body["created_by"] = caller.Id
+	}
+
+	created, err := s.posts.Create(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	return api.Success(dto.GetPostResponse(&created)), nil
+}
+
+func (s *postService) UpdatePost(ctx context.Context, req dto.PostUpdate) (api.Response, error) {
+	post, err := s.editablePost(ctx, req.Id, permission.Update)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validatePost(ctx, req.PostCreate); err != nil {
+		return nil, err
+	}
+
+	// keep the published URL stable unless a new slug is asked for explicitly
+	slug, err := nextSlug(ctx, s.slugs, "posts", req.Id, post.Slug, req.Slug, req.Title, false)
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"title":      req.Title,
+		"slug":       slug,
+		"content":    req.Content,
+		"thumbnail":  req.Thumbnail,
+		"type":       req.Type,
+		"metadata":   req.Metadata,
+		"updated_at": time.Now(),
+	}
+	if req.Assignee != "" {
+		body["assignee"] = req.Assignee
+	}
+
+	updated, err := s.posts.Update(ctx, req.Id, body)
+	if err != nil {
+		return nil, err
+	}
+	if err := retireSlug(ctx, s.slugs, "posts", req.Id, post.Slug, slug); err != nil {
+		return nil, err
+	}
+	return api.Success(dto.GetPostResponse(&updated)), nil
+}
+
+func (s *postService) DeletePost(ctx context.Context, id string) (api.Response, error) {
+	if _, err := s.editablePost(ctx, id, permission.Delete); err != nil {
+		return nil, err
+	}
+	if err := s.posts.SoftDelete(ctx, id); err != nil {
+		return nil, err
+	}
+	return api.Success("Post deleted successfully"), nil
+}
+
+// AssignPost hands a post over to another marketing user or admin.
+func (s *postService) AssignPost(ctx context.Context, req dto.PostAssign) (api.Response, error) {
+	if _, err := s.editablePost(ctx, req.Id, permission.Manage); err != nil {
+		return nil, err
+	}
+	if err := s.validAssignee(ctx, req.Assignee); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.posts.Update(ctx, req.Id, map[string]interface{}{"assignee": req.Assignee, "updated_at": time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	return api.Success(dto.GetPostResponse(&updated)), nil
+}
+
+// PublishPost moves a post between draft and published. The first publication date
+// is kept when a post is taken offline and published again.
+func (s *postService) PublishPost(ctx context.Context, req dto.PostPublish) (api.Response, error) {
+	if req.Status != enum.Draft && req.Status != enum.Published {
+		return nil, errors.BadRequest("invalid post status %q", req.Status)
+	}
+	post, err := s.editablePost(ctx, req.Id, permission.Manage)
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"status":     req.Status == enum.Published,
+		"updated_at": time.Now(),
+	}
+	if req.Status == enum.Published && post.PublishedAt == nil {
+		body["published_at"] = time.Now()
+	}
+
+	updated, err := s.posts.Update(ctx, req.Id, body)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status == enum.Published && !post.Status {
+		s.events.Publish(enum.PostPublishedEvent, dto.PostEvent{
+			Id:    post.Id,
+			Title: updated.Title,
+			Slug:  updated.Slug,
+			Type:  updated.Type,
+		}, ws.BroadcastRoom)
+	}
+	return api.Success(dto.GetPostResponse(&updated)), nil
+}
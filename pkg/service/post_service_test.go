@@ -0,0 +1,62 @@
+package service
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"SangXanh/pkg/repository"
+	"SangXanh/pkg/ws"
+	"context"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPostWorkflow(t *testing.T) {
+	di := do.New()
+	store := repository.InjectMemory(di)
+	do.ProvideValue(di, ws.New())
+	do.Provide(di, NewPostService)
+	store.Seed("users",
+		map[string]interface{}{"id": "m1", "username": "lan", "role": enum.Marketing},
+		map[string]interface{}{"id": "m2", "username": "hoa", "role": enum.Marketing},
+		map[string]interface{}{"id": "u1", "username": "minh", "role": enum.User},
+	)
+	posts := do.MustInvoke[PostService](di)
+	lan, hoa := signedIn("m1", enum.Marketing), signedIn("m2", enum.Marketing)
+
+	resp, err := posts.CreatePost(lan, dto.PostCreate{Title: "Mùa lúa mới", Type: enum.PostNews})
+	require.NoError(t, err)
+	var post dto.PostResponse
+	responseData(t, resp, &post)
+	assert.Equal(t, "mua-lua-moi", post.Slug)
+	assert.Equal(t, enum.Draft, post.Status)
+
+	_, err = posts.AssignPost(lan, dto.PostAssign{Id: post.Id, Assignee: "u1"})
+	var unprocessable *errors.UnprocessableError
+	assert.True(t, errors.As(err, &unprocessable), "only marketing staff or admins take posts")
+	_, err = posts.AssignPost(lan, dto.PostAssign{Id: post.Id, Assignee: "m2"})
+	require.NoError(t, err)
+	_, err = posts.PublishPost(lan, dto.PostPublish{Id: post.Id, Status: enum.Published})
+	var httpErr errors.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, "permission_denied", httpErr.ErrorCode(), "the post is hoa's now")
+
+	_, err = posts.PublishPost(hoa, dto.PostPublish{Id: post.Id, Status: enum.Published})
+	require.NoError(t, err)
+	filter := dto.PostFilter{}
+	filter.Page, filter.Limit = 1, 10
+	resp, err = posts.ListPublishedPosts(context.Background(), filter)
+	require.NoError(t, err)
+	var published []dto.PostResponse
+	responseData(t, resp, &published)
+	require.Len(t, published, 1)
+	assert.NotNil(t, published[0].PublishedAt)
+
+	_, err = posts.DeletePost(hoa, post.Id)
+	require.NoError(t, err)
+	_, err = posts.GetPostBySlug(context.Background(), "mua-lua-moi")
+	var notFound *errors.NotFoundError
+	assert.True(t, errors.As(err, &notFound))
+}
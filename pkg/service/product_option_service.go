@@ -2,13 +2,15 @@ package service
 
 import (
 	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
 	"SangXanh/pkg/dto"
 	"context"
 	"fmt"
 	"time"
 
-	"github.com/nedpals/supabase-go"
+	"SangXanh/pkg/repository"
 	"github.com/samber/do/v2"
+	"github.com/samber/lo"
 )
 
 type ProductOptionService interface {
@@ -21,15 +23,25 @@ type ProductOptionService interface {
 }
 
 type productOptionService struct {
-	db *supabase.Client
+	options  repository.ProductOptionRepository
+	variants repository.ProductVariantRepository
+	products repository.ProductRepository
 }
 
 func NewProductOptionService(di do.Injector) (ProductOptionService, error) {
-	db, err := do.Invoke[*supabase.Client](di)
+	options, err := do.Invoke[repository.ProductOptionRepository](di)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize ProductOptionService: %w", err)
 	}
-	return &productOptionService{db: db}, nil
+	variants, err := do.Invoke[repository.ProductVariantRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductOptionService: %w", err)
+	}
+	products, err := do.Invoke[repository.ProductRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductOptionService: %w", err)
+	}
+	return &productOptionService{options: options, variants: variants, products: products}, nil
 }
 
 func (s *productOptionService) ListProductOptions(
@@ -38,14 +50,9 @@ func (s *productOptionService) ListProductOptions(
 ) (api.Response, error) {
 
 	// ─── ① fetch options (same as before) ──────────────────────────────────
-	var raw []dto.ProductOption
-	if err := s.db.DB.
-		From("product_options").
-		Select("id,name,product_id,price,detail,metadata,created_at,updated_at").
-		Eq("product_id", productId).
-		IsNull("deleted_at").
-		Execute(&raw); err != nil {
-		return nil, fmt.Errorf("failed to fetch product options: %w", err)
+	raw, err := s.options.ListByProduct(ctx, productId)
+	if err != nil {
+		return nil, err
 	}
 
 	// ─── ② collect every variant_id we saw ─────────────────────────────────
@@ -57,7 +64,7 @@ func (s *productOptionService) ListProductOptions(
 	}
 
 	// ─── ③ one query to resolve names ─────────────────────────────────────
-	nameByID, err := s.loadVariantNames(productId, variantIDs)
+	nameByID, err := s.variants.Names(ctx, productId, variantIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -70,6 +77,7 @@ func (s *productOptionService) ListProductOptions(
 			Name:      opt.Name,
 			ProductId: opt.ProductId,
 			Price:     opt.Price,
+			Stock:     opt.Stock,
 			Metadata:  opt.Metadata,
 			CreatedAt: opt.CreatedAt,
 			UpdatedAt: opt.UpdatedAt,
@@ -89,20 +97,19 @@ func (s *productOptionService) ListProductOptions(
 
 func (s *productOptionService) CreateProductOption(ctx context.Context, req dto.ProductOptionCreate) (api.Response, error) {
 	// Validate product existence
-	if err := s.validProduct(req.ProductId); err != nil {
+	if err := s.validProduct(ctx, req.ProductId); err != nil {
 		return nil, err
 	}
 
-	var created []dto.ProductOption
-	if err := s.db.DB.
-		From("product_options").
-		Insert(req).
-		Execute(&created); err != nil {
-		return nil, fmt.Errorf("failed to create product option: %v", err)
+	created, err := s.options.Create(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 	return api.Success(created[0]), nil
 }
 
+// UpdateProductOption leaves the stock alone, it only changes through the guarded
+// adjustments of the InventoryService.
 func (s *productOptionService) UpdateProductOption(ctx context.Context, req dto.ProductOptionUpdate) (api.Response, error) {
 	updateData := map[string]interface{}{
 		"name":       req.Name,
@@ -112,59 +119,40 @@ func (s *productOptionService) UpdateProductOption(ctx context.Context, req dto.
 		"updated_at": time.Now(),
 	}
 
-	var updated []dto.ProductOption
-	if err := s.db.DB.
-		From("product_options").
-		Update(updateData).
-		Eq("id", req.Id).
-		Execute(&updated); err != nil {
-		return nil, fmt.Errorf("failed to update product option: %v", err)
+	updated, err := s.options.Update(ctx, req.Id, "", updateData)
+	if err != nil {
+		return nil, err
 	}
 
-	return api.Success(updated[0]), nil
+	return api.Success(updated), nil
 }
 
 func (s *productOptionService) DeleteProductOption(ctx context.Context, id string) (api.Response, error) {
-	updateData := map[string]interface{}{
-		"deleted_at": time.Now(),
-	}
-
-	var updated []dto.ProductOption
-	if err := s.db.DB.
-		From("product_options").
-		Update(updateData).
-		Eq("id", id).
-		Execute(&updated); err != nil {
-		return nil, fmt.Errorf("failed to delete product option: %v", err)
+	if err := s.options.SoftDelete(ctx, id); err != nil {
+		return nil, err
 	}
 
 	return api.Success("Product option deleted successfully"), nil
 }
 
-func (s *productOptionService) validProduct(id string) error {
-	var product []dto.Product
-	err := s.db.DB.
-		From("products").
-		Select("id").
-		Eq("id", id).
-		IsNull("deleted_at").
-		Execute(&product)
+func (s *productOptionService) validProduct(ctx context.Context, id string) error {
+	exists, err := s.products.Exists(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to find product: %v", err)
+		return err
 	}
-	if len(product) == 0 {
-		return fmt.Errorf("product not found")
+	if !exists {
+		return errors.NotFound("product not found")
 	}
 	return nil
 }
 
 func (s *productOptionService) CreateBulkProductOption(ctx context.Context, req dto.ProductOptionCreateBulk) (api.Response, error) {
 	if len(req.Options) == 0 {
-		return nil, fmt.Errorf("no product options to create")
+		return nil, errors.BadRequest("no product options to create")
 	}
 
 	// Validate the product only once
-	if err := s.validProduct(req.ProductId); err != nil {
+	if err := s.validProduct(ctx, req.ProductId); err != nil {
 		return nil, fmt.Errorf("validation failed for product_id %s: %w", req.ProductId, err)
 	}
 
@@ -174,7 +162,7 @@ func (s *productOptionService) CreateBulkProductOption(ctx context.Context, req
 			allIds = append(allIds, d.VariantId)
 		}
 	}
-	_, err := s.fetchAndValidateVariants(req.ProductId, allIds)
+	_, err := s.fetchAndValidateVariants(ctx, req.ProductId, allIds)
 	if err != nil {
 		return nil, err
 	}
@@ -184,12 +172,9 @@ func (s *productOptionService) CreateBulkProductOption(ctx context.Context, req
 		req.Options[i].ProductId = req.ProductId
 	}
 
-	var created []dto.ProductOption
-	if err := s.db.DB.
-		From("product_options").
-		Insert(req.Options).
-		Execute(&created); err != nil {
-		return nil, fmt.Errorf("failed to create bulk product options: %v", err)
+	created, err := s.options.Create(ctx, req.Options)
+	if err != nil {
+		return nil, err
 	}
 
 	return api.Success(created), nil
@@ -202,9 +187,9 @@ func (s *productOptionService) UpdateBulkProductOption(
 
 	// 0️⃣  Sanity checks -----------------------------------------------------
 	if len(req.Options) == 0 {
-		return nil, fmt.Errorf("no product options to update")
+		return nil, errors.BadRequest("no product options to update")
 	}
-	if err := s.validProduct(req.ProductId); err != nil {
+	if err := s.validProduct(ctx, req.ProductId); err != nil {
 		return nil, err
 	}
 	var allIds []string
@@ -213,20 +198,15 @@ func (s *productOptionService) UpdateBulkProductOption(
 			allIds = append(allIds, d.VariantId)
 		}
 	}
-	_, err := s.fetchAndValidateVariants(req.ProductId, allIds)
+	_, err := s.fetchAndValidateVariants(ctx, req.ProductId, allIds)
 	if err != nil {
 		return nil, err
 	}
 
 	// 1️⃣  Load current, non-deleted options for this product ---------------
-	var current []dto.ProductOption
-	if err := s.db.DB.
-		From("product_options").
-		Select("id").
-		Eq("product_id", req.ProductId).
-		IsNull("deleted_at").
-		Execute(&current); err != nil {
-		return nil, fmt.Errorf("failed to fetch current product options: %v", err)
+	current, err := s.options.ListByProduct(ctx, req.ProductId)
+	if err != nil {
+		return nil, err
 	}
 
 	existingIDs := map[string]bool{}
@@ -246,15 +226,13 @@ func (s *productOptionService) UpdateBulkProductOption(
 				ProductId: req.ProductId,
 				Name:      opt.Name,
 				Price:     opt.Price,
+				Stock:     opt.Stock,
 				Detail:    opt.Detail,
 				Metadata:  opt.Metadata,
 			}
-			var created []dto.ProductOption
-			if err := s.db.DB.
-				From("product_options").
-				Insert(createProduct).
-				Execute(&created); err != nil {
-				return nil, fmt.Errorf("failed to create option %q: %v", opt.Name, err)
+			created, err := s.options.Create(ctx, createProduct)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create option %q: %w", opt.Name, err)
 			}
 			result = append(result, created...)
 			continue
@@ -266,124 +244,54 @@ func (s *productOptionService) UpdateBulkProductOption(
 		updateData := map[string]interface{}{
 			"name":       opt.Name,
 			"price":      opt.Price,
+			"stock":      opt.Stock,
 			"detail":     opt.Detail,
 			"metadata":   opt.Metadata,
 			"updated_at": now,
 		}
 
-		var updated []dto.ProductOption
-		if err := s.db.DB.
-			From("product_options").
-			Update(updateData).
-			Eq("id", opt.Id).
-			Eq("product_id", req.ProductId). // extra guard
-			Execute(&updated); err != nil {
-			return nil, fmt.Errorf("failed to update option %s: %v", opt.Id, err)
+		updated, err := s.options.Update(ctx, opt.Id, req.ProductId, updateData)
+		if err != nil {
+			return nil, err
 		}
-		result = append(result, updated...)
+		result = append(result, updated)
 	}
 
 	// 3️⃣  Soft-delete rows not included in the payload ---------------------
+	var removed []string
 	for id := range existingIDs {
 		if !payloadIDs[id] {
-			if err := s.db.DB.
-				From("product_options").
-				Update(map[string]interface{}{"deleted_at": now}).
-				Eq("id", id).
-				Execute(nil); err != nil { // no RETURNING needed
-				return nil, fmt.Errorf("failed to soft-delete option %s: %v", id, err)
-			}
+			removed = append(removed, id)
 		}
 	}
+	if err := s.options.SoftDelete(ctx, removed...); err != nil {
+		return nil, err
+	}
 
 	return api.Success(result), nil
 }
 
 func (s *productOptionService) fetchAndValidateVariants(
+	ctx context.Context,
 	productId string,
 	variantIDs []string,
 ) (map[string]string, error) {
 
-	// de-duplicate to keep the SQL IN (…) short
-	uniq := make(map[string]struct{}, len(variantIDs))
-	for _, id := range variantIDs {
-		uniq[id] = struct{}{}
-	}
-	ids := make([]string, 0, len(uniq))
-	for id := range uniq {
-		ids = append(ids, id)
-	}
-
-	// ---- single query ----------------------------------------------------
-	var rows []struct {
-		Id   string `json:"id"`
-		Name string `json:"name"`
-	}
-	if err := s.db.DB.
-		From("product_variants").
-		Select("id,name").
-		Eq("product_id", productId).
-		In("id", ids).
-		IsNull("deleted_at").
-		Execute(&rows); err != nil {
-		return nil, fmt.Errorf("failed to load variants: %w", err)
-	}
-
-	names := make(map[string]string, len(rows))
-	for _, r := range rows {
-		names[r.Id] = r.Name
-		delete(uniq, r.Id) // remove anything we actually found
+	names, err := s.variants.Names(ctx, productId, variantIDs)
+	if err != nil {
+		return nil, err
 	}
 
-	// anything still in uniq is missing in DB ------------------------------
-	if len(uniq) > 0 {
-		missing := make([]string, 0, len(uniq))
-		for id := range uniq {
+	// anything we did not find is missing in DB ----------------------------
+	var missing []string
+	for _, id := range variantIDs {
+		if _, ok := names[id]; !ok && !lo.Contains(missing, id) {
 			missing = append(missing, id)
 		}
-		return names, fmt.Errorf("unknown variant_id(s): %v", missing)
 	}
-
-	return names, nil // every id is legit
-}
-
-// returns map[variantId]variantName (empty map if no IDs given)
-func (s *productOptionService) loadVariantNames(
-	productId string,
-	variantIDs []string,
-) (map[string]string, error) {
-
-	if len(variantIDs) == 0 {
-		return map[string]string{}, nil
-	}
-
-	// deduplicate so the SQL IN (…) stays short
-	uniq := make(map[string]struct{}, len(variantIDs))
-	for _, id := range variantIDs {
-		uniq[id] = struct{}{}
-	}
-	ids := make([]string, 0, len(uniq))
-	for id := range uniq {
-		ids = append(ids, id)
-	}
-
-	var rows []struct {
-		Id   string `json:"id"`
-		Name string `json:"name"`
-	}
-	if err := s.db.DB.
-		From("product_variants").
-		Select("id,name").
-		Eq("product_id", productId).
-		In("id", ids).
-		IsNull("deleted_at").
-		Execute(&rows); err != nil {
-		return nil, fmt.Errorf("failed to load variant names: %w", err)
+	if len(missing) > 0 {
+		return names, errors.Unprocessable("unknown variant_id(s): %v", missing)
 	}
 
-	out := make(map[string]string, len(rows))
-	for _, r := range rows {
-		out[r.Id] = r.Name
-	}
-	return out, nil
+	return names, nil // every id is legit
 }
@@ -3,105 +3,96 @@ package service
 import (
 	"SangXanh/pkg/common/api"
 	"SangXanh/pkg/dto"
+	"SangXanh/pkg/repository"
 	"context"
 	"fmt"
-	"github.com/nedpals/supabase-go"
 	"github.com/samber/do/v2"
-	"net/url"
-	"strconv"
 	"time"
 )
 
 type ProductService interface {
 	ListProducts(ctx context.Context, filter dto.ProductFilter) (api.Response, error)
+	SearchProducts(ctx context.Context, req dto.ProductSearch) (api.Response, error)
 	CreateProduct(ctx context.Context, req dto.ProductCreated) (api.Response, error)
 	UpdateProduct(ctx context.Context, req dto.ProductUpdated) (api.Response, error)
 	DeleteProduct(ctx context.Context, id string) (api.Response, error)
 	GetProductById(ctx context.Context, id string) (api.Response, error)
+	// GetProductBySlug returns the product like GetProductById, or redirects to the
+	// current slug when slug is one the product had before.
+	GetProductBySlug(ctx context.Context, slug string) (api.Response, error)
 }
 
 type productService struct {
-	db *supabase.Client
+	products repository.ProductRepository
+	options  repository.ProductOptionRepository
+	variants repository.ProductVariantRepository
+	category repository.CategoryRepository
+	searcher repository.ProductSearcher
+	slugs    repository.SlugRepository
 }
 
 func NewProductService(di do.Injector) (ProductService, error) {
-	db, err := do.Invoke[*supabase.Client](di)
+	products, err := do.Invoke[repository.ProductRepository](di)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize UserService: %w", err)
+		return nil, fmt.Errorf("failed to initialize ProductService: %w", err)
 	}
-
-	return &productService{db: db}, nil
-}
-
-func (s *productService) countProducts(ctx context.Context, filter dto.ProductFilter) (int, error) {
-	// Start the same base query you use in ListProducts
-	q := s.db.DB.From("products").
-		Select("id").
-		IsNull("deleted_at") // keep soft‑deleted rows out
-	// Apply the same filter conditions
-	if filter.Name != "" {
-		encoded := url.QueryEscape("%" + filter.Name + "%")
-		q = q.Like("name", encoded)
-	}
-	if filter.CategoryId != "" {
-		q = q.Eq("category_id", filter.CategoryId)
+	options, err := do.Invoke[repository.ProductOptionRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductService: %w", err)
 	}
-	if filter.IsDiscount {
-		q = q.Not().IsNull("discount")
+	variants, err := do.Invoke[repository.ProductVariantRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductService: %w", err)
 	}
-	if filter.GreaterThan > 0 {
-		q = q.Gt("price", strconv.FormatFloat(filter.GreaterThan, 'f', -1, 32))
+	category, err := do.Invoke[repository.CategoryRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductService: %w", err)
 	}
-	if filter.SmallerThan > 0 {
-		q = q.Lt("price", strconv.FormatFloat(filter.SmallerThan, 'f', -1, 32))
+	searcher, err := do.Invoke[repository.ProductSearcher](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductService: %w", err)
 	}
-
-	// Execute, discard the row data, keep the count
-	var tmp []struct{} // dummy slice – we only care about the header that carries the count
-	if err := q.Execute(&tmp); err != nil {
-		return 0, fmt.Errorf("failed to count products: %w", err)
+	slugs, err := do.Invoke[repository.SlugRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductService: %w", err)
 	}
 
-	return len(tmp), nil
+	return &productService{products: products, options: options, variants: variants, category: category, searcher: searcher, slugs: slugs}, nil
 }
 
 func (s *productService) ListProducts(ctx context.Context, filter dto.ProductFilter) (api.Response, error) {
-	total, err := s.countProducts(ctx, filter)
+	filter.Correct()
+	total, err := s.products.Count(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	// 2. fetch the current page
-	var products []dto.ProductList
-	query := s.db.DB.From("products").
-		Select("id,name,price,content,image_detail,category_id,thumbnail,discount,discount_type,categories!inner(id,name),created_at,updated_at").
-		LimitWithOffset(int(filter.Limit), int((filter.Page-1)*filter.Limit)).
-		IsNull("deleted_at")
-
-	if filter.Name != "" {
-		encoded := url.QueryEscape("%" + filter.Name + "%")
-		query = query.Like("name", encoded)
-	}
-	if filter.CategoryId != "" {
-		query = query.Eq("category_id", filter.CategoryId)
-	}
-	if filter.IsDiscount {
-		query = query.Not().IsNull("discount")
+	products, cursors, err := s.products.List(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
-	if filter.GreaterThan > 0 {
-		query = query.Gt("price", strconv.FormatFloat(filter.GreaterThan, 'f', -1, 32))
+
+	filter.SetTotal(int64(total))
+	filter.NextCursor, filter.PrevCursor = cursors.Next, cursors.Prev
+	if !filter.Facets {
+		return api.SuccessPagination(products, &filter.Pagination), nil
 	}
-	if filter.SmallerThan > 0 {
-		query = query.Lt("price", strconv.FormatFloat(filter.SmallerThan, 'f', -1, 32))
+	facets, err := s.products.Facets(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
+	return api.SuccessFacets(products, &filter.Pagination, facets), nil
+}
 
-	if err := query.Execute(&products); err != nil {
-		return nil, fmt.Errorf("failed to fetch products: %w", err)
+// SearchProducts ranks products by relevance, the sort parameter does not apply.
+func (s *productService) SearchProducts(ctx context.Context, req dto.ProductSearch) (api.Response, error) {
+	req.Correct()
+	hits, total, err := s.searcher.Search(ctx, req)
+	if err != nil {
+		return nil, err
 	}
-
-	// 3. fill in pagination meta & return
-	filter.Pagination.Total = int64(total)
-	return api.SuccessPagination(products, &filter.Pagination), nil
+	req.SetTotal(int64(total))
+	return api.SuccessPagination(hits, &req.Pagination), nil
 }
 
 func (s *productService) CreateProduct(ctx context.Context, req dto.ProductCreated) (api.Response, error) {
@@ -117,66 +108,90 @@ func (s *productService) CreateProduct(ctx context.Context, req dto.ProductCreat
 		Metadata:     req.Metadata,
 		Description:  req.Description,
 		ProductCode:  req.ProductCode,
+		Seo:          req.Seo,
 	}
 
-	err := s.validCategory(req.CategoryId)
-	var product []dto.Product
-	err = s.db.DB.From("products").Insert(newProduct).Execute(&product)
+	if err := s.validCategory(ctx, req.CategoryId); err != nil {
+		return nil, err
+	}
+	slug, err := s.slugs.Unique(ctx, "products", req.Slug, req.Name, "")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create product: %v", err)
+		return nil, err
 	}
+	newProduct.Slug = slug
+	product, err := s.products.Create(ctx, newProduct)
+	if err != nil {
+		return nil, err
+	}
+	s.searcher.Invalidate()
 
-	return api.Success(product[0]), nil
+	return api.Success(product), nil
 }
 
 func (s *productService) UpdateProduct(ctx context.Context, req dto.ProductUpdated) (api.Response, error) {
+	current, err := s.products.Get(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	slug, err := nextSlug(ctx, s.slugs, "products", req.Id, current.Slug, req.Slug, req.Name, req.Name != current.Name)
+	if err != nil {
+		return nil, err
+	}
 	updateData := map[string]interface{}{
-		"name":          req.Name,
-		"price":         req.Price,
-		"content":       req.Content,
-		"image_detail":  req.ImageDetail,
-		"thumbnail":     req.Thumbnail,
-		"category_id":   req.CategoryId,
-		"discount":      req.Discount,
-		"description":   req.Description,
-		"product_code":  req.ProductCode,
-		"discount_type": req.DiscountType,
-		"metadata":      req.Metadata,
-		"updated_at":    time.Now(),
+		"name":             req.Name,
+		"slug":             slug,
+		"price":            req.Price,
+		"content":          req.Content,
+		"image_detail":     req.ImageDetail,
+		"thumbnail":        req.Thumbnail,
+		"category_id":      req.CategoryId,
+		"discount":         req.Discount,
+		"description":      req.Description,
+		"product_code":     req.ProductCode,
+		"discount_type":    req.DiscountType,
+		"metadata":         req.Metadata,
+		"meta_title":       req.MetaTitle,
+		"meta_description": req.MetaDescription,
+		"canonical_image":  req.CanonicalImage,
+		"updated_at":       time.Now(),
+	}
+	if err := s.validCategory(ctx, req.CategoryId); err != nil {
+		return nil, err
 	}
-	err := s.validCategory(req.CategoryId)
 
-	var product []dto.Product
-	err = s.db.DB.From("products").Update(updateData).Eq("id", req.Id).Execute(&product)
+	product, err := s.products.Update(ctx, req.Id, updateData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update product: %v", err)
+		return nil, err
+	}
+	if err := retireSlug(ctx, s.slugs, "products", req.Id, current.Slug, slug); err != nil {
+		return nil, err
 	}
-	return api.Success(product[0]), nil
+	s.searcher.Invalidate()
+	return api.Success(product), nil
 }
 
 func (s *productService) DeleteProduct(ctx context.Context, id string) (api.Response, error) {
-	updateData := map[string]interface{}{
-		"deleted_at": time.Now(),
-	}
-
-	var product []dto.Product
-	err := s.db.DB.From("products").Update(updateData).Eq("id", id).Execute(&product)
-	if err != nil {
-		return nil, fmt.Errorf("failed to soft delete product: %v", err)
+	if err := s.products.SoftDelete(ctx, id); err != nil {
+		return nil, err
 	}
+	s.searcher.Invalidate()
 	return api.Success("Product deleted successfully"), nil
 }
 
-func (s *productService) validCategory(id string) error {
-	var category []dto.Category
-	err := s.db.DB.From("categories").Select("id").Eq("id", id).IsNull("deleted_at").Execute(&category)
+func (s *productService) validCategory(ctx context.Context, id string) error {
+	_, err := s.category.Get(ctx, id)
+	return err
+}
+
+func (s *productService) GetProductBySlug(ctx context.Context, slug string) (api.Response, error) {
+	id, current, err := s.slugs.Resolve(ctx, "products", slug)
 	if err != nil {
-		return fmt.Errorf("failed to find category: %v", err)
+		return nil, err
 	}
-	if len(category) == 0 {
-		return fmt.Errorf("category not found")
+	if current != slug {
+		return api.Redirect(current), nil
 	}
-	return nil
+	return s.GetProductById(ctx, id)
 }
 
 // GetProductById returns a full product document (base info + category +
@@ -189,31 +204,17 @@ func (s *productService) GetProductById(
 	/*───────────────────────────────────────────────────────*
 	 * 1)   Base product (+ category)                       *
 	 *───────────────────────────────────────────────────────*/
-	var rows []dto.ProductDetail
-	if err := s.db.DB.
-		From("products").
-		Select("id,name,price,content,image_detail,description,product_code,category_id,thumbnail,discount,discount_type,categories!inner(id,name),created_at,updated_at").
-		Eq("id", id).
-		IsNull("deleted_at").
-		Execute(&rows); err != nil {
-		return nil, fmt.Errorf("failed to fetch product: %w", err)
-	}
-	if len(rows) == 0 {
-		return nil, fmt.Errorf("product not found")
+	product, err := s.products.Get(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	product := rows[0]
 
 	/*───────────────────────────────────────────────────────*
 	 * 2)   Options (raw)                                   *
 	 *───────────────────────────────────────────────────────*/
-	var optRaw []dto.ProductOption
-	if err := s.db.DB.
-		From("product_options").
-		Select("id,name,product_id,price,detail,metadata,created_at,updated_at").
-		Eq("product_id", id).
-		IsNull("deleted_at").
-		Execute(&optRaw); err != nil {
-		return nil, fmt.Errorf("failed to fetch product options: %w", err)
+	optRaw, err := s.options.ListByProduct(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 
 	/*── gather variant_ids for a single name-lookup query ─*/
@@ -223,7 +224,7 @@ func (s *productService) GetProductById(
 			variantIDs = append(variantIDs, d.VariantId)
 		}
 	}
-	nameByID, err := s.loadVariantNames(id, variantIDs)
+	nameByID, err := s.variants.Names(ctx, id, variantIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -251,6 +252,7 @@ func (s *productService) GetProductById(
 			Name:      o.Name,
 			ProductId: o.ProductId,
 			Price:     o.Price,
+			Stock:     o.Stock,
 			Metadata:  o.Metadata,
 			CreatedAt: o.CreatedAt,
 			UpdatedAt: o.UpdatedAt,
@@ -269,14 +271,9 @@ func (s *productService) GetProductById(
 	/*───────────────────────────────────────────────────────*
 	 * 3)   Variants list (unchanged fetch)                 *
 	 *───────────────────────────────────────────────────────*/
-	var variants []dto.ProductVariant
-	if err := s.db.DB.
-		From("product_variants").
-		Select("id,name,product_id,detail,metadata,created_at,updated_at").
-		Eq("product_id", id).
-		IsNull("deleted_at").
-		Execute(&variants); err != nil {
-		return nil, fmt.Errorf("failed to fetch product variants: %w", err)
+	variants, err := s.variants.ListByProduct(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 
 	/*───────────────────────────────────────────────────────*
@@ -289,44 +286,3 @@ func (s *productService) GetProductById(
 
 	return api.Success(product), nil
 }
-
-// returns map[variantId]variantName (empty map if no IDs given)
-func (s *productService) loadVariantNames(
-	productId string,
-	variantIDs []string,
-) (map[string]string, error) {
-
-	if len(variantIDs) == 0 {
-		return map[string]string{}, nil
-	}
-
-	// deduplicate so the SQL IN (…) stays short
-	uniq := make(map[string]struct{}, len(variantIDs))
-	for _, id := range variantIDs {
-		uniq[id] = struct{}{}
-	}
-	ids := make([]string, 0, len(uniq))
-	for id := range uniq {
-		ids = append(ids, id)
-	}
-
-	var rows []struct {
-		Id   string `json:"id"`
-		Name string `json:"name"`
-	}
-	if err := s.db.DB.
-		From("product_variants").
-		Select("id,name").
-		Eq("product_id", productId).
-		In("id", ids).
-		IsNull("deleted_at").
-		Execute(&rows); err != nil {
-		return nil, fmt.Errorf("failed to load variant names: %w", err)
-	}
-
-	out := make(map[string]string, len(rows))
-	for _, r := range rows {
-		out[r.Id] = r.Name
-	}
-	return out, nil
-}
@@ -2,12 +2,13 @@ package service
 
 import (
 	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
 	"SangXanh/pkg/dto"
 	"context"
 	"fmt"
 	"time"
 
-	"github.com/nedpals/supabase-go"
+	"SangXanh/pkg/repository"
 	"github.com/samber/do/v2"
 )
 
@@ -21,28 +22,33 @@ type ProductVariantService interface {
 }
 
 type productVariantService struct {
-	db *supabase.Client
+	variants repository.ProductVariantRepository
+	options  repository.ProductOptionRepository
+	products repository.ProductRepository
 }
 
 func NewProductVariantService(di do.Injector) (ProductVariantService, error) {
-	db, err := do.Invoke[*supabase.Client](di)
+	variants, err := do.Invoke[repository.ProductVariantRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductVariantService: %w", err)
+	}
+	options, err := do.Invoke[repository.ProductOptionRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ProductVariantService: %w", err)
+	}
+	products, err := do.Invoke[repository.ProductRepository](di)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize ProductVariantService: %w", err)
 	}
 
-	return &productVariantService{db: db}, nil
+	return &productVariantService{variants: variants, options: options, products: products}, nil
 }
 
 // ListProductVariants fetches all variants that have not been soft-deleted (deleted_at IS NULL).
 func (s *productVariantService) ListProductVariants(ctx context.Context, productId string) (api.Response, error) {
-	var variants []dto.ProductVariant
-	err := s.db.DB.From("product_variants").
-		Select("id,name,product_id,detail,metadata,created_at,updated_at").
-		Eq("product_id", productId).
-		IsNull("deleted_at").
-		Execute(&variants)
+	variants, err := s.variants.ListByProduct(ctx, productId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch product variants: %v", err)
+		return nil, err
 	}
 
 	return api.Success(variants), nil
@@ -51,16 +57,13 @@ func (s *productVariantService) ListProductVariants(ctx context.Context, product
 // CreateProductVariant inserts a new product variant into the database.
 func (s *productVariantService) CreateProductVariant(ctx context.Context, req dto.ProductVariantCreate) (api.Response, error) {
 	// Ensure the referenced product exists (similar to validCategory in your product service).
-	if err := s.validProduct(req.ProductId); err != nil {
+	if err := s.validProduct(ctx, req.ProductId); err != nil {
 		return nil, err
 	}
 
-	var created []dto.ProductVariant
-	err := s.db.DB.From("product_variants").
-		Insert(req).
-		Execute(&created)
+	created, err := s.variants.Create(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create product variant: %v", err)
+		return nil, err
 	}
 
 	return api.Success(created[0]), nil
@@ -76,16 +79,12 @@ func (s *productVariantService) UpdateProductVariant(ctx context.Context, req dt
 		"updated_at": time.Now(),
 	}
 
-	var updated []dto.ProductVariant
-	err := s.db.DB.From("product_variants").
-		Update(updateData).
-		Eq("id", req.Id).
-		Execute(&updated)
+	updated, err := s.variants.Update(ctx, req.Id, "", updateData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update product variant: %v", err)
+		return nil, err
 	}
 
-	return api.Success(updated[0]), nil
+	return api.Success(updated), nil
 }
 
 // DeleteProductVariant performs a soft delete by setting deleted_at,
@@ -100,43 +99,24 @@ func (s *productVariantService) DeleteProductVariant(
 
 	// ── 0. Guard clause ────────────────────────────────────────────────────
 	if id == "" {
-		return nil, fmt.Errorf("variant id must not be empty")
+		return nil, errors.BadRequest("variant id must not be empty")
 	}
 
 	// ── 1. Look up the product_id for this variant ────────────────────────
-	var row []struct {
-		ProductId string `json:"product_id"`
-	}
-	if err := s.db.DB.
-		From("product_variants").
-		Select("product_id").
-		Eq("id", id).
-		Execute(&row); err != nil {
-		return nil, fmt.Errorf("variant not found: %v", err)
+	variant, err := s.variants.Get(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	productID := row[0].ProductId
-
-	// ── 2. Timestamp to apply to all records we are marking deleted ───────
-	now := time.Now()
+	productID := variant.ProductId
 
-	// ── 3. Soft-delete ALL variants for that product ──────────────────────
-	if err := s.db.DB.
-		From("product_variants").
-		Update(map[string]interface{}{"deleted_at": now}).
-		Eq("id", id).
-		IsNull("deleted_at").
-		Execute(nil); err != nil {
-		return nil, fmt.Errorf("failed to delete product variants: %v", err)
+	// ── 2. Soft-delete the variant ────────────────────────────────────────
+	if err := s.variants.SoftDelete(ctx, id); err != nil {
+		return nil, err
 	}
 
-	// ── 4. Soft-delete ALL product options for that product ───────────────
-	if err := s.db.DB.
-		From("product_options").
-		Update(map[string]interface{}{"deleted_at": now}).
-		Eq("product_id", productID).
-		IsNull("deleted_at").
-		Execute(nil); err != nil {
-		return nil, fmt.Errorf("failed to delete product options: %v", err)
+	// ── 3. Soft-delete ALL product options for that product ───────────────
+	if err := s.options.SoftDeleteByProduct(ctx, productID); err != nil {
+		return nil, err
 	}
 
 	return api.Success("All variants and options for the product were deleted successfully"), nil
@@ -144,25 +124,20 @@ func (s *productVariantService) DeleteProductVariant(
 
 // validProduct checks that the product_id provided in a variant actually exists
 // and has not been soft-deleted.
-func (s *productVariantService) validProduct(id string) error {
-	var product []dto.Product
-	err := s.db.DB.From("products").
-		Select("id").
-		Eq("id", id).
-		IsNull("deleted_at").
-		Execute(&product)
+func (s *productVariantService) validProduct(ctx context.Context, id string) error {
+	exists, err := s.products.Exists(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to find product: %v", err)
+		return err
 	}
-	if len(product) == 0 {
-		return fmt.Errorf("product not found")
+	if !exists {
+		return errors.NotFound("product not found")
 	}
 	return nil
 }
 
 func (s *productVariantService) CreateBulkProductVariant(ctx context.Context, reqs dto.ProductVariantCreateBulk) (api.Response, error) {
 	// Validate if product exists
-	if err := s.validProduct(reqs.ProductId); err != nil {
+	if err := s.validProduct(ctx, reqs.ProductId); err != nil {
 		return nil, err
 	}
 
@@ -171,12 +146,9 @@ func (s *productVariantService) CreateBulkProductVariant(ctx context.Context, re
 		reqs.Variants[i].ProductId = reqs.ProductId
 	}
 
-	var created []dto.ProductVariant
-	err := s.db.DB.From("product_variants").
-		Insert(reqs.Variants).
-		Execute(&created)
+	created, err := s.variants.Create(ctx, reqs.Variants)
 	if err != nil {
-		return nil, fmt.Errorf("failed to bulk create product variants: %v", err)
+		return nil, err
 	}
 
 	return api.Success(created), nil
@@ -192,21 +164,16 @@ func (s *productVariantService) UpdateBulkProductVariant(
 
 	// 0️⃣  Basic validation --------------------------------------------------
 	if len(req.Variants) == 0 {
-		return nil, fmt.Errorf("no product variants to update")
+		return nil, errors.BadRequest("no product variants to update")
 	}
-	if err := s.validProduct(req.ProductId); err != nil {
+	if err := s.validProduct(ctx, req.ProductId); err != nil {
 		return nil, err
 	}
 
 	// 1️⃣  Fetch current, non-deleted variant IDs ---------------------------
-	var current []dto.ProductVariant
-	if err := s.db.DB.
-		From("product_variants").
-		Select("id").
-		Eq("product_id", req.ProductId).
-		IsNull("deleted_at").
-		Execute(&current); err != nil {
-		return nil, fmt.Errorf("failed to fetch current product variants: %v", err)
+	current, err := s.variants.ListByProduct(ctx, req.ProductId)
+	if err != nil {
+		return nil, err
 	}
 
 	existingIDs := make(map[string]struct{}, len(current))
@@ -230,12 +197,9 @@ func (s *productVariantService) UpdateBulkProductVariant(
 				Metadata:  v.Metadata,
 			}
 
-			var created []dto.ProductVariant
-			if err := s.db.DB.
-				From("product_variants").
-				Insert(createReq).
-				Execute(&created); err != nil {
-				return nil, fmt.Errorf("failed to create variant %q: %v", v.Name, err)
+			created, err := s.variants.Create(ctx, createReq)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create variant %q: %w", v.Name, err)
 			}
 			result = append(result, created...)
 			continue
@@ -251,60 +215,30 @@ func (s *productVariantService) UpdateBulkProductVariant(
 			"updated_at": now,
 		}
 
-		var updated []dto.ProductVariant
-		if err := s.db.DB.
-			From("product_variants").
-			Update(updateData).
-			Eq("id", v.Id).
-			Eq("product_id", req.ProductId). // safety guard
-			Execute(&updated); err != nil {
-			return nil, fmt.Errorf("failed to update variant %s: %v", v.Id, err)
+		updated, err := s.variants.Update(ctx, v.Id, req.ProductId, updateData)
+		if err != nil {
+			return nil, err
 		}
-		result = append(result, updated...)
+		result = append(result, updated)
 	}
 
 	// 3️⃣  Soft-delete rows missing from the payload -----------------------
-	needOptionCleanup := false
-
+	var removed []string
 	for id := range existingIDs {
 		if _, keep := payloadIDs[id]; !keep {
-			if err := s.db.DB.
-				From("product_variants").
-				Update(map[string]interface{}{"deleted_at": now}).
-				Eq("id", id).
-				Execute(nil); err != nil {
-				return nil, fmt.Errorf("failed to soft-delete variant %s: %v", id, err)
-			}
-			needOptionCleanup = true
+			removed = append(removed, id)
 		}
 	}
+	if err := s.variants.SoftDelete(ctx, removed...); err != nil {
+		return nil, err
+	}
 
 	// 4️⃣  Cascade-style cleanup of product_options ------------------------
-	if needOptionCleanup {
-		if err := s.db.DB.
-			From("product_options").
-			Update(map[string]interface{}{"deleted_at": now}).
-			Eq("product_id", req.ProductId).
-			IsNull("deleted_at").
-			Execute(nil); err != nil {
-			return nil, fmt.Errorf("failed to soft-delete product options for product %s: %v", req.ProductId, err)
+	if len(removed) > 0 {
+		if err := s.options.SoftDeleteByProduct(ctx, req.ProductId); err != nil {
+			return nil, err
 		}
 	}
 
 	return api.Success(result), nil
 }
-
-// deleteAllVariantsByProduct sets deleted_at for every non-deleted row
-// belonging to the given product.  It is idempotent.
-func (s *productVariantService) deleteAllVariantsByProduct(
-	ctx context.Context,
-	productID string,
-) error {
-	err := s.db.DB.
-		From("product_variants").
-		Update(map[string]interface{}{"deleted_at": time.Now()}).
-		Eq("product_id", productID).
-		IsNull("deleted_at").
-		Execute(nil) // we don’t need RETURNING
-	return err
-}
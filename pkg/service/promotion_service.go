@@ -0,0 +1,409 @@
+package service
+
+import (
+	"SangXanh/pkg/auth"
+	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"SangXanh/pkg/repository"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"github.com/samber/lo"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRedeemRetries bounds the attempts at moving used_count while other orders keep
+// moving it too.
+const maxRedeemRetries = 3
+
+type PromotionService interface {
+	ListPromotions(ctx context.Context, filter dto.PromotionFilter) (api.Response, error)
+	GetPromotionById(ctx context.Context, id string) (api.Response, error)
+	CreatePromotion(ctx context.Context, req dto.PromotionCreate) (api.Response, error)
+	UpdatePromotion(ctx context.Context, req dto.PromotionUpdate) (api.Response, error)
+	DeletePromotion(ctx context.Context, id string) (api.Response, error)
+	PreviewPromotion(ctx context.Context, req dto.PromotionValidate) (api.Response, error)
+
+	// Evaluate checks a code against the priced lines of an order for the given user.
+	// excludeOrderId lets an order that already redeemed the code be re-evaluated.
+	Evaluate(ctx context.Context, code, userId, excludeOrderId string, lines []dto.PromotionLine) (dto.AppliedPromotion, error)
+	Redeem(ctx context.Context, applied dto.AppliedPromotion, orderId, userId string) error
+	// UpdateRedemption records the discount an order that redeemed the promotion
+	// gets once its lines changed.
+	UpdateRedemption(ctx context.Context, applied dto.AppliedPromotion, orderId string) error
+	ReleaseRedemption(ctx context.Context, orderId string) error
+}
+
+type promotionService struct {
+	promotions repository.PromotionRepository
+	options    repository.ProductOptionRepository
+}
+
+func NewPromotionService(di do.Injector) (PromotionService, error) {
+	promotions, err := do.Invoke[repository.PromotionRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PromotionService: %w", err)
+	}
+	options, err := do.Invoke[repository.ProductOptionRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize PromotionService: %w", err)
+	}
+	return &promotionService{promotions: promotions, options: options}, nil
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func validatePromotion(req dto.PromotionCreate) error {
+	if normalizeCode(req.Code) == "" {
+		return errors.BadRequest("code is required")
+	}
+	switch req.DiscountType {
+	case enum.Percent:
+		if req.DiscountValue <= 0 || req.DiscountValue > 100 {
+			return errors.BadRequest("percent discount must be between 0 and 100")
+		}
+	case enum.Number:
+		if req.DiscountValue <= 0 {
+			return errors.BadRequest("discount value must be greater than 0")
+		}
+	default:
+		return errors.BadRequest("invalid discount type %q", req.DiscountType)
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return errors.BadRequest("ends_at must be after starts_at")
+	}
+	if req.UsageLimit < 0 || req.UsageLimitPerUser < 0 || req.MinOrderValue < 0 || req.MaxDiscount < 0 {
+		return errors.BadRequest("limits must not be negative")
+	}
+	return nil
+}
+
+func promotionBody(req dto.PromotionCreate) map[string]interface{} {
+	return map[string]interface{}{
+		"code":                 normalizeCode(req.Code),
+		"description":          req.Description,
+		"discount_type":        req.DiscountType,
+		"discount_value":       req.DiscountValue,
+		"max_discount":         req.MaxDiscount,
+		"min_order_value":      req.MinOrderValue,
+		"starts_at":            req.StartsAt,
+		"ends_at":              req.EndsAt,
+		"usage_limit":          req.UsageLimit,
+		"usage_limit_per_user": req.UsageLimitPerUser,
+		"category_ids":         lo.Ternary(req.CategoryIds == nil, []string{}, req.CategoryIds),
+		"product_ids":          lo.Ternary(req.ProductIds == nil, []string{}, req.ProductIds),
+		"status":               req.Status,
+	}
+}
+
+// findByCode returns the live promotion with code, or nil.
+func (s *promotionService) findByCode(ctx context.Context, code string) (*dto.Promotion, error) {
+	promotion, err := s.promotions.GetByCode(ctx, normalizeCode(code))
+	var notFound *errors.NotFoundError
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+/* ------------------------------------------------------------------
+   CRUD
+   ------------------------------------------------------------------*/
+
+func (s *promotionService) ListPromotions(ctx context.Context, filter dto.PromotionFilter) (api.Response, error) {
+	filter.Correct()
+	filter.Code = normalizeCode(filter.Code)
+	total, err := s.promotions.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	promotions, err := s.promotions.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.SetTotal(int64(total))
+	return api.SuccessPagination(promotions, &filter.Pagination), nil
+}
+
+func (s *promotionService) GetPromotionById(ctx context.Context, id string) (api.Response, error) {
+	promotion, err := s.promotions.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return api.Success(promotion), nil
+}
+
+func (s *promotionService) CreatePromotion(ctx context.Context, req dto.PromotionCreate) (api.Response, error) {
+	if err := validatePromotion(req); err != nil {
+		return nil, err
+	}
+	existing, err := s.findByCode(ctx, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.Conflict("promotion code %s already exists", normalizeCode(req.Code))
+	}
+
+	body := promotionBody(req)
+	body["used_count"] = 0
+	if caller, ok := auth.FromContext(ctx); ok {
+		body["created_by"] = caller.Id
+	}
+
+	created, err := s.promotions.Create(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	return api.Success(created), nil
+}
+
+func (s *promotionService) UpdatePromotion(ctx context.Context, req dto.PromotionUpdate) (api.Response, error) {
+	if err := validatePromotion(req.PromotionCreate); err != nil {
+		return nil, err
+	}
+	existing, err := s.findByCode(ctx, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Id != req.Id {
+		return nil, errors.Conflict("promotion code %s already exists", normalizeCode(req.Code))
+	}
+
+	body := promotionBody(req.PromotionCreate)
+	body["updated_at"] = time.Now()
+
+	updated, err := s.promotions.Update(ctx, req.Id, body)
+	if err != nil {
+		return nil, err
+	}
+	return api.Success(updated), nil
+}
+
+func (s *promotionService) DeletePromotion(ctx context.Context, id string) (api.Response, error) {
+	if err := s.promotions.SoftDelete(ctx, id); err != nil {
+		return nil, err
+	}
+	return api.Success("Promotion deleted successfully"), nil
+}
+
+/* ------------------------------------------------------------------
+   Evaluation & redemption
+   ------------------------------------------------------------------*/
+
+// PreviewPromotion tells a shopper what a code would take off the given lines
+// without redeeming it.
+func (s *promotionService) PreviewPromotion(ctx context.Context, req dto.PromotionValidate) (api.Response, error) {
+	caller, err := auth.Authenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lines, _, err := priceOrderLines(ctx, s.options, req.OrderDetails)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := s.Evaluate(ctx, req.Code, caller.Id, "", pricedPromotionLines(lines))
+	if err != nil {
+		return nil, err
+	}
+	return api.Success(applied), nil
+}
+
+func (s *promotionService) Evaluate(ctx context.Context, code, userId, excludeOrderId string, lines []dto.PromotionLine) (dto.AppliedPromotion, error) {
+	promotion, err := s.findByCode(ctx, code)
+	if err != nil {
+		return dto.AppliedPromotion{}, err
+	}
+	if promotion == nil {
+		return dto.AppliedPromotion{}, errors.Unprocessable("promotion code %s is not valid", normalizeCode(code))
+	}
+
+	if promotion.UsageLimitPerUser > 0 && userId != "" {
+		used, err := s.promotions.CountUsages(ctx, promotion.Id, userId, excludeOrderId)
+		if err != nil {
+			return dto.AppliedPromotion{}, err
+		}
+		if used >= promotion.UsageLimitPerUser {
+			return dto.AppliedPromotion{}, errors.Unprocessable("promotion code %s has already been used", promotion.Code)
+		}
+	}
+
+	// an order re-evaluating its own code already counts towards used_count
+	alreadyRedeemed := false
+	if excludeOrderId != "" {
+		own, err := s.promotions.Usages(ctx, excludeOrderId, promotion.Id)
+		if err != nil {
+			return dto.AppliedPromotion{}, err
+		}
+		alreadyRedeemed = len(own) > 0
+	}
+
+	return applyPromotion(*promotion, time.Now(), alreadyRedeemed, lines)
+}
+
+// applyPromotion holds the rules that do not need the database: status, validity
+// window, global usage, minimum order value and product/category scope.
+func applyPromotion(p dto.Promotion, now time.Time, alreadyRedeemed bool, lines []dto.PromotionLine) (dto.AppliedPromotion, error) {
+	applied := dto.AppliedPromotion{PromotionId: p.Id, Code: p.Code}
+
+	if !p.Status {
+		return applied, errors.Unprocessable("promotion code %s is not active", p.Code)
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return applied, errors.Unprocessable("promotion code %s is not valid yet", p.Code)
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return applied, errors.Unprocessable("promotion code %s has expired", p.Code)
+	}
+	if p.UsageLimit > 0 && !alreadyRedeemed && p.UsedCount >= p.UsageLimit {
+		return applied, errors.Unprocessable("promotion code %s has reached its usage limit", p.Code)
+	}
+
+	var orderValue float64
+	for _, l := range lines {
+		orderValue += l.LineTotal
+		if len(p.ProductIds) == 0 && len(p.CategoryIds) == 0 ||
+			lo.Contains(p.ProductIds, l.ProductId) ||
+			lo.Contains(p.CategoryIds, l.CategoryId) {
+			applied.EligibleSubtotal += l.LineTotal
+		}
+	}
+	if orderValue < p.MinOrderValue {
+		return applied, errors.Unprocessable("order value must be at least %s to use promotion code %s",
+			strconv.FormatFloat(p.MinOrderValue, 'f', -1, 64), p.Code)
+	}
+	if applied.EligibleSubtotal <= 0 {
+		return applied, errors.Unprocessable("promotion code %s does not apply to any item in the order", p.Code)
+	}
+
+	discount := p.DiscountType.Amount(applied.EligibleSubtotal, p.DiscountValue)
+	if p.MaxDiscount > 0 {
+		discount = min(discount, p.MaxDiscount)
+	}
+	applied.EligibleSubtotal = roundMoney(applied.EligibleSubtotal)
+	applied.Discount = roundMoney(discount)
+	return applied, nil
+}
+
+// Redeem records the usage and bumps used_count. The counter update is guarded on the
+// value that was read so the global limit cannot be overshot by concurrent orders;
+// the per-user limit is checked again once the usage is stored.
+func (s *promotionService) Redeem(ctx context.Context, applied dto.AppliedPromotion, orderId, userId string) error {
+	for attempt := 0; attempt < maxRedeemRetries; attempt++ {
+		p, err := s.promotions.Get(ctx, applied.PromotionId)
+		var notFound *errors.NotFoundError
+		if errors.As(err, &notFound) {
+			return errors.Unprocessable("promotion code %s is not valid", applied.Code)
+		}
+		if err != nil {
+			return err
+		}
+		if p.UsageLimit > 0 && p.UsedCount >= p.UsageLimit {
+			return errors.Unprocessable("promotion code %s has reached its usage limit", p.Code)
+		}
+
+		ok, err := s.promotions.SetUsedCount(ctx, p.Id, p.UsedCount, p.UsedCount+1)
+		if err != nil {
+			return fmt.Errorf("failed to redeem promotion: %w", err)
+		}
+		if !ok {
+			continue
+		}
+
+		usage, err := s.promotions.AddUsage(ctx, dto.PromotionUsage{PromotionId: p.Id, OrderId: orderId, UserId: userId, Discount: applied.Discount})
+		if err != nil {
+			_ = s.adjustUsedCount(ctx, p.Id, -1)
+			return err
+		}
+		return s.checkUserLimit(ctx, p, usage)
+	}
+	return errors.Conflict("promotion %s is being redeemed too often, please retry", applied.Code)
+}
+
+// checkUserLimit counts the other usages of the user after usage has been stored, so
+// of two orders of the same user redeeming the code at once at least one sees the
+// other. Usage is taken back when the user went over the limit.
+func (s *promotionService) checkUserLimit(ctx context.Context, p dto.Promotion, usage dto.PromotionUsage) error {
+	if p.UsageLimitPerUser <= 0 || usage.UserId == "" {
+		return nil
+	}
+	used, countErr := s.promotions.CountUsages(ctx, p.Id, usage.UserId, usage.OrderId)
+	if countErr == nil && used < p.UsageLimitPerUser {
+		return nil
+	}
+	if err := s.promotions.SoftDeleteUsage(ctx, usage.Id); err != nil {
+		return err
+	}
+	if err := s.adjustUsedCount(ctx, p.Id, -1); err != nil {
+		return err
+	}
+	if countErr != nil {
+		return countErr
+	}
+	return errors.Unprocessable("promotion code %s has already been used", p.Code)
+}
+
+func (s *promotionService) UpdateRedemption(ctx context.Context, applied dto.AppliedPromotion, orderId string) error {
+	usages, err := s.promotions.Usages(ctx, orderId, applied.PromotionId)
+	if err != nil {
+		return err
+	}
+	for _, u := range usages {
+		if u.Discount == applied.Discount {
+			continue
+		}
+		if err := s.promotions.SetUsageDiscount(ctx, u.Id, applied.Discount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReleaseRedemption gives the usage of an order back, e.g. when it is cancelled.
+func (s *promotionService) ReleaseRedemption(ctx context.Context, orderId string) error {
+	usages, err := s.promotions.Usages(ctx, orderId, "")
+	if err != nil {
+		return err
+	}
+	for _, u := range usages {
+		if err := s.promotions.SoftDeleteUsage(ctx, u.Id); err != nil {
+			return err
+		}
+		if err := s.adjustUsedCount(ctx, u.PromotionId, -1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *promotionService) adjustUsedCount(ctx context.Context, promotionId string, delta int) error {
+	for attempt := 0; attempt < maxRedeemRetries; attempt++ {
+		// the count of a deleted promotion is not kept up any more
+		p, err := s.promotions.Get(ctx, promotionId)
+		var notFound *errors.NotFoundError
+		if errors.As(err, &notFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ok, err := s.promotions.SetUsedCount(ctx, promotionId, p.UsedCount, max(p.UsedCount+delta, 0))
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to update usage count of promotion %s, please retry", promotionId)
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/enum"
+	"SangXanh/pkg/repository"
+	"context"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newPromotionTest(t *testing.T) (PromotionService, *repository.MemoryStore) {
+	di := do.New()
+	store := repository.InjectMemory(di)
+	do.Provide(di, NewPromotionService)
+	promotions, err := do.Invoke[PromotionService](di)
+	require.NoError(t, err)
+	return promotions, store
+}
+
+func usedCount(store *repository.MemoryStore, code string) float64 {
+	for _, row := range store.Rows("promotions") {
+		if row["code"] == code {
+			return row["used_count"].(float64)
+		}
+	}
+	return -1
+}
+
+func TestPromotionRedemption(t *testing.T) {
+	promotions, store := newPromotionTest(t)
+	ctx := signedIn("admin", enum.Admin)
+	_, err := promotions.CreatePromotion(ctx, dto.PromotionCreate{
+		Code:              " tet ",
+		DiscountType:      enum.Percent,
+		DiscountValue:     10,
+		UsageLimit:        2,
+		UsageLimitPerUser: 1,
+		Status:            true,
+	})
+	require.NoError(t, err)
+	_, err = promotions.CreatePromotion(ctx, dto.PromotionCreate{Code: "TET", DiscountType: enum.Number, DiscountValue: 5})
+	var conflict *errors.ConflictError
+	assert.True(t, errors.As(err, &conflict), "codes are unique whatever their case")
+
+	lines := []dto.PromotionLine{{ProductId: "p1", LineTotal: 200}}
+	applied, err := promotions.Evaluate(context.Background(), "tet", "u1", "", lines)
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, applied.Discount)
+	require.NoError(t, promotions.Redeem(context.Background(), applied, "order-1", "u1"))
+	assert.Equal(t, 1.0, usedCount(store, "TET"))
+
+	_, err = promotions.Evaluate(context.Background(), "TET", "u1", "", lines)
+	assert.Error(t, err, "u1 has used the code once already")
+	repriced, err := promotions.Evaluate(context.Background(), "TET", "u1", "order-1", []dto.PromotionLine{{ProductId: "p1", LineTotal: 300}})
+	assert.NoError(t, err, "the order that redeemed it may be priced again")
+	require.NoError(t, promotions.UpdateRedemption(context.Background(), repriced, "order-1"))
+	assert.Equal(t, 30.0, store.Rows("promotion_usages")[0]["discount"], "the usage follows the edited order")
+
+	// a second order priced before the first one was redeemed
+	err = promotions.Redeem(context.Background(), applied, "order-2", "u1")
+	var unprocessable *errors.UnprocessableError
+	assert.True(t, errors.As(err, &unprocessable), "the per-user limit holds at redemption too")
+	assert.Equal(t, 1.0, usedCount(store, "TET"))
+
+	require.NoError(t, promotions.ReleaseRedemption(context.Background(), "order-1"))
+	assert.Equal(t, 0.0, usedCount(store, "TET"))
+	_, err = promotions.Evaluate(context.Background(), "TET", "u1", "", lines)
+	assert.NoError(t, err, "a cancelled order gives the usage back")
+}
+
+func TestApplyPromotion(t *testing.T) {
+	now := time.Date(2026, 2, 10, 8, 0, 0, 0, time.UTC)
+	yesterday, tomorrow := now.AddDate(0, 0, -1), now.AddDate(0, 0, 1)
+	lines := []dto.PromotionLine{
+		{ProductId: "p1", CategoryId: "seeds", LineTotal: 300},
+		{ProductId: "p2", CategoryId: "tools", LineTotal: 100},
+	}
+	base := dto.Promotion{Code: "TET", DiscountType: enum.Percent, DiscountValue: 10, Status: true}
+
+	tests := []struct {
+		name            string
+		edit            func(p *dto.Promotion)
+		alreadyRedeemed bool
+		discount        float64
+		eligible        float64
+		err             bool
+	}{
+		{name: "whole order", edit: func(p *dto.Promotion) {}, discount: 40, eligible: 400},
+		{name: "inactive", edit: func(p *dto.Promotion) { p.Status = false }, err: true},
+		{name: "inside the window", edit: func(p *dto.Promotion) { p.StartsAt, p.EndsAt = &yesterday, &tomorrow }, discount: 40, eligible: 400},
+		{name: "not started", edit: func(p *dto.Promotion) { p.StartsAt = &tomorrow }, err: true},
+		{name: "ended", edit: func(p *dto.Promotion) { p.EndsAt = &now }, err: true},
+		{name: "minimum reached", edit: func(p *dto.Promotion) { p.MinOrderValue = 400 }, discount: 40, eligible: 400},
+		{name: "below minimum", edit: func(p *dto.Promotion) { p.MinOrderValue = 400.5 }, err: true},
+		{name: "product scope", edit: func(p *dto.Promotion) { p.ProductIds = []string{"p2"} }, discount: 10, eligible: 100},
+		{name: "category scope", edit: func(p *dto.Promotion) { p.CategoryIds = []string{"seeds"} }, discount: 30, eligible: 300},
+		{name: "out of scope", edit: func(p *dto.Promotion) { p.ProductIds = []string{"p3"} }, err: true},
+		{name: "max discount", edit: func(p *dto.Promotion) { p.MaxDiscount = 25 }, discount: 25, eligible: 400},
+		{name: "fixed amount", edit: func(p *dto.Promotion) { p.DiscountType, p.DiscountValue = enum.Number, 50 }, discount: 50, eligible: 400},
+		{name: "usage left", edit: func(p *dto.Promotion) { p.UsageLimit, p.UsedCount = 2, 1 }, discount: 40, eligible: 400},
+		{name: "usage limit reached", edit: func(p *dto.Promotion) { p.UsageLimit, p.UsedCount = 2, 2 }, err: true},
+		{name: "usage limit reached by this order", edit: func(p *dto.Promotion) { p.UsageLimit, p.UsedCount = 2, 2 }, alreadyRedeemed: true, discount: 40, eligible: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := base
+			tt.edit(&p)
+			applied, err := applyPromotion(p, now, tt.alreadyRedeemed, lines)
+			if tt.err {
+				var unprocessable *errors.UnprocessableError
+				assert.True(t, errors.As(err, &unprocessable), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.discount, applied.Discount)
+			assert.Equal(t, tt.eligible, applied.EligibleSubtotal)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"SangXanh/pkg/auth"
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/log"
+	"SangXanh/pkg/repository"
+	"SangXanh/pkg/ws"
+	"context"
+	"fmt"
+	"github.com/samber/do/v2"
+	"sync"
+	"time"
+)
+
+const (
+	// sessionCacheTTL is how long a session is trusted without looking at the table
+	// again, so the longest another server instance takes to notice a logout.
+	sessionCacheTTL = 30 * time.Second
+	// sessionCacheSize is the number of cached sessions above which stale entries
+	// are swept out.
+	sessionCacheSize = 10000
+)
+
+// SessionService keeps track of the devices a user is signed in on. A session is
+// known by the session_id claim of its access tokens; once revoked, every token of
+// the session is refused even though its signature is still good.
+type SessionService interface {
+	// Start records the session of a fresh login.
+	Start(ctx context.Context, p auth.Principal, client dto.SessionClient) error
+	// Verify fails with 401 Unauthorized when the session of p was revoked. A session
+	// seen for the first time is recorded, unless the user logged out everywhere
+	// after its token was issued.
+	Verify(ctx context.Context, p auth.Principal, client dto.SessionClient) error
+	List(ctx context.Context, userId string) ([]dto.Session, error)
+	Revoke(ctx context.Context, id string) error
+	// RevokeAll revokes the sessions of a user and returns how many there were.
+	RevokeAll(ctx context.Context, userId string) (int, error)
+}
+
+type sessionState struct {
+	userId    string
+	revoked   bool
+	checkedAt time.Time
+}
+
+type sessionService struct {
+	sessions repository.SessionRepository
+	// conns are the websockets of this instance, dropped along with their session
+	conns ws.Disconnector
+
+	mu    sync.Mutex
+	cache map[string]sessionState
+}
+
+func NewSessionService(di do.Injector) (SessionService, error) {
+	sessions, err := do.Invoke[repository.SessionRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize SessionService: %w", err)
+	}
+	hub, err := do.Invoke[*ws.Hub](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize SessionService: %w", err)
+	}
+	return &sessionService{sessions: sessions, conns: hub, cache: make(map[string]sessionState)}, nil
+}
+
+func errSessionRevoked() error {
+	return errors.Unauthorized("session has been logged out").WithCode("session_revoked")
+}
+
+func (s *sessionService) cached(id string) (sessionState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.cache[id]
+	// a revoked session never comes back, however old the entry
+	if !ok || (!state.revoked && time.Since(state.checkedAt) > sessionCacheTTL) {
+		return sessionState{}, false
+	}
+	return state, true
+}
+
+func (s *sessionService) remember(userId string, revoked bool, ids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if len(s.cache) >= sessionCacheSize {
+		for id, state := range s.cache {
+			if now.Sub(state.checkedAt) > sessionCacheTTL {
+				delete(s.cache, id)
+			}
+		}
+	}
+	for _, id := range ids {
+		s.cache[id] = sessionState{userId: userId, revoked: revoked, checkedAt: now}
+	}
+}
+
+func (s *sessionService) Start(ctx context.Context, p auth.Principal, client dto.SessionClient) error {
+	if p.SessionId == "" {
+		return nil
+	}
+	_, err := s.sessions.Get(ctx, p.SessionId)
+	var notFound *errors.NotFoundError
+	if !errors.As(err, &notFound) {
+		// recorded already, or the table could not be read
+		return err
+	}
+	_, err = s.record(ctx, p, client)
+	return err
+}
+
+func (s *sessionService) Verify(ctx context.Context, p auth.Principal, client dto.SessionClient) error {
+	if p.SessionId == "" {
+		// tokens that are not bound to a session cannot be revoked one by one
+		return nil
+	}
+	if state, ok := s.cached(p.SessionId); ok {
+		if state.revoked || state.userId != p.Id {
+			return errSessionRevoked()
+		}
+		return nil
+	}
+
+	session, err := s.sessions.Get(ctx, p.SessionId)
+	var notFound *errors.NotFoundError
+	switch {
+	case errors.As(err, &notFound):
+		if session, err = s.record(ctx, p, client); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case session.RevokedAt == nil && session.UserId == p.Id:
+		if err := s.sessions.Touch(ctx, p.SessionId, time.Now()); err != nil {
+			log.Errorf("failed to touch session %s: %v", p.SessionId, err)
+		}
+	}
+
+	revoked := session.RevokedAt != nil
+	s.remember(session.UserId, revoked, p.SessionId)
+	if revoked || session.UserId != p.Id {
+		return errSessionRevoked()
+	}
+	return nil
+}
+
+// record adds a session seen for the first time, revoked from the start when its
+// token dates from before the user last logged out everywhere. It returns the
+// session as stored.
+func (s *sessionService) record(ctx context.Context, p auth.Principal, client dto.SessionClient) (dto.Session, error) {
+	cutoff, err := s.sessions.RevokedBefore(ctx, p.Id)
+	if err != nil {
+		return dto.Session{}, err
+	}
+	now := time.Now()
+	session := dto.Session{
+		Id:         p.SessionId,
+		UserId:     p.Id,
+		Device:     client.Device,
+		UserAgent:  client.UserAgent,
+		Ip:         client.Ip,
+		LastSeenAt: now,
+	}
+	if !cutoff.IsZero() && p.IssuedAt.Before(cutoff) {
+		session.RevokedAt = &now
+	}
+	if err := s.sessions.Create(ctx, session); err != nil {
+		// two first requests of the same session may race to record it
+		existing, getErr := s.sessions.Get(ctx, p.SessionId)
+		if getErr != nil {
+			return dto.Session{}, err
+		}
+		return existing, nil
+	}
+	return session, nil
+}
+
+func (s *sessionService) List(ctx context.Context, userId string) ([]dto.Session, error) {
+	return s.sessions.ListActive(ctx, userId)
+}
+
+func (s *sessionService) Revoke(ctx context.Context, id string) error {
+	if err := s.sessions.Revoke(ctx, id, time.Now()); err != nil {
+		return err
+	}
+	s.remember("", true, id)
+	s.conns.Disconnect(ws.SessionRoom(id))
+	return nil
+}
+
+func (s *sessionService) RevokeAll(ctx context.Context, userId string) (int, error) {
+	ids, err := s.sessions.RevokeUser(ctx, userId, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	s.remember(userId, true, ids...)
+	s.conns.Disconnect(ws.UserRoom(userId))
+	return len(ids), nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"SangXanh/pkg/auth"
+	"SangXanh/pkg/common/errors"
+	"SangXanh/pkg/dto"
+	"SangXanh/pkg/repository"
+	"SangXanh/pkg/ws"
+	"context"
+	"github.com/samber/do/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newSessionTest(t *testing.T) (SessionService, *repository.MemoryStore) {
+	di := do.New()
+	store := repository.InjectMemory(di)
+	do.ProvideValue(di, ws.New())
+	do.Provide(di, NewSessionService)
+	store.Seed("users", map[string]interface{}{"id": "u1", "username": "lan"})
+	sessions, err := do.Invoke[SessionService](di)
+	require.NoError(t, err)
+	return sessions, store
+}
+
+func assertSessionRevoked(t *testing.T, err error) {
+	var httpErr errors.HTTPError
+	require.True(t, errors.As(err, &httpErr), "not an HTTP error: %v", err)
+	assert.Equal(t, "session_revoked", httpErr.ErrorCode())
+}
+
+// disconnects records the rooms whose websockets were closed.
+type disconnects []string
+
+func (d *disconnects) Disconnect(rooms ...string) { *d = append(*d, rooms...) }
+
+func TestSessionRevokeClosesSockets(t *testing.T) {
+	sessions, _ := newSessionTest(t)
+	closed := &disconnects{}
+	sessions.(*sessionService).conns = closed
+	ctx := context.Background()
+	require.NoError(t, sessions.Start(ctx, auth.Principal{Id: "u1", SessionId: "s-phone", IssuedAt: time.Now()}, dto.SessionClient{}))
+
+	require.NoError(t, sessions.Revoke(ctx, "s-phone"))
+	_, err := sessions.RevokeAll(ctx, "u1")
+	require.NoError(t, err)
+	assert.Equal(t, disconnects{ws.SessionRoom("s-phone"), ws.UserRoom("u1")}, *closed)
+}
+
+func TestSessionRevoke(t *testing.T) {
+	sessions, store := newSessionTest(t)
+	ctx := context.Background()
+	phone := auth.Principal{Id: "u1", SessionId: "s-phone", IssuedAt: time.Now()}
+	laptop := auth.Principal{Id: "u1", SessionId: "s-laptop", IssuedAt: time.Now()}
+
+	require.NoError(t, sessions.Start(ctx, phone, dto.SessionClient{Device: "Lan's phone"}))
+	require.NoError(t, sessions.Verify(ctx, phone, dto.SessionClient{}))
+	// a session that did not log in through the API is picked up on first use
+	require.NoError(t, sessions.Verify(ctx, laptop, dto.SessionClient{UserAgent: "Firefox"}))
+	listed, err := sessions.List(ctx, "u1")
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+
+	require.NoError(t, sessions.Revoke(ctx, "s-phone"))
+	assertSessionRevoked(t, sessions.Verify(ctx, phone, dto.SessionClient{}))
+	assert.NoError(t, sessions.Verify(ctx, laptop, dto.SessionClient{}))
+
+	// another instance, without the cache, sees the revocation in the table
+	other, err := NewSessionService(func() do.Injector {
+		di := do.New()
+		do.ProvideValue[repository.Store](di, store)
+		do.Provide(di, repository.NewSessionRepository)
+		do.ProvideValue(di, ws.New())
+		return di
+	}())
+	require.NoError(t, err)
+	assertSessionRevoked(t, other.Verify(ctx, phone, dto.SessionClient{}))
+	assert.NoError(t, other.Verify(ctx, laptop, dto.SessionClient{}))
+
+	stolen := auth.Principal{Id: "u2", SessionId: "s-laptop", IssuedAt: time.Now()}
+	assertSessionRevoked(t, other.Verify(ctx, stolen, dto.SessionClient{}))
+	assert.NoError(t, other.Verify(ctx, laptop, dto.SessionClient{}))
+}
+
+func TestSessionRevokeAll(t *testing.T) {
+	sessions, _ := newSessionTest(t)
+	ctx := context.Background()
+	before := time.Now().Add(-time.Minute)
+	seen := auth.Principal{Id: "u1", SessionId: "s-seen", IssuedAt: before}
+	unseen := auth.Principal{Id: "u1", SessionId: "s-unseen", IssuedAt: before}
+	require.NoError(t, sessions.Verify(ctx, seen, dto.SessionClient{}))
+
+	revoked, err := sessions.RevokeAll(ctx, "u1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, revoked)
+	assertSessionRevoked(t, sessions.Verify(ctx, seen, dto.SessionClient{}))
+	// tokens issued before are refused too, even for a session not seen yet
+	assertSessionRevoked(t, sessions.Verify(ctx, unseen, dto.SessionClient{}))
+
+	again := auth.Principal{Id: "u1", SessionId: "s-again", IssuedAt: time.Now().Add(time.Second)}
+	require.NoError(t, sessions.Start(ctx, again, dto.SessionClient{}))
+	assert.NoError(t, sessions.Verify(ctx, again, dto.SessionClient{}), "a login after it works")
+	listed, err := sessions.List(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "s-again", listed[0].Id)
+}
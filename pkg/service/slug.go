@@ -0,0 +1,28 @@
+package service
+
+import (
+	"SangXanh/pkg/repository"
+	"SangXanh/pkg/util"
+	"context"
+)
+
+// nextSlug is the slug of a row being updated: a requested slug wins, a new name
+// derives a new one, and otherwise the current slug stays. A row from before slugs
+// existed gets one on its first update.
+func nextSlug(ctx context.Context, slugs repository.SlugRepository, table, id, current, requested, name string, renamed bool) (string, error) {
+	switch {
+	case requested != "" && util.Slugify(requested) != current:
+		return slugs.Unique(ctx, table, requested, "", id)
+	case renamed && util.Slugify(name) != current, current == "":
+		return slugs.Unique(ctx, table, "", name, id)
+	}
+	return current, nil
+}
+
+// retireSlug keeps the old slug of a row redirecting once the row has moved to next.
+func retireSlug(ctx context.Context, slugs repository.SlugRepository, table, id, old, next string) error {
+	if old == "" || old == next {
+		return nil
+	}
+	return slugs.Retire(ctx, table, id, old)
+}
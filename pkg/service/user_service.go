@@ -1,22 +1,32 @@
 package service
 
 import (
+	"SangXanh/pkg/auth"
 	"SangXanh/pkg/common/api"
+	"SangXanh/pkg/common/errors"
 	"SangXanh/pkg/dto"
 	"SangXanh/pkg/enum"
 	"SangXanh/pkg/log"
+	"SangXanh/pkg/permission"
+	"SangXanh/pkg/ratelimit"
+	"SangXanh/pkg/repository"
 	"context"
 	"fmt"
-	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
-	"net/http"
-	"net/url"
+	"strings"
 	"time"
 
 	"github.com/nedpals/supabase-go"
 	"github.com/samber/do/v2"
 )
 
+var (
+	registerPerIp  = ratelimit.Rate{Burst: 5, Per: time.Hour}
+	magicLinkPerIp = ratelimit.Rate{Burst: 5, Per: 10 * time.Minute}
+	// magicLinkPerEmail keeps an inbox from being flooded from many addresses.
+	magicLinkPerEmail = ratelimit.Rate{Burst: 3, Per: time.Hour}
+)
+
 type UserService interface {
 	ListUser(ctx context.Context, user dto.ListUser) (api.Response, error)
 	Register(ctx context.Context, req dto.UserRegisterRequest) (api.Response, error)
@@ -29,38 +39,42 @@ type UserService interface {
 }
 
 type userService struct {
-	db *supabase.Client
+	identity IdentityProvider
+	users    repository.UserRepository
+	limiter  *ratelimit.Limiter
 }
 
 func NewUserService(di do.Injector) (UserService, error) {
-	db, err := do.Invoke[*supabase.Client](di)
+	identity, err := do.Invoke[IdentityProvider](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize UserService: %w", err)
+	}
+	users, err := do.Invoke[repository.UserRepository](di)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize UserService: %w", err)
+	}
+	limiter, err := do.Invoke[*ratelimit.Limiter](di)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize UserService: %w", err)
 	}
-	return &userService{db: db}, nil
+	return &userService{identity: identity, users: users, limiter: limiter}, nil
 }
 
 func (s *userService) GetUserById(ctx context.Context, id string) (api.Response, error) {
 	if id == "" {
-		return nil, fmt.Errorf("user ID is required")
+		return nil, errors.BadRequest("user ID is required")
+	}
+	if _, err := permission.CheckOwner(ctx, permission.Users, permission.Read, id); err != nil {
+		return nil, err
 	}
 
-	var users []dto.UserInfo
-	err := s.db.DB.
-		From("users").
-		Select("*").
-		Eq("id", id).
-		IsNull("deleted_at").
-		Execute(&users)
+	user, err := s.users.Info(ctx, id)
 	if err != nil {
 		log.Errorf("failed to query user: %v", err)
-		return nil, fmt.Errorf("failed to retrieve user")
-	}
-	if len(users) == 0 {
-		return nil, fmt.Errorf("user not found")
+		return nil, err
 	}
 
-	return api.Success(users[0]), nil
+	return api.Success(user), nil
 }
 
 // List all users (excluding soft-deleted ones)
@@ -69,89 +83,55 @@ func (s *userService) GetUserById(ctx context.Context, id string) (api.Response,
 // ---------------------------------------------------------------------
 
 func (s *userService) ListUser(ctx context.Context, filter dto.ListUser) (api.Response, error) {
-	// 1. how many records satisfy the filter?
-	name := filter.Name
-	total, err := s.countUsers(ctx, filter, name)
+	// reading one's own profile does not extend to the list of everyone
+	scope, err := permission.Check(ctx, permission.Users, permission.Read)
 	if err != nil {
 		return nil, err
 	}
-
-	// 2. fetch the current page
-	var users []dto.User
-	query := s.db.DB.
-		From("users").
-		// pull just the columns you really need
-		Select("id,username,email,role,avatar,phone,basic_address,metadata,created_at,updated_at").
-		LimitWithOffset(int(filter.Limit), int((filter.Page-1)*filter.Limit)).
-		IsNull("deleted_at") // keep soft-deleted rows out
-
-	// -----------------------------------------------------------------
-	// apply the SAME filters you used in countUsers
-	// -----------------------------------------------------------------
-	if name != "" {
-		encoded := url.QueryEscape("%" + name + "%")
-		query = query.Like("username", encoded)
-	}
-	if filter.Role != "" {
-		query = query.Eq("role", string(filter.Role))
+	if scope != permission.Any {
+		return nil, errors.Forbidden("only admins may list users").WithCode("permission_denied")
 	}
-	if filter.Status != "" {
-		query = query.Eq("status", string(filter.Status)) // example boolean / enum
+	filter.Correct()
+	// 1. how many records satisfy the filter?
+	total, err := s.users.Count(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := query.Execute(&users); err != nil {
-		return nil, fmt.Errorf("failed to list users: %w", err)
+	// 2. fetch the current page
+	users, err := s.users.List(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
 	// 3. add pagination meta & return
-	filter.Pagination.Total = int64(total)
+	filter.SetTotal(int64(total))
 	return api.SuccessPagination(users, &filter.Pagination), nil
 }
 
-// ---------------------------------------------------------------------
-// helper: count the total number of rows for pagination
-// ---------------------------------------------------------------------
-
-func (s *userService) countUsers(ctx context.Context, filter dto.ListUser, name string) (int, error) {
-	q := s.db.DB.
-		From("users").
-		Select("id").
-		IsNull("deleted_at")
-
-	// replicate *exactly* the same filters used in ListUser
-	if name != "" {
-		encoded := url.QueryEscape("%" + name + "%")
-		q = q.Like("username", encoded)
-	}
-	if filter.Role != "" {
-		q = q.Eq("role", string(filter.Role))
-	}
-	if filter.Status != "" {
-		q = q.Eq("status", string(filter.Status))
-	}
-
-	var tmp []struct{} // we only care about the header that carries the count
-	if err := q.Execute(&tmp); err != nil {
-		return 0, fmt.Errorf("failed to count users: %w", err)
-	}
-	return len(tmp), nil
-}
-
 // Register a new user with validation and password hashing
 func (s *userService) Register(ctx context.Context, req dto.UserRegisterRequest) (api.Response, error) {
 	if req.Username == "" || req.Password == "" || req.Email == "" {
-		return nil, fmt.Errorf("username, password and email are required")
+		return nil, errors.BadRequest("username, password and email are required")
+	}
+	if req.Client.Ip != "" {
+		if err := s.limiter.Allow(ctx, "register:ip:"+req.Client.Ip, registerPerIp); err != nil {
+			return nil, err
+		}
 	}
 
 	// Check if user already exists
-	var existing []dto.User
-	err := s.db.DB.From("users").Select("*").Eq("username", req.Username).Execute(&existing)
+	taken, err := s.users.UsernameTaken(ctx, req.Username)
 	if err != nil {
 		log.Errorf("failed to check existing user: %v", err)
 		return nil, fmt.Errorf("failed to register user")
 	}
-	if len(existing) > 0 {
-		return nil, fmt.Errorf("username already exists")
+	// Unlike a failed login or an email already registered, a taken username is told
+	// apart: whoever signs up has to pick another one. Guessing usernames in bulk is
+	// bounded by registerPerIp, and a username alone signs no one in, as logins fail
+	// with the same errInvalidCredentials whatever is wrong and lock out after a few.
+	if taken {
+		return nil, errors.Conflict("username already exists")
 	}
 
 	userData := dto.UserRegisterData{
@@ -170,10 +150,11 @@ func (s *userService) Register(ctx context.Context, req dto.UserRegisterRequest)
 		Data:     userData,
 	}
 
-	_, err = s.db.Auth.SignUp(ctx, user)
+	_, err = s.identity.SignUp(ctx, user)
 	if err != nil {
+		// Supabase's own message would tell whether the email is registered
 		log.Errorf("failed to insert user: %v", err)
-		return nil, fmt.Errorf(err.Error())
+		return nil, fmt.Errorf("failed to register user")
 	}
 
 	return api.Success(user), nil
@@ -181,18 +162,16 @@ func (s *userService) Register(ctx context.Context, req dto.UserRegisterRequest)
 
 func (s *userService) UpdateUser(ctx context.Context, req dto.UserUpdateRequest) (api.Response, error) {
 	if req.Id == "" {
-		return nil, fmt.Errorf("user ID is required")
+		return nil, errors.BadRequest("user ID is required")
+	}
+	if _, err := permission.CheckOwner(ctx, permission.Users, permission.Update, req.Id); err != nil {
+		return nil, err
 	}
 
 	// Check if user exists
-	var users []dto.User
-	err := s.db.DB.From("users").Select("*").Eq("id", req.Id).Execute(&users)
-	if err != nil {
+	if _, err := s.users.Get(ctx, req.Id); err != nil {
 		log.Errorf("failed to find user: %v", err)
-		return nil, fmt.Errorf("user not found")
-	}
-	if len(users) == 0 {
-		return nil, fmt.Errorf("user does not exist")
+		return nil, err
 	}
 
 	updateData := map[string]interface{}{
@@ -205,72 +184,63 @@ func (s *userService) UpdateUser(ctx context.Context, req dto.UserUpdateRequest)
 		"updated_at":    time.Now(),
 	}
 
-	log.Info("===============")
-	var updated []dto.User
-	err = s.db.DB.From("users").Update(updateData).Eq("id", req.Id).Execute(&updated)
+	updated, err := s.users.Update(ctx, req.Id, updateData)
 	if err != nil {
 		log.Errorf("failed to update user: %v", err)
-		return nil, fmt.Errorf("update failed")
+		return nil, err
 	}
 
-	return api.Success(updated[0]), nil
+	return api.Success(updated), nil
 }
 
 func (s *userService) UpdateUserAddress(ctx context.Context, req dto.UserUpdateAddressRequest) (api.Response, error) {
 	if req.Id == "" {
-		return nil, fmt.Errorf("user ID is required")
+		return nil, errors.BadRequest("user ID is required")
+	}
+	if _, err := permission.CheckOwner(ctx, permission.Users, permission.Update, req.Id); err != nil {
+		return nil, err
 	}
 
 	// Check if user exists
-	var users []dto.User
-	err := s.db.DB.From("users").Select("*").Eq("id", req.Id).Execute(&users)
-	if err != nil {
+	if _, err := s.users.Get(ctx, req.Id); err != nil {
 		log.Errorf("failed to find user: %v", err)
-		return nil, fmt.Errorf("user not found")
-	}
-	if len(users) == 0 {
-		return nil, fmt.Errorf("user does not exist")
+		return nil, err
 	}
 
 	updateData := map[string]interface{}{
 		"address":    req.Address,
 		"updated_at": time.Now(),
 	}
-	var updated []dto.User
-	err = s.db.DB.From("users").Update(updateData).Eq("id", req.Id).Execute(&updated)
+	updated, err := s.users.Update(ctx, req.Id, updateData)
 	if err != nil {
 		log.Errorf("failed to update user: %v", err)
-		return nil, fmt.Errorf("update failed")
+		return nil, err
 	}
 
-	return api.Success(updated[0]), nil
+	return api.Success(updated), nil
 }
 
 func (s *userService) ChangePassword(ctx context.Context, req dto.ChangePassword) (api.Response, error) {
-	userID, ok := ctx.Value("user_id").(string)
-	if !ok || userID == "" {
-		return nil, echo.NewHTTPError(http.StatusUnauthorized, "User ID not found in context")
-	}
-	userToken, ok := ctx.Value("token").(string)
-	if !ok || userToken == "" {
-		return nil, echo.NewHTTPError(http.StatusUnauthorized, "User Token not found in context")
+	caller, err := auth.Authenticated(ctx)
+	if err != nil {
+		return nil, err
 	}
+	userID, userToken := caller.Id, caller.Token
 
-	var users []dto.User
-	err := s.db.DB.From("users").Select("*").Eq("id", userID).Execute(&users)
+	current, err := s.users.Get(ctx, userID)
 	if err != nil {
 		log.Errorf("failed to find user: %v", err)
-		return nil, fmt.Errorf("user not found")
+		return nil, err
 	}
-	if bcrypt.CompareHashAndPassword([]byte(users[0].Password), []byte(req.OldPassword)) != nil {
-		return nil, echo.NewHTTPError(http.StatusUnauthorized, "User old password is not correct")
+	if bcrypt.CompareHashAndPassword([]byte(current.Password), []byte(req.OldPassword)) != nil {
+		return nil, errors.Unprocessable("User old password is not correct")
 	}
 
 	updateData := map[string]interface{}{
 		"password":   req.NewPassword,
 		"updated_at": time.Now(),
 	}
-	user, err := s.db.Auth.UpdateUser(ctx, userToken, updateData)
+	user, err := s.identity.UpdateUser(ctx, userToken, updateData)
 	if err != nil {
 		log.Errorf("failed to update user: %v", err)
 		return nil, err
@@ -283,48 +253,53 @@ func (s *userService) ChangePassword(ctx context.Context, req dto.ChangePassword
 		"password":   string(password),
 		"updated_at": time.Now(),
 	}
-	err = s.db.DB.From("users").Update(updateUser).Eq("id", userID).Execute(nil)
-	if err != nil {
+	if _, err := s.users.Update(ctx, userID, updateUser); err != nil {
 		log.Errorf("failed to update user: %v", err)
 		return nil, err
 	}
 	return api.Success(user), nil
 }
 
+// SendMagicLink answers the same whether or not the email is registered.
 func (s *userService) SendMagicLink(ctx context.Context, request dto.ResetPasswordRequest) (api.Response, error) {
-	err := s.db.Auth.SendMagicLink(ctx, request.Email)
-	if err != nil {
+	if request.Email == "" {
+		return nil, errors.BadRequest("email is required")
+	}
+	if request.Client.Ip != "" {
+		if err := s.limiter.Allow(ctx, "magic-link:ip:"+request.Client.Ip, magicLinkPerIp); err != nil {
+			return nil, err
+		}
+	}
+	email := strings.ToLower(strings.TrimSpace(request.Email))
+	if err := s.limiter.Allow(ctx, "magic-link:email:"+email, magicLinkPerEmail); err != nil {
 		return nil, err
 	}
-	return api.Success("email sent successfully"), nil
+	if err := s.identity.SendMagicLink(ctx, request.Email); err != nil {
+		log.Errorf("failed to send magic link: %v", err)
+	}
+	return api.Success("if the email is registered, a sign-in link has been sent"), nil
 }
 
 func (s *userService) ForgotPassword(ctx context.Context, request dto.ForgotPasswordRequest) (api.Response, error) {
-	userID, ok := ctx.Value("user_id").(string)
-	if !ok || userID == "" {
-		return nil, echo.NewHTTPError(http.StatusUnauthorized, "User ID not found in context")
-	}
-	userToken, ok := ctx.Value("token").(string)
-	if !ok || userToken == "" {
-		return nil, echo.NewHTTPError(http.StatusUnauthorized, "User Token not found in context")
-	}
-	var users []dto.User
-	err := s.db.DB.From("users").Select("*").Eq("id", userID).Execute(&users)
+	caller, err := auth.Authenticated(ctx)
 	if err != nil {
+		return nil, err
+	}
+	userID, userToken := caller.Id, caller.Token
+	if _, err := s.users.Get(ctx, userID); err != nil {
 		log.Errorf("failed to find user: %v", err)
-		return nil, fmt.Errorf("user not found")
+		return nil, err
 	}
 	updateData := map[string]interface{}{
 		"password":   request.NewPassword,
 		"updated_at": time.Now(),
 	}
-	user, err := s.db.Auth.UpdateUser(ctx, userToken, updateData)
+	user, err := s.identity.UpdateUser(ctx, userToken, updateData)
 	if err != nil {
 		log.Errorf("failed to update user: %v", err)
 		return nil, err
 	}
-	err = s.db.DB.From("users").Update(updateData).Eq("id", userID).Execute(nil)
-	if err != nil {
+	if _, err := s.users.Update(ctx, userID, updateData); err != nil {
 		log.Errorf("failed to update user: %v", err)
 		return nil, err
 	}
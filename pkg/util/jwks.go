@@ -0,0 +1,205 @@
+package util
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKey is returned for a token signed with a key the key set does not have.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// jwksRetryInterval keeps tokens with made-up key ids from making the key set fetch
+// the document on every request.
+const jwksRetryInterval = 30 * time.Second
+
+// KeySet holds the public keys of a JWKS document, loaded from a URL or a file. The
+// document is loaded again once it is older than refresh, and early when a token
+// names a key that is not known yet, which is how a rotated key gets picked up.
+type KeySet struct {
+	source  string
+	refresh time.Duration
+	client  *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	loadedAt  time.Time
+	triedAt   time.Time
+	loadError error
+}
+
+func NewKeySet(source string, refresh time.Duration) *KeySet {
+	return &KeySet{
+		source:  source,
+		refresh: refresh,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Key returns the public key with key id kid.
+func (s *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	stale := time.Since(s.loadedAt) > s.refresh
+	s.mu.RUnlock()
+	if ok && !stale {
+		return key, nil
+	}
+
+	if err := s.reload(ctx); err != nil && !ok {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+}
+
+// reload loads the document again unless that was tried a moment ago. When loading
+// fails the keys loaded before stay in use.
+func (s *KeySet) reload(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.triedAt) < jwksRetryInterval {
+		return s.loadError
+	}
+	s.triedAt = time.Now()
+
+	keys, err := s.load(ctx)
+	s.loadError = err
+	if err != nil {
+		return err
+	}
+	s.keys, s.loadedAt = keys, time.Now()
+	return nil
+}
+
+func (s *KeySet) load(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var raw []byte
+	if strings.HasPrefix(s.source, "http://") || strings.HasPrefix(s.source, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+		}
+		if raw, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20)); err != nil {
+			return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+		}
+	} else {
+		var err error
+		if raw, err = os.ReadFile(s.source); err != nil {
+			return nil, fmt.Errorf("failed to read JWKS: %w", err)
+		}
+	}
+	return ParseJWKS(raw)
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS reads the RSA and EC signing keys of a JWKS document by key id. Keys of
+// other types or for encryption are left out.
+func ParseJWKS(raw []byte) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var (
+			key crypto.PublicKey
+			err error
+		)
+		switch k.Kty {
+		case "RSA":
+			key, err = k.rsa()
+		case "EC":
+			key, err = k.ecdsa()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jwk) rsa() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 2 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("bad modulus or exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func (k jwk) ecdsa() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if _, err := key.ECDH(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
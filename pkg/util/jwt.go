@@ -1,9 +1,12 @@
 package util
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
 	"errors"
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -15,39 +18,135 @@ type CustomClaims struct {
 }
 
 var (
-	ErrInvalid   = errors.New("invalid token")
-	ErrExpired   = errors.New("token expired")
-	ErrMalformed = errors.New("malformed token")
-	ErrBadAlg    = errors.New("bad signing method")
+	ErrInvalid     = errors.New("invalid token")
+	ErrExpired     = errors.New("token expired")
+	ErrNotYetValid = errors.New("token not valid yet")
+	ErrMalformed   = errors.New("malformed token")
+	ErrBadAlg      = errors.New("bad signing method")
+	// ErrClaims is a token issued by someone else or for another audience.
+	ErrClaims = errors.New("token not issued for this service")
 )
 
-func VerifyJWT(tokenString, jwtKey string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Only accept HMAC
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrBadAlg
-		}
-		return []byte(jwtKey), nil
+// JWTOptions says which tokens a JWTVerifier accepts. Secret verifies HMAC tokens,
+// JWKS (a URL or a file path) RS256 and ES256 tokens; at least one has to be set.
+type JWTOptions struct {
+	Secret      string
+	JWKS        string
+	JWKSRefresh time.Duration
+	// Issuer and Audience are checked when set.
+	Issuer    string
+	Audience  string
+	ClockSkew time.Duration
+}
+
+// JWTVerifier checks the signature and registered claims of access tokens.
+type JWTVerifier struct {
+	secret []byte
+	keys   *KeySet
+	parser *jwt.Parser
+}
+
+func NewJWTVerifier(opts JWTOptions) (*JWTVerifier, error) {
+	if opts.Secret == "" && opts.JWKS == "" {
+		return nil, errors.New("no JWT secret or JWKS configured")
+	}
+	v := &JWTVerifier{}
+	if opts.Secret != "" {
+		v.secret = []byte(opts.Secret)
+	}
+	if opts.JWKS != "" {
+		v.keys = NewKeySet(opts.JWKS, opts.JWKSRefresh)
+	}
+
+	parserOpts := []jwt.ParserOption{jwt.WithExpirationRequired(), jwt.WithLeeway(opts.ClockSkew)}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	v.parser = jwt.NewParser(parserOpts...)
+	return v, nil
+}
+
+// Verify parses tokenString and returns the token when it is valid. Errors are one
+// of the Err values of this package, wrapping the cause.
+func (v *JWTVerifier) Verify(ctx context.Context, tokenString string) (*jwt.Token, error) {
+	token, err := v.parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return v.key(ctx, token)
 	})
 	if err != nil {
-		// Detect common errors via error message string (fallback safe way)
-		msg := err.Error()
-
-		switch {
-		case strings.Contains(msg, "token is expired"):
-			return nil, ErrExpired
-		case strings.Contains(msg, "token is malformed"):
-			return nil, ErrMalformed
-		case strings.Contains(msg, "signature is invalid"):
-			return nil, ErrInvalid
-		default:
-			return nil, fmt.Errorf("jwt parse error: %w", err)
-		}
+		return nil, classifyJWTError(err)
 	}
-
 	if !token.Valid {
 		return nil, ErrInvalid
 	}
-
 	return token, nil
 }
+
+// key picks the key token has to be signed with; the algorithm must match the kind
+// of key, so a public key can never be used as an HMAC secret.
+func (v *JWTVerifier) key(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if v.secret == nil {
+			return nil, ErrBadAlg
+		}
+		return v.secret, nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		alg := token.Method.Alg()
+		if v.keys == nil || (alg != jwt.SigningMethodRS256.Alg() && alg != jwt.SigningMethodES256.Alg()) {
+			return nil, ErrBadAlg
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, err := v.keys.Key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case *rsa.PublicKey:
+			if alg == jwt.SigningMethodRS256.Alg() {
+				return key, nil
+			}
+		case *ecdsa.PublicKey:
+			if alg == jwt.SigningMethodES256.Alg() {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: key %q is not for %s", ErrBadAlg, kid, alg)
+	default:
+		return nil, ErrBadAlg
+	}
+}
+
+// classifyJWTError wraps err in the sentinel that describes it, so callers can branch
+// with errors.Is and still log why the token was refused.
+func classifyJWTError(err error) error {
+	var kind error
+	switch {
+	case errors.Is(err, ErrBadAlg):
+		kind = ErrBadAlg
+	case errors.Is(err, jwt.ErrTokenExpired):
+		kind = ErrExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		kind = ErrNotYetValid
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		kind = ErrMalformed
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer), errors.Is(err, jwt.ErrTokenInvalidAudience):
+		kind = ErrClaims
+	default:
+		kind = ErrInvalid
+	}
+	return fmt.Errorf("%w: %w", kind, err)
+}
+
+// JWTReason returns the sentinel a verification error was classified as, which is
+// safe to show to the client where the cause is not.
+func JWTReason(err error) error {
+	for _, kind := range []error{ErrBadAlg, ErrExpired, ErrNotYetValid, ErrMalformed, ErrClaims} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return ErrInvalid
+}
@@ -1,26 +1,161 @@
 package util
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
-	"testing"
+	"github.com/stretchr/testify/require"
 )
 
 // helper to create a signed token
-func createTestToken(secret string, claims jwt.MapClaims) string {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, _ := token.SignedString([]byte(secret))
+func createTestToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	tokenString, err := token.SignedString(key)
+	require.NoError(t, err)
 	return tokenString
 }
 
+func claimsFor(exp time.Time) jwt.MapClaims {
+	return jwt.MapClaims{"sub": "u1", "iss": "https://auth.sangxanh.vn", "aud": "sangxanh", "exp": exp.Unix()}
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func jwksOf(keys map[string]interface{}) []byte {
+	var doc struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for kid, key := range keys {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			doc.Keys = append(doc.Keys, map[string]string{"kid": kid, "kty": "RSA", "use": "sig", "n": b64(k.N.Bytes()), "e": b64(big.NewInt(int64(k.E)).Bytes())})
+		case *ecdsa.PublicKey:
+			doc.Keys = append(doc.Keys, map[string]string{"kid": kid, "kty": "EC", "crv": "P-256", "x": b64(k.X.FillBytes(make([]byte, 32))), "y": b64(k.Y.FillBytes(make([]byte, 32)))})
+		}
+	}
+	raw, _ := json.Marshal(doc)
+	return raw
+}
+
 func TestVerifyJWT(t *testing.T) {
+	ctx := context.Background()
+	v, err := NewJWTVerifier(JWTOptions{Secret: "secret", Issuer: "https://auth.sangxanh.vn", Audience: "sangxanh", ClockSkew: time.Minute})
+	require.NoError(t, err)
 
 	t.Run("valid token", func(t *testing.T) {
-		tokenString := "eyJhbGciOiJIUzI1NiIsImtpZCI6Ik5aTGxqK2RLcG9sZjNuR20iLCJ0eXAiOiJKV1QifQ.eyJhYWwiOiJhYWwxIiwiYW1yIjpbeyJtZXRob2QiOiJwYXNzd29yZCIsInRpbWVzdGFtcCI6MTc0NzIwOTkxMH1dLCJhcHBfbWV0YWRhdGEiOnsicHJvdmlkZXIiOiJlbWFpbCIsInByb3ZpZGVycyI6WyJlbWFpbCJdfSwiYXVkIjoiYXV0aGVudGljYXRlZCIsImVtYWlsIjoibnRubTIwMDNAZ21haWwuY29tIiwiZXhwIjoxNzQ3MjEzNTEwLCJpYXQiOjE3NDcyMDk5MTAsImlzX2Fub255bW91cyI6ZmFsc2UsImlzcyI6Imh0dHBzOi8veGhuZGp3dHRyb2dubGp4ZGt1em0uc3VwYWJhc2UuY28vYXV0aC92MSIsInBob25lIjoiIiwicm9sZSI6ImF1dGhlbnRpY2F0ZWQiLCJzZXNzaW9uX2lkIjoiNzgzM2FiMzctMjg4NS00NjQ5LWE2MmUtZmY1N2Y1ODhkMzk5Iiwic3ViIjoiMzVhMWU3MGYtZGU5Ni00NWQ4LTg2MTctYTg1YTk4ZGZiYmQ3IiwidXNlcl9tZXRhZGF0YSI6eyJlbWFpbF92ZXJpZmllZCI6dHJ1ZX0sInVzZXJfcm9sZSI6ImFkbWluIn0.ZNu7a9WxMQpVvgfC1V1O80NHt1QEgh1FsgBQwmrl3KU"
-
-		token, err := VerifyJWT(tokenString)
+		token, err := v.Verify(ctx, createTestToken(t, jwt.SigningMethodHS256, "", []byte("secret"), claimsFor(time.Now().Add(time.Hour))))
 		assert.NoError(t, err)
-		assert.NotNil(t, token)
 		assert.True(t, token.Valid)
 	})
+
+	t.Run("expired within the clock skew", func(t *testing.T) {
+		_, err := v.Verify(ctx, createTestToken(t, jwt.SigningMethodHS256, "", []byte("secret"), claimsFor(time.Now().Add(-30*time.Second))))
+		assert.NoError(t, err)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		otherIssuer := claimsFor(time.Now().Add(time.Hour))
+		otherIssuer["iss"] = "https://elsewhere.example"
+		noExpiry := claimsFor(time.Now())
+		delete(noExpiry, "exp")
+		for name, c := range map[string]struct {
+			token string
+			want  error
+		}{
+			"expired":        {createTestToken(t, jwt.SigningMethodHS256, "", []byte("secret"), claimsFor(time.Now().Add(-time.Hour))), ErrExpired},
+			"wrong secret":   {createTestToken(t, jwt.SigningMethodHS256, "", []byte("guess"), claimsFor(time.Now().Add(time.Hour))), ErrInvalid},
+			"malformed":      {"not.a.token", ErrMalformed},
+			"other issuer":   {createTestToken(t, jwt.SigningMethodHS256, "", []byte("secret"), otherIssuer), ErrClaims},
+			"without expiry": {createTestToken(t, jwt.SigningMethodHS256, "", []byte("secret"), noExpiry), ErrInvalid},
+		} {
+			_, err := v.Verify(ctx, c.token)
+			assert.ErrorIs(t, err, c.want, name)
+		}
+
+		// the cause stays in the chain for logging
+		_, err := v.Verify(ctx, createTestToken(t, jwt.SigningMethodHS256, "", []byte("secret"), claimsFor(time.Now().Add(-time.Hour))))
+		assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+		assert.Equal(t, ErrExpired, JWTReason(err))
+	})
+}
+
+func TestVerifyJWTWithJWKSFile(t *testing.T) {
+	ctx := context.Background()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwksOf(map[string]interface{}{"rsa-1": &rsaKey.PublicKey}), 0o600))
+
+	v, err := NewJWTVerifier(JWTOptions{JWKS: path, JWKSRefresh: time.Hour})
+	require.NoError(t, err)
+
+	_, err = v.Verify(ctx, createTestToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claimsFor(time.Now().Add(time.Hour))))
+	assert.NoError(t, err)
+
+	_, err = v.Verify(ctx, createTestToken(t, jwt.SigningMethodRS512, "rsa-1", rsaKey, claimsFor(time.Now().Add(time.Hour))))
+	assert.ErrorIs(t, err, ErrBadAlg)
+	_, err = v.Verify(ctx, createTestToken(t, jwt.SigningMethodHS256, "", []byte("secret"), claimsFor(time.Now().Add(time.Hour))))
+	assert.ErrorIs(t, err, ErrBadAlg, "no secret, no HMAC tokens")
+	_, err = v.Verify(ctx, createTestToken(t, jwt.SigningMethodRS256, "rsa-2", rsaKey, claimsFor(time.Now().Add(time.Hour))))
+	assert.ErrorIs(t, err, ErrInvalid)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestVerifyJWTKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var doc atomic.Value
+	doc.Store(jwksOf(map[string]interface{}{"ec-1": &oldKey.PublicKey}))
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		_, _ = w.Write(doc.Load().([]byte))
+	}))
+	defer srv.Close()
+
+	v, err := NewJWTVerifier(JWTOptions{JWKS: srv.URL, JWKSRefresh: time.Hour})
+	require.NoError(t, err)
+	signed := func(kid string, key *ecdsa.PrivateKey) string {
+		return createTestToken(t, jwt.SigningMethodES256, kid, key, claimsFor(time.Now().Add(time.Hour)))
+	}
+
+	_, err = v.Verify(ctx, signed("ec-1", oldKey))
+	require.NoError(t, err)
+	_, err = v.Verify(ctx, signed("ec-1", oldKey))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), fetches.Load(), "the document is cached")
+
+	// the issuer rotates to a new key; the first token naming it loads the document again
+	doc.Store(jwksOf(map[string]interface{}{"ec-1": &oldKey.PublicKey, "ec-2": &newKey.PublicKey}))
+	v.keys.triedAt = time.Time{}
+	_, err = v.Verify(ctx, signed("ec-2", newKey))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	// unknown key ids do not hammer the server
+	_, err = v.Verify(ctx, signed("ec-3", newKey))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.Equal(t, int32(2), fetches.Load())
 }
@@ -0,0 +1,27 @@
+package util
+
+import (
+	"SangXanh/pkg/search"
+	"strings"
+	"unicode"
+)
+
+// Slugify turns a title into a lowercase, dash separated ASCII slug; Vietnamese
+// diacritics are dropped the same way search folds them ("Hạt giống" becomes
+// "hat-giong").
+func Slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range search.Fold(s) {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			dash = b.Len() > 0
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
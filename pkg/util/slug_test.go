@@ -0,0 +1,24 @@
+package util
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Hạt giống":                      "hat-giong",
+		"  Mùa lúa MỚI!  ":               "mua-lua-moi",
+		"Đồng bằng sông Cửu Long":        "dong-bang-song-cuu-long",
+		"Lúa ST25 -- 5kg/bao":            "lua-st25-5kg-bao",
+		"Cầy":                            "cay",
+		"Phân bón (NPK) & thuốc trừ sâu": "phan-bon-npk-thuoc-tru-sau",
+		"日本語":                            "",
+		"":                               "",
+	}
+	// decomposed input slugs the same as precomposed
+	tests["Ha\u0323t gio\u0302\u0301ng"] = "hat-giong"
+	for title, slug := range tests {
+		assert.Equal(t, slug, Slugify(title), title)
+	}
+}
@@ -0,0 +1,337 @@
+package ws
+
+import (
+	"SangXanh/pkg/enum"
+	"SangXanh/pkg/log"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/samber/do/v2"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	AdminRoom     = "role:admin"
+	BroadcastRoom = "broadcast"
+
+	writeWait    = 10 * time.Second
+	pongWait     = 60 * time.Second
+	pingPeriod   = pongWait * 9 / 10
+	historySize  = 64
+	sendBuffer   = 4 * historySize // room for a full replay of every room a client is in
+	maxReadBytes = 4096
+	// roomIdle is how long the history of a room without connections is kept for a
+	// client to resume; sweepPeriod is how often Publish looks for such rooms.
+	roomIdle    = 15 * time.Minute
+	sweepPeriod = time.Minute
+)
+
+func UserRoom(userId string) string {
+	return "user:" + userId
+}
+
+// SessionRoom holds the connections of one login session, so that they can be
+// dropped when it is logged out.
+func SessionRoom(sessionId string) string {
+	return "session:" + sessionId
+}
+
+// Message is what clients receive. Seq grows by one for every published event, so
+// a client that reconnects can ask for everything after the last Seq it saw.
+type Message struct {
+	Seq   uint64              `json:"seq,omitempty"`
+	Event enum.WebsocketEvent `json:"event"`
+	Data  any                 `json:"data,omitempty"`
+	Time  time.Time           `json:"time"`
+}
+
+// clientMessage is what clients may send: {"type":"ping"} or {"type":"resume","since":42}.
+type clientMessage struct {
+	Type  string `json:"type"`
+	Since uint64 `json:"since"`
+}
+
+type Publisher interface {
+	Publish(event enum.WebsocketEvent, data any, rooms ...string)
+}
+
+type Disconnector interface {
+	Disconnect(rooms ...string)
+}
+
+// Hub fans events out to the connections in each room and keeps the last events of
+// every room for clients that resume after a reconnect. The history of a room that
+// has had no connection for roomIdle is dropped.
+type Hub struct {
+	mu      sync.Mutex
+	seq     uint64
+	rooms   map[string]map[*client]bool
+	history map[string][]Message
+	evicted map[string]uint64
+	// idle holds when the rooms with history but no connection lost their last one
+	idle map[string]time.Time
+	// forgotten is the last Seq of the histories dropped so far
+	forgotten uint64
+	swept     time.Time
+	now       func() time.Time
+	upgrader  websocket.Upgrader
+}
+
+// client fields other than conn are guarded by Hub.mu
+type client struct {
+	hub    *Hub
+	conn   *websocket.Conn
+	send   chan Message
+	rooms  []string
+	closed bool
+}
+
+func New() *Hub {
+	return &Hub{
+		rooms:   make(map[string]map[*client]bool),
+		history: make(map[string][]Message),
+		evicted: make(map[string]uint64),
+		idle:    make(map[string]time.Time),
+		now:     time.Now,
+		upgrader: websocket.Upgrader{
+			// the API is open to every origin already, see the CORS middleware
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+func NewHub(_ do.Injector) (*Hub, error) {
+	return New(), nil
+}
+
+// RoomsFor lists the rooms a user is put in on connect; they are derived from the
+// token on every connection, so a reconnect always ends up in the same rooms.
+func RoomsFor(userId, role, sessionId string) []string {
+	rooms := []string{BroadcastRoom, UserRoom(userId)}
+	if role == enum.Admin {
+		rooms = append(rooms, AdminRoom)
+	}
+	if sessionId != "" {
+		rooms = append(rooms, SessionRoom(sessionId))
+	}
+	return rooms
+}
+
+// Publish sends one event to every connection in any of the rooms; a connection in
+// several of them receives it once.
+func (h *Hub) Publish(event enum.WebsocketEvent, data any, rooms ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	h.sweep(now)
+	h.seq++
+	msg := Message{Seq: h.seq, Event: event, Data: data, Time: now}
+	targets := make(map[*client]bool)
+	for _, room := range rooms {
+		if _, ok := h.idle[room]; !ok && len(h.rooms[room]) == 0 {
+			h.idle[room] = now
+		}
+		history := append(h.history[room], msg)
+		if len(history) > historySize {
+			h.evicted[room] = history[0].Seq
+			history = history[1:]
+		}
+		h.history[room] = history
+		for c := range h.rooms[room] {
+			targets[c] = true
+		}
+	}
+	for c := range targets {
+		c.enqueue(msg)
+	}
+}
+
+// Disconnect closes every connection in any of the rooms, e.g. those of a session
+// that was logged out; the sockets were only authenticated when they connected.
+func (h *Hub) Disconnect(rooms ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, room := range rooms {
+		for c := range h.rooms[room] {
+			c.closeLocked()
+		}
+	}
+}
+
+// sweep drops the history of the rooms that have been idle for roomIdle; it must be
+// called with h.mu held.
+func (h *Hub) sweep(now time.Time) {
+	if now.Sub(h.swept) < sweepPeriod {
+		return
+	}
+	h.swept = now
+	for room, since := range h.idle {
+		if now.Sub(since) < roomIdle {
+			continue
+		}
+		if history := h.history[room]; len(history) > 0 {
+			h.forgotten = max(h.forgotten, history[len(history)-1].Seq)
+		}
+		delete(h.history, room)
+		delete(h.evicted, room)
+		delete(h.idle, room)
+	}
+}
+
+// Serve upgrades the request and attaches the connection to rooms. Events after
+// since that are still remembered are sent first.
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, rooms []string, since uint64) error {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+	c := &client{hub: h, conn: conn, send: make(chan Message, sendBuffer), rooms: rooms}
+
+	h.mu.Lock()
+	for _, room := range rooms {
+		if h.rooms[room] == nil {
+			h.rooms[room] = make(map[*client]bool)
+		}
+		h.rooms[room][c] = true
+		delete(h.idle, room)
+	}
+	if since > 0 {
+		h.replay(c, since)
+	}
+	h.mu.Unlock()
+
+	go c.writePump()
+	go c.readPump()
+	return nil
+}
+
+// replay must be called with h.mu held, so no live event can slip in between.
+func (h *Hub) replay(c *client, since uint64) {
+	seen := make(map[uint64]bool)
+	var missed []Message
+	gap := false
+	for _, room := range c.rooms {
+		if h.evicted[room] > since {
+			gap = true
+		}
+		// without a history the room may have been dropped with events after since
+		if _, ok := h.history[room]; !ok && h.forgotten > since {
+			gap = true
+		}
+		for _, msg := range h.history[room] {
+			if msg.Seq > since && !seen[msg.Seq] {
+				seen[msg.Seq] = true
+				missed = append(missed, msg)
+			}
+		}
+	}
+	if gap {
+		c.enqueue(Message{Event: enum.ResyncEvent, Time: time.Now()})
+	}
+	sort.Slice(missed, func(i, j int) bool { return missed[i].Seq < missed[j].Seq })
+	for _, msg := range missed {
+		c.enqueue(msg)
+	}
+}
+
+// enqueue must be called with Hub.mu held. It never blocks the publisher: a client
+// that cannot keep up is dropped and is expected to reconnect and resume.
+func (c *client) enqueue(msg Message) {
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- msg:
+	default:
+		go c.close()
+	}
+}
+
+func (c *client) close() {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	c.closeLocked()
+}
+
+// closeLocked must be called with Hub.mu held.
+func (c *client) closeLocked() {
+	h := c.hub
+	if c.closed {
+		return
+	}
+	c.closed = true
+	for _, room := range c.rooms {
+		delete(h.rooms[room], c)
+		if len(h.rooms[room]) == 0 {
+			delete(h.rooms, room)
+			h.idle[room] = h.now()
+		}
+	}
+	close(c.send)
+}
+
+func (c *client) readPump() {
+	defer func() {
+		c.close()
+		_ = c.conn.Close()
+	}()
+	c.conn.SetReadLimit(maxReadBytes)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, raw, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		var in clientMessage
+		if err := json.Unmarshal(raw, &in); err != nil {
+			continue
+		}
+		switch in.Type {
+		case "ping":
+			c.hub.mu.Lock()
+			c.enqueue(Message{Event: enum.PongEvent, Time: time.Now()})
+			c.hub.mu.Unlock()
+		case "resume":
+			c.hub.mu.Lock()
+			c.hub.replay(c, in.Since)
+			c.hub.mu.Unlock()
+		}
+	}
+}
+
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				_ = c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteJSON(msg); err != nil {
+				log.Errorf("failed to write websocket message: %v", err)
+				return
+			}
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
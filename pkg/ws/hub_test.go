@@ -0,0 +1,159 @@
+package ws
+
+import (
+	"SangXanh/pkg/enum"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// connect opens a socket for a user through a test server in front of hub
+func connect(t *testing.T, hub *Hub, userId, role string, since uint64) *websocket.Conn {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = hub.Serve(w, r, RoomsFor(userId, role, ""), since)
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func read(t *testing.T, conn *websocket.Conn) Message {
+	var msg Message
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	require.NoError(t, conn.ReadJSON(&msg))
+	return msg
+}
+
+// waitForRooms blocks until n connections are attached, as Serve attaches them
+// after the handshake has completed on the client side.
+func waitForRooms(t *testing.T, hub *Hub, room string, n int) {
+	require.Eventually(t, func() bool {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		return len(hub.rooms[room]) == n
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestHub_PublishToRooms(t *testing.T) {
+	hub := New()
+	owner := connect(t, hub, "u1", enum.User, 0)
+	admin := connect(t, hub, "a1", enum.Admin, 0)
+	other := connect(t, hub, "u2", enum.User, 0)
+	waitForRooms(t, hub, BroadcastRoom, 3)
+
+	hub.Publish(enum.OrderCreatedEvent, "order-1", UserRoom("u1"), AdminRoom)
+	hub.Publish(enum.PostPublishedEvent, "post-1", BroadcastRoom)
+
+	assert.Equal(t, enum.OrderCreatedEvent, read(t, owner).Event)
+	assert.Equal(t, enum.OrderCreatedEvent, read(t, admin).Event)
+	// other only sees the broadcast
+	assert.Equal(t, enum.PostPublishedEvent, read(t, other).Event)
+	assert.Equal(t, enum.PostPublishedEvent, read(t, owner).Event)
+}
+
+func TestHub_PublishOncePerConnection(t *testing.T) {
+	hub := New()
+	admin := connect(t, hub, "a1", enum.Admin, 0)
+	waitForRooms(t, hub, AdminRoom, 1)
+
+	hub.Publish(enum.OrderCreatedEvent, "order-1", UserRoom("a1"), AdminRoom)
+	hub.Publish(enum.StockLowEvent, "option-1", AdminRoom)
+
+	assert.Equal(t, uint64(1), read(t, admin).Seq)
+	assert.Equal(t, uint64(2), read(t, admin).Seq)
+}
+
+func TestHub_ResumeAfterReconnect(t *testing.T) {
+	hub := New()
+	hub.Publish(enum.OrderCreatedEvent, "order-1", UserRoom("u1"))
+	hub.Publish(enum.OrderStatusChangedEvent, "order-1", UserRoom("u1"))
+	hub.Publish(enum.OrderCreatedEvent, "order-2", UserRoom("u2"))
+
+	conn := connect(t, hub, "u1", enum.User, 1)
+	msg := read(t, conn)
+	assert.Equal(t, uint64(2), msg.Seq)
+	assert.Equal(t, enum.OrderStatusChangedEvent, msg.Event)
+
+	require.NoError(t, conn.WriteJSON(clientMessage{Type: "resume", Since: 0}))
+	assert.Equal(t, uint64(1), read(t, conn).Seq)
+	assert.Equal(t, uint64(2), read(t, conn).Seq)
+}
+
+func TestHub_ResyncWhenHistoryIsGone(t *testing.T) {
+	hub := New()
+	for i := 0; i < historySize+5; i++ {
+		hub.Publish(enum.StockLowEvent, strconv.Itoa(i), AdminRoom)
+	}
+
+	conn := connect(t, hub, "a1", enum.Admin, 1)
+	assert.Equal(t, enum.ResyncEvent, read(t, conn).Event)
+	assert.Equal(t, uint64(6), read(t, conn).Seq)
+}
+
+func TestHub_Ping(t *testing.T) {
+	hub := New()
+	conn := connect(t, hub, "u1", enum.User, 0)
+
+	require.NoError(t, conn.WriteJSON(clientMessage{Type: "ping"}))
+	assert.Equal(t, enum.PongEvent, read(t, conn).Event)
+}
+
+func TestHub_DropIdleRooms(t *testing.T) {
+	hub := New()
+	now := time.Now()
+	hub.now = func() time.Time { return now }
+	admin := connect(t, hub, "a1", enum.Admin, 0)
+	waitForRooms(t, hub, AdminRoom, 1)
+
+	hub.Publish(enum.OrderCreatedEvent, "order-1", UserRoom("u1"), AdminRoom)
+	assert.Equal(t, uint64(1), read(t, admin).Seq)
+	now = now.Add(roomIdle)
+	hub.Publish(enum.StockLowEvent, "option-1", AdminRoom)
+	assert.Equal(t, uint64(2), read(t, admin).Seq)
+
+	hub.mu.Lock()
+	assert.NotContains(t, hub.history, UserRoom("u1"), "nobody has been in the room of u1")
+	assert.Len(t, hub.history[AdminRoom], 2, "a room with a connection keeps its history")
+	assert.NotContains(t, hub.idle, AdminRoom)
+	hub.mu.Unlock()
+
+	// u1 missed order-1 and cannot get it back any more
+	conn := connect(t, hub, "u1", enum.User, 0)
+	waitForRooms(t, hub, UserRoom("u1"), 1)
+	require.NoError(t, conn.WriteJSON(clientMessage{Type: "resume", Since: 0}))
+	assert.Equal(t, enum.ResyncEvent, read(t, conn).Event)
+}
+
+func TestHub_DisconnectSession(t *testing.T) {
+	hub := New()
+	dial := func(sessionId string) *websocket.Conn {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = hub.Serve(w, r, RoomsFor("u1", enum.User, sessionId), 0)
+		}))
+		t.Cleanup(srv.Close)
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+	phone, laptop := dial("s-phone"), dial("s-laptop")
+	waitForRooms(t, hub, UserRoom("u1"), 2)
+
+	hub.Disconnect(SessionRoom("s-phone"))
+	_ = phone.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := phone.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNoStatusReceived), "the logged out session is closed, got %v", err)
+
+	hub.Publish(enum.OrderCreatedEvent, "order-1", UserRoom("u1"))
+	assert.Equal(t, enum.OrderCreatedEvent, read(t, laptop).Event, "the other session stays")
+	waitForRooms(t, hub, UserRoom("u1"), 1)
+}
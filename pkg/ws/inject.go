@@ -0,0 +1,7 @@
+package ws
+
+import "github.com/samber/do/v2"
+
+func Inject(di do.Injector) {
+	do.Provide(di, NewHub)
+}
@@ -0,0 +1,15 @@
+-- Every status change of an order, oldest first in GET /api/order/:id/history.
+-- from_status is NULL for the entry written when the order is placed, created_by for
+-- changes the server makes on its own, such as a payment callback.
+create table if not exists order_status_history (
+  id          uuid primary key default gen_random_uuid(),
+  order_id    uuid not null references orders (id) on delete cascade,
+  from_status text,
+  to_status   text not null,
+  note        text not null default '',
+  created_by  uuid,
+  created_at  timestamptz not null default now()
+);
+
+create index if not exists order_status_history_order_idx
+  on order_status_history (order_id, created_at);
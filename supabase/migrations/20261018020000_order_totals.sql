@@ -0,0 +1,11 @@
+-- Prices are snapshotted when an order is placed, so later price changes and the
+-- client cannot alter what the order costs.
+alter table orders
+  add column if not exists subtotal       numeric(14, 2) not null default 0,
+  add column if not exists discount_total numeric(14, 2) not null default 0,
+  add column if not exists grand_total    numeric(14, 2) not null default 0;
+
+alter table order_details
+  add column if not exists unit_price      numeric(14, 2) not null default 0,
+  add column if not exists discount_amount numeric(14, 2) not null default 0,
+  add column if not exists line_total      numeric(14, 2) not null default 0;
@@ -0,0 +1,13 @@
+-- Units of a product option that can still be sold. Orders reserve stock with updates
+-- guarded on the value read, the check is the last line against overselling.
+alter table product_options add column if not exists stock int not null default 0;
+
+-- the quantity column was never read by the API, it is the best guess of what is on hand
+update product_options set stock = greatest(quantity, 0) where quantity is not null and stock = 0;
+
+alter table product_options drop constraint if exists product_options_stock_check;
+alter table product_options add constraint product_options_stock_check check (stock >= 0);
+
+-- GET /api/inventory/low-stock
+create index if not exists product_options_stock_idx
+  on product_options (stock) where deleted_at is null;
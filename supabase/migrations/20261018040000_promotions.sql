@@ -0,0 +1,45 @@
+-- Promotion codes and their redemptions. Zero limits mean no limit; empty
+-- category_ids and product_ids make a code apply to the whole order.
+create table if not exists promotions (
+  id                   uuid primary key default gen_random_uuid(),
+  code                 text not null,
+  description          text not null default '',
+  discount_type        text not null,
+  discount_value       numeric(14, 2) not null,
+  max_discount         numeric(14, 2) not null default 0,
+  min_order_value      numeric(14, 2) not null default 0,
+  starts_at            timestamptz,
+  ends_at              timestamptz,
+  usage_limit          int not null default 0,
+  usage_limit_per_user int not null default 0,
+  used_count           int not null default 0 check (used_count >= 0),
+  category_ids         uuid[] not null default '{}',
+  product_ids          uuid[] not null default '{}',
+  status               boolean not null default false,
+  created_by           uuid,
+  created_at           timestamptz not null default now(),
+  updated_at           timestamptz,
+  deleted_at           timestamptz
+);
+
+create unique index if not exists promotions_code_key on promotions (code) where deleted_at is null;
+
+create table if not exists promotion_usages (
+  id           uuid primary key default gen_random_uuid(),
+  promotion_id uuid not null references promotions (id),
+  order_id     uuid not null references orders (id) on delete cascade,
+  user_id      uuid not null,
+  discount     numeric(14, 2) not null default 0,
+  created_at   timestamptz not null default now(),
+  deleted_at   timestamptz
+);
+
+-- an order redeems a code once; a released usage stays for the record
+create unique index if not exists promotion_usages_order_key
+  on promotion_usages (promotion_id, order_id) where deleted_at is null;
+create index if not exists promotion_usages_user_idx
+  on promotion_usages (promotion_id, user_id) where deleted_at is null;
+
+alter table orders
+  add column if not exists promotion_code     text,
+  add column if not exists promotion_discount numeric(14, 2) not null default 0;
@@ -0,0 +1,31 @@
+-- Payments of orders and the gateway callbacks applied to them. A callback is
+-- recognised by its event id, so a redelivery is ignored.
+create table if not exists payments (
+  id             uuid primary key default gen_random_uuid(),
+  order_id       uuid not null references orders (id) on delete cascade,
+  user_id        uuid not null,
+  method         text not null,
+  status         text not null default 'pending',
+  amount         numeric(14, 2) not null,
+  reference      text not null unique,
+  transaction_id text,
+  instructions   jsonb,
+  paid_at        timestamptz,
+  created_at     timestamptz not null default now(),
+  updated_at     timestamptz
+);
+
+create index if not exists payments_order_idx on payments (order_id, created_at desc);
+-- only one payment may be open per order
+create unique index if not exists payments_order_pending_key on payments (order_id) where status = 'pending';
+
+create table if not exists payment_events (
+  id         uuid primary key default gen_random_uuid(),
+  payment_id uuid not null references payments (id) on delete cascade,
+  method     text not null,
+  event_id   text not null,
+  status     text not null,
+  payload    jsonb,
+  created_at timestamptz not null default now(),
+  unique (method, event_id)
+);
@@ -0,0 +1,12 @@
+-- Titles, slugs and the first publication date of posts. Posts written before have no
+-- title to derive a slug from and get one from their id.
+alter table posts
+  add column if not exists title        text not null default '',
+  add column if not exists slug         text,
+  add column if not exists published_at timestamptz;
+
+update posts set slug = 'post-' || left(id::text, 8) where slug is null;
+-- status true means published; the creation date is the best guess for those
+update posts set published_at = created_at where status and published_at is null;
+
+create unique index if not exists posts_slug_key on posts (slug) where deleted_at is null;
@@ -0,0 +1,31 @@
+-- Full-text product search used by GET /api/product/search. Text is compared
+-- lowercased and unaccented, so "cay xanh" finds "Cây xanh"; word similarity on the
+-- name, code and category keeps a typo from losing the match.
+create extension if not exists unaccent;
+create extension if not exists pg_trgm;
+
+create or replace function search_products(search text, max_results int default 1000)
+returns table (id uuid, rank real)
+language sql stable
+as $$
+  with q as (
+    select lower(unaccent(search)) as term
+  ), docs as (
+    select p.id,
+           lower(unaccent(coalesce(p.name, '') || ' ' || coalesce(p.product_code, '') || ' ' || coalesce(c.name, ''))) as head,
+           setweight(to_tsvector('simple', lower(unaccent(coalesce(p.name, '')))), 'A') ||
+           setweight(to_tsvector('simple', lower(unaccent(coalesce(p.product_code, '')))), 'B') ||
+           setweight(to_tsvector('simple', lower(unaccent(coalesce(c.name, '')))), 'C') ||
+           setweight(to_tsvector('simple', lower(unaccent(coalesce(p.description, '')))), 'D') as document
+    from products p
+    left join categories c on c.id = p.category_id
+    where p.deleted_at is null
+  )
+  select docs.id,
+         (ts_rank(docs.document, plainto_tsquery('simple', q.term)) + word_similarity(q.term, docs.head))::real as rank
+  from docs, q
+  where docs.document @@ plainto_tsquery('simple', q.term)
+     or q.term <% docs.head
+  order by rank desc, docs.id
+  limit max_results;
+$$;
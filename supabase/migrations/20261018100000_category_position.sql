@@ -0,0 +1,3 @@
+-- Sibling order of the category menu, set by PUT /api/category/move and /reorder.
+-- Existing categories all start at 0 and keep their creation order until reordered.
+alter table categories add column if not exists position int not null default 0;
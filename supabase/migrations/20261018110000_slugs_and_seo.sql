@@ -0,0 +1,68 @@
+-- Slugs and SEO fields for products and categories, and the slugs products,
+-- categories and posts had before, which GET /api/<entity>/slug/:slug redirects.
+create extension if not exists unaccent;
+
+alter table products
+  add column if not exists slug text,
+  add column if not exists meta_title text,
+  add column if not exists meta_description text,
+  add column if not exists canonical_image text;
+
+alter table categories
+  add column if not exists slug text,
+  add column if not exists meta_title text,
+  add column if not exists meta_description text,
+  add column if not exists canonical_image text;
+
+create table if not exists slug_history (
+  id         uuid primary key default gen_random_uuid(),
+  entity     text not null,
+  entity_id  uuid not null,
+  slug       text not null,
+  created_at timestamptz not null default now(),
+  unique (entity, slug)
+);
+
+-- same as util.Slugify, for the rows that exist already
+create or replace function slugify(value text)
+returns text
+language sql stable
+as $$
+  select trim(both '-' from regexp_replace(lower(unaccent(translate(value, 'đĐ', 'dd'))), '[^a-z0-9]+', '-', 'g'))
+$$;
+
+-- Gives every live row without a slug the first of base, base-2, base-3, ... that no
+-- other live row has, like slugRepository.Unique, oldest rows first. Numbering
+-- against the slugs taken so far, rather than within each base, keeps "Cay xanh 2"
+-- from colliding with the second "Cây xanh".
+do $$
+declare
+  entity record;
+  item record;
+  candidate text;
+  taken boolean;
+  n int;
+begin
+  for entity in select * from (values ('products', 'product'), ('categories', 'category')) as t(tbl, fallback) loop
+    for item in execute format(
+      'select id, coalesce(nullif(slugify(name), %L), %L) as base from %I
+       where slug is null and deleted_at is null order by created_at, id',
+      '', entity.fallback, entity.tbl)
+    loop
+      candidate := item.base;
+      n := 1;
+      loop
+        execute format('select exists (select 1 from %I where slug = $1 and deleted_at is null)', entity.tbl)
+          into taken using candidate;
+        exit when not taken;
+        n := n + 1;
+        candidate := item.base || '-' || n;
+      end loop;
+      execute format('update %I set slug = $1 where id = $2', entity.tbl) using candidate, item.id;
+    end loop;
+  end loop;
+end
+$$;
+
+create unique index if not exists products_slug_key on products (slug) where deleted_at is null;
+create unique index if not exists categories_slug_key on categories (slug) where deleted_at is null;
@@ -0,0 +1,19 @@
+-- Devices users are signed in on, keyed by the session_id claim of their access
+-- tokens. A revoked session is refused by the authentication middleware.
+create table if not exists user_sessions (
+  id           uuid primary key,
+  user_id      uuid not null,
+  device       text not null default '',
+  user_agent   text not null default '',
+  ip           text not null default '',
+  created_at   timestamptz not null default now(),
+  last_seen_at timestamptz not null default now(),
+  revoked_at   timestamptz
+);
+
+create index if not exists user_sessions_user_active_idx
+  on user_sessions (user_id, last_seen_at desc) where revoked_at is null;
+
+-- set by POST /api/auth/logout-all: tokens issued before it are refused, also those
+-- of sessions the server has not seen yet
+alter table users add column if not exists sessions_revoked_at timestamptz;
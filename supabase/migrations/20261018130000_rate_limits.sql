@@ -0,0 +1,78 @@
+-- Counters of the rate limiter shared by every API instance (RATE_LIMIT_STORE=supabase):
+-- token buckets of the throttled requests and failed logins of the accounts.
+create table if not exists rate_limit_buckets (
+  key        text primary key,
+  tokens     double precision not null,
+  updated_at timestamptz not null default now()
+);
+
+create table if not exists rate_limit_failures (
+  key             text primary key,
+  failures        int not null,
+  last_failure_at timestamptz not null default now()
+);
+
+-- Takes a token from the bucket of bucket_key and returns 0, or the seconds until
+-- the next token when the bucket is empty.
+create or replace function rate_limit_take(bucket_key text, burst int, per_seconds double precision)
+returns double precision
+language plpgsql
+as $$
+declare
+  per_second double precision := burst / per_seconds;
+  available double precision;
+begin
+  insert into rate_limit_buckets as b (key, tokens, updated_at)
+  values (bucket_key, burst, now())
+  on conflict (key) do update
+    set tokens = least(burst, b.tokens + extract(epoch from now() - b.updated_at) * per_second),
+        updated_at = now()
+  returning tokens into available;
+
+  -- buckets of keys that went quiet are full again and can go
+  if random() < 0.001 then
+    delete from rate_limit_buckets where updated_at < now() - interval '1 day';
+    delete from rate_limit_failures where last_failure_at < now() - interval '7 days';
+  end if;
+
+  if available >= 1 then
+    update rate_limit_buckets set tokens = tokens - 1 where key = bucket_key;
+    return 0;
+  end if;
+  return (1 - available) / per_second;
+end;
+$$;
+
+create or replace function rate_limit_failures(failure_key text)
+returns table (failures int, last_failure_at timestamptz)
+language sql stable
+as $$
+  select f.failures, f.last_failure_at from rate_limit_failures f where f.key = failure_key;
+$$;
+
+-- Counts a failure of failure_key, starting over when the last one is older than
+-- window_seconds.
+create or replace function rate_limit_fail(failure_key text, window_seconds double precision)
+returns table (failures int, last_failure_at timestamptz)
+language sql
+as $$
+  insert into rate_limit_failures as f (key, failures, last_failure_at)
+  values (failure_key, 1, now())
+  on conflict (key) do update
+    set failures = case
+          when f.last_failure_at < now() - make_interval(secs => window_seconds) then 1
+          else f.failures + 1
+        end,
+        last_failure_at = now()
+  returning f.failures, f.last_failure_at;
+$$;
+
+create or replace function rate_limit_reset(failure_key text)
+returns int
+language sql
+as $$
+  with deleted as (
+    delete from rate_limit_failures f where f.key = failure_key returning 1
+  )
+  select count(*)::int from deleted;
+$$;
@@ -0,0 +1,105 @@
+-- Facet filters and counts of GET /api/product, done in the database rather than by
+-- reading every product into the API.
+
+-- One row per value of a live product variant, e.g. ("Kích thước", "Lớn"); the
+-- variant filter joins it to products.
+create or replace view product_variant_values
+with (security_invoker = true) as
+select v.product_id, trim(v.name) as name, trim(d.detail ->> 'name') as value
+from product_variants v
+cross join lateral jsonb_array_elements(
+  case when jsonb_typeof(v.detail::jsonb) = 'array' then v.detail::jsonb else '[]'::jsonb end
+) as d(detail)
+where v.deleted_at is null
+  and d.detail ->> 'name' is not null;
+
+create index if not exists product_variants_product_id_idx
+  on product_variants (product_id) where deleted_at is null;
+
+create index if not exists product_options_product_id_idx
+  on product_options (product_id) where deleted_at is null;
+
+-- Counts the products for every facet value with the filters of the other facets
+-- applied, as rows of (facet, name, value, count):
+--   category  the products of each category itself, rolled up to the parents by the API
+--   price     the products of each bucket, value is its position from 1
+--   variant   the products of each value of the variant name
+--   in_stock  the products with a live option in stock
+-- The first parameters are the filters of productQuery in pkg/repository and must
+-- stay in step with it; category_ids is the subtree of the selected categories,
+-- variants maps a variant name to the values asked for.
+create or replace function product_facets(
+  name_filter  text default null,
+  category_id  uuid default null,
+  is_discount  boolean default false,
+  greater_than numeric default null,
+  smaller_than numeric default null,
+  category_ids uuid[] default null,
+  price_min    numeric[] default null,
+  price_max    numeric[] default null,
+  variants     jsonb default '{}',
+  in_stock     boolean default false,
+  bucket_min   numeric[] default '{}',
+  bucket_max   numeric[] default '{}'
+)
+returns table (facet text, name text, value text, count bigint)
+language sql stable
+as $$
+  with base as (
+    select p.id, p.category_id, p.price
+    from products p
+    where p.deleted_at is null
+      and (name_filter is null or p.name ilike '%' || name_filter || '%')
+      and (product_facets.category_id is null or p.category_id = product_facets.category_id)
+      and (not is_discount or p.discount is not null)
+      and (greater_than is null or p.price > greater_than)
+      and (smaller_than is null or p.price < smaller_than)
+  ), vals as (
+    select distinct v.product_id, v.name, v.value
+    from product_variant_values v
+    join base b on b.id = v.product_id
+  ), flags as (
+    select b.id, b.category_id, b.price,
+           (product_facets.category_ids is null or b.category_id = any(product_facets.category_ids)) as in_category,
+           (price_min is null or exists (
+              select 1 from unnest(price_min, price_max) as r(lo, hi)
+              where b.price >= r.lo and (r.hi is null or b.price < r.hi))) as in_price,
+           exists (
+              select 1 from product_options o
+              where o.product_id = b.id and o.deleted_at is null and o.stock > 0) as stocked,
+           -- the variants named in the filter the product has none of the values of
+           coalesce((
+              select array_agg(f.key)
+              from jsonb_each(variants) as f(key, wanted)
+              where not exists (
+                select 1 from vals
+                where vals.product_id = b.id and vals.name = f.key
+                  and vals.value in (select jsonb_array_elements_text(f.wanted)))), '{}') as missed
+    from base b
+  ), counted as (
+    select * from flags where not product_facets.in_stock or stocked
+  )
+  select 'category'::text, null::text, c.category_id::text, count(*)
+  from counted c
+  where c.in_price and cardinality(c.missed) = 0
+  group by c.category_id
+  union all
+  select 'price', null, r.n::text, count(c.id)
+  from unnest(bucket_min, bucket_max) with ordinality as r(lo, hi, n)
+  left join counted c
+    on c.in_category and cardinality(c.missed) = 0
+   and c.price >= r.lo and (r.hi is null or c.price < r.hi)
+  group by r.n
+  union all
+  -- a variant is counted without its own filter, so its other values stay visible
+  select 'variant', v.name, v.value, count(*)
+  from counted c
+  join vals v on v.product_id = c.id
+  where c.in_category and c.in_price
+    and (cardinality(c.missed) = 0 or c.missed = array[v.name])
+  group by v.name, v.value
+  union all
+  select 'in_stock', null, null, count(*)
+  from flags f
+  where f.in_category and f.in_price and cardinality(f.missed) = 0 and f.stocked;
+$$;